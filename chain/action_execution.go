@@ -0,0 +1,147 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"encoding/binary"
+
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ava-labs/avalanchego/database/versiondb"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/hashing"
+)
+
+// Blocks close the actions whose voting ended before their timestamp, after
+// executing their txs: approved actions whose [VotingPolicy] executes them
+// automatically run once their timelock passed, and rejected or expired ones
+// are recorded. Each action closed this way gets a receipt, stored after the
+// receipts of the txs of the block, under [AutoExecutionID].
+
+// ActionReceipt describes an action closed by a block rather than a tx
+type ActionReceipt struct {
+	ActionID   ids.ShortID `serialize:"true" json:"actionId"`
+	ActionType uint64      `serialize:"true" json:"actionType"`
+	Status     string      `serialize:"true" json:"status"`
+	// Why the execution failed
+	Error string `serialize:"true" json:"error,omitempty"`
+}
+
+// AutoExecutionID identifies the closing of [actionID] by the block at
+// [height]. It doesn't depend on the block ID, which commits to the state
+// the execution changes.
+func AutoExecutionID(height uint64, actionID ids.ShortID) ids.ID {
+	b := make([]byte, 8+len(actionID))
+	binary.BigEndian.PutUint64(b, height)
+	copy(b[8:], actionID[:])
+	return ids.ID(hashing.ComputeHash256Array(b))
+}
+
+// ProcessActions closes the actions due at [blkTime] in [db] and returns
+// their receipts. Actions are only closed by blocks from [ForkPhase1].
+func ProcessActions(g *Genesis, db database.Database, rules Rules, blkID ids.ID, height uint64, blkTime uint64) ([]*Receipt, error) {
+	if !rules.IsPhase1 {
+		return nil, nil
+	}
+	actions, err := SamaNew(db, g).GetDueActions(blkTime)
+	if err != nil {
+		return nil, err
+	}
+	receipts := []*Receipt{}
+	for _, action := range actions {
+		receipt, err := processAction(g, db, action, height, blkTime)
+		if err != nil {
+			return nil, err
+		}
+		if receipt != nil {
+			receipt.BlkID = blkID
+			receipts = append(receipts, receipt)
+		}
+	}
+	return receipts, nil
+}
+
+// processAction closes [action] if it is due, returning nil if it is not
+func processAction(g *Genesis, db database.Database, action *ActionMeta, height uint64, blkTime uint64) (*Receipt, error) {
+	state := SamaNew(db, g)
+	policy, err := state.GetVotingPolicy(action.ActionType)
+	if err != nil {
+		return nil, err
+	}
+	status := action.StatusAt(blkTime)
+	if status == ActionApproved && !(policy.AutoExecute && blkTime > action.EndTime+policy.Timelock) {
+		// Waiting for its timelock or a [GovernTx]
+		return nil, nil
+	}
+
+	txID := AutoExecutionID(height, action.ActionID)
+	tdb := versiondb.New(db)
+	defer tdb.Abort()
+	tstate := SamaNew(tdb, g)
+	result := &ActionReceipt{ActionID: action.ActionID, ActionType: action.ActionType}
+	if status == ActionApproved {
+		status, result.Error, err = executeAction(&TransactionContext{
+			Genesis:   g,
+			Database:  tdb,
+			BlockTime: blkTime,
+			TxID:      txID,
+			State:     tstate,
+		}, action)
+		if err != nil {
+			return nil, err
+		}
+	}
+	result.Status = status
+	action.Transition(status, txID, blkTime)
+	if err := tstate.PutAction(action.ActionID, action); err != nil {
+		return nil, err
+	}
+
+	batch, err := tdb.CommitBatch()
+	if err != nil {
+		return nil, err
+	}
+	r := &effectRecorder{db: db}
+	if err := batch.Replay(r); err != nil {
+		return nil, err
+	}
+	effects, err := r.effects()
+	if err != nil {
+		return nil, err
+	}
+	if err := batch.Write(); err != nil {
+		return nil, err
+	}
+	return &Receipt{
+		TxID:    txID,
+		Hght:    height,
+		Effects: effects,
+		Actions: []*ActionReceipt{result},
+	}, nil
+}
+
+// executeAction runs an approved [action] in [t.Database], returning the
+// status it ends in and why it failed. Failed executions leave no changes.
+func executeAction(t *TransactionContext, action *ActionMeta) (string, string, error) {
+	ga, err := GetGovernanceAction(action.ActionType)
+	if err != nil {
+		return "", "", err
+	}
+	approved, err := ga.Approved(t.State, action)
+	if err != nil {
+		return "", "", err
+	}
+	if !approved {
+		// Voters lost their stake since they voted
+		return ActionRejected, "", nil
+	}
+	edb := versiondb.New(t.Database)
+	defer edb.Abort()
+	et := *t
+	et.Database = edb
+	et.State = SamaNew(edb, t.Genesis)
+	if err := ga.Execute(&et, action); err != nil {
+		return ActionFailed, err.Error(), nil
+	}
+	return ActionExecuted, "", edb.Commit()
+}
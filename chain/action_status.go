@@ -4,8 +4,9 @@
 package chain
 
 import (
+	"encoding/binary"
+	"errors"
 	"fmt"
-	"time"
 
 	"github.com/ava-labs/avalanchego/database"
 	"github.com/ava-labs/avalanchego/ids"
@@ -19,6 +20,11 @@ const (
 	actionTypeAddUserType
 	actionTypeModifyUserType
 	actionTypeModifyFoundation
+	actionTypeSetVotingPolicy
+	actionTypeRemoveStaker
+	actionTypeSuspendStaker
+	actionTypeSlashStaker
+	actionTypeSetSlashingPolicy
 	actionTypeEnd
 )
 
@@ -38,81 +44,250 @@ func baseActionsPrefix() (k []byte) {
 	return
 }
 
+// actionsMigratedKey is set once the actions stored before [ForkPhase1] moved
+// to the current layout
+var actionsMigratedKey = []byte{actionsPrefix}
+
 var _ ActionsState = &actionsState{}
 
+// Actions are pending until they have enough votes, and approved ones can be
+// executed until [ActionExecutionWindow] after voting ends. Actions are kept
+// once they are closed (executed, rejected, expired, withdrawn or failed) so
+// every governance decision can be audited.
+const (
+	ActionPending   = "pending"
+	ActionApproved  = "approved"
+	ActionExecuted  = "executed"
+	ActionRejected  = "rejected"
+	ActionExpired   = "expired"
+	ActionWithdrawn = "withdrawn"
+	// Automatic execution failed
+	ActionFailed = "failed"
+)
+
+var (
+	ActionExecutionWindow = Seconds7Day
+
+	ErrActionClosed = errors.New("action is closed")
+)
+
+// ActionTransition records the tx that moved an action to [Status]. Actions
+// closed by a block are recorded with their [AutoExecutionID].
+type ActionTransition struct {
+	Status    string `serialize:"true" json:"status"`
+	TxID      ids.ID `serialize:"true" json:"txId"`
+	BlockTime uint64 `serialize:"true" json:"blockTime"`
+}
+
 type ActionMeta struct {
-	ActionID   ids.ShortID      `serialize:"true" json:"actionID"`
-	ActionType uint64           `serialize:"true" json:"actionType"`
-	StartTime  uint64           `serialize:"true" json:"startTime"`
-	EndTime    uint64           `serialize:"true" json:"endTime"`
-	TxIDs      []ids.ID         `serialize:"true" json:"txids"`
-	Voters     []common.Address `serialize:"true" json:"voters"`
-	Key        string           `serialize:"true" json:"key"`
-	NewValue   string           `serialize:"true" json:"newValue"`
+	ActionID   ids.ShortID `serialize:"true" json:"actionID"`
+	ActionType uint64      `serialize:"true" json:"actionType"`
+	StartTime  uint64      `serialize:"true" json:"startTime"`
+	EndTime    uint64      `serialize:"true" json:"endTime"`
+	TxIDs      []ids.ID    `serialize:"true" json:"txids"`
+	// Voters for, against and abstaining
+	Voters      []common.Address    `serialize:"true" json:"voters"`
+	Against     []common.Address    `serializeV1:"true" json:"against"`
+	Abstain     []common.Address    `serializeV1:"true" json:"abstain"`
+	Key         string              `serialize:"true" json:"key"`
+	NewValue    string              `serialize:"true" json:"newValue"`
+	Proposer    common.Address      `serializeV1:"true" json:"proposer"`
+	Status      string              `serializeV1:"true" json:"status"`
+	Transitions []*ActionTransition `serializeV1:"true" json:"transitions"`
+}
+
+// StatusAt returns the status of the action at [blkTime], including
+// outcomes not recorded yet
+func (a *ActionMeta) StatusAt(blkTime uint64) string {
+	switch a.Status {
+	case "", ActionPending:
+		if blkTime > a.EndTime {
+			return ActionRejected
+		}
+		return ActionPending
+	case ActionApproved:
+		if blkTime > a.EndTime+ActionExecutionWindow {
+			return ActionExpired
+		}
+	}
+	return a.Status
+}
+
+// VoteOf returns the vote of [voter] on the action, or "" if they didn't vote
+func (a *ActionMeta) VoteOf(voter common.Address) string {
+	for choice, voters := range map[string][]common.Address{
+		VoteFor:     a.Voters,
+		VoteAgainst: a.Against,
+		VoteAbstain: a.Abstain,
+	} {
+		for _, v := range voters {
+			if v == voter {
+				return choice
+			}
+		}
+	}
+	return ""
+}
+
+// setVote replaces the vote of [voter] with [choice] ("" removes it)
+func (a *ActionMeta) setVote(voter common.Address, choice string) {
+	remove := func(voters []common.Address) []common.Address {
+		kept := make([]common.Address, 0, len(voters))
+		for _, v := range voters {
+			if v != voter {
+				kept = append(kept, v)
+			}
+		}
+		return kept
+	}
+	a.Voters = remove(a.Voters)
+	a.Against = remove(a.Against)
+	a.Abstain = remove(a.Abstain)
+	switch choice {
+	case VoteFor:
+		a.Voters = append(a.Voters, voter)
+	case VoteAgainst:
+		a.Against = append(a.Against, voter)
+	case VoteAbstain:
+		a.Abstain = append(a.Abstain, voter)
+	}
+}
+
+// closed reports whether the outcome of the action is recorded
+func (a *ActionMeta) closed() bool {
+	return a.Status != "" && a.Status != ActionPending && a.Status != ActionApproved
+}
+
+// IsOpen reports whether the action can still be voted on or executed at
+// [blkTime]
+func (a *ActionMeta) IsOpen(blkTime uint64) bool {
+	status := a.StatusAt(blkTime)
+	return status == ActionPending || status == ActionApproved
+}
+
+// Transition moves the action to [status], recording the outcome it reached
+// before [blkTime] first
+func (a *ActionMeta) Transition(status string, txID ids.ID, blkTime uint64) {
+	if current := a.StatusAt(blkTime); current != a.Status {
+		a.record(current, txID, blkTime)
+	}
+	if status != a.Status {
+		a.record(status, txID, blkTime)
+	}
+}
+
+func (a *ActionMeta) record(status string, txID ids.ID, blkTime uint64) {
+	a.Status = status
+	a.Transitions = append(a.Transitions, &ActionTransition{
+		Status:    status,
+		TxID:      txID,
+		BlockTime: blkTime,
+	})
 }
 
 type ActionsState interface {
-	GetActionMeta(actionID ids.ShortID) (*ActionMeta, bool, error)
-	PutAction(db database.Database, actionID ids.ShortID, pmeta *ActionMeta) error
+	GetActionMeta(actionID ids.ShortID, blkTime uint64) (*ActionMeta, bool, error)
+	PutAction(actionID ids.ShortID, pmeta *ActionMeta) error
 	GetActions() ([]*ActionMeta, error)
-	DelAction(db database.Database, actionID ids.ShortID) error
-	ReloadActions(db database.Database) error
-	CacheActionsCommit() error
-	CacheActionsAbort() error
+	DelAction(actionID ids.ShortID) error
 	GetVotersNum(actionType uint64, key string) (int, ids.ShortID, error)
-	ProposalRepeat(actionID ids.ShortID, key string, newValue string) error
+	ProposalRepeat(actionID ids.ShortID, actionType uint64, key string, newValue string, blkTime uint64) error
+	SettleActions(actionType uint64, key string, txID ids.ID, blkTime uint64) error
+	GetActionsByKey(key string) ([]*ActionMeta, error)
+	GetDueActions(blkTime uint64) ([]*ActionMeta, error)
 	CheckActionType(actionType uint64) bool
 }
 
 type actionsState struct {
-	curActions        map[ids.ShortID]*ActionMeta
-	pendingActionsAdd map[ids.ShortID]*ActionMeta
-	pendingActionsDel map[ids.ShortID]*ActionMeta
+	db database.Database
 }
 
-func NewActionsState(db database.Database) (*actionsState, error) {
-	s := &actionsState{
-		curActions:        make(map[ids.ShortID]*ActionMeta),
-		pendingActionsAdd: make(map[ids.ShortID]*ActionMeta),
-		pendingActionsDel: make(map[ids.ShortID]*ActionMeta),
-	}
-
-	err := s.ReloadActions(db)
-	return s, err
+func NewActionsState(db database.Database) *actionsState {
+	return &actionsState{db: db}
 }
 
-func (s *actionsState) GetActionMeta(actionID ids.ShortID) (*ActionMeta, bool, error) {
-	pmeta, ok := s.curActions[actionID]
-	if !ok {
-		return nil, false, nil
-	}
-	if pmeta.EndTime < uint64(time.Now().Unix()) {
-		return nil, false, fmt.Errorf("action is overdue ")
+// GetActionMeta returns the action with [actionID]. Its status at [blkTime]
+// is given by [ActionMeta.StatusAt].
+func (s *actionsState) GetActionMeta(actionID ids.ShortID, blkTime uint64) (*ActionMeta, bool, error) {
+	pmeta := new(ActionMeta)
+	exist, err := getState(s.db, PrefixActionsKey(actionID), pmeta)
+	if !exist || err != nil {
+		return nil, false, err
 	}
 	return pmeta, true, nil
 }
 
-func (s *actionsState) PutAction(db database.Database, actionID ids.ShortID, pmeta *ActionMeta) error {
-	delete(s.pendingActionsDel, actionID)
-	s.pendingActionsAdd[actionID] = pmeta
-	k := PrefixActionsKey(actionID)
-	pvmeta, err := Marshal(pmeta)
+func (s *actionsState) PutAction(actionID ids.ShortID, pmeta *ActionMeta) error {
+	prev := new(ActionMeta)
+	exist, err := getState(s.db, PrefixActionsKey(actionID), prev)
 	if err != nil {
 		return err
 	}
-	return db.Put(k, pvmeta)
+	if exist && prev.Key != pmeta.Key {
+		if err := s.db.Delete(PrefixActionKeyIndex(prev.Key, actionID)); err != nil {
+			return err
+		}
+	}
+	if err := s.db.Put(PrefixActionKeyIndex(pmeta.Key, actionID), nil); err != nil {
+		return err
+	}
+	if exist && !prev.closed() && (pmeta.closed() || prev.EndTime != pmeta.EndTime) {
+		if err := s.db.Delete(PrefixActionDeadlineIndex(prev.EndTime, actionID)); err != nil {
+			return err
+		}
+	}
+	if !pmeta.closed() {
+		if err := s.db.Put(PrefixActionDeadlineIndex(pmeta.EndTime, actionID), nil); err != nil {
+			return err
+		}
+	}
+	return putState(s.db, PrefixActionsKey(actionID), pmeta)
+}
+
+// GetActionsByKey returns all actions targeting [key], ordered by ID
+func (s *actionsState) GetActionsByKey(key string) ([]*ActionMeta, error) {
+	actions := []*ActionMeta(nil)
+	err := iterateState(s.db, baseActionKeyIndexPrefix(key), len(ids.ShortID{}), func(k []byte, _ []byte) error {
+		actionID, err := ids.ToShortID(k[len(k)-len(ids.ShortID{}):])
+		if err != nil {
+			return err
+		}
+		pmeta := new(ActionMeta)
+		exist, err := getState(s.db, PrefixActionsKey(actionID), pmeta)
+		if err != nil {
+			return err
+		}
+		if exist {
+			actions = append(actions, pmeta)
+		}
+		return nil
+	})
+	return actions, err
 }
 
 func (s *actionsState) GetActions() ([]*ActionMeta, error) {
 	actions := []*ActionMeta(nil)
-	for _, action := range s.curActions {
-		actions = append(actions, action)
-	}
-	return actions, nil
+	err := iterateState(s.db, baseActionsPrefix(), len(ids.ShortID{}), func(_ []byte, v []byte) error {
+		pmeta := new(ActionMeta)
+		if _, err := Unmarshal(v, pmeta); err != nil {
+			return err
+		}
+		actions = append(actions, pmeta)
+		return nil
+	})
+	return actions, err
 }
 
 func (s *actionsState) GetVotersNum(actionType uint64, key string) (int, ids.ShortID, error) {
-	for _, action := range s.curActions {
+	actions, err := s.GetActionsByKey(key)
+	if err != nil {
+		return 0, ids.ShortID{}, err
+	}
+	for _, action := range actions {
+		// Skip actions whose outcome is recorded
+		if action.closed() {
+			continue
+		}
 		if action.ActionType == actionType && action.Key == key {
 			num := len(action.Voters)
 			return num, action.ActionID, nil
@@ -121,92 +296,159 @@ func (s *actionsState) GetVotersNum(actionType uint64, key string) (int, ids.Sho
 	return 0, ids.ShortID{}, fmt.Errorf("not found ")
 }
 
-func (s *actionsState) DelAction(db database.Database, actionID ids.ShortID) error {
-	delete(s.pendingActionsAdd, actionID)
-	s.pendingActionsDel[actionID] = &ActionMeta{}
-
+func (s *actionsState) DelAction(actionID ids.ShortID) error {
+	pmeta := new(ActionMeta)
+	exist, err := getState(s.db, PrefixActionsKey(actionID), pmeta)
+	if err != nil || !exist {
+		return err
+	}
+	if err := s.db.Delete(PrefixActionKeyIndex(pmeta.Key, actionID)); err != nil {
+		return err
+	}
+	if err := s.db.Delete(PrefixActionDeadlineIndex(pmeta.EndTime, actionID)); err != nil {
+		return err
+	}
 	k := PrefixActionsKey(actionID)
-	return db.Delete(k)
-
+	return s.db.Delete(k)
 }
 
-func (s *actionsState) ReloadActions(db database.Database) error {
-	basePrefix := baseActionsPrefix()
-	cursor := db.NewIteratorWithPrefix(basePrefix)
+// GetDueActions returns the actions whose voting ended before [blkTime] and
+// whose outcome is not recorded yet, ordered by end time
+func (s *actionsState) GetDueActions(blkTime uint64) ([]*ActionMeta, error) {
+	prefix := baseActionDeadlinePrefix()
+	cursor := s.db.NewIteratorWithPrefix(prefix)
 	defer cursor.Release()
+
+	actionIDs := []ids.ShortID{}
 	for cursor.Next() {
-		nmeta := cursor.Value()
+		k := cursor.Key()
+		if len(k) != len(prefix)+8+len(ids.ShortID{}) {
+			continue
+		}
+		if binary.BigEndian.Uint64(k[len(prefix):]) >= blkTime {
+			break
+		}
+		actionID, err := ids.ToShortID(k[len(prefix)+8:])
+		if err != nil {
+			return nil, err
+		}
+		actionIDs = append(actionIDs, actionID)
+	}
+	if err := cursor.Error(); err != nil {
+		return nil, err
+	}
 
+	actions := make([]*ActionMeta, 0, len(actionIDs))
+	for _, actionID := range actionIDs {
 		pmeta := new(ActionMeta)
-		if _, err := Unmarshal(nmeta, pmeta); err != nil {
-			return err
+		exist, err := getState(s.db, PrefixActionsKey(actionID), pmeta)
+		if err != nil {
+			return nil, err
 		}
-		if pmeta.EndTime < uint64(time.Now().Unix()) {
-			//delete
-			continue
+		if exist {
+			actions = append(actions, pmeta)
 		}
-
-		s.curActions[pmeta.ActionID] = pmeta
 	}
-	return nil
+	return actions, nil
 }
 
-func (s *actionsState) CacheActionsCommit() error {
-	for actionID, pendingAction := range s.pendingActionsAdd {
-		delete(s.curActions, actionID)
-		s.curActions[actionID] = pendingAction
-	}
+// sharesKeys reports whether actions of [actionType] and [other] on the same
+// key exclude each other. The action types before [actionTypeSetVotingPolicy]
+// share their keys, later ones have their own.
+func sharesKeys(actionType uint64, other uint64) bool {
+	return actionType == other || (actionType < actionTypeSetVotingPolicy && other < actionTypeSetVotingPolicy)
+}
 
-	for actionID := range s.pendingActionsDel {
-		delete(s.curActions, actionID)
+// ProposalRepeat ensures [actionID] is new and no action of an action type
+// sharing keys with [actionType] that is still open at [blkTime] targets
+// [key].
+func (s *actionsState) ProposalRepeat(actionID ids.ShortID, actionType uint64, key string, newValue string, blkTime uint64) error {
+	exist, err := s.db.Has(PrefixActionsKey(actionID))
+	if err != nil {
+		return err
 	}
-	for actionID := range s.pendingActionsAdd {
-		delete(s.pendingActionsAdd, actionID)
+	if exist {
+		return fmt.Errorf("action id exist")
 	}
-	for actionID := range s.pendingActionsDel {
-		delete(s.pendingActionsDel, actionID)
+	actions, err := s.GetActionsByKey(key)
+	if err != nil {
+		return err
+	}
+	for _, action := range actions {
+		if sharesKeys(actionType, action.ActionType) && action.IsOpen(blkTime) {
+			return fmt.Errorf("key exist")
+		}
 	}
-
 	return nil
 }
 
-func (s *actionsState) CacheActionsAbort() error {
-	for actionID := range s.pendingActionsAdd {
-		delete(s.pendingActionsAdd, actionID)
+// SettleActions records the outcome of the actions of an action type sharing
+// keys with [actionType] that target [key] and closed before [blkTime]
+func (s *actionsState) SettleActions(actionType uint64, key string, txID ids.ID, blkTime uint64) error {
+	actions, err := s.GetActionsByKey(key)
+	if err != nil {
+		return err
 	}
-	for actionID := range s.pendingActionsDel {
-		delete(s.pendingActionsDel, actionID)
+	for _, action := range actions {
+		if !sharesKeys(actionType, action.ActionType) || action.StatusAt(blkTime) == action.Status {
+			continue
+		}
+		action.Transition(action.StatusAt(blkTime), txID, blkTime)
+		if err := s.PutAction(action.ActionID, action); err != nil {
+			return err
+		}
 	}
-
 	return nil
 }
 
-func (s *actionsState) ProposalRepeat(actionID ids.ShortID, key string, newValue string) error {
-	_, exist := s.curActions[actionID]
-	if exist {
-		return fmt.Errorf("action exist curActions")
+func (s *actionsState) CheckActionType(actionType uint64) bool {
+	_, err := GetGovernanceAction(actionType)
+	return err == nil
+}
+
+// migrateActions moves the actions stored before [ForkPhase1] to the current
+// layout once it is active. Open actions get the status their votes reach.
+// Legacy chains didn't record whether an action was executed, so actions
+// whose voting ended are closed as expired rather than executed again.
+func migrateActions(g *Genesis, db database.Database, rules Rules, blkTime uint64) error {
+	if !rules.IsPhase1 {
+		return nil
 	}
-	for _, action := range s.curActions {
-		if action.Key == key {
-			return fmt.Errorf("key exist curActions")
+	if migrated, err := db.Has(actionsMigratedKey); err != nil || migrated {
+		return err
+	}
+	legacy := []*ActionMeta{}
+	if err := iterateState(db, baseActionsPrefix(), len(ids.ShortID{}), func(_ []byte, v []byte) error {
+		action := new(ActionMeta)
+		version, err := Unmarshal(v, action)
+		if err != nil {
+			return err
+		}
+		if version == legacyCodecVersion {
+			legacy = append(legacy, action)
 		}
+		return nil
+	}); err != nil {
+		return err
 	}
-	_, exist = s.pendingActionsAdd[actionID]
-	if exist {
-		return fmt.Errorf("action id exist pending")
+	t := &TransactionContext{
+		Genesis:   g,
+		Database:  db,
+		BlockTime: blkTime,
+		Rules:     rules,
+		State:     SamaNew(db, g),
 	}
-	for _, action := range s.pendingActionsAdd {
-		if action.Key == key {
-			return fmt.Errorf("key exist pending")
+	for _, action := range legacy {
+		if blkTime > action.EndTime {
+			action.record(ActionExpired, ids.Empty, blkTime)
+			if err := t.State.PutAction(action.ActionID, action); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := putVotedAction(t, action); err != nil {
+			return err
 		}
 	}
-
-	return nil
-}
-
-func (s *actionsState) CheckActionType(actionType uint64) bool {
-	if actionType > actionTypeStart && actionType < actionTypeEnd {
-		return true
-	}
-	return false
+	return db.Put(actionsMigratedKey, nil)
 }
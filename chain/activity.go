@@ -3,7 +3,16 @@
 
 package chain
 
-import "github.com/ava-labs/avalanchego/ids"
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+	log "github.com/inconshreveable/log15"
+)
 
 type Activity struct {
 	Tmstmp       int64  `serialize:"true" json:"timestamp"`
@@ -39,4 +48,249 @@ type Activity struct {
 	WorkKey    string `serialize:"true" json:"workKey,omitempty"`
 	UserType   uint64 `serialize:"true" json:"userType,omitempty"`
 	ActionType uint64 `serialize:"true" json:"actionType,omitempty"`
+
+	// Position of the tx in the chain (only set for indexed activity)
+	Hght  uint64 `serialize:"true" json:"height,omitempty"`
+	Index uint32 `serialize:"true" json:"index,omitempty"`
+}
+
+// The activity of every account is indexed when blocks are accepted:
+//
+// 0x1a/0x0/[address][height][index] (activity by address)
+//   -> activity
+// 0x1a/0x1/[address][len(type)][type][height][index] (activity by type)
+//   -> nil
+
+const (
+	addressActivity = 0x0
+	typeActivity    = 0x1
+
+	activityPositionLen = 8 + 4
+)
+
+var (
+	activityIndexVersion = []byte("activity_index_version")
+
+	ErrInvalidActivityType = errors.New("invalid activity type")
+)
+
+const currentActivityIndexVersion = 1
+
+// [activityPrefix] + [delimiter] + [addressActivity] + [address]
+func baseAddressActivityPrefix(address common.Address) (k []byte) {
+	k = make([]byte, 3+common.AddressLength)
+	k[0] = activityPrefix
+	k[1] = ByteDelimiter
+	k[2] = addressActivity
+	copy(k[3:], address[:])
+	return
+}
+
+// [activityPrefix] + [delimiter] + [typeActivity] + [address] + [len(type)] + [type]
+func baseTypeActivityPrefix(address common.Address, typ string) (k []byte) {
+	k = make([]byte, 3+common.AddressLength+1+len(typ))
+	k[0] = activityPrefix
+	k[1] = ByteDelimiter
+	k[2] = typeActivity
+	copy(k[3:], address[:])
+	k[3+common.AddressLength] = byte(len(typ))
+	copy(k[4+common.AddressLength:], typ)
+	return
+}
+
+func appendActivityPosition(k []byte, height uint64, index uint32) []byte {
+	p := make([]byte, activityPositionLen)
+	binary.BigEndian.PutUint64(p, height)
+	binary.BigEndian.PutUint32(p[8:], index)
+	return append(k, p...)
+}
+
+// [activityPrefix] + [delimiter] + [addressActivity] + [address] + [height] + [index]
+func PrefixAddressActivityKey(address common.Address, height uint64, index uint32) []byte {
+	return appendActivityPosition(baseAddressActivityPrefix(address), height, index)
+}
+
+// [activityPrefix] + [delimiter] + [typeActivity] + [address] + [len(type)] + [type] + [height] + [index]
+func PrefixTypeActivityKey(address common.Address, typ string, height uint64, index uint32) []byte {
+	return appendActivityPosition(baseTypeActivityPrefix(address, typ), height, index)
+}
+
+// ActivityAddresses returns the accounts involved in [tx]: the sender and any
+// recipient, staker, user or multisig account it names
+func ActivityAddresses(tx *Transaction) []common.Address {
+	addrs := append([]common.Address{tx.Sender()}, txAddresses(tx.UnsignedTransaction)...)
+
+	unique := addrs[:0]
+	seen := map[common.Address]struct{}{}
+	for _, addr := range addrs {
+		if _, ok := seen[addr]; ok || addr == (common.Address{}) {
+			continue
+		}
+		seen[addr] = struct{}{}
+		unique = append(unique, addr)
+	}
+	return unique
+}
+
+// txAddresses returns the accounts named by [utx], and for a [MultisigTx]
+// the account, the members that approved it and those named by the inner tx
+func txAddresses(utx UnsignedTransaction) []common.Address {
+	switch t := utx.(type) {
+	case *TransferTx:
+		return []common.Address{t.To}
+	case *StakeTx:
+		return []common.Address{t.StakerAddr}
+	case *RegisterTx:
+		return []common.Address{t.StakerAddr}
+	case *AddUserTx:
+		return []common.Address{t.Address}
+	case *ProofTx:
+		return []common.Address{t.Ser}
+	case *DelegateVoteTx:
+		return []common.Address{t.Delegate}
+	case *DelegateStakeTx:
+		return []common.Address{t.StakerAddr}
+	case *UndelegateStakeTx:
+		return []common.Address{t.StakerAddr}
+	case *ReportOffenceTx:
+		return []common.Address{t.Staker}
+	case *CreateMultisigTx:
+		account, err := NewMultisigAccount(t.Members, t.Threshold)
+		if err != nil {
+			return t.Members
+		}
+		return append([]common.Address{account.Address}, account.Members...)
+	case *MultisigTx:
+		addrs := []common.Address{t.Account}
+		if t.Tx == nil {
+			return addrs
+		}
+		if signers, err := t.signers(t.Tx.GetMagic()); err == nil {
+			addrs = append(addrs, signers...)
+		}
+		return append(addrs, txAddresses(t.Tx)...)
+	}
+	return nil
+}
+
+// WriteActivityIndex indexes the activity of [txs], accepted at [height] and
+// [tmstmp], by the accounts involved and by type
+func WriteActivityIndex(db database.KeyValueWriter, height uint64, tmstmp int64, txs []*Transaction) error {
+	for i, tx := range txs {
+		activity := tx.Activity()
+		activity.Tmstmp = tmstmp
+		activity.Hght = height
+		activity.Index = uint32(i)
+		if len(activity.Typ) > 255 {
+			return ErrInvalidActivityType
+		}
+		b, err := Marshal(activity)
+		if err != nil {
+			return err
+		}
+		for _, addr := range ActivityAddresses(tx) {
+			if err := db.Put(PrefixAddressActivityKey(addr, height, uint32(i)), b); err != nil {
+				return err
+			}
+			if err := db.Put(PrefixTypeActivityKey(addr, activity.Typ, height, uint32(i)), nil); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// GetActivityByAddress returns up to [limit] activities of [address] (only
+// those of type [typ] if not empty) from position [fromIndex] in the block at
+// [fromHeight] onwards, oldest first. [more] is true if there are more.
+func GetActivityByAddress(
+	db database.Database,
+	address common.Address,
+	typ string,
+	fromHeight uint64,
+	fromIndex uint32,
+	limit int,
+) (activity []*Activity, more bool, err error) {
+	if len(typ) > 255 {
+		return nil, false, ErrInvalidActivityType
+	}
+	prefix := baseAddressActivityPrefix(address)
+	if len(typ) > 0 {
+		prefix = baseTypeActivityPrefix(address, typ)
+	}
+	it := db.NewIteratorWithStartAndPrefix(appendActivityPosition(prefix, fromHeight, fromIndex), prefix)
+	defer it.Release()
+
+	activity = []*Activity{}
+	for it.Next() {
+		if len(activity) >= limit {
+			return activity, true, nil
+		}
+		k := it.Key()
+		if len(k) != len(prefix)+activityPositionLen {
+			continue
+		}
+		v := it.Value()
+		if len(typ) > 0 {
+			height := binary.BigEndian.Uint64(k[len(prefix):])
+			index := binary.BigEndian.Uint32(k[len(prefix)+8:])
+			v, err = db.Get(PrefixAddressActivityKey(address, height, index))
+			if err != nil {
+				return nil, false, err
+			}
+		}
+		a := new(Activity)
+		if _, err := Unmarshal(v, a); err != nil {
+			return nil, false, err
+		}
+		activity = append(activity, a)
+	}
+	return activity, false, it.Error()
+}
+
+// BuildActivityIndex indexes the activity of the accepted blocks stored in
+// [db] before the index existed. It is a no-op once the index is up to date.
+func BuildActivityIndex(db database.Database, g *Genesis) error {
+	version, err := getCount(db, activityIndexVersion)
+	if err != nil {
+		return err
+	}
+	if version >= currentActivityIndexVersion {
+		return nil
+	}
+
+	start := time.Now()
+	batch := db.NewBatch()
+	blocks := 0
+	if err := walkAcceptedBlocks(db, func(_ ids.ID, blk *StatefulBlock) error {
+		for _, tx := range blk.Txs {
+			if err := tx.Init(g); err != nil {
+				return err
+			}
+		}
+		if err := WriteActivityIndex(batch, blk.Hght, blk.Tmstmp, blk.Txs); err != nil {
+			return err
+		}
+		blocks++
+		if batch.Size() > treeBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	v := make([]byte, 8)
+	binary.BigEndian.PutUint64(v, currentActivityIndexVersion)
+	if err := batch.Put(activityIndexVersion, v); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	log.Info("built activity index", "blocks", blocks, "t", time.Since(start))
+	return nil
 }
@@ -0,0 +1,232 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/SamaNetwork/SamaVM/tdata"
+	"github.com/ava-labs/avalanchego/database/memdb"
+	"github.com/ava-labs/avalanchego/database/versiondb"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/golang/mock/gomock"
+)
+
+func TestActivityIndex(t *testing.T) {
+	t.Parallel()
+
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := crypto.PubkeyToAddress(priv.PublicKey)
+	recipient := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	staker := common.HexToAddress("0x0000000000000000000000000000000000000002")
+	g := DefaultGenesis()
+
+	sign := func(utx UnsignedTransaction) *Transaction {
+		dh, err := DigestHash(utx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := Sign(dh, priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx := NewTx(utx, sig)
+		if err := tx.Init(g); err != nil {
+			t.Fatal(err)
+		}
+		return tx
+	}
+	base := func() *BaseTx { return &BaseTx{BlockID: ids.GenerateTestID(), Magic: g.Magic, Price: g.MinPrice} }
+	blocks := [][]*Transaction{
+		{},
+		{
+			sign(&TransferTx{BaseTx: base(), To: recipient, Units: 1}),
+			sign(&StakeTx{BaseTx: base(), StakerType: stakerTypeRoute, StakerAddr: staker, StakeAmount: 5}),
+		},
+		{
+			sign(&TransferTx{BaseTx: base(), To: recipient, Units: 2}),
+			sign(&TransferTx{BaseTx: base(), To: sender, Units: 3}),
+		},
+	}
+
+	check := func(db *memdb.Database) {
+		// All activity of the sender in pages of 2
+		activity, more, err := GetActivityByAddress(db, sender, "", 0, 0, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(activity) != 2 || !more {
+			t.Fatalf("expected full page, got %d (more=%t)", len(activity), more)
+		}
+		if activity[0].Hght != 1 || activity[0].Index != 0 || activity[1].Hght != 1 || activity[1].Index != 1 {
+			t.Fatalf("unexpected page %+v %+v", activity[0], activity[1])
+		}
+		if activity[0].Tmstmp != 1 || activity[0].TxID != blocks[1][0].ID() {
+			t.Fatalf("unexpected activity %+v", activity[0])
+		}
+		activity, more, err = GetActivityByAddress(db, sender, "", 1, 2, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(activity) != 2 || more || activity[0].Hght != 2 || activity[1].Index != 1 {
+			t.Fatalf("unexpected last page (%d, more=%t)", len(activity), more)
+		}
+
+		// Transfers to the recipient only
+		activity, _, err = GetActivityByAddress(db, recipient, Transfer, 0, 0, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(activity) != 2 || activity[0].Units != 1 || activity[1].Units != 2 {
+			t.Fatalf("unexpected recipient activity %+v", activity)
+		}
+		activity, _, err = GetActivityByAddress(db, recipient, Stake, 0, 0, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(activity) != 0 {
+			t.Fatalf("unexpected stake activity %+v", activity)
+		}
+
+		// Stakers see the stake and a self-transfer is indexed once
+		activity, _, err = GetActivityByAddress(db, staker, Stake, 0, 0, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(activity) != 1 || activity[0].StakeAmount != 5 {
+			t.Fatalf("unexpected staker activity %+v", activity)
+		}
+		activity, _, err = GetActivityByAddress(db, sender, Transfer, 2, 0, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(activity) != 2 {
+			t.Fatalf("expected 2 transfers, got %d", len(activity))
+		}
+	}
+
+	// Index written as blocks are accepted
+	db := memdb.New()
+	defer db.Close()
+	accepting := NewMockVM(gomock.NewController(t))
+	accepting.EXPECT().State().Return(db).AnyTimes()
+	accepting.EXPECT().Accepted(gomock.Any()).AnyTimes()
+	for i, txs := range blocks {
+		blk := &StatelessBlock{
+			StatefulBlock: &StatefulBlock{Hght: uint64(i), Tmstmp: int64(i), Txs: txs},
+			vm:            accepting,
+			onAcceptDB:    versiondb.New(db),
+		}
+		if err := blk.Accept(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	check(db)
+
+	// Index built from stored blocks
+	built := memdb.New()
+	defer built.Close()
+	vm := NewMockVM(gomock.NewController(t))
+	vm.EXPECT().Genesis().Return(g).AnyTimes()
+	parent := ids.Empty
+	for i, txs := range blocks {
+		blk := &StatelessBlock{
+			StatefulBlock: &StatefulBlock{Prnt: parent, Hght: uint64(i), Tmstmp: int64(i), Txs: txs},
+			vm:            vm,
+		}
+		if err := blk.init(); err != nil {
+			t.Fatal(err)
+		}
+		if err := SetLastAccepted(built, blk); err != nil {
+			t.Fatal(err)
+		}
+		parent = blk.ID()
+	}
+	if err := BuildActivityIndex(built, g); err != nil {
+		t.Fatal(err)
+	}
+	check(built)
+}
+
+func TestActivityAddresses(t *testing.T) {
+	t.Parallel()
+
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := crypto.PubkeyToAddress(priv.PublicKey)
+	other := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	g := DefaultGenesis()
+	base := func() *BaseTx { return &BaseTx{BlockID: ids.GenerateTestID(), Magic: g.Magic, Price: g.MinPrice} }
+
+	memberPriv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	member := crypto.PubkeyToAddress(memberPriv.PublicKey)
+	account, err := NewMultisigAccount([]common.Address{member, sender}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner := &TransferTx{BaseTx: base(), To: other, Units: 1}
+	td, err := MultisigTypedData(g.Magic, account.Address, 0, inner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dh, err := tdata.DigestHash(td)
+	if err != nil {
+		t.Fatal(err)
+	}
+	approval, err := Sign(dh, memberPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name     string
+		utx      UnsignedTransaction
+		expected []common.Address
+	}{
+		{"transfer", &TransferTx{BaseTx: base(), To: other, Units: 1}, []common.Address{sender, other}},
+		{"to self", &TransferTx{BaseTx: base(), To: sender, Units: 1}, []common.Address{sender}},
+		{"delegate vote", &DelegateVoteTx{BaseTx: base(), Delegate: other}, []common.Address{sender, other}},
+		{"delegate stake", &DelegateStakeTx{BaseTx: base(), StakerAddr: other, Amount: 1}, []common.Address{sender, other}},
+		{"undelegate stake", &UndelegateStakeTx{BaseTx: base(), StakerAddr: other, Amount: 1}, []common.Address{sender, other}},
+		{"report offence", &ReportOffenceTx{BaseTx: base(), Staker: other, OffenceType: OffenceDowntime}, []common.Address{sender, other}},
+		{
+			"create multisig",
+			&CreateMultisigTx{BaseTx: base(), Members: []common.Address{member, sender}, Threshold: 1},
+			append([]common.Address{sender, account.Address}, member),
+		},
+		{
+			"multisig",
+			&MultisigTx{BaseTx: base(), Account: account.Address, Tx: inner, Signatures: [][]byte{approval}},
+			[]common.Address{sender, account.Address, member, other},
+		},
+	} {
+		dh, err := DigestHash(tt.utx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := Sign(dh, priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx := NewTx(tt.utx, sig)
+		if err := tx.Init(g); err != nil {
+			t.Fatal(err)
+		}
+		if addrs := ActivityAddresses(tx); !reflect.DeepEqual(addrs, tt.expected) {
+			t.Fatalf("%s: expected %v, got %v", tt.name, tt.expected, addrs)
+		}
+	}
+}
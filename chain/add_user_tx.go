@@ -7,12 +7,15 @@ import (
 	"bytes"
 	"fmt"
 	"strconv"
-	"time"
 
 	"github.com/SamaNetwork/SamaVM/tdata"
 	"github.com/ethereum/go-ethereum/common"
 )
 
+// maxUserStartDrift is how far before the block timestamp a subscription
+// may start.
+const maxUserStartDrift = 60
+
 var _ UnsignedTransaction = &AddUserTx{}
 
 type AddUserTx struct {
@@ -33,28 +36,28 @@ func (a *AddUserTx) Execute(t *TransactionContext) error {
 		return fmt.Errorf("time > 31 days")
 	case a.EndTime-a.StartTime < 28*24*60*60:
 		return fmt.Errorf("time < 28 days")
-	case a.StartTime < uint64(time.Now().Unix())-60:
+	case a.StartTime+maxUserStartDrift < t.BlockTime:
 		return fmt.Errorf("start time err")
 	}
 
 	if bytes.Equal(a.Address[:], zeroAddress[:]) {
 		return ErrNonActionable
 	}
-	samaState := t.vm.SamaState()
+	samaState := t.State
 
 	ok := samaState.CheckUserType(a.UserType)
 	if !ok {
 		return fmt.Errorf("user type err")
 	}
 
-	ok, err := samaState.CheckPayAmount(t.Database, a.UserType, a.PayAmount, a.StartTime, a.EndTime)
+	ok, err := samaState.CheckPayAmount(a.UserType, a.PayAmount, a.StartTime, a.EndTime)
 	if err != nil {
 		return err
 	}
 	if !ok {
 		return fmt.Errorf("pay amount check fail")
 	}
-	_, exists, _ := samaState.GetUserMeta(t.Database, a.Address)
+	_, exists, _ := samaState.GetUserMeta(a.Address)
 	if exists {
 		//checkEndTime
 		return fmt.Errorf("ueser have already exist ")
@@ -64,7 +67,7 @@ func (a *AddUserTx) Execute(t *TransactionContext) error {
 		return err
 	}
 
-	return samaState.DealAddUserTx(t.Database, t.TxID, t.BlockTime, &UserMeta{
+	return samaState.DealAddUserTx(t.TxID, t.BlockTime, &UserMeta{
 		StartTime:   a.StartTime,
 		EndTime:     a.EndTime,
 		UserType:    a.UserType,
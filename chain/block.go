@@ -31,7 +31,25 @@ type StatefulBlock struct {
 	Price       uint64         `serialize:"true" json:"price"`
 	Cost        uint64         `serialize:"true" json:"cost"`
 	AccessProof common.Hash    `serialize:"true" json:"accessProof"`
+	StateRoot   common.Hash    `serializeV1:"true" json:"stateRoot"`
 	Txs         []*Transaction `serialize:"true" json:"txs"`
+
+	// version is the codec version the block is encoded with
+	version uint16
+}
+
+// blockCodecVersion returns the codec version of blocks under [rules]
+func blockCodecVersion(rules Rules) uint16 {
+	if rules.IsPhase1 {
+		return codecVersion
+	}
+	return legacyCodecVersion
+}
+
+// HasStateRoot reports whether the block commits to [StateRoot], blocks
+// before [ForkPhase1] don't
+func (b *StatefulBlock) HasStateRoot() bool {
+	return b.version >= codecVersion
 }
 
 // Stateless is defined separately from "Block"
@@ -58,6 +76,8 @@ func NewBlock(vm VM, parent snowman.Block, tmstp int64, context *Context) *State
 			Hght:   parent.Height() + 1,
 			Price:  context.NextPrice,
 			Cost:   context.NextCost,
+
+			version: blockCodecVersion(context.Rules),
 		},
 		vm: vm,
 		st: choices.Processing,
@@ -70,9 +90,11 @@ func ParseBlock(
 	vm VM,
 ) (*StatelessBlock, error) {
 	blk := new(StatefulBlock)
-	if _, err := Unmarshal(source, blk); err != nil {
+	version, err := Unmarshal(source, blk)
+	if err != nil {
 		return nil, err
 	}
+	blk.version = version
 	return ParseStatefulBlock(blk, source, status, vm)
 }
 
@@ -83,7 +105,7 @@ func ParseStatefulBlock(
 	vm VM,
 ) (*StatelessBlock, error) {
 	if len(source) == 0 {
-		b, err := Marshal(blk)
+		b, err := marshalVersion(blk.version, blk)
 		if err != nil {
 			return nil, err
 		}
@@ -111,7 +133,7 @@ func ParseStatefulBlock(
 }
 
 func (b *StatelessBlock) init() error {
-	bytes, err := Marshal(b.StatefulBlock)
+	bytes, err := marshalVersion(b.version, b.StatefulBlock)
 	if err != nil {
 		return err
 	}
@@ -163,7 +185,7 @@ func (b *StatelessBlock) verify() (*StatelessBlock, *versiondb.Database, error)
 	if len(b.Txs) == 0 {
 		return nil, nil, ErrNoTxs
 	}
-	if b.Timestamp().Unix() >= time.Now().Add(futureBound).Unix() {
+	if b.Timestamp().Unix() >= b.vm.Now().Add(futureBound).Unix() {
 		return nil, nil, ErrTimestampTooLate
 	}
 	blockSize := uint64(0)
@@ -194,6 +216,12 @@ func (b *StatelessBlock) verify() (*StatelessBlock, *versiondb.Database, error)
 	if b.Price != context.NextPrice {
 		return nil, nil, ErrInvalidPrice
 	}
+	if expected := blockCodecVersion(context.Rules); b.version != expected {
+		return nil, nil, fmt.Errorf("%w: expected=%d found=%d", ErrInvalidCodecVersion, expected, b.version)
+	}
+	if context.Rules.IsPhase1 && b.Hght != parent.Hght+1 {
+		return nil, nil, fmt.Errorf("%w: expected=%d found=%d", ErrInvalidHeight, parent.Hght+1, b.Hght)
+	}
 
 	parentState, err := parent.onAccept()
 	if err != nil {
@@ -206,12 +234,26 @@ func (b *StatelessBlock) verify() (*StatelessBlock, *versiondb.Database, error)
 	if b.AccessProof != accessProof {
 		return nil, nil, ErrInvalidAccessProof
 	}
+	if err := migrateRewards(g, onAcceptDB, context.Rules, uint64(b.Tmstmp)); err != nil {
+		return nil, nil, err
+	}
+	if err := migrateActions(g, onAcceptDB, context.Rules, uint64(b.Tmstmp)); err != nil {
+		return nil, nil, err
+	}
+	if err := migrateFoundation(g, onAcceptDB, context.Rules); err != nil {
+		return nil, nil, err
+	}
 
 	// Process new transactions
 	log.Debug("build context", "height", b.Hght, "price", b.Price, "cost", b.Cost)
 	surplusFee := uint64(0)
-	for _, tx := range b.Txs {
-		if err := tx.Execute(g, onAcceptDB, b, context); err != nil {
+	for i, tx := range b.Txs {
+		receipt, err := tx.executeWithReceipt(g, onAcceptDB, b, context)
+		if err != nil {
+			return nil, nil, err
+		}
+		receipt.Index = uint32(i)
+		if err := PutReceipt(onAcceptDB, receipt); err != nil {
 			return nil, nil, err
 		}
 		surplusFee += (tx.GetPrice() - b.Price) * tx.FeeUnits(g)
@@ -221,9 +263,40 @@ func (b *StatelessBlock) verify() (*StatelessBlock, *versiondb.Database, error)
 	if surplusFee < requiredSurplus {
 		return nil, nil, fmt.Errorf("%w: required=%d found=%d", ErrInsufficientSurplus, requiredSurplus, surplusFee)
 	}
+
+	// Close the governance actions due at this block
+	receipts, err := ProcessActions(g, onAcceptDB, context.Rules, b.ID(), b.Hght, uint64(b.Tmstmp))
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, receipt := range receipts {
+		receipt.Index = uint32(len(b.Txs) + i)
+	}
+	if err := PutBlockReceipts(onAcceptDB, b.ID(), receipts); err != nil {
+		return nil, nil, err
+	}
+
+	// Ensure the resulting state matches the one committed to
+	stateRoot, err := commitState(onAcceptDB)
+	if err != nil {
+		return nil, nil, err
+	}
+	if b.HasStateRoot() && b.StateRoot != stateRoot {
+		return nil, nil, fmt.Errorf("%w: expected=%s found=%s", ErrInvalidStateRoot, stateRoot, b.StateRoot)
+	}
 	return parent, onAcceptDB, nil
 }
 
+// commitState updates the state tree with the changes made in [db] and
+// returns the new state root
+func commitState(db *versiondb.Database) (common.Hash, error) {
+	changes, err := db.CommitBatch()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return UpdateStateTree(db, changes)
+}
+
 // implements "snowman.Block"
 func (b *StatelessBlock) Verify(ctx context.Context) error {
 	parent, onAcceptDB, err := b.verify()
@@ -240,15 +313,31 @@ func (b *StatelessBlock) Verify(ctx context.Context) error {
 
 	parent.addChild(b)
 	b.vm.Verified(b)
-	//b.vm.SamaState().Commit()
 	return nil
 }
 
 // implements "snowman.Block.choices.Decidable"
 func (b *StatelessBlock) Accept(ctx context.Context) error {
-	if err := b.onAcceptDB.Commit(); err != nil {
+	batch, err := b.onAcceptDB.CommitBatch()
+	if err != nil {
+		return err
+	}
+	// Keep what this block overwrote so peers can be served older states
+	if err := WriteStateDiff(b.vm.State(), batch, b.Hght); err != nil {
 		return err
 	}
+	if err := WriteArchive(b.vm.State(), batch, b.Hght); err != nil {
+		return err
+	}
+	// Index the history with the block, so it can't be lost if the node stops
+	// in between
+	if err := WriteActivityIndex(batch, b.Hght, b.Tmstmp, b.Txs); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	b.onAcceptDB.Abort()
 	for _, child := range b.children {
 		if err := child.onAcceptDB.SetDatabase(b.vm.State()); err != nil {
 			return err
@@ -256,8 +345,7 @@ func (b *StatelessBlock) Accept(ctx context.Context) error {
 	}
 	b.st = choices.Accepted
 	b.vm.Accepted(b)
-	err := b.vm.SamaState().Commit()
-	return err
+	return nil
 }
 
 // implements "snowman.Block.choices.Decidable"
@@ -301,6 +389,17 @@ func (b *StatelessBlock) onAccept() (database.Database, error) {
 	return nil, ErrParentBlockNotVerified
 }
 
+// SamaState returns a view of the chain state as of this block. The view of a
+// processing block is layered on its parent and never observes changes made
+// by sibling blocks.
+func (b *StatelessBlock) SamaState() (SamaState, error) {
+	db, err := b.onAccept()
+	if err != nil {
+		return nil, err
+	}
+	return SamaNew(db, b.vm.Genesis()), nil
+}
+
 func (b *StatelessBlock) addChild(c *StatelessBlock) {
 	b.children = append(b.children, c)
 }
@@ -11,6 +11,7 @@ import (
 
 	"github.com/ava-labs/avalanchego/ids"
 	"github.com/ava-labs/avalanchego/snow/choices"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	gomock "github.com/golang/mock/gomock"
 )
@@ -148,6 +149,49 @@ func TestBlock(t *testing.T) {
 			},
 			expectedVerifyErr: ErrInvalidPrice,
 		},
+		{
+			createBlk: func() *StatelessBlock {
+				blk := createTestBlk(
+					t,
+					&StatelessBlock{
+						StatefulBlock: &StatefulBlock{
+							Tmstmp: 1,
+							Prnt:   ids.ID{0, 1, 2, 4, 5},
+							Hght:   1, Price: 1000, Cost: 1000,
+						},
+						st: choices.Accepted,
+					},
+					2,
+					&Context{NextPrice: 1000, NextCost: 1, Rules: Rules{Timestamp: 2, IsPhase1: true}},
+					&Context{NextPrice: 1000, NextCost: 1, Rules: Rules{Timestamp: 2, IsPhase1: true}},
+					1,
+				)
+				blk.Hght = 3
+				return blk
+			},
+			expectedVerifyErr: ErrInvalidHeight,
+		},
+		{
+			createBlk: func() *StatelessBlock {
+				blk := createTestBlk(
+					t,
+					&StatelessBlock{
+						StatefulBlock: &StatefulBlock{
+							Tmstmp: 1,
+							Prnt:   ids.ID{0, 1, 2, 4, 5},
+							Hght:   1, Price: 1000, Cost: 1000,
+						},
+						st: choices.Accepted,
+					},
+					2,
+					&Context{NextPrice: 1000, NextCost: 1},
+					&Context{NextPrice: 1000, NextCost: 1, Rules: Rules{Timestamp: 2, IsPhase1: true}},
+					1,
+				)
+				return blk
+			},
+			expectedVerifyErr: ErrInvalidCodecVersion,
+		},
 	}
 	for i, tv := range tt {
 		blk := tv.createBlk()
@@ -159,6 +203,41 @@ func TestBlock(t *testing.T) {
 	}
 }
 
+func TestBlockCodecVersions(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	vm := NewMockVM(ctrl)
+	vm.EXPECT().Genesis().Return(DefaultGenesis()).AnyTimes()
+	parent := &StatelessBlock{StatefulBlock: &StatefulBlock{Tmstmp: 1, Hght: 1}, vm: vm}
+	if err := parent.init(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, rules := range []Rules{{}, {IsPhase1: true}} {
+		blk := NewBlock(vm, parent, 2, &Context{Rules: rules})
+		blk.StateRoot = common.Hash{1}
+		if err := blk.init(); err != nil {
+			t.Fatal(err)
+		}
+		parsed, err := ParseBlock(blk.Bytes(), choices.Processing, vm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed.ID() != blk.ID() || parsed.HasStateRoot() != rules.IsPhase1 {
+			t.Fatalf("phase1=%t: parsed %s (root %t), expected %s", rules.IsPhase1, parsed.ID(), parsed.HasStateRoot(), blk.ID())
+		}
+		// Blocks before the fork don't carry the state root
+		expected := common.Hash{}
+		if rules.IsPhase1 {
+			expected = blk.StateRoot
+		}
+		if parsed.StateRoot != expected {
+			t.Fatalf("phase1=%t: expected state root %s, got %s", rules.IsPhase1, expected, parsed.StateRoot)
+		}
+	}
+}
+
 func createTestBlk(
 	t *testing.T,
 	parentBlk *StatelessBlock,
@@ -172,6 +251,7 @@ func createTestBlk(
 	ctrl := gomock.NewController(t)
 	vm := NewMockVM(ctrl)
 	vm.EXPECT().Genesis().Return(DefaultGenesis()).AnyTimes()
+	vm.EXPECT().Now().Return(time.Now()).AnyTimes()
 	parentBlk.vm = vm
 	if err := parentBlk.init(); err != nil {
 		t.Fatal(err)
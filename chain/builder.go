@@ -4,8 +4,6 @@
 package chain
 
 import (
-	"time"
-
 	"github.com/ava-labs/avalanchego/database/versiondb"
 	"github.com/ava-labs/avalanchego/ids"
 	"github.com/ava-labs/avalanchego/snow/consensus/snowman"
@@ -16,12 +14,17 @@ func BuildBlock(vm VM, preferred ids.ID) (snowman.Block, error) {
 	g := vm.Genesis()
 
 	log.Debug("attempting block building")
-	nextTime := time.Now().Unix()
+	nextTime := vm.Now().Unix()
 	parent, err := vm.GetStatelessBlock(preferred)
 	if err != nil {
 		log.Debug("block building failed: couldn't get parent", "err", err)
 		return nil, err
 	}
+	// Never build a block that is older than its parent, even if the local
+	// clock lags behind the network
+	if nextTime < parent.Tmstmp {
+		nextTime = parent.Tmstmp
+	}
 	context, err := vm.ExecutionContext(nextTime, parent)
 	if err != nil {
 		log.Debug("block building failed: couldn't get execution context", "err", err)
@@ -42,6 +45,15 @@ func BuildBlock(vm VM, preferred ids.ID) (snowman.Block, error) {
 
 	// Generate access proof from random value
 	b.AccessProof = generateAccessProof(vdb, parent.ID(), b.Hght)
+	if err := migrateRewards(g, vdb, context.Rules, uint64(b.Tmstmp)); err != nil {
+		return nil, err
+	}
+	if err := migrateActions(g, vdb, context.Rules, uint64(b.Tmstmp)); err != nil {
+		return nil, err
+	}
+	if err := migrateFoundation(g, vdb, context.Rules); err != nil {
+		return nil, err
+	}
 
 	b.Txs = []*Transaction{}
 	units := uint64(0)
@@ -71,6 +83,7 @@ func BuildBlock(vm VM, preferred ids.ID) (snowman.Block, error) {
 		tvdb := versiondb.New(vdb)
 		if err := next.Execute(g, tvdb, b, context); err != nil {
 			log.Debug("skipping tx: failed verification", "err", err)
+			mempool.Drop(next.ID(), err)
 			continue
 		}
 		if err := tvdb.Commit(); err != nil {
@@ -80,8 +93,17 @@ func BuildBlock(vm VM, preferred ids.ID) (snowman.Block, error) {
 		b.Txs = append(b.Txs, next)
 		units += nextLoad
 	}
+	if _, err := ProcessActions(g, vdb, context.Rules, ids.Empty, b.Hght, uint64(b.Tmstmp)); err != nil {
+		return nil, err
+	}
+	stateRoot, err := commitState(vdb)
+	if err != nil {
+		return nil, err
+	}
+	if b.HasStateRoot() {
+		b.StateRoot = stateRoot
+	}
 	vdb.Abort()
-	vm.SamaState().Abort()
 
 	// Compute block hash and marshaled representation
 	if err := b.init(); err != nil {
@@ -93,7 +115,6 @@ func BuildBlock(vm VM, preferred ids.ID) (snowman.Block, error) {
 		log.Debug("block building failed: failed verification", "err", err)
 		return nil, err
 	}
-	vm.SamaState().Abort()
 
 	return b, nil
 }
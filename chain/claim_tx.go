@@ -6,7 +6,6 @@ package chain
 import (
 	"fmt"
 	"strconv"
-	"time"
 
 	"github.com/SamaNetwork/SamaVM/tdata"
 )
@@ -20,7 +19,7 @@ type ClaimTx struct {
 }
 
 func (c *ClaimTx) Execute(t *TransactionContext) error {
-	samaState := t.vm.SamaState()
+	samaState := t.State
 
 	//sender is staker
 	//ok, stakerType, _ := samaState.IsStaker(t.Sender)
@@ -31,16 +30,25 @@ func (c *ClaimTx) Execute(t *TransactionContext) error {
 	if !ok {
 		return fmt.Errorf("sender must staker or foundation")
 	}
+	_, suspended, err := samaState.GetSuspension(t.Sender)
+	if err != nil {
+		return err
+	}
+	if suspended {
+		return ErrSuspended
+	}
 
 	lastClaimTime, _ := samaState.GetLastClaimTime(t.Sender)
-	if uint64(time.Now().Unix())-lastClaimTime < Seconds7Day {
+	if t.BlockTime < lastClaimTime+Seconds7Day {
 		return fmt.Errorf("time interval need > 7 days")
 	}
 
+	// The claim may have been prepared against the parent block, so a day
+	// boundary between the parent and this block is tolerated.
 	createTime := samaState.GetChainCreateTime()
-	endTime := ((uint64(time.Now().Unix())-createTime)/SecondsDay)*SecondsDay + createTime
-
-	if endTime != c.EndTime && endTime != c.EndTime+1 {
+	endTime := claimEndTime(createTime, t.BlockTime)
+	parentEndTime := claimEndTime(createTime, t.ParentTime)
+	if endTime != c.EndTime && endTime != c.EndTime+1 && parentEndTime != c.EndTime {
 		return fmt.Errorf("end time err")
 	}
 	base, merit, yield, err := samaState.CalcReward(claimerType, t.Sender, c.EndTime)
@@ -52,18 +60,25 @@ func (c *ClaimTx) Execute(t *TransactionContext) error {
 		return fmt.Errorf("reward amount is err")
 	}
 
-	if _, err := ModifyBalance(t.Database, t.Sender, true, uint64(totalReawrd)); err != nil {
-		return err
-	}
-
 	switch claimerType {
 	case 0:
-		err = samaState.UpdateFoundationReward(t.Database, t.Sender, t.TxID, c.EndTime)
+		if _, err := ModifyBalance(t.Database, t.Sender, true, uint64(totalReawrd)); err != nil {
+			return err
+		}
+		err = samaState.UpdateFoundationReward(t.Sender, t.TxID, c.EndTime)
 		if err != nil {
 			return err
 		}
 	default:
-		err = samaState.UpdateStakerReward(t.Database, claimerType, t.Sender, t.TxID, c.EndTime)
+		// The reward of the node is shared with its delegators
+		staker, _, err := samaState.GetStakerMeta(claimerType, t.Sender)
+		if err != nil {
+			return err
+		}
+		if err := payStakerReward(t, staker, totalReawrd); err != nil {
+			return err
+		}
+		err = samaState.UpdateStakerReward(claimerType, t.Sender, t.TxID, c.EndTime)
 		if err != nil {
 			return err
 		}
@@ -72,6 +87,15 @@ func (c *ClaimTx) Execute(t *TransactionContext) error {
 	return nil
 }
 
+// claimEndTime returns the last day boundary (counted from chain creation)
+// at or before [blkTime].
+func claimEndTime(createTime uint64, blkTime uint64) uint64 {
+	if blkTime < createTime {
+		return createTime
+	}
+	return ((blkTime-createTime)/SecondsDay)*SecondsDay + createTime
+}
+
 func (c *ClaimTx) FeeUnits(g *Genesis) uint64 {
 	return c.BaseTx.FeeUnits(g) //+ valueUnits(g, uint64(len(c.Value)))
 }
@@ -6,23 +6,47 @@ package chain
 import (
 	"github.com/ava-labs/avalanchego/codec"
 	"github.com/ava-labs/avalanchego/codec/linearcodec"
+	"github.com/ava-labs/avalanchego/codec/reflectcodec"
 	"github.com/ava-labs/avalanchego/utils/units"
 	"github.com/ava-labs/avalanchego/utils/wrappers"
 )
 
 const (
+	// legacyCodecVersion encodes the layouts from before [ForkPhase1], it
+	// skips the fields tagged "serializeV1"
+	legacyCodecVersion = 0
 	// codecVersion is the current default codec version
-	codecVersion = 0
+	codecVersion = 1
 
 	// maxSize is 4MB to support large values
 	maxSize = 4 * units.MiB
+	// maxSliceLen is the default of [linearcodec.NewDefault]
+	maxSliceLen = 256 * 1024
 )
 
 var codecManager codec.Manager
 
+// versioned is implemented by values that can be encoded by an older codec
+// version, so their bytes (and IDs) don't change when the codec does
+type versioned interface {
+	minCodecVersion() uint16
+}
+
 func init() {
-	c := linearcodec.NewDefault()
 	codecManager = codec.NewManager(maxSize)
+	errs := wrappers.Errs{}
+	errs.Add(
+		registerCodec(legacyCodecVersion, linearcodec.NewDefault()),
+		registerCodec(codecVersion, linearcodec.New([]string{reflectcodec.DefaultTagName, "serializeV1"}, maxSliceLen)),
+	)
+	if errs.Errored() {
+		panic(errs.Err)
+	}
+}
+
+// registerCodec registers every type to [c] and [c] as [version]. Types must
+// be registered in the same order in all versions.
+func registerCodec(version uint16, c linearcodec.Codec) error {
 	errs := wrappers.Errs{}
 	errs.Add(
 		c.RegisterType(&BaseTx{}),
@@ -44,18 +68,38 @@ func init() {
 		c.RegisterType(&VoteTx{}),
 		c.RegisterType(&ProofTx{}),
 		c.RegisterType(&ProposalTx{}),
+		c.RegisterType(&DelegateVoteTx{}),
+		c.RegisterType(&CreateMultisigTx{}),
+		c.RegisterType(&MultisigTx{}),
+		c.RegisterType(&DelegateStakeTx{}),
+		c.RegisterType(&UndelegateStakeTx{}),
+		c.RegisterType(&SetCommissionTx{}),
+		c.RegisterType(&WithdrawStakeTx{}),
+		c.RegisterType(&ReportOffenceTx{}),
 
-		codecManager.RegisterCodec(codecVersion, c),
+		codecManager.RegisterCodec(version, c),
 	)
-	if errs.Errored() {
-		panic(errs.Err)
-	}
+	return errs.Err
 }
 
 func Marshal(source interface{}) ([]byte, error) {
 	return codecManager.Marshal(codecVersion, source)
 }
 
+// marshalVersion encodes [source] with the codec [version]
+func marshalVersion(version uint16, source interface{}) ([]byte, error) {
+	return codecManager.Marshal(version, source)
+}
+
+// minCodecVersion returns the oldest codec version that encodes all fields
+// of [source]
+func minCodecVersion(source interface{}) uint16 {
+	if v, ok := source.(versioned); ok {
+		return v.minCodecVersion()
+	}
+	return legacyCodecVersion
+}
+
 func Unmarshal(source []byte, destination interface{}) (uint16, error) {
 	return codecManager.Unmarshal(source, destination)
 }
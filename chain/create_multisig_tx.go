@@ -0,0 +1,89 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/SamaNetwork/SamaVM/tdata"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var _ UnsignedTransaction = &CreateMultisigTx{}
+
+// CreateMultisigTx creates the account of [Threshold] of [Members], see
+// [NewMultisigAccount] for its address
+type CreateMultisigTx struct {
+	*BaseTx   `serialize:"true" json:"baseTx"`
+	Members   []common.Address `serialize:"true" json:"members"`
+	Threshold uint32           `serialize:"true" json:"threshold"`
+}
+
+// Multisig accounts start with [ForkPhase4]
+func (*CreateMultisigTx) minFork() string {
+	return ForkPhase4
+}
+
+func (c *CreateMultisigTx) Execute(t *TransactionContext) error {
+	account, err := NewMultisigAccount(c.Members, c.Threshold)
+	if err != nil {
+		return err
+	}
+	_, exist, err := t.State.GetMultisig(account.Address)
+	if err != nil {
+		return err
+	}
+	if exist {
+		return ErrKeyExists
+	}
+	return t.State.PutMultisig(account)
+}
+
+func (c *CreateMultisigTx) FeeUnits(g *Genesis) uint64 {
+	return c.BaseTx.FeeUnits(g) + uint64(len(c.Members))
+}
+
+func (c *CreateMultisigTx) LoadUnits(g *Genesis) uint64 {
+	return c.FeeUnits(g)
+}
+
+func (c *CreateMultisigTx) Copy() UnsignedTransaction {
+	members := make([]common.Address, len(c.Members))
+	copy(members, c.Members)
+	return &CreateMultisigTx{
+		BaseTx:    c.BaseTx.Copy(),
+		Members:   members,
+		Threshold: c.Threshold,
+	}
+}
+
+func (c *CreateMultisigTx) TypedData() *tdata.TypedData {
+	members := make([]string, len(c.Members))
+	for i, member := range c.Members {
+		members[i] = member.Hex()
+	}
+	return tdata.CreateTypedData(
+		c.Magic, CreateMultisig,
+		[]tdata.Type{
+			{Name: tdMembers, Type: tdString},
+			{Name: tdThreshold, Type: tdUint64},
+			{Name: tdPrice, Type: tdUint64},
+			{Name: tdBlockID, Type: tdString},
+		},
+		tdata.TypedDataMessage{
+			tdMembers:   strings.Join(members, ","),
+			tdThreshold: strconv.FormatUint(uint64(c.Threshold), 10),
+			tdPrice:     strconv.FormatUint(c.Price, 10),
+			tdBlockID:   c.BlockID.String(),
+		},
+	)
+}
+
+func (c *CreateMultisigTx) Activity() *Activity {
+	return &Activity{
+		Typ:     CreateMultisig,
+		Address: MultisigAddress(c.Members, c.Threshold).Hex(),
+	}
+}
@@ -6,6 +6,7 @@ package chain
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -30,31 +31,52 @@ const (
 	Withdrawn = "withdrawn"
 	Vote      = "vote"
 	Proposal  = "proposal"
+
+	DelegateVote = "delegateVote"
+
+	CreateMultisig   = "createMultisig"
+	Multisig         = "multisig"
+	MultisigApproval = "multisigApproval"
+
+	DelegateStake   = "delegateStake"
+	UndelegateStake = "undelegateStake"
+	SetCommission   = "setCommission"
+
+	WithdrawStake = "withdrawStake"
+	ReportOffence = "reportOffence"
 )
 
 type Input struct {
-	Typ          string         `json:"type"`
-	Key          string         `json:"key"`
-	Value        []byte         `json:"value"`
-	To           common.Address `json:"to"`
-	Units        uint64         `json:"units"`
-	StakerType   uint64         `json:"stakerType"`
-	StakerAddr   common.Address `json:"stakerAddr"`
-	StakeAmount  uint64         `json:"stakeAmount"`
-	RewardAmount uint64         `json:"rewardAmount"`
-	ActionID     ids.ShortID    `json:"actionID"`
-	LocalIP      string         `json:"localIP"`
-	MinPort      uint64         `json:"minPort"`
-	MaxPort      uint64         `json:"maxPort"`
-	PublicIP     string         `json:"publicIP"`
-	CheckPort    uint64         `json:"checkPort"`
-	Netflow      uint64         `json:"netflow"`
-	StartTime    uint64         `json:"startTime"`
-	EndTime      uint64         `json:"endTime"`
-	Ser          common.Address `json:"ser"`
-	NewValue     string         `json:"newValue"`
-	Country      string         `json:"country"`
-	WorkKey      string         `json:"workKey"`
+	Typ          string           `json:"type"`
+	Key          string           `json:"key"`
+	Value        []byte           `json:"value"`
+	To           common.Address   `json:"to"`
+	Units        uint64           `json:"units"`
+	StakerType   uint64           `json:"stakerType"`
+	StakerAddr   common.Address   `json:"stakerAddr"`
+	StakeAmount  uint64           `json:"stakeAmount"`
+	RewardAmount uint64           `json:"rewardAmount"`
+	ActionID     ids.ShortID      `json:"actionID"`
+	LocalIP      string           `json:"localIP"`
+	MinPort      uint64           `json:"minPort"`
+	MaxPort      uint64           `json:"maxPort"`
+	PublicIP     string           `json:"publicIP"`
+	CheckPort    uint64           `json:"checkPort"`
+	Netflow      uint64           `json:"netflow"`
+	StartTime    uint64           `json:"startTime"`
+	EndTime      uint64           `json:"endTime"`
+	Ser          common.Address   `json:"ser"`
+	NewValue     string           `json:"newValue"`
+	Country      string           `json:"country"`
+	WorkKey      string           `json:"workKey"`
+	Choice       string           `json:"choice"`
+	Delegate     common.Address   `json:"delegate"`
+	Members      []common.Address `json:"members"`
+	Threshold    uint32           `json:"threshold"`
+	Amount       uint64           `json:"amount"`
+	Commission   uint32           `json:"commission"`
+	OffenceType  uint64           `json:"offenceType"`
+	Evidence     []byte           `json:"evidence"`
 }
 
 func (i *Input) Decode() (UnsignedTransaction, error) {
@@ -129,7 +151,9 @@ func (i *Input) Decode() (UnsignedTransaction, error) {
 		}, nil
 	case Vote:
 		return &VoteTx{
+			BaseTx:   &BaseTx{},
 			ActionID: i.ActionID,
+			Choice:   i.Choice,
 		}, nil
 	case Proposal:
 		return &ProposalTx{
@@ -140,6 +164,45 @@ func (i *Input) Decode() (UnsignedTransaction, error) {
 			StartTime: i.StartTime,
 			EndTime:   i.EndTime,
 		}, nil
+	case DelegateVote:
+		return &DelegateVoteTx{
+			BaseTx:   &BaseTx{},
+			Delegate: i.Delegate,
+		}, nil
+	case CreateMultisig:
+		return &CreateMultisigTx{
+			BaseTx:    &BaseTx{},
+			Members:   i.Members,
+			Threshold: i.Threshold,
+		}, nil
+	case DelegateStake:
+		return &DelegateStakeTx{
+			BaseTx:     &BaseTx{},
+			StakerAddr: i.StakerAddr,
+			Amount:     i.Amount,
+		}, nil
+	case UndelegateStake:
+		return &UndelegateStakeTx{
+			BaseTx:     &BaseTx{},
+			StakerAddr: i.StakerAddr,
+			Amount:     i.Amount,
+		}, nil
+	case SetCommission:
+		return &SetCommissionTx{
+			BaseTx:         &BaseTx{},
+			CommissionPerc: i.Commission,
+		}, nil
+	case WithdrawStake:
+		return &WithdrawStakeTx{
+			BaseTx: &BaseTx{},
+		}, nil
+	case ReportOffence:
+		return &ReportOffenceTx{
+			BaseTx:      &BaseTx{},
+			Staker:      i.StakerAddr,
+			OffenceType: i.OffenceType,
+			Evidence:    i.Evidence,
+		}, nil
 	default:
 		return nil, ErrInvalidType
 	}
@@ -166,7 +229,18 @@ const (
 	tdStartTime   = "startTime"
 	tdConnections = "connections"
 	tdActionID    = "actionID"
+	tdChoice      = "choice"
+	tdDelegate    = "delegate"
+	tdMembers     = "members"
+	tdThreshold   = "threshold"
+	tdAccount     = "account"
+	tdNonce       = "nonce"
+	tdTxHash      = "txHash"
+	tdApprovals   = "approvals"
+	tdCommission  = "commission"
 	tdKey         = "key"
+	tdOffenceType = "offenceType"
+	tdEvidence    = "evidence"
 
 	tdReward  = "reward"
 	tdSer     = "ser"
@@ -432,7 +506,73 @@ func ParseTypedData(td *tdata.TypedData) (UnsignedTransaction, error) {
 		}
 
 		actionID, _ := ids.ShortFromString(ractionID)
-		return &VoteTx{BaseTx: bTx, ActionID: actionID}, nil
+		choice, ok := td.Message[tdChoice].(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrTypedDataKeyMissing, tdChoice)
+		}
+		return &VoteTx{BaseTx: bTx, ActionID: actionID, Choice: choice}, nil
+	case DelegateVote:
+		delegate, ok := td.Message[tdDelegate].(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrTypedDataKeyMissing, tdDelegate)
+		}
+		return &DelegateVoteTx{BaseTx: bTx, Delegate: common.HexToAddress(delegate)}, nil
+	case CreateMultisig:
+		rmembers, ok := td.Message[tdMembers].(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrTypedDataKeyMissing, tdMembers)
+		}
+		members := []common.Address{}
+		for _, member := range strings.Split(rmembers, ",") {
+			members = append(members, common.HexToAddress(member))
+		}
+		threshold, err := parseUint64Message(td, tdThreshold)
+		if err != nil {
+			return nil, err
+		}
+		return &CreateMultisigTx{BaseTx: bTx, Members: members, Threshold: uint32(threshold)}, nil
+	case DelegateStake, UndelegateStake:
+		stakerAddr, ok := td.Message[tdStakeAddr].(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrTypedDataKeyMissing, tdStakeAddr)
+		}
+		amount, err := parseUint64Message(td, tdAmount)
+		if err != nil {
+			return nil, err
+		}
+		if td.PrimaryType == DelegateStake {
+			return &DelegateStakeTx{BaseTx: bTx, StakerAddr: common.HexToAddress(stakerAddr), Amount: amount}, nil
+		}
+		return &UndelegateStakeTx{BaseTx: bTx, StakerAddr: common.HexToAddress(stakerAddr), Amount: amount}, nil
+	case SetCommission:
+		commission, err := parseUint64Message(td, tdCommission)
+		if err != nil {
+			return nil, err
+		}
+		if commission > 100 {
+			return nil, ErrInvalidCommission
+		}
+		return &SetCommissionTx{BaseTx: bTx, CommissionPerc: uint32(commission)}, nil
+	case WithdrawStake:
+		return &WithdrawStakeTx{BaseTx: bTx}, nil
+	case ReportOffence:
+		stakerAddr, ok := td.Message[tdStakeAddr].(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrTypedDataKeyMissing, tdStakeAddr)
+		}
+		offenceType, err := parseUint64Message(td, tdOffenceType)
+		if err != nil {
+			return nil, err
+		}
+		revidence, ok := td.Message[tdEvidence].(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrTypedDataKeyMissing, tdEvidence)
+		}
+		evidence, err := hexutil.Decode(revidence)
+		if err != nil {
+			return nil, err
+		}
+		return &ReportOffenceTx{BaseTx: bTx, Staker: common.HexToAddress(stakerAddr), OffenceType: offenceType, Evidence: evidence}, nil
 	default:
 		return nil, ErrInvalidType
 	}
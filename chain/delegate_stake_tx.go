@@ -0,0 +1,119 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"strconv"
+
+	"github.com/SamaNetwork/SamaVM/tdata"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var _ UnsignedTransaction = &DelegateStakeTx{}
+
+// DelegateStakeTx bonds [Amount] of the sender to the route or ser node
+// [StakerAddr], see [SplitStakerReward] for its share of the node reward
+type DelegateStakeTx struct {
+	*BaseTx    `serialize:"true" json:"baseTx"`
+	StakerAddr common.Address `serialize:"true" json:"stakerAddr"`
+	Amount     uint64         `serialize:"true" json:"amount"`
+}
+
+// Stake delegation starts with [ForkPhase4]
+func (*DelegateStakeTx) minFork() string {
+	return ForkPhase4
+}
+
+func (d *DelegateStakeTx) Execute(t *TransactionContext) error {
+	samaState := t.State
+	if d.Amount == 0 {
+		return ErrStakeAmount
+	}
+	if d.StakerAddr == t.Sender {
+		return ErrSelfDelegation
+	}
+	if _, err := delegatableStaker(samaState, d.StakerAddr); err != nil {
+		return err
+	}
+	_, suspended, err := samaState.GetSuspension(d.StakerAddr)
+	if err != nil {
+		return err
+	}
+	if suspended {
+		return ErrSuspended
+	}
+
+	if _, err := ModifyBalance(t.Database, t.Sender, false, d.Amount); err != nil {
+		return err
+	}
+	if _, err := ModifyStakeBalance(t.Database, t.Sender, true, d.Amount); err != nil {
+		return err
+	}
+
+	delegation, exist, err := samaState.GetStakeDelegation(d.StakerAddr, t.Sender)
+	if err != nil {
+		return err
+	}
+	if !exist {
+		delegation = &StakeDelegation{
+			Delegator:      t.Sender,
+			StakerAddr:     d.StakerAddr,
+			LastUpdateTime: t.BlockTime,
+		}
+	}
+	delegation.accrue(t.BlockTime)
+	delegation.Amount += d.Amount
+	delegation.TxID = t.TxID
+	if err := samaState.PutStakeDelegation(delegation); err != nil {
+		return err
+	}
+	pool, err := samaState.GetStakerPool(d.StakerAddr)
+	if err != nil {
+		return err
+	}
+	pool.Delegated += d.Amount
+	return samaState.PutStakerPool(pool)
+}
+
+func (d *DelegateStakeTx) FeeUnits(g *Genesis) uint64 {
+	return d.BaseTx.FeeUnits(g)
+}
+
+func (d *DelegateStakeTx) LoadUnits(g *Genesis) uint64 {
+	return d.FeeUnits(g)
+}
+
+func (d *DelegateStakeTx) Copy() UnsignedTransaction {
+	return &DelegateStakeTx{
+		BaseTx:     d.BaseTx.Copy(),
+		StakerAddr: d.StakerAddr,
+		Amount:     d.Amount,
+	}
+}
+
+func (d *DelegateStakeTx) TypedData() *tdata.TypedData {
+	return tdata.CreateTypedData(
+		d.Magic, DelegateStake,
+		[]tdata.Type{
+			{Name: tdStakeAddr, Type: tdAddress},
+			{Name: tdAmount, Type: tdUint64},
+			{Name: tdPrice, Type: tdUint64},
+			{Name: tdBlockID, Type: tdString},
+		},
+		tdata.TypedDataMessage{
+			tdStakeAddr: d.StakerAddr.Hex(),
+			tdAmount:    strconv.FormatUint(d.Amount, 10),
+			tdPrice:     strconv.FormatUint(d.Price, 10),
+			tdBlockID:   d.BlockID.String(),
+		},
+	)
+}
+
+func (d *DelegateStakeTx) Activity() *Activity {
+	return &Activity{
+		Typ:         DelegateStake,
+		StakerAddr:  d.StakerAddr.Hex(),
+		StakeAmount: d.Amount,
+	}
+}
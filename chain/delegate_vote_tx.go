@@ -0,0 +1,112 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/SamaNetwork/SamaVM/tdata"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var _ UnsignedTransaction = &DelegateVoteTx{}
+
+// DelegateVoteTx delegates the governance weight of the sender, a staker, to
+// [Delegate]. The zero address revokes the delegation.
+type DelegateVoteTx struct {
+	*BaseTx  `serialize:"true" json:"baseTx"`
+	Delegate common.Address `serialize:"true" json:"delegate"`
+}
+
+// Vote delegation starts with [ForkPhase4]
+func (*DelegateVoteTx) minFork() string {
+	return ForkPhase4
+}
+
+func (d *DelegateVoteTx) Execute(t *TransactionContext) error {
+	samaState := t.State
+	ok, _, err := samaState.IsStaker(t.Sender)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("sender must staker")
+	}
+	prev, delegated, err := samaState.GetVoteDelegation(t.Sender)
+	if err != nil {
+		return err
+	}
+
+	if d.Delegate == (common.Address{}) {
+		if !delegated {
+			return ErrNotDelegated
+		}
+		return samaState.DelVoteDelegation(t.Sender)
+	}
+	if d.Delegate == t.Sender {
+		return ErrSelfDelegation
+	}
+	if delegated && prev.Delegate == d.Delegate {
+		return fmt.Errorf("equal")
+	}
+	_, chained, err := samaState.GetVoteDelegation(d.Delegate)
+	if err != nil {
+		return err
+	}
+	if chained {
+		return fmt.Errorf("%w: %s delegated its weight", ErrNestedDelegation, d.Delegate)
+	}
+	delegators, err := samaState.GetDelegators(t.Sender)
+	if err != nil {
+		return err
+	}
+	if len(delegators) > 0 {
+		return fmt.Errorf("%w: sender holds delegations", ErrNestedDelegation)
+	}
+	return samaState.PutVoteDelegation(&VoteDelegation{
+		Delegator:    t.Sender,
+		Delegate:     d.Delegate,
+		TxID:         t.TxID,
+		DelegateTime: t.BlockTime,
+	})
+}
+
+func (d *DelegateVoteTx) FeeUnits(g *Genesis) uint64 {
+	return d.BaseTx.FeeUnits(g)
+}
+
+func (d *DelegateVoteTx) LoadUnits(g *Genesis) uint64 {
+	return d.FeeUnits(g)
+}
+
+func (d *DelegateVoteTx) Copy() UnsignedTransaction {
+	return &DelegateVoteTx{
+		BaseTx:   d.BaseTx.Copy(),
+		Delegate: d.Delegate,
+	}
+}
+
+func (d *DelegateVoteTx) TypedData() *tdata.TypedData {
+	return tdata.CreateTypedData(
+		d.Magic, DelegateVote,
+		[]tdata.Type{
+			{Name: tdDelegate, Type: tdAddress},
+			{Name: tdPrice, Type: tdUint64},
+			{Name: tdBlockID, Type: tdString},
+		},
+		tdata.TypedDataMessage{
+			tdDelegate: d.Delegate.Hex(),
+			tdPrice:    strconv.FormatUint(d.Price, 10),
+			tdBlockID:  d.BlockID.String(),
+		},
+	)
+}
+
+func (d *DelegateVoteTx) Activity() *Activity {
+	return &Activity{
+		Typ: DelegateVote,
+		To:  d.Delegate.Hex(),
+	}
+}
@@ -18,9 +18,12 @@ var (
 	ErrNoTxs                  = errors.New("no transactions")
 	ErrInvalidCost            = errors.New("invalid block cost")
 	ErrInvalidPrice           = errors.New("invalid price")
+	ErrInvalidHeight          = errors.New("invalid block height")
 	ErrInsufficientSurplus    = errors.New("insufficient surplus fee")
 	ErrParentBlockNotVerified = errors.New("parent block not verified or accepted")
 	ErrInvalidAccessProof     = errors.New("invalid access proof")
+	ErrInvalidStateRoot       = errors.New("invalid state root")
+	ErrInvalidCodecVersion    = errors.New("invalid codec version")
 
 	// Tx Correctness
 	ErrInvalidBlockID      = errors.New("invalid blockID")
@@ -28,6 +31,7 @@ var (
 	ErrDuplicateTx         = errors.New("duplicate transaction")
 	ErrInsufficientPrice   = errors.New("insufficient price")
 	ErrInvalidType         = errors.New("invalid tx type")
+	ErrInactiveFork        = errors.New("tx type not active")
 	ErrTypedDataKeyMissing = errors.New("typed data key missing")
 
 	// Execution Correctness
@@ -41,6 +45,7 @@ var (
 	ErrNonActionable  = errors.New("transaction doesn't do anything")
 	ErrBlockTooBig    = errors.New("block too big")
 	ErrStakerType     = errors.New("staker type err")
+	ErrSuspended      = errors.New("staker is suspended")
 
 	ErrStakeAmount     = errors.New("stake amount err")
 	ErrIDErr           = errors.New("id err")
@@ -67,6 +67,10 @@ type Genesis struct {
 
 	MinerPerc      uint32 `serialize:"true" json:"minerPerc"`
 	FoundationPerc uint32 `serialize:"true" json:"foundationPerc"`
+	// Vesting schedule of the foundation share in seconds from chain
+	// creation, see [FoundationVestingMeta]
+	FoundationCliff   uint64 `serialize:"true" json:"foundationCliff"`
+	FoundationVesting uint64 `serialize:"true" json:"foundationVesting"`
 
 	RoutePerc     uint32 `serialize:"true" json:"routePerc"`
 	SerPerc       uint32 `serialize:"true" json:"serPerc"`
@@ -87,9 +91,24 @@ type Genesis struct {
 	AnnualCard uint64 `serialize:"true" json:"annual"`
 
 	MinStakeTime uint64 `serialize:"true" json:"minStakeTime"`
+	// UnbondingTime is how long the stake of a leaving staker stays locked
+	UnbondingTime uint64 `serialize:"true" json:"unbondingTime"`
 
 	RootAddress    string `serialize:"true" json:"rootAddress"`
 	FoundationAddr string `serialize:"true" json:"foundation"`
+
+	// Multisig accounts created at genesis, so the root and foundation can
+	// be multisig accounts from the start
+	Multisigs []*MultisigAccount `serialize:"true" json:"multisigs"`
+
+	// Governance params, action types without a policy use
+	// [DefaultVotingPolicy]
+	VotingPolicies []*VotingPolicy `serialize:"true" json:"votingPolicies"`
+	// Offence types without a policy use [DefaultSlashingPolicy]
+	SlashingPolicies []*SlashingPolicy `serialize:"true" json:"slashingPolicies"`
+
+	// State sync params
+	StateSyncInterval uint64 `serialize:"true" json:"stateSyncInterval"` // blocks
 }
 
 func DefaultGenesis() *Genesis {
@@ -117,7 +136,8 @@ func DefaultGenesis() *Genesis {
 		TotalYears:       10,
 		RateSustainYears: 1,
 
-		MinStakeTime: 90 * 24 * 60 * 60,
+		MinStakeTime:  90 * 24 * 60 * 60,
+		UnbondingTime: 14 * 24 * 60 * 60,
 
 		MinerPerc:      20,
 		FoundationPerc: 40,
@@ -142,6 +162,8 @@ func DefaultGenesis() *Genesis {
 		BurnPerc:       20,
 		RootAddress:    "0x8db97c7cece249c2b98bdc0226cc4c2a57bf52fc",
 		FoundationAddr: "0x8db97c7cece249c2b98bdc0226cc4c2a57bf52fc",
+
+		StateSyncInterval: 1024,
 	}
 }
 
@@ -159,6 +181,34 @@ func (g *Genesis) Verify() error {
 	if g.TargetBlockRate == 0 {
 		return ErrInvalidBlockRate
 	}
+	for i, policy := range g.VotingPolicies {
+		if err := policy.Verify(); err != nil {
+			return err
+		}
+		for _, prev := range g.VotingPolicies[:i] {
+			if prev.ActionType == policy.ActionType {
+				return fmt.Errorf("%w: duplicate policy of action type %d", ErrInvalidVotingPolicy, policy.ActionType)
+			}
+		}
+	}
+	for i, policy := range g.SlashingPolicies {
+		if err := policy.Verify(); err != nil {
+			return err
+		}
+		for _, prev := range g.SlashingPolicies[:i] {
+			if prev.OffenceType == policy.OffenceType {
+				return fmt.Errorf("%w: duplicate policy of offence type %d", ErrInvalidSlashingPolicy, policy.OffenceType)
+			}
+		}
+	}
+	for _, account := range g.Multisigs {
+		if err := account.Verify(); err != nil {
+			return err
+		}
+		if account.Address != MultisigAddress(account.Members, account.Threshold) {
+			return fmt.Errorf("%w: expected address %s", ErrInvalidMultisig, MultisigAddress(account.Members, account.Threshold))
+		}
+	}
 	return nil
 }
 
@@ -200,6 +250,13 @@ func (g *Genesis) Load(db database.Database, airdropData []byte) error {
 		log.Debug("applied custom allocation", "addr", alloc.Address, "balance", alloc.Balance)
 	}
 
+	for _, account := range g.Multisigs {
+		if err := NewMultisigState(vdb).PutMultisig(account); err != nil {
+			return fmt.Errorf("%w: multisig=%s", err, account.Address)
+		}
+		log.Debug("created multisig account", "addr", account.Address, "threshold", account.Threshold)
+	}
+
 	// Commit as a batch to improve speed
 	return vdb.Commit()
 }
@@ -6,11 +6,9 @@ package chain
 import (
 	"fmt"
 	"strconv"
-	"time"
 
 	"github.com/SamaNetwork/SamaVM/tdata"
 	"github.com/ava-labs/avalanchego/ids"
-	"github.com/ethereum/go-ethereum/common"
 )
 
 var _ UnsignedTransaction = &GovernTx{}
@@ -21,69 +19,44 @@ type GovernTx struct {
 }
 
 func (g *GovernTx) Execute(t *TransactionContext) error {
-	samaState := t.vm.SamaState()
+	samaState := t.State
 
-	action, exist, err := samaState.GetActionMeta(g.ActionID)
+	action, exist, err := samaState.GetActionMeta(g.ActionID, t.BlockTime)
 	if err != nil {
 		return err
 	}
 	if !exist {
 		return fmt.Errorf("action not found")
 	}
-
-	//if uint64(time.Now().Unix()) < action.EndTime {
-	//	return fmt.Errorf("need > 7 days")
-	//}
-
-	if uint64(time.Now().Unix()) > action.EndTime+Seconds7Day {
-		return fmt.Errorf("need < 14 days")
+	if !action.IsOpen(t.BlockTime) {
+		return ErrActionClosed
 	}
 
-	isBeConfirmed := false
-	votersNum := len(action.Voters)
-	routeNum, _, _ := samaState.GetStakersNum()
-	if routeNum < 3 {
-		sroot := samaState.GetRootAddress()
-		for _, voter := range action.Voters {
-			if voter == common.HexToAddress(sroot) {
-				isBeConfirmed = true
-				break
-			}
-		}
-	} else {
-		if votersNum >= routeNum*2/3 {
-			isBeConfirmed = true
-		}
+	policy, err := samaState.GetVotingPolicy(action.ActionType)
+	if err != nil {
+		return err
 	}
-	if !isBeConfirmed {
+	// Blocks execute these actions from [ForkPhase1]
+	if policy.AutoExecute && t.Rules.IsPhase1 {
+		return ErrAutoExecuted
+	}
+	ga, err := GetGovernanceAction(action.ActionType)
+	if err != nil {
+		return err
+	}
+	approved, err := ga.Approved(samaState, action)
+	if err != nil {
+		return err
+	}
+	if !approved {
 		return fmt.Errorf("action not be confirmed")
 	}
-	key := action.Key
-	newValue := action.NewValue
-	switch action.ActionType {
-	case actionTypeModifySysParam, actionTypeModifyFoundation:
-		err = samaState.ModifyParams(t.Database, key, newValue, t.TxID, t.BlockTime)
-	case actionTypeAddUserType, actionTypeModifyUserType:
-		userType, err := strconv.ParseUint(key, 10, 64)
-		if err != nil {
-			return err
-		}
-		userFee, err := strconv.ParseUint(newValue, 10, 64)
-		if err != nil {
-			return err
-		}
-		err = samaState.AddUserType(t.Database, &UserType{
-			TypeID:   userType,
-			FeeUnits: userFee,
-		})
-		if err != nil {
-			return err
-		}
-	default:
-		return fmt.Errorf("action type err")
+	if err := ga.Execute(t, action); err != nil {
+		return err
 	}
-
-	return err
+	action.TxIDs = append(action.TxIDs, t.TxID)
+	action.Transition(ActionExecuted, t.TxID, t.BlockTime)
+	return samaState.PutAction(action.ActionID, action)
 }
 
 func (g *GovernTx) FeeUnits(genesis *Genesis) uint64 {
@@ -0,0 +1,227 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	ErrUnknownActionType = errors.New("action type not exist")
+	ErrNotProposable     = errors.New("action is proposed by another tx")
+	ErrNotExecutable     = errors.New("action is executed by another tx")
+	ErrAutoExecuted      = errors.New("action is executed automatically")
+)
+
+// GovernanceAction defines how proposals of an action type are checked,
+// approved and applied. Each action type registers one in [init].
+type GovernanceAction interface {
+	// Validate checks a proposal to change [key] to [newValue]
+	Validate(t *TransactionContext, key string, newValue string) error
+	// Approved reports whether [action] has enough votes to be executed
+	Approved(s SamaState, action *ActionMeta) (bool, error)
+	// Rejected reports whether [action] has enough votes against it to be
+	// rejected before voting ends
+	Rejected(s SamaState, action *ActionMeta) (bool, error)
+	// Execute applies an approved [action]
+	Execute(t *TransactionContext, action *ActionMeta) error
+}
+
+var governanceActions = map[uint64]GovernanceAction{}
+
+func init() {
+	RegisterGovernanceAction(actionTypeAddStaker, &addStakerAction{})
+	RegisterGovernanceAction(actionTypeModifySysParam, &sysParamAction{actionType: actionTypeModifySysParam})
+	RegisterGovernanceAction(actionTypeAddUserType, &addUserTypeAction{})
+	RegisterGovernanceAction(actionTypeModifyUserType, &modifyUserTypeAction{})
+	RegisterGovernanceAction(actionTypeModifyFoundation, &sysParamAction{actionType: actionTypeModifyFoundation})
+	RegisterGovernanceAction(actionTypeSetVotingPolicy, &votingPolicyAction{})
+	RegisterGovernanceAction(actionTypeRemoveStaker, &removeStakerAction{})
+	RegisterGovernanceAction(actionTypeSuspendStaker, &suspendStakerAction{})
+	RegisterGovernanceAction(actionTypeSlashStaker, &slashStakerAction{})
+	RegisterGovernanceAction(actionTypeSetSlashingPolicy, &slashingPolicyAction{})
+}
+
+// RegisterGovernanceAction makes [actionType] governable. It panics if the
+// type is already registered.
+func RegisterGovernanceAction(actionType uint64, action GovernanceAction) {
+	if _, ok := governanceActions[actionType]; ok {
+		panic(fmt.Sprintf("governance action %d registered twice", actionType))
+	}
+	governanceActions[actionType] = action
+}
+
+func GetGovernanceAction(actionType uint64) (GovernanceAction, error) {
+	action, ok := governanceActions[actionType]
+	if !ok {
+		return nil, ErrUnknownActionType
+	}
+	return action, nil
+}
+
+// proposalVoters returns the initial voters of a proposal of [actionType] by
+// [sender]: the proposer votes for it if they are allowed to vote
+func proposalVoters(s SamaState, actionType uint64, sender common.Address) ([]common.Address, error) {
+	voters := []common.Address{}
+	policy, err := s.GetVotingPolicy(actionType)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := canVote(s, policy, sender)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		voters = append(voters, sender)
+	}
+	return voters, nil
+}
+
+// putVotedAction stores [action] after its votes changed, moving it between
+// pending and approved or rejecting it
+func putVotedAction(t *TransactionContext, action *ActionMeta) error {
+	ga, err := GetGovernanceAction(action.ActionType)
+	if err != nil {
+		return err
+	}
+	approved, err := ga.Approved(t.State, action)
+	if err != nil {
+		return err
+	}
+	rejected, err := ga.Rejected(t.State, action)
+	if err != nil {
+		return err
+	}
+	status := ActionPending
+	switch {
+	case rejected:
+		status = ActionRejected
+	case approved:
+		status = ActionApproved
+	}
+	action.Transition(status, t.TxID, t.BlockTime)
+	return t.State.PutAction(action.ActionID, action)
+}
+
+// tallyApproval approves actions according to the [VotingPolicy] of their
+// type
+type tallyApproval struct{}
+
+func (tallyApproval) Approved(s SamaState, action *ActionMeta) (bool, error) {
+	tally, err := TallyAction(s, action)
+	if err != nil {
+		return false, err
+	}
+	return tally.Approved, nil
+}
+
+func (tallyApproval) Rejected(s SamaState, action *ActionMeta) (bool, error) {
+	tally, err := TallyAction(s, action)
+	if err != nil {
+		return false, err
+	}
+	return tally.Rejected, nil
+}
+
+// addStakerAction approves a node registered by [RegisterTx] ([key] is its
+// staker address) to stake with [StakeTx]
+type addStakerAction struct {
+	tallyApproval
+}
+
+func (*addStakerAction) Validate(*TransactionContext, string, string) error {
+	return ErrNotProposable
+}
+
+func (*addStakerAction) Execute(*TransactionContext, *ActionMeta) error {
+	return ErrNotExecutable
+}
+
+// sysParamAction sets the system parameter [key] to [newValue]. Each param
+// is governed by the action type of its [ParamSchema].
+type sysParamAction struct {
+	tallyApproval
+	actionType uint64
+}
+
+func (a *sysParamAction) Validate(t *TransactionContext, key string, newValue string) error {
+	schema, err := GetParamSchema(key)
+	if err != nil {
+		return err
+	}
+	if schema.ActionType != a.actionType {
+		return fmt.Errorf("%w: %s is changed by action type %d", ErrInvalidParam, key, schema.ActionType)
+	}
+	return t.State.CompCurParam(key, newValue)
+}
+
+func (*sysParamAction) Execute(t *TransactionContext, action *ActionMeta) error {
+	return t.State.ModifyParams(action.Key, action.NewValue, t.TxID, t.BlockTime)
+}
+
+// addUserTypeAction adds the user type [key] with fee [newValue]
+type addUserTypeAction struct {
+	tallyApproval
+}
+
+func (*addUserTypeAction) Validate(t *TransactionContext, key string, _ string) error {
+	newType, err := strconv.ParseUint(key, 10, 64)
+	if err != nil {
+		return err
+	}
+	if t.State.CheckUserType(newType) {
+		return fmt.Errorf("user type exist")
+	}
+	return nil
+}
+
+func (*addUserTypeAction) Execute(t *TransactionContext, action *ActionMeta) error {
+	return putUserType(t.State, action)
+}
+
+// modifyUserTypeAction changes the fee of the user type [key] to [newValue]
+type modifyUserTypeAction struct {
+	tallyApproval
+}
+
+func (*modifyUserTypeAction) Validate(t *TransactionContext, key string, newValue string) error {
+	userType, err := strconv.ParseUint(key, 10, 64)
+	if err != nil {
+		return err
+	}
+	userFee, err := strconv.ParseUint(newValue, 10, 64)
+	if err != nil {
+		return err
+	}
+	if !t.State.CheckUserType(userType) {
+		return fmt.Errorf("user type not exist")
+	}
+	if t.State.CheckFee(userType, userFee) {
+		return fmt.Errorf("equal")
+	}
+	return nil
+}
+
+func (*modifyUserTypeAction) Execute(t *TransactionContext, action *ActionMeta) error {
+	return putUserType(t.State, action)
+}
+
+func putUserType(s SamaState, action *ActionMeta) error {
+	userType, err := strconv.ParseUint(action.Key, 10, 64)
+	if err != nil {
+		return err
+	}
+	userFee, err := strconv.ParseUint(action.NewValue, 10, 64)
+	if err != nil {
+		return err
+	}
+	return s.AddUserType(&UserType{
+		TypeID:   userType,
+		FeeUnits: userFee,
+	})
+}
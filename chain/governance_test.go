@@ -0,0 +1,441 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/database/memdb"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestGovernanceActions(t *testing.T) {
+	t.Parallel()
+
+	for actionType := uint64(actionTypeStart + 1); actionType < actionTypeEnd; actionType++ {
+		if _, err := GetGovernanceAction(actionType); err != nil {
+			t.Fatalf("action type %d not registered", actionType)
+		}
+	}
+	if _, err := GetGovernanceAction(actionTypeEnd); !errors.Is(err, ErrUnknownActionType) {
+		t.Fatalf("expected %v, got %v", ErrUnknownActionType, err)
+	}
+
+	db := memdb.New()
+	defer db.Close()
+	g := DefaultGenesis()
+	if err := g.Load(db, nil); err != nil {
+		t.Fatal(err)
+	}
+	state := SamaNew(db, g)
+	root := common.HexToAddress(state.GetRootAddress())
+	other := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	tctx := testTxContexts(g, db, Rules{})
+
+	// Proposals are checked by their action type
+	propose := func(actionID ids.ShortID, actionType uint64, sender common.Address) error {
+		return (&ProposalTx{ActionID: actionID, ActionType: actionType, Key: "7", NewValue: "100"}).Execute(tctx(sender, 10))
+	}
+	if err := propose(ids.ShortID{1}, actionTypeAddStaker, root); !errors.Is(err, ErrNotProposable) {
+		t.Fatalf("expected %v, got %v", ErrNotProposable, err)
+	}
+	if err := propose(ids.ShortID{1}, actionTypeEnd, root); !errors.Is(err, ErrUnknownActionType) {
+		t.Fatalf("expected %v, got %v", ErrUnknownActionType, err)
+	}
+	if err := propose(ids.ShortID{1}, actionTypeModifyUserType, root); err == nil {
+		t.Fatal("modified missing user type")
+	}
+
+	// Without route nodes, actions are approved by the root address
+	if err := propose(ids.ShortID{1}, actionTypeAddUserType, other); err != nil {
+		t.Fatal(err)
+	}
+	govern := &GovernTx{ActionID: ids.ShortID{1}}
+	if err := govern.Execute(tctx(other, 11)); err == nil {
+		t.Fatal("executed action without votes")
+	}
+	if err := (&VoteTx{ActionID: ids.ShortID{1}, Choice: VoteFor}).Execute(tctx(root, 11)); err != nil {
+		t.Fatal(err)
+	}
+	if approved, err := state.ProposalStatus(ids.ShortID{1}, 11); err != nil || !approved {
+		t.Fatalf("action not approved (%v)", err)
+	}
+	if err := govern.Execute(tctx(other, 12)); err != nil {
+		t.Fatal(err)
+	}
+	if !state.CheckUserType(7) || !state.CheckFee(7, 100) {
+		t.Fatal("user type not added")
+	}
+	if err := propose(ids.ShortID{2}, actionTypeAddUserType, root); err == nil {
+		t.Fatal("added existing user type")
+	}
+}
+
+func TestActionLifecycle(t *testing.T) {
+	t.Parallel()
+
+	db := memdb.New()
+	defer db.Close()
+	g := DefaultGenesis()
+	if err := g.Load(db, nil); err != nil {
+		t.Fatal(err)
+	}
+	state := SamaNew(db, g)
+	root := common.HexToAddress(state.GetRootAddress())
+	other := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	tctx := testTxContexts(g, db, Rules{})
+	propose := func(actionID ids.ShortID, key string, sender common.Address, blockTime uint64) {
+		proposal := &ProposalTx{ActionID: actionID, ActionType: actionTypeAddUserType, Key: key, NewValue: "100"}
+		if err := proposal.Execute(tctx(sender, blockTime)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// checkStatus checks the status of [actionID] at [blockTime] and the
+	// transitions recorded so far
+	checkStatus := func(actionID ids.ShortID, blockTime uint64, status string, recorded ...string) {
+		action, exist, err := state.GetActionMeta(actionID, blockTime)
+		if err != nil || !exist {
+			t.Fatalf("action %s not found (%v)", actionID, err)
+		}
+		if s := action.StatusAt(blockTime); s != status {
+			t.Fatalf("expected %s, got %s", status, s)
+		}
+		statuses := make([]string, len(action.Transitions))
+		for i, tr := range action.Transitions {
+			statuses[i] = tr.Status
+		}
+		if fmt.Sprint(statuses) != fmt.Sprint(recorded) {
+			t.Fatalf("expected transitions %v, got %v", recorded, statuses)
+		}
+	}
+
+	// The root address approves its own proposal while there are no routes
+	propose(ids.ShortID{1}, "7", root, 10)
+	checkStatus(ids.ShortID{1}, 10, ActionApproved, ActionPending, ActionApproved)
+	if err := (&GovernTx{ActionID: ids.ShortID{1}}).Execute(tctx(other, 11)); err != nil {
+		t.Fatal(err)
+	}
+	checkStatus(ids.ShortID{1}, 11, ActionExecuted, ActionPending, ActionApproved, ActionExecuted)
+	if err := (&GovernTx{ActionID: ids.ShortID{1}}).Execute(tctx(other, 12)); !errors.Is(err, ErrActionClosed) {
+		t.Fatalf("expected %v, got %v", ErrActionClosed, err)
+	}
+
+	// Proposers may withdraw their actions
+	propose(ids.ShortID{2}, "8", other, 10)
+	checkStatus(ids.ShortID{2}, 10, ActionPending, ActionPending)
+	if err := (&WithdrawnTx{ActionID: ids.ShortID{2}}).Execute(tctx(other, 11)); err != nil {
+		t.Fatal(err)
+	}
+	checkStatus(ids.ShortID{2}, 11, ActionWithdrawn, ActionPending, ActionWithdrawn)
+	if err := (&VoteTx{ActionID: ids.ShortID{2}, Choice: VoteFor}).Execute(tctx(root, 12)); !errors.Is(err, ErrActionClosed) {
+		t.Fatalf("expected %v, got %v", ErrActionClosed, err)
+	}
+
+	// Actions not approved in time are rejected, and the outcome is recorded
+	// by the next proposal for the key
+	propose(ids.ShortID{3}, "9", other, 10)
+	end := 10 + Seconds7Day
+	checkStatus(ids.ShortID{3}, end+1, ActionRejected, ActionPending)
+	propose(ids.ShortID{4}, "9", root, end+1)
+	checkStatus(ids.ShortID{3}, end+1, ActionRejected, ActionPending, ActionRejected)
+
+	// Approved actions expire if they are not executed
+	checkStatus(ids.ShortID{4}, end+1, ActionApproved, ActionPending, ActionApproved)
+	expiry := end + 1 + Seconds7Day + ActionExecutionWindow + 1
+	checkStatus(ids.ShortID{4}, expiry, ActionExpired, ActionPending, ActionApproved)
+	if err := (&GovernTx{ActionID: ids.ShortID{4}}).Execute(tctx(other, expiry)); !errors.Is(err, ErrActionClosed) {
+		t.Fatalf("expected %v, got %v", ErrActionClosed, err)
+	}
+}
+
+func TestLegacyActions(t *testing.T) {
+	t.Parallel()
+
+	db := memdb.New()
+	defer db.Close()
+	g := DefaultGenesis()
+	if err := g.Load(db, nil); err != nil {
+		t.Fatal(err)
+	}
+	state := SamaNew(db, g)
+	root := common.HexToAddress(state.GetRootAddress())
+
+	// Votes without a choice keep their encoding and signed data
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vote := &VoteTx{BaseTx: &BaseTx{BlockID: ids.GenerateTestID(), Magic: g.Magic}, ActionID: ids.ShortID{1}}
+	if td := vote.TypedData(); td.PrimaryType != Govern {
+		t.Fatalf("expected legacy typed data, got %s", td.PrimaryType)
+	}
+	dh, err := DigestHash(vote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := Sign(dh, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := NewTx(vote, sig)
+	if err := tx.Init(g); err != nil {
+		t.Fatal(err)
+	}
+	if version, err := Unmarshal(tx.Bytes(), new(Transaction)); err != nil || version != legacyCodecVersion {
+		t.Fatalf("expected codec version %d, got %d (%v)", legacyCodecVersion, version, err)
+	}
+	if minCodecVersion(&VoteTx{Choice: VoteAgainst}) != codecVersion {
+		t.Fatal("vote choice encoded with the legacy codec")
+	}
+
+	// Actions stored before the fork move to the current layout once
+	for _, action := range []*ActionMeta{
+		{ActionID: ids.ShortID{1}, ActionType: actionTypeAddUserType, StartTime: 1, EndTime: 100, Key: "7", NewValue: "100"},
+		{ActionID: ids.ShortID{2}, ActionType: actionTypeAddUserType, StartTime: 1, EndTime: 5, Key: "8", NewValue: "100", Voters: []common.Address{root}},
+	} {
+		b, err := marshalVersion(legacyCodecVersion, action)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Put(PrefixActionsKey(action.ActionID), b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := migrateActions(g, db, Rules{}, 10); err != nil {
+		t.Fatal(err)
+	}
+	if actions, err := state.GetActionsByKey("7"); err != nil || len(actions) != 0 {
+		t.Fatalf("migrated before the fork: %d (%v)", len(actions), err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := migrateActions(g, db, Rules{IsPhase1: true}, 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Actions whose voting ended are not executed again
+	action, _, err := state.GetActionMeta(ids.ShortID{2}, 10)
+	if err != nil || action.Status != ActionExpired || len(action.Transitions) != 1 {
+		t.Fatalf("unexpected legacy action %+v (%v)", action, err)
+	}
+	actions, err := state.GetActionsByKey("7")
+	if err != nil || len(actions) != 1 || actions[0].Status != ActionPending {
+		t.Fatalf("unexpected legacy actions %+v (%v)", actions, err)
+	}
+
+	// A vote without a choice is for the action
+	if err := vote.Execute(&TransactionContext{
+		Genesis:   g,
+		Database:  db,
+		BlockTime: 11,
+		TxID:      ids.GenerateTestID(),
+		Sender:    root,
+		State:     state,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	action, _, err = state.GetActionMeta(ids.ShortID{1}, 11)
+	if err != nil || action.VoteOf(root) != VoteFor || action.Status != ActionApproved {
+		t.Fatalf("unexpected action %+v (%v)", action, err)
+	}
+	if due, err := state.GetDueActions(101); err != nil || len(due) != 1 || due[0].ActionID != action.ActionID {
+		t.Fatalf("unexpected due actions %+v (%v)", due, err)
+	}
+}
+
+func TestAutoExecution(t *testing.T) {
+	t.Parallel()
+
+	db := memdb.New()
+	defer db.Close()
+	g := DefaultGenesis()
+	policy := DefaultVotingPolicy(actionTypeAddUserType)
+	policy.AutoExecute = true
+	policy.Timelock = 100
+	g.VotingPolicies = []*VotingPolicy{policy}
+	if err := g.Load(db, nil); err != nil {
+		t.Fatal(err)
+	}
+	state := SamaNew(db, g)
+	root := common.HexToAddress(state.GetRootAddress())
+	other := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	tctx := testTxContexts(g, db, Rules{IsPhase1: true})
+	for i, sender := range []common.Address{root, other} {
+		proposal := &ProposalTx{ActionID: ids.ShortID{byte(i + 1)}, ActionType: actionTypeAddUserType, Key: fmt.Sprint(7 + i), NewValue: "100"}
+		if err := proposal.Execute(tctx(sender, 10)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := (&GovernTx{ActionID: ids.ShortID{1}}).Execute(tctx(other, 11)); !errors.Is(err, ErrAutoExecuted) {
+		t.Fatalf("expected %v, got %v", ErrAutoExecuted, err)
+	}
+
+	end := 10 + Seconds7Day
+	// Blocks before the fork don't close actions
+	if receipts, err := ProcessActions(g, db, Rules{}, ids.GenerateTestID(), 1, end+1); err != nil || len(receipts) != 0 {
+		t.Fatalf("closed %d actions before the fork (%v)", len(receipts), err)
+	}
+	process := func(height uint64, blockTime uint64) []*Receipt {
+		receipts, err := ProcessActions(g, db, Rules{IsPhase1: true}, ids.GenerateTestID(), height, blockTime)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return receipts
+	}
+	if receipts := process(1, end); len(receipts) != 0 {
+		t.Fatalf("closed %d actions before the deadline", len(receipts))
+	}
+
+	// The action without votes is rejected once voting ends, the approved one
+	// waits for its timelock
+	receipts := process(2, end+1)
+	if len(receipts) != 1 || receipts[0].TxID != AutoExecutionID(2, ids.ShortID{2}) || receipts[0].Actions[0].Status != ActionRejected {
+		t.Fatalf("unexpected receipts %+v", receipts)
+	}
+	if state.CheckUserType(7) {
+		t.Fatal("action executed before its timelock")
+	}
+	receipts = process(3, end+101)
+	if len(receipts) != 1 || receipts[0].Actions[0].ActionID != (ids.ShortID{1}) || receipts[0].Actions[0].Status != ActionExecuted {
+		t.Fatalf("unexpected receipts %+v", receipts)
+	}
+	if !state.CheckUserType(7) || state.CheckUserType(8) {
+		t.Fatal("unexpected user types")
+	}
+	action, _, err := state.GetActionMeta(ids.ShortID{1}, end+101)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := action.Transitions[len(action.Transitions)-1]
+	if action.Status != ActionExecuted || last.TxID != receipts[0].TxID || last.BlockTime != end+101 {
+		t.Fatalf("execution not recorded %+v", last)
+	}
+	if receipts := process(4, end+ActionExecutionWindow+1); len(receipts) != 0 {
+		t.Fatalf("closed %d actions twice", len(receipts))
+	}
+}
+
+func TestActionKeys(t *testing.T) {
+	t.Parallel()
+
+	db := memdb.New()
+	defer db.Close()
+	g := DefaultGenesis()
+	if err := g.Load(db, nil); err != nil {
+		t.Fatal(err)
+	}
+	root := common.HexToAddress(SamaNew(db, g).GetRootAddress())
+	tctx := testTxContexts(g, db, Rules{IsPhase4: true})
+	policy, err := json.Marshal(DefaultVotingPolicy(actionTypeRemoveStaker))
+	if err != nil {
+		t.Fatal(err)
+	}
+	propose := func(actionID ids.ShortID, actionType uint64, newValue string) error {
+		proposal := &ProposalTx{ActionID: actionID, ActionType: actionType, Key: "7", NewValue: newValue}
+		return proposal.Execute(tctx(root, 10))
+	}
+
+	// Adding user type 7 and setting the voting policy of action type 7 are
+	// open on the same key, but not twice each
+	if err := propose(ids.ShortID{1}, actionTypeAddUserType, "100"); err != nil {
+		t.Fatal(err)
+	}
+	if err := propose(ids.ShortID{2}, actionTypeSetVotingPolicy, string(policy)); err != nil {
+		t.Fatal(err)
+	}
+	if err := propose(ids.ShortID{3}, actionTypeAddUserType, "200"); err == nil {
+		t.Fatal("repeated user type proposal")
+	}
+	if err := propose(ids.ShortID{3}, actionTypeSetVotingPolicy, string(policy)); err == nil {
+		t.Fatal("repeated voting policy proposal")
+	}
+}
+
+func TestStakerGovernance(t *testing.T) {
+	t.Parallel()
+
+	db := memdb.New()
+	defer db.Close()
+	g := DefaultGenesis()
+	if err := g.Load(db, nil); err != nil {
+		t.Fatal(err)
+	}
+	state := SamaNew(db, g)
+	root := common.HexToAddress(state.GetRootAddress())
+	route, work := common.Address{1}, common.Address{2}
+	if err := state.PutStaker(&StakerMeta{StakerType: stakerTypeRoute, StakeAmount: 1000, StakeTime: 10, StakerAddr: route}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetStakeBalance(db, route, 1000); err != nil {
+		t.Fatal(err)
+	}
+	if err := state.PutDetail(work, &DetailMeta{StakerType: stakerTypeRoute, WorkAddress: work, StakeAddress: route}); err != nil {
+		t.Fatal(err)
+	}
+	tctx := testTxContexts(g, db, Rules{IsPhase4: true})
+	// govern proposes and executes an action, approved by the root address
+	// while there are few routes
+	govern := func(actionID ids.ShortID, actionType uint64, newValue string) error {
+		proposal := &ProposalTx{ActionID: actionID, ActionType: actionType, Key: route.Hex(), NewValue: newValue}
+		if err := proposal.Execute(tctx(root, 20)); err != nil {
+			return err
+		}
+		return (&GovernTx{ActionID: actionID}).Execute(tctx(root, 21))
+	}
+
+	if err := govern(ids.ShortID{1}, actionTypeSuspendStaker, "false"); err == nil {
+		t.Fatal("reinstated active staker")
+	}
+	if err := govern(ids.ShortID{1}, actionTypeSuspendStaker, "true"); err != nil {
+		t.Fatal(err)
+	}
+	if _, suspended, err := state.GetSuspension(route); err != nil || !suspended {
+		t.Fatalf("staker not suspended (%v)", err)
+	}
+	if ok, _, err := state.IsValidWorkAddress(work); err != nil || ok {
+		t.Fatalf("suspended node can submit proofs (%v)", err)
+	}
+	policy := DefaultVotingPolicy(actionTypeAddUserType)
+	if ok, err := canVote(state, policy, route); err != nil || ok {
+		t.Fatalf("suspended node can vote (%v)", err)
+	}
+	if err := (&UnStakeTx{StakerType: stakerTypeRoute, EndTime: 30}).Execute(tctx(route, 30)); !errors.Is(err, ErrSuspended) {
+		t.Fatalf("expected %v, got %v", ErrSuspended, err)
+	}
+
+	if err := govern(ids.ShortID{2}, actionTypeRemoveStaker, `{"slashPerc":101}`); !errors.Is(err, ErrInvalidRemoval) {
+		t.Fatalf("expected %v, got %v", ErrInvalidRemoval, err)
+	}
+	if err := govern(ids.ShortID{2}, actionTypeRemoveStaker, `{"forfeitRewards":true,"slashPerc":30}`); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _, err := state.IsStaker(route); err != nil || ok {
+		t.Fatalf("staker not removed (%v)", err)
+	}
+	if _, suspended, _ := state.GetSuspension(route); suspended {
+		t.Fatal("suspension not cleared")
+	}
+	if _, exist, _ := state.GetDetailMeta(work); exist {
+		t.Fatal("registration not cleared")
+	}
+	foundation := common.HexToAddress(state.GetFoundationAddress())
+	for _, tt := range []struct {
+		address common.Address
+		balance uint64
+	}{
+		{route, 700},
+		{foundation, 300},
+	} {
+		if bal, err := GetBalance(db, tt.address); err != nil || bal != tt.balance {
+			t.Fatalf("expected balance %d, got %d (%v)", tt.balance, bal, err)
+		}
+	}
+	if bal, err := GetStakeBalance(db, route); err != nil || bal != 0 {
+		t.Fatalf("stake not released %d (%v)", bal, err)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ava-labs/avalanchego/ids"
+	log "github.com/inconshreveable/log15"
+)
+
+// 0x1b/[height] (accepted blocks by height)
+//   -> [blockID]
+
+var heightIndexVersion = []byte("height_index_version")
+
+const currentHeightIndexVersion = 1
+
+// [heightPrefix] + [delimiter] + [height]
+func PrefixHeightKey(height uint64) (k []byte) {
+	k = make([]byte, 2+8)
+	k[0] = heightPrefix
+	k[1] = ByteDelimiter
+	binary.BigEndian.PutUint64(k[2:], height)
+	return
+}
+
+// GetBlockIDAtHeight returns the ID of the block accepted at [height] or
+// [database.ErrNotFound] if it isn't stored
+func GetBlockIDAtHeight(db database.KeyValueReader, height uint64) (ids.ID, error) {
+	v, err := db.Get(PrefixHeightKey(height))
+	if err != nil {
+		return ids.Empty, err
+	}
+	return ids.ToID(v)
+}
+
+// walkAcceptedBlocks calls [f] with the stored accepted blocks from the last
+// accepted one back to genesis (or the oldest one stored after a state sync)
+func walkAcceptedBlocks(db database.Database, f func(ids.ID, *StatefulBlock) error) error {
+	blkID, err := GetLastAccepted(db)
+	if err != nil {
+		return err
+	}
+	for blkID != ids.Empty {
+		blk, err := GetBlock(db, blkID)
+		if errors.Is(err, database.ErrNotFound) {
+			// Blocks before a state sync summary are never stored
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := f(blkID, blk); err != nil {
+			return err
+		}
+		if blk.Hght == 0 {
+			return nil
+		}
+		blkID = blk.Prnt
+	}
+	return nil
+}
+
+// BuildHeightIndex indexes the accepted blocks stored in [db] before the index
+// existed. It is a no-op once the index is up to date.
+func BuildHeightIndex(db database.Database) error {
+	version, err := getCount(db, heightIndexVersion)
+	if err != nil {
+		return err
+	}
+	if version >= currentHeightIndexVersion {
+		return nil
+	}
+
+	start := time.Now()
+	batch := db.NewBatch()
+	blocks := 0
+	if err := walkAcceptedBlocks(db, func(blkID ids.ID, blk *StatefulBlock) error {
+		if err := batch.Put(PrefixHeightKey(blk.Hght), blkID[:]); err != nil {
+			return err
+		}
+		blocks++
+		if batch.Size() > treeBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	v := make([]byte, 8)
+	binary.BigEndian.PutUint64(v, currentHeightIndexVersion)
+	if err := batch.Put(heightIndexVersion, v); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	log.Info("built height index", "blocks", blocks, "t", time.Since(start))
+	return nil
+}
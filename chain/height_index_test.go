@@ -0,0 +1,66 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"testing"
+
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ava-labs/avalanchego/database/memdb"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/golang/mock/gomock"
+)
+
+func TestHeightIndex(t *testing.T) {
+	t.Parallel()
+
+	db := memdb.New()
+	defer db.Close()
+	vm := NewMockVM(gomock.NewController(t))
+	vm.EXPECT().Genesis().Return(DefaultGenesis()).AnyTimes()
+
+	blkIDs := []ids.ID{}
+	parent := ids.Empty
+	for i := 0; i < 5; i++ {
+		blk := &StatelessBlock{
+			StatefulBlock: &StatefulBlock{Prnt: parent, Hght: uint64(i), Tmstmp: int64(i)},
+			vm:            vm,
+		}
+		if err := blk.init(); err != nil {
+			t.Fatal(err)
+		}
+		if err := SetLastAccepted(db, blk); err != nil {
+			t.Fatal(err)
+		}
+		parent = blk.ID()
+		blkIDs = append(blkIDs, parent)
+	}
+
+	check := func() {
+		for height, expected := range blkIDs {
+			blkID, err := GetBlockIDAtHeight(db, uint64(height))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if blkID != expected {
+				t.Fatalf("expected %s at height %d, got %s", expected, height, blkID)
+			}
+		}
+		if _, err := GetBlockIDAtHeight(db, uint64(len(blkIDs))); err != database.ErrNotFound {
+			t.Fatalf("expected %v, got %v", database.ErrNotFound, err)
+		}
+	}
+	check()
+
+	// Blocks stored before the index existed
+	for height := range blkIDs {
+		if err := db.Delete(PrefixHeightKey(uint64(height))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := BuildHeightIndex(db); err != nil {
+		t.Fatal(err)
+	}
+	check()
+}
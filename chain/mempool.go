@@ -13,5 +13,6 @@ type Mempool interface {
 	Prune(set.Set[ids.ID])
 	PopMax() (*Transaction, uint64)
 	Add(*Transaction) bool
+	Drop(ids.ID, error)
 	NewTxs(uint64) []*Transaction
 }
@@ -53,6 +53,18 @@ func (mr *MockMempoolMockRecorder) Add(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add", reflect.TypeOf((*MockMempool)(nil).Add), arg0)
 }
 
+// Drop mocks base method.
+func (m *MockMempool) Drop(arg0 ids.ID, arg1 error) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Drop", arg0, arg1)
+}
+
+// Drop indicates an expected call of Drop.
+func (mr *MockMempoolMockRecorder) Drop(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Drop", reflect.TypeOf((*MockMempool)(nil).Drop), arg0, arg1)
+}
+
 // Len mocks base method.
 func (m *MockMempool) Len() int {
 	m.ctrl.T.Helper()
@@ -0,0 +1,157 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/SamaNetwork/SamaVM/tdata"
+)
+
+// A multisig account is an address without a key, controlled by [Threshold]
+// of its [Members]. Setting the root or foundation address to a multisig
+// account means their privileges can only be used through a [MultisigTx]
+// approved by enough members.
+
+const MaxMultisigMembers = 32
+
+var (
+	ErrInvalidMultisig  = errors.New("invalid multisig account")
+	ErrNotMultisig      = errors.New("not a multisig account")
+	ErrThresholdNotMet  = errors.New("multisig threshold not met")
+	ErrInvalidNonce     = errors.New("invalid multisig nonce")
+	ErrMultisigTxDenied = errors.New("tx can't be sent by a multisig account")
+)
+
+type MultisigAccount struct {
+	Address   common.Address   `serialize:"true" json:"address"`
+	Members   []common.Address `serialize:"true" json:"members"`
+	Threshold uint32           `serialize:"true" json:"threshold"`
+	// Nonce is the number of [MultisigTx] the account sent, approvals are
+	// only valid for the next one
+	Nonce uint64 `serialize:"true" json:"nonce"`
+}
+
+// NewMultisigAccount returns the [threshold] of [members] account, its
+// members are sorted so the account doesn't depend on their order
+func NewMultisigAccount(members []common.Address, threshold uint32) (*MultisigAccount, error) {
+	sorted := make([]common.Address, len(members))
+	copy(sorted, members)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i][:], sorted[j][:]) < 0
+	})
+	m := &MultisigAccount{Members: sorted, Threshold: threshold}
+	if err := m.Verify(); err != nil {
+		return nil, err
+	}
+	m.Address = MultisigAddress(sorted, threshold)
+	return m, nil
+}
+
+// MultisigAddress derives the address of the account of [threshold] of the
+// sorted [members]
+func MultisigAddress(members []common.Address, threshold uint32) common.Address {
+	b := make([]byte, len(members)*common.AddressLength+4)
+	for i, member := range members {
+		copy(b[i*common.AddressLength:], member[:])
+	}
+	binary.BigEndian.PutUint32(b[len(members)*common.AddressLength:], threshold)
+	return common.BytesToAddress(crypto.Keccak256([]byte("multisig"), b)[12:])
+}
+
+func (m *MultisigAccount) Verify() error {
+	if len(m.Members) == 0 || len(m.Members) > MaxMultisigMembers {
+		return fmt.Errorf("%w: %d members", ErrInvalidMultisig, len(m.Members))
+	}
+	if m.Threshold == 0 || int(m.Threshold) > len(m.Members) {
+		return fmt.Errorf("%w: threshold %d of %d", ErrInvalidMultisig, m.Threshold, len(m.Members))
+	}
+	for i, member := range m.Members {
+		if member == (common.Address{}) {
+			return fmt.Errorf("%w: empty member", ErrInvalidMultisig)
+		}
+		if i > 0 && bytes.Compare(m.Members[i-1][:], member[:]) >= 0 {
+			return fmt.Errorf("%w: members not sorted or duplicated", ErrInvalidMultisig)
+		}
+	}
+	return nil
+}
+
+func (m *MultisigAccount) IsMember(address common.Address) bool {
+	for _, member := range m.Members {
+		if member == address {
+			return true
+		}
+	}
+	return false
+}
+
+// MultisigTypedData is the typed data members sign to approve [utx] as the
+// [nonce]th tx of [account]
+func MultisigTypedData(magic uint64, account common.Address, nonce uint64, utx UnsignedTransaction) (*tdata.TypedData, error) {
+	dh, err := DigestHash(utx)
+	if err != nil {
+		return nil, err
+	}
+	return tdata.CreateTypedData(
+		magic, MultisigApproval,
+		[]tdata.Type{
+			{Name: tdAccount, Type: tdAddress},
+			{Name: tdNonce, Type: tdUint64},
+			{Name: tdTxHash, Type: tdBytes},
+		},
+		tdata.TypedDataMessage{
+			tdAccount: account.Hex(),
+			tdNonce:   strconv.FormatUint(nonce, 10),
+			tdTxHash:  hexutil.Encode(dh),
+		},
+	), nil
+}
+
+// [multisigsPrefix] + [delimiter] + [address]
+func PrefixMultisigKey(address common.Address) (k []byte) {
+	k = make([]byte, 2+common.AddressLength)
+	k[0] = multisigsPrefix
+	k[1] = ByteDelimiter
+	copy(k[2:], address[:])
+	return
+}
+
+var _ MultisigState = &multisigState{}
+
+type MultisigState interface {
+	GetMultisig(address common.Address) (*MultisigAccount, bool, error)
+	PutMultisig(account *MultisigAccount) error
+}
+
+type multisigState struct {
+	db database.Database
+}
+
+func NewMultisigState(db database.Database) *multisigState {
+	return &multisigState{db: db}
+}
+
+func (m *multisigState) GetMultisig(address common.Address) (*MultisigAccount, bool, error) {
+	pmeta := new(MultisigAccount)
+	exist, err := getState(m.db, PrefixMultisigKey(address), pmeta)
+	if !exist || err != nil {
+		return nil, false, err
+	}
+	return pmeta, true, nil
+}
+
+func (m *multisigState) PutMultisig(account *MultisigAccount) error {
+	return putState(m.db, PrefixMultisigKey(account.Address), account)
+}
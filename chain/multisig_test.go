@@ -0,0 +1,165 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/database/memdb"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/SamaNetwork/SamaVM/tdata"
+)
+
+func TestMultisigRoot(t *testing.T) {
+	t.Parallel()
+
+	privs := make([]*ecdsa.PrivateKey, 4)
+	members := []common.Address{}
+	for i := range privs {
+		priv, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		privs[i] = priv
+		members = append(members, crypto.PubkeyToAddress(priv.PublicKey))
+	}
+	// The last key is not a member
+	account, err := NewMultisigAccount(members[:3], 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewMultisigAccount(members[:3], 4); !errors.Is(err, ErrInvalidMultisig) {
+		t.Fatalf("expected %v, got %v", ErrInvalidMultisig, err)
+	}
+	if _, err := NewMultisigAccount([]common.Address{members[0], members[0]}, 1); !errors.Is(err, ErrInvalidMultisig) {
+		t.Fatalf("expected %v, got %v", ErrInvalidMultisig, err)
+	}
+
+	g := DefaultGenesis()
+	g.Magic = 1
+	g.RootAddress = account.Address.Hex()
+	g.Multisigs = []*MultisigAccount{account}
+	if err := g.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	db := memdb.New()
+	defer db.Close()
+	if err := g.Load(db, nil); err != nil {
+		t.Fatal(err)
+	}
+	state := SamaNew(db, g)
+	tctx := testTxContexts(g, db, Rules{})
+	approve := func(nonce uint64, utx UnsignedTransaction, signers ...int) [][]byte {
+		td, err := MultisigTypedData(g.Magic, account.Address, nonce, utx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dh, err := tdata.DigestHash(td)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sigs := [][]byte{}
+		for _, signer := range signers {
+			sig, err := Sign(dh, privs[signer])
+			if err != nil {
+				t.Fatal(err)
+			}
+			sigs = append(sigs, sig)
+		}
+		return sigs
+	}
+
+	// With no routes the root alone decides
+	proposal := &ProposalTx{BaseTx: &BaseTx{Magic: 1}, ActionID: ids.ShortID{1}, ActionType: actionTypeAddUserType, Key: "1", NewValue: "100"}
+	for i, tt := range []struct {
+		tx  *MultisigTx
+		err error
+	}{
+		{&MultisigTx{Account: account.Address, Tx: proposal, Signatures: approve(0, proposal, 0)}, ErrThresholdNotMet},
+		{&MultisigTx{Account: account.Address, Tx: proposal, Signatures: approve(0, proposal, 0, 0)}, ErrThresholdNotMet},
+		{&MultisigTx{Account: account.Address, Tx: proposal, Signatures: approve(0, proposal, 0, 3)}, ErrUnauthorized},
+		{&MultisigTx{Account: account.Address, Nonce: 1, Tx: proposal, Signatures: approve(1, proposal, 0, 1)}, ErrInvalidNonce},
+		{&MultisigTx{Account: members[0], Tx: proposal, Signatures: approve(0, proposal, 0, 1)}, ErrNotMultisig},
+		{&MultisigTx{Account: account.Address, Tx: &StakeTx{BaseTx: &BaseTx{Magic: 1}}, Signatures: approve(0, proposal, 0, 1)}, ErrMultisigTxDenied},
+	} {
+		if err := tt.tx.Execute(tctx(members[3], 10)); !errors.Is(err, tt.err) {
+			t.Fatalf("#%d: expected %v, got %v", i, tt.err, err)
+		}
+	}
+	if err := proposal.Execute(tctx(members[0], 10)); err != nil {
+		t.Fatal(err)
+	}
+	action, _, err := state.GetActionMeta(ids.ShortID{1}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tally, err := TallyAction(state, action); err != nil || tally.Approved {
+		t.Fatalf("member approved as root (%v)", err)
+	}
+
+	// Signed by 2 of 3 members, the tx is sent as the root
+	vote := &VoteTx{BaseTx: &BaseTx{Magic: 1}, ActionID: ids.ShortID{1}, Choice: VoteFor}
+	mtx := &MultisigTx{BaseTx: &BaseTx{}, Account: account.Address, Tx: vote, Signatures: approve(0, vote, 2, 1)}
+	if err := mtx.Execute(tctx(members[3], 10)); err != nil {
+		t.Fatal(err)
+	}
+	action, _, err = state.GetActionMeta(ids.ShortID{1}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tally, err := TallyAction(state, action); err != nil || !tally.RootDecides || !tally.Approved {
+		t.Fatalf("root vote not counted (%v)", err)
+	}
+	if err := mtx.Execute(tctx(members[3], 10)); !errors.Is(err, ErrInvalidNonce) {
+		t.Fatalf("expected %v, got %v", ErrInvalidNonce, err)
+	}
+	if account, _, err := state.GetMultisig(account.Address); err != nil || account.Nonce != 1 {
+		t.Fatalf("nonce not increased (%v)", err)
+	}
+
+	// The inner tx survives encoding
+	priv := privs[3]
+	tx := NewTx(mtx, nil)
+	dh, err := DigestHash(mtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Signature, err = Sign(dh, priv); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Init(g); err != nil {
+		t.Fatal(err)
+	}
+	dtx := new(Transaction)
+	if _, err := Unmarshal(tx.Bytes(), dtx); err != nil {
+		t.Fatal(err)
+	}
+	if err := dtx.Init(g); err != nil {
+		t.Fatal(err)
+	}
+	if dtx.ID() != tx.ID() || dtx.Sender() != members[3] {
+		t.Fatal("multisig tx changed by encoding")
+	}
+	if inner, ok := dtx.UnsignedTransaction.(*MultisigTx).Tx.(*VoteTx); !ok || inner.ActionID != vote.ActionID {
+		t.Fatal("inner tx changed by encoding")
+	}
+
+	// Accounts can be created after genesis too
+	create := &CreateMultisigTx{Members: []common.Address{members[2], members[1], members[0]}, Threshold: 2}
+	if err := create.Execute(tctx(members[3], 10)); !errors.Is(err, ErrKeyExists) {
+		t.Fatalf("expected %v, got %v", ErrKeyExists, err)
+	}
+	create.Threshold = 3
+	if err := create.Execute(tctx(members[3], 10)); err != nil {
+		t.Fatal(err)
+	}
+	if _, exist, err := state.GetMultisig(MultisigAddress(account.Members, 3)); err != nil || !exist {
+		t.Fatalf("account not created (%v)", err)
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/SamaNetwork/SamaVM/tdata"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var _ UnsignedTransaction = &MultisigTx{}
+
+// MultisigTx executes [Tx] as the multisig [Account]. [Signatures] are the
+// approvals of its members over [MultisigTypedData], the sender of the
+// MultisigTx only pays its fee.
+type MultisigTx struct {
+	*BaseTx    `serialize:"true" json:"baseTx"`
+	Account    common.Address      `serialize:"true" json:"account"`
+	Nonce      uint64              `serialize:"true" json:"nonce"`
+	Tx         UnsignedTransaction `serialize:"true" json:"tx"`
+	Signatures [][]byte            `serialize:"true" json:"signatures"`
+}
+
+// Multisig accounts start with [ForkPhase4], whose blocks are all encoded
+// with [codecVersion]
+func (*MultisigTx) minCodecVersion() uint16 {
+	return codecVersion
+}
+
+func (*MultisigTx) minFork() string {
+	return ForkPhase4
+}
+
+// multisigAllowed reports whether a multisig account may send [utx]: the
+// root and foundation privileges, and moving its funds
+func multisigAllowed(utx UnsignedTransaction) bool {
+	switch utx.(type) {
+	case *ProposalTx, *VoteTx, *GovernTx, *ClaimTx, *TransferTx:
+		return true
+	default:
+		return false
+	}
+}
+
+func (m *MultisigTx) Execute(t *TransactionContext) error {
+	samaState := t.State
+	account, exist, err := samaState.GetMultisig(m.Account)
+	if err != nil {
+		return err
+	}
+	if !exist {
+		return fmt.Errorf("%w: %s", ErrNotMultisig, m.Account)
+	}
+	if m.Nonce != account.Nonce {
+		return fmt.Errorf("%w: expected %d, got %d", ErrInvalidNonce, account.Nonce, m.Nonce)
+	}
+	if m.Tx == nil || !multisigAllowed(m.Tx) {
+		return ErrMultisigTxDenied
+	}
+	if m.Tx.GetMagic() != t.Genesis.Magic {
+		return ErrInvalidMagic
+	}
+
+	signers, err := m.signers(t.Genesis.Magic)
+	if err != nil {
+		return err
+	}
+	approved := map[common.Address]bool{}
+	for _, signer := range signers {
+		if !account.IsMember(signer) {
+			return fmt.Errorf("%w: %s is not a member", ErrUnauthorized, signer)
+		}
+		approved[signer] = true
+	}
+	if len(approved) < int(account.Threshold) {
+		return fmt.Errorf("%w: %d of %d", ErrThresholdNotMet, len(approved), account.Threshold)
+	}
+
+	account.Nonce++
+	if err := samaState.PutMultisig(account); err != nil {
+		return err
+	}
+	inner := *t
+	inner.Sender = account.Address
+	return m.Tx.Execute(&inner)
+}
+
+// signers returns the addresses that approved the inner tx on the chain of
+// [magic]
+func (m *MultisigTx) signers(magic uint64) ([]common.Address, error) {
+	td, err := MultisigTypedData(magic, m.Account, m.Nonce, m.Tx)
+	if err != nil {
+		return nil, err
+	}
+	dh, err := tdata.DigestHash(td)
+	if err != nil {
+		return nil, err
+	}
+	signers := make([]common.Address, 0, len(m.Signatures))
+	for _, sig := range m.Signatures {
+		pk, err := DeriveSender(dh, sig)
+		if err != nil {
+			return nil, err
+		}
+		signers = append(signers, crypto.PubkeyToAddress(*pk))
+	}
+	return signers, nil
+}
+
+func (m *MultisigTx) FeeUnits(g *Genesis) uint64 {
+	return m.BaseTx.FeeUnits(g) + uint64(len(m.Signatures)) + m.Tx.FeeUnits(g)
+}
+
+func (m *MultisigTx) LoadUnits(g *Genesis) uint64 {
+	return m.BaseTx.FeeUnits(g) + uint64(len(m.Signatures)) + m.Tx.LoadUnits(g)
+}
+
+func (m *MultisigTx) Copy() UnsignedTransaction {
+	sigs := make([][]byte, len(m.Signatures))
+	for i, sig := range m.Signatures {
+		sigs[i] = make([]byte, len(sig))
+		copy(sigs[i], sig)
+	}
+	return &MultisigTx{
+		BaseTx:     m.BaseTx.Copy(),
+		Account:    m.Account,
+		Nonce:      m.Nonce,
+		Tx:         m.Tx.Copy(),
+		Signatures: sigs,
+	}
+}
+
+// TypedData is signed by the sender. Members approve the inner tx through
+// [MultisigTypedData] instead.
+func (m *MultisigTx) TypedData() *tdata.TypedData {
+	dh, _ := DigestHash(m.Tx)
+	return tdata.CreateTypedData(
+		m.Magic, Multisig,
+		[]tdata.Type{
+			{Name: tdAccount, Type: tdAddress},
+			{Name: tdNonce, Type: tdUint64},
+			{Name: tdTxHash, Type: tdBytes},
+			{Name: tdApprovals, Type: tdBytes},
+			{Name: tdPrice, Type: tdUint64},
+			{Name: tdBlockID, Type: tdString},
+		},
+		tdata.TypedDataMessage{
+			tdAccount:   m.Account.Hex(),
+			tdNonce:     strconv.FormatUint(m.Nonce, 10),
+			tdTxHash:    hexutil.Encode(dh),
+			tdApprovals: hexutil.Encode(crypto.Keccak256(m.Signatures...)),
+			tdPrice:     strconv.FormatUint(m.Price, 10),
+			tdBlockID:   m.BlockID.String(),
+		},
+	)
+}
+
+func (m *MultisigTx) Activity() *Activity {
+	activity := m.Tx.Activity()
+	activity.Address = m.Account.Hex()
+	return activity
+}
@@ -43,106 +43,96 @@ type DetailMeta struct {
 
 type DetailsState interface {
 	GetDetailMeta(address common.Address) (*DetailMeta, bool, error)
-	PutDetail(db database.Database, address common.Address, pmeta *DetailMeta) error
+	GetDetailByWorkAddress(workAddr common.Address) (*DetailMeta, bool, error)
+	PutDetail(address common.Address, pmeta *DetailMeta) error
 	GetDetails() ([]*DetailMeta, error)
-	DelDetail(db database.Database, address common.Address) error
-	ReloadDetails(db database.Database) error
-	CacheDetailsCommit() error
-	CacheDetailsAbort() error
+	DelDetail(address common.Address) error
 }
 
 type detailsState struct {
-	curNodes        map[common.Address]*DetailMeta
-	pendingNodesAdd map[common.Address]*DetailMeta
-	pendingNodesDel map[common.Address]*DetailMeta
+	db database.Database
 }
 
-func NewDetailstate(db database.Database) (*detailsState, error) {
-	s := &detailsState{
-		curNodes:        make(map[common.Address]*DetailMeta),
-		pendingNodesAdd: make(map[common.Address]*DetailMeta),
-		pendingNodesDel: make(map[common.Address]*DetailMeta),
-	}
-
-	err := s.ReloadDetails(db)
-	return s, err
+func NewDetailstate(db database.Database) *detailsState {
+	return &detailsState{db: db}
 }
 
 func (d *detailsState) GetDetailMeta(address common.Address) (*DetailMeta, bool, error) {
-	pmeta, ok := d.curNodes[address]
-	if !ok {
-		return nil, false, nil
+	pmeta := new(DetailMeta)
+	exist, err := getState(d.db, PrefixDetailsKey(address), pmeta)
+	if !exist || err != nil {
+		return nil, false, err
 	}
 	return pmeta, true, nil
 }
 
-func (d *detailsState) PutDetail(db database.Database, address common.Address, pmeta *DetailMeta) error {
-	delete(d.pendingNodesDel, address)
-	d.pendingNodesAdd[address] = pmeta
-
-	k := PrefixDetailsKey(address)
-	pvmeta, err := Marshal(pmeta)
-	if err != nil {
+func (d *detailsState) PutDetail(address common.Address, pmeta *DetailMeta) error {
+	if err := d.delWorkAddressIndex(address); err != nil {
 		return err
 	}
-	return db.Put(k, pvmeta)
-}
-
-func (d *detailsState) GetDetails() ([]*DetailMeta, error) {
-	details := []*DetailMeta(nil)
-	for _, detail := range d.curNodes {
-		details = append(details, detail)
+	if workAddr, err := workAddress(pmeta.WorkKey); err == nil {
+		if err := d.db.Put(PrefixWorkAddressIndex(workAddr), address[:]); err != nil {
+			return err
+		}
 	}
-	return details, nil
+	return putState(d.db, PrefixDetailsKey(address), pmeta)
 }
 
-func (d *detailsState) DelDetail(db database.Database, address common.Address) error {
-	delete(d.pendingNodesAdd, address)
-	d.pendingNodesDel[address] = &DetailMeta{}
-
-	k := PrefixDetailsKey(address)
-	return db.Delete(k)
-}
-
-func (d *detailsState) ReloadDetails(db database.Database) error {
-	basePrefix := baseDetailsPrefix()
-	cursor := db.NewIteratorWithPrefix(basePrefix)
-	defer cursor.Release()
-	for cursor.Next() {
-		nmeta := cursor.Value()
-		pmeta := new(DetailMeta)
-		if _, err := Unmarshal(nmeta, pmeta); err != nil {
-			return err
-		}
-		d.curNodes[pmeta.StakeAddress] = pmeta
+// GetDetailByWorkAddress returns the detail whose work key belongs to
+// [workAddr].
+func (d *detailsState) GetDetailByWorkAddress(workAddr common.Address) (*DetailMeta, bool, error) {
+	v, err := d.db.Get(PrefixWorkAddressIndex(workAddr))
+	if err == database.ErrNotFound {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
 	}
-	return nil
+	return d.GetDetailMeta(common.BytesToAddress(v))
 }
 
-func (d *detailsState) CacheDetailsCommit() error {
-	for addr, pendingDetail := range d.pendingNodesAdd {
-		delete(d.curNodes, addr)
-		d.curNodes[addr] = pendingDetail
+// delWorkAddressIndex removes the work address index entry of the detail
+// stored at [address], if it still points there
+func (d *detailsState) delWorkAddressIndex(address common.Address) error {
+	prev, exist, err := d.GetDetailMeta(address)
+	if err != nil || !exist {
+		return err
+	}
+	workAddr, err := workAddress(prev.WorkKey)
+	if err != nil {
+		return nil
 	}
-	for addr := range d.pendingNodesDel {
-		delete(d.curNodes, addr)
+	k := PrefixWorkAddressIndex(workAddr)
+	v, err := d.db.Get(k)
+	if err == database.ErrNotFound {
+		return nil
 	}
-	for a := range d.pendingNodesAdd {
-		delete(d.pendingNodesAdd, a)
+	if err != nil {
+		return err
 	}
-	for a := range d.pendingNodesDel {
-		delete(d.pendingNodesDel, a)
+	if common.BytesToAddress(v) != address {
+		return nil
 	}
-	return nil
+	return d.db.Delete(k)
 }
 
-func (d *detailsState) CacheDetailsAbort() error {
-	for a := range d.pendingNodesAdd {
-		delete(d.pendingNodesAdd, a)
-	}
-	for a := range d.pendingNodesDel {
-		delete(d.pendingNodesDel, a)
-	}
+func (d *detailsState) GetDetails() ([]*DetailMeta, error) {
+	details := []*DetailMeta(nil)
+	err := iterateState(d.db, baseDetailsPrefix(), common.AddressLength, func(_ []byte, v []byte) error {
+		pmeta := new(DetailMeta)
+		if _, err := Unmarshal(v, pmeta); err != nil {
+			return err
+		}
+		details = append(details, pmeta)
+		return nil
+	})
+	return details, err
+}
 
-	return nil
+func (d *detailsState) DelDetail(address common.Address) error {
+	if err := d.delWorkAddressIndex(address); err != nil {
+		return err
+	}
+	k := PrefixDetailsKey(address)
+	return d.db.Delete(k)
 }
@@ -0,0 +1,173 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Kinds of the system params governance can change
+const (
+	ParamKindUint64     = "uint64"
+	ParamKindDuration   = "duration" // seconds
+	ParamKindAddress    = "address"
+	ParamKindPercentage = "percentage"
+)
+
+var ErrInvalidParam = errors.New("invalid param")
+
+// ParamSchema describes a system param changed by [ModifyParams]. Params
+// without a schema are fixed at genesis.
+type ParamSchema struct {
+	Key        string `json:"key"`
+	Kind       string `json:"kind"`
+	ActionType uint64 `json:"actionType"`
+	// Bounds of numeric params
+	Min uint64 `json:"min,omitempty"`
+	Max uint64 `json:"max,omitempty"`
+	// Percentage moved the other way when this one changes, so their sum
+	// stays the same
+	Pair string `json:"pair,omitempty"`
+
+	uint64Field func(*SysParamsMeta) *uint64
+	percField   func(*SysParamsMeta) *uint32
+	addrField   func(*SysParamsMeta) *string
+}
+
+func percentageParam(key string, pair string, field func(*SysParamsMeta) *uint32) *ParamSchema {
+	return &ParamSchema{
+		Key:        key,
+		Kind:       ParamKindPercentage,
+		ActionType: actionTypeModifySysParam,
+		Min:        MinPercentage,
+		Max:        MaxPercentage,
+		Pair:       pair,
+		percField:  field,
+	}
+}
+
+func uint64Param(key string, kind string, min uint64, max uint64, field func(*SysParamsMeta) *uint64) *ParamSchema {
+	return &ParamSchema{
+		Key:         key,
+		Kind:        kind,
+		ActionType:  actionTypeModifySysParam,
+		Min:         min,
+		Max:         max,
+		uint64Field: field,
+	}
+}
+
+var paramSchemas = []*ParamSchema{
+	percentageParam("routePerc", "serPerc", func(m *SysParamsMeta) *uint32 { return &m.RoutePerc }),
+	percentageParam("serPerc", "routePerc", func(m *SysParamsMeta) *uint32 { return &m.SerPerc }),
+	percentageParam("routeBase", "routeMerit", func(m *SysParamsMeta) *uint32 { return &m.BaseRoutePerc }),
+	percentageParam("routeMerit", "routeBase", func(m *SysParamsMeta) *uint32 { return &m.MeritRoutePerc }),
+	percentageParam("serBase", "serMerit", func(m *SysParamsMeta) *uint32 { return &m.BaseSerPerc }),
+	percentageParam("serMerit", "serBase", func(m *SysParamsMeta) *uint32 { return &m.MeritSerPerc }),
+	percentageParam("burn", "", func(m *SysParamsMeta) *uint32 { return &m.BurnPerc }),
+
+	uint64Param("routeAmount", ParamKindUint64, 1, math.MaxUint64, func(m *SysParamsMeta) *uint64 { return &m.RouteStakeAmount }),
+	uint64Param("serAmount", ParamKindUint64, 1, math.MaxUint64, func(m *SysParamsMeta) *uint64 { return &m.SerStakeAmount }),
+	uint64Param("claimMinUnits", ParamKindUint64, 1, math.MaxUint64, func(m *SysParamsMeta) *uint64 { return &m.ClaimMinUnits }),
+	uint64Param("month", ParamKindUint64, 1, math.MaxUint64, func(m *SysParamsMeta) *uint64 { return &m.MonthCard }),
+	uint64Param("season", ParamKindUint64, 1, math.MaxUint64, func(m *SysParamsMeta) *uint64 { return &m.SeasonCard }),
+	uint64Param("annual", ParamKindUint64, 1, math.MaxUint64, func(m *SysParamsMeta) *uint64 { return &m.AnnualCard }),
+	uint64Param("minStakeTime", ParamKindDuration, SecondsDay, SecondsYear, func(m *SysParamsMeta) *uint64 { return &m.MinStakeTime }),
+	uint64Param("claimMinInterval", ParamKindDuration, SecondsDay, SecondsYear, func(m *SysParamsMeta) *uint64 { return &m.ClaimMinInterval }),
+
+	{
+		Key:        "foundation",
+		Kind:       ParamKindAddress,
+		ActionType: actionTypeModifyFoundation,
+		addrField:  func(m *SysParamsMeta) *string { return &m.FoundationAddr },
+	},
+}
+
+var paramSchemasByKey = map[string]*ParamSchema{}
+
+func init() {
+	for _, schema := range paramSchemas {
+		paramSchemasByKey[schema.Key] = schema
+	}
+}
+
+// ParamSchemas returns the schemas of all governed params
+func ParamSchemas() []*ParamSchema {
+	return paramSchemas
+}
+
+func GetParamSchema(key string) (*ParamSchema, error) {
+	schema, ok := paramSchemasByKey[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown key %s", ErrInvalidParam, key)
+	}
+	return schema, nil
+}
+
+// Value formats the param in [params]
+func (p *ParamSchema) Value(params *SysParamsMeta) string {
+	switch {
+	case p.percField != nil:
+		return strconv.FormatUint(uint64(*p.percField(params)), 10)
+	case p.uint64Field != nil:
+		return strconv.FormatUint(*p.uint64Field(params), 10)
+	default:
+		return common.HexToAddress(*p.addrField(params)).Hex()
+	}
+}
+
+func (p *ParamSchema) checkBounds(key string, v uint64) error {
+	if v < p.Min || v > p.Max {
+		return fmt.Errorf("%w: %s must be between %d and %d", ErrInvalidParam, key, p.Min, p.Max)
+	}
+	return nil
+}
+
+// Set parses [value] and sets the param in [params] to it
+func (p *ParamSchema) Set(params *SysParamsMeta, value string) error {
+	if p.addrField != nil {
+		if !common.IsHexAddress(value) {
+			return fmt.Errorf("%w: %s is not an address", ErrInvalidParam, value)
+		}
+		addr := common.HexToAddress(value)
+		if addr == (common.Address{}) {
+			return fmt.Errorf("%w: %s can't be the zero address", ErrInvalidParam, p.Key)
+		}
+		*p.addrField(params) = addr.Hex()
+		return nil
+	}
+
+	v, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: failed to parse %s", ErrInvalidParam, value)
+	}
+	if err := p.checkBounds(p.Key, v); err != nil {
+		return err
+	}
+	if p.uint64Field != nil {
+		*p.uint64Field(params) = v
+		return nil
+	}
+
+	field := p.percField(params)
+	if len(p.Pair) > 0 {
+		pair := paramSchemasByKey[p.Pair]
+		pairField := pair.percField(params)
+		total := uint64(*field) + uint64(*pairField)
+		if v > total {
+			return fmt.Errorf("%w: %s must be at most %d", ErrInvalidParam, p.Key, total)
+		}
+		if err := pair.checkBounds(pair.Key, total-v); err != nil {
+			return err
+		}
+		*pairField = uint32(total - v)
+	}
+	*field = uint32(v)
+	return nil
+}
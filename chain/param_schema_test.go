@@ -0,0 +1,90 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/database/memdb"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestParamSchema(t *testing.T) {
+	t.Parallel()
+
+	db := memdb.New()
+	defer db.Close()
+	g := DefaultGenesis()
+	if err := g.Load(db, nil); err != nil {
+		t.Fatal(err)
+	}
+	state := SamaNew(db, g)
+	root := common.HexToAddress(state.GetRootAddress())
+	tctx := testTxContexts(g, db, Rules{})
+	propose := func(actionID ids.ShortID, actionType uint64, key string, newValue string) error {
+		proposal := &ProposalTx{ActionID: actionID, ActionType: actionType, Key: key, NewValue: newValue}
+		return proposal.Execute(tctx(root, 10))
+	}
+
+	// Invalid proposals are rejected up front
+	for _, tt := range []struct {
+		actionType uint64
+		key        string
+		newValue   string
+	}{
+		{actionTypeModifySysParam, "symbol", "SAMA"},
+		{actionTypeModifySysParam, "burn", "96"},
+		{actionTypeModifySysParam, "routePerc", "78"},
+		{actionTypeModifySysParam, "routeAmount", "0"},
+		{actionTypeModifySysParam, "minStakeTime", "60"},
+		{actionTypeModifySysParam, "month", "-1"},
+		{actionTypeModifyFoundation, "foundation", "0x01"},
+		{actionTypeModifyFoundation, "foundation", common.Address{}.Hex()},
+		{actionTypeModifySysParam, "foundation", common.Address{1}.Hex()},
+		{actionTypeModifyFoundation, "burn", "30"},
+	} {
+		if err := propose(ids.ShortID{1}, tt.actionType, tt.key, tt.newValue); !errors.Is(err, ErrInvalidParam) {
+			t.Fatalf("%s=%s: expected %v, got %v", tt.key, tt.newValue, ErrInvalidParam, err)
+		}
+	}
+	if err := propose(ids.ShortID{1}, actionTypeModifySysParam, "burn", "20"); err == nil {
+		t.Fatal("proposed the current value")
+	}
+
+	// Each kind is applied once approved by the root address
+	for i, tt := range []struct {
+		actionType uint64
+		key        string
+		newValue   string
+	}{
+		{actionTypeModifySysParam, "routePerc", "40"},
+		{actionTypeModifySysParam, "serAmount", "5000"},
+		{actionTypeModifySysParam, "minStakeTime", "864000"},
+		{actionTypeModifyFoundation, "foundation", common.Address{1}.Hex()},
+	} {
+		actionID := ids.ShortID{byte(i + 2)}
+		if err := propose(actionID, tt.actionType, tt.key, tt.newValue); err != nil {
+			t.Fatal(err)
+		}
+		if err := (&GovernTx{ActionID: actionID}).Execute(tctx(root, 11)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	params := state.GetSysParams()
+	// The pair keeps its sum, leaving the validator share unchanged
+	if params.RoutePerc != 40 || params.SerPerc != 40 {
+		t.Fatalf("unexpected percentages %d/%d", params.RoutePerc, params.SerPerc)
+	}
+	if state.GetSerStakeAmount() != 5000 || state.GetRouteStakeAmount() != g.RouteStake || state.GetMinStakeTime() != 864000 {
+		t.Fatal("amounts not changed")
+	}
+	if common.HexToAddress(state.GetFoundationAddress()) != (common.Address{1}) {
+		t.Fatal("foundation not changed")
+	}
+	if params.UpdateTime != 11 {
+		t.Fatalf("unexpected update time %d", params.UpdateTime)
+	}
+}
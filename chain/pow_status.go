@@ -36,7 +36,6 @@ func basePowPrefix(powType byte) (k []byte) {
 }
 
 var _ PowState = &powState{}
-var powTypes = []byte{powTypeSer, powTypeRoute}
 
 type ProofMeta struct {
 	Netflow    uint64         `serialize:"true" json:"netflow"`
@@ -56,202 +55,86 @@ type PowMeta struct {
 }
 
 type PowState interface {
-	GetCurPow(powType byte) (map[common.Address]*PowMeta, error)
-	GetPendingPow(powType byte) (map[common.Address]*PowMeta, map[common.Address]*PowMeta, error)
 	GetPowMeta(powType byte, address common.Address) (*PowMeta, bool, error)
 
-	PutPow(db database.Database, powType byte, pmeta *ProofMeta) error
+	PutPow(powType byte, pmeta *ProofMeta) error
 
 	GetPows(powType byte) ([]*PowMeta, error)
 
-	DelPow(db database.Database, powType byte, address common.Address) error
-	ReloadPows(db database.Database) error
-	CachePowsCommit() error
-	CachePowsAbort() error
+	DelPow(powType byte, address common.Address) error
 
 	TotalPowTime(powType byte) (uint64, error)
 }
 
 type powState struct {
-	updateTime uint64
-
-	curSer   map[common.Address]*PowMeta
-	curRoute map[common.Address]*PowMeta
-
-	pendingRouteAdd map[common.Address]*PowMeta
-	pendingSerAdd   map[common.Address]*PowMeta
-
-	pendingRouteDel map[common.Address]*PowMeta
-	pendingSerDel   map[common.Address]*PowMeta
+	db database.Database
 }
 
-func NewPowState(db database.Database) (*powState, error) {
-	s := &powState{
-		updateTime: 0,
-
-		curSer:   make(map[common.Address]*PowMeta),
-		curRoute: make(map[common.Address]*PowMeta),
-
-		pendingRouteAdd: make(map[common.Address]*PowMeta),
-		pendingSerAdd:   make(map[common.Address]*PowMeta),
-
-		pendingRouteDel: make(map[common.Address]*PowMeta),
-		pendingSerDel:   make(map[common.Address]*PowMeta),
-	}
-	err := s.ReloadPows(db)
-	return s, err
-}
-func (f *powState) GetPendingPow(powType byte) (map[common.Address]*PowMeta, map[common.Address]*PowMeta, error) {
-	switch powType {
-	case powTypeSer:
-		return f.pendingRouteAdd, f.pendingRouteDel, nil
-	case powTypeRoute:
-		return f.pendingSerAdd, f.pendingSerDel, nil
-	default:
-		return nil, nil, ErrIDErr
-	}
-}
-
-func (f *powState) GetCurPow(powType byte) (map[common.Address]*PowMeta, error) {
-	switch powType {
-	case powTypeSer:
-		return f.curSer, nil
-	case powTypeRoute:
-		return f.curRoute, nil
-	default:
-		return nil, ErrIDErr
-	}
+func NewPowState(db database.Database) *powState {
+	return &powState{db: db}
 }
 
 func (f *powState) GetPowMeta(powType byte, address common.Address) (*PowMeta, bool, error) {
-	curMap, _ := f.GetCurPow(powType)
-	pmeta, ok := curMap[address]
-	if !ok {
-		return nil, false, nil
+	pmeta := new(PowMeta)
+	exist, err := getState(f.db, PrefixPowKey(powType, address), pmeta)
+	if !exist || err != nil {
+		return nil, false, err
 	}
 	return pmeta, true, nil
 }
 
-func (f *powState) PutPow(db database.Database, powType byte, proof *ProofMeta) error {
-	k := PrefixPowKey(powType, proof.Miner)
-	pendingAdd, pendingDel, _ := f.GetPendingPow(powType)
-	delete(pendingDel, proof.Miner)
-	pendingMeta, ok := pendingAdd[proof.Miner]
-	if ok {
-		pendingMeta.Totalflow += proof.Netflow
-		pendingMeta.TotalTime += proof.WorkTime
-		pendingMeta.LastUpdateTXID = proof.TxID
-		pendingMeta.LastUpdateTime = proof.UpdateTime
-
-		pvmeta, err := Marshal(pendingMeta)
-		if err != nil {
-			return err
+func (f *powState) PutPow(powType byte, proof *ProofMeta) error {
+	pmeta, exist, err := f.GetPowMeta(powType, proof.Miner)
+	if err != nil {
+		return err
+	}
+	if exist {
+		if pmeta.PowType != uint64(powType) {
+			return fmt.Errorf("pow type err")
 		}
-		return db.Put(k, pvmeta)
+		pmeta.Totalflow += proof.Netflow
+		pmeta.TotalTime += proof.WorkTime
 	} else {
-		pmeta := new(PowMeta)
-		curMap, _ := f.GetCurPow(powType)
-		curMeta, ok := curMap[proof.Miner]
-		if ok {
-			if curMeta.PowType != uint64(powType) {
-				return fmt.Errorf("pow type err")
-			}
-			pmeta.Totalflow = curMeta.Totalflow + proof.Netflow
-			pmeta.TotalTime = curMeta.TotalTime + proof.WorkTime
-		} else {
-			pmeta.Totalflow = proof.Netflow
-			pmeta.TotalTime = proof.WorkTime
+		pmeta = &PowMeta{
+			Totalflow: proof.Netflow,
+			TotalTime: proof.WorkTime,
 		}
-		pmeta.PowType = uint64(powType)
-		pmeta.LastUpdateTXID = proof.TxID
-		pmeta.LastUpdateTime = proof.UpdateTime
-		pmeta.Miner = proof.Miner
-		pendingAdd[proof.Miner] = pmeta
-		pvmeta, err := Marshal(pmeta)
-		if err != nil {
-			return err
-		}
-		return db.Put(k, pvmeta)
 	}
+	pmeta.PowType = uint64(powType)
+	pmeta.LastUpdateTXID = proof.TxID
+	pmeta.LastUpdateTime = proof.UpdateTime
+	pmeta.Miner = proof.Miner
+	if err := modifyCount(f.db, PrefixCountKey(powPrefix, powType), true, proof.WorkTime); err != nil {
+		return err
+	}
+	return putState(f.db, PrefixPowKey(powType, proof.Miner), pmeta)
 }
 
 func (f *powState) TotalPowTime(powType byte) (uint64, error) {
-	total := uint64(0)
-	curMap, _ := f.GetCurPow(powType)
-	for _, pow := range curMap {
-		total += pow.TotalTime
-	}
-	return total, nil
+	return getCount(f.db, PrefixCountKey(powPrefix, powType))
 }
 
 func (f *powState) GetPows(powType byte) ([]*PowMeta, error) {
 	pows := []*PowMeta(nil)
-	curMap, _ := f.GetCurPow(powType)
-	for _, pow := range curMap {
-		pows = append(pows, pow)
-	}
-	return pows, nil
-}
-
-func (f *powState) DelPow(db database.Database, powType byte, address common.Address) error {
-	pendingAdd, pendingDel, _ := f.GetPendingPow(powType)
-	delete(pendingAdd, address)
-	pendingDel[address] = &PowMeta{}
-
-	k := PrefixPowKey(powType, address)
-	return db.Delete(k)
-}
-
-func (f *powState) ReloadPows(db database.Database) error {
-	for _, powType := range powTypes {
-		basePrefix := basePowPrefix(powType)
-		cursor := db.NewIteratorWithPrefix(basePrefix)
-		defer cursor.Release()
-		for cursor.Next() {
-			nmeta := cursor.Value()
-
-			pmeta := new(PowMeta)
-			if _, err := Unmarshal(nmeta, pmeta); err != nil {
-				return err
-			}
-			curMap, _ := f.GetCurPow(powType)
-			curMap[pmeta.Miner] = pmeta
+	err := iterateState(f.db, basePowPrefix(powType), common.AddressLength, func(_ []byte, v []byte) error {
+		pmeta := new(PowMeta)
+		if _, err := Unmarshal(v, pmeta); err != nil {
+			return err
 		}
-	}
-	return nil
+		pows = append(pows, pmeta)
+		return nil
+	})
+	return pows, err
 }
 
-func (f *powState) CachePowsCommit() error {
-	for _, powType := range powTypes {
-		pendingAdd, pendingDel, _ := f.GetPendingPow(powType)
-		curMap, _ := f.GetCurPow(powType)
-
-		for addr, pendingPow := range pendingAdd {
-			delete(curMap, addr)
-			curMap[addr] = pendingPow
-		}
-		for addr := range pendingDel {
-			delete(curMap, addr)
-		}
-		for a := range pendingAdd {
-			delete(pendingAdd, a)
-		}
-		for a := range pendingDel {
-			delete(pendingDel, a)
-		}
+func (f *powState) DelPow(powType byte, address common.Address) error {
+	pmeta, exist, err := f.GetPowMeta(powType, address)
+	if err != nil || !exist {
+		return err
 	}
-	return nil
-}
-
-func (f *powState) CachePowsAbort() error {
-	for _, powType := range powTypes {
-		pendingAdd, pendingDel, _ := f.GetPendingPow(powType)
-		for a := range pendingAdd {
-			delete(pendingAdd, a)
-		}
-		for a := range pendingDel {
-			delete(pendingDel, a)
-		}
+	if err := modifyCount(f.db, PrefixCountKey(powPrefix, powType), false, pmeta.TotalTime); err != nil {
+		return err
 	}
-	return nil
+	k := PrefixPowKey(powType, address)
+	return f.db.Delete(k)
 }
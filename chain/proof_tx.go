@@ -6,7 +6,6 @@ package chain
 import (
 	"fmt"
 	"strconv"
-	"time"
 
 	"github.com/SamaNetwork/SamaVM/tdata"
 	"github.com/ethereum/go-ethereum/common"
@@ -17,6 +16,10 @@ const (
 	maxFlow = 1000
 	minTime = 1
 	maxTime = 60
+
+	// proofMinAge is how long before the block timestamp a proof window
+	// must have started.
+	proofMinAge = 50
 )
 
 var _ UnsignedTransaction = &ProofTx{}
@@ -30,7 +33,7 @@ type ProofTx struct {
 }
 
 func (p *ProofTx) Execute(t *TransactionContext) error {
-	samaState := t.vm.SamaState()
+	samaState := t.State
 	ok, powType, err := samaState.IsValidWorkAddress(t.Sender)
 	if err != nil {
 		return err
@@ -39,6 +42,33 @@ func (p *ProofTx) Execute(t *TransactionContext) error {
 		return fmt.Errorf("sender must route or ser node")
 	}
 
+	if err := p.verifyParams(); err != nil {
+		return err
+	}
+	switch {
+	case p.StartTime+proofMinAge > t.BlockTime:
+		return fmt.Errorf("start time err")
+	case p.EndTime > t.BlockTime:
+		return fmt.Errorf("endtime err")
+	}
+	// The new minutes only count from the last reward update
+	if err := samaState.SettlePow(powType, t.Sender); err != nil {
+		return err
+	}
+	err = samaState.PutPow(powType, &ProofMeta{
+		Netflow:    p.Netflow,
+		WorkTime:   p.EndTime - p.StartTime,
+		Miner:      t.Sender,
+		TxID:       t.TxID,
+		UpdateTime: t.BlockTime,
+	})
+
+	return err
+}
+
+// verifyParams checks the params of the proof that don't depend on the block
+// it is in, so a signed proof failing them is an [OffenceInvalidProof]
+func (p *ProofTx) verifyParams() error {
 	interval := p.EndTime - p.StartTime
 	switch {
 	case p.Netflow < minFlow:
@@ -47,24 +77,12 @@ func (p *ProofTx) Execute(t *TransactionContext) error {
 		return fmt.Errorf("netflow too big")
 	case p.StartTime > p.EndTime:
 		return fmt.Errorf("start time > endtime")
-	case p.StartTime > uint64(time.Now().Unix())-50:
-		return fmt.Errorf("start time err")
-	case p.EndTime > uint64(time.Now().Unix()):
-		return fmt.Errorf("endtime err")
 	case interval < minTime:
 		return fmt.Errorf("interval too small")
 	case interval > maxTime:
 		return fmt.Errorf("interval to big")
 	}
-	err = samaState.PutPow(t.Database, powType, &ProofMeta{
-		Netflow:    p.Netflow,
-		WorkTime:   p.EndTime - p.StartTime,
-		Miner:      t.Sender,
-		TxID:       t.TxID,
-		UpdateTime: t.BlockTime,
-	})
-
-	return err
+	return nil
 }
 
 func (p *ProofTx) FeeUnits(g *Genesis) uint64 {
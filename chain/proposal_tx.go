@@ -9,7 +9,6 @@ import (
 
 	"github.com/SamaNetwork/SamaVM/tdata"
 	"github.com/ava-labs/avalanchego/ids"
-	"github.com/ethereum/go-ethereum/common"
 )
 
 var _ UnsignedTransaction = &ProposalTx{}
@@ -25,70 +24,37 @@ type ProposalTx struct {
 }
 
 func (p *ProposalTx) Execute(t *TransactionContext) error {
-	samaState := t.vm.SamaState()
-	_, exist, _ := samaState.GetActionMeta(p.ActionID)
+	samaState := t.State
+	_, exist, _ := samaState.GetActionMeta(p.ActionID, t.BlockTime)
 	if exist {
 		return fmt.Errorf("action ID exist")
 	}
-	switch p.ActionType {
-	//case actionTypeAddStaker:
-
-	case actionTypeModifySysParam, actionTypeModifyFoundation:
-		err := samaState.CompCurParam(p.Key, p.NewValue)
-		if err != nil {
-			return err
-		}
-	case actionTypeAddUserType:
-		newType, err := strconv.ParseUint(p.Key, 10, 64)
-		if err != nil {
-			return err
-		}
-		exist := samaState.CheckUserType(newType)
-		if exist {
-			return fmt.Errorf("user type exist")
-		}
-	case actionTypeModifyUserType:
-		userType, err := strconv.ParseUint(p.Key, 10, 64)
-		if err != nil {
-			return err
-		}
-
-		userFee, err := strconv.ParseUint(p.NewValue, 10, 64)
-		if err != nil {
-			return err
-		}
-		exist := samaState.CheckUserType(userType)
-		if !exist {
-			return fmt.Errorf("user type not exist")
-		}
-		eq := samaState.CheckFee(userType, userFee)
-		if eq {
-			return fmt.Errorf("equal")
-		}
-	default:
-		return fmt.Errorf("action type not exist")
+	ga, err := GetGovernanceAction(p.ActionType)
+	if err != nil {
+		return err
+	}
+	// Action types introduced by a fork don't exist before it
+	if !t.Rules.Enables(ga) {
+		return fmt.Errorf("%w: %d", ErrUnknownActionType, p.ActionType)
+	}
+	if err := ga.Validate(t, p.Key, p.NewValue); err != nil {
+		return err
 	}
 
-	err := samaState.ProposalRepeat(p.ActionID, p.Key, p.NewValue)
+	err = samaState.ProposalRepeat(p.ActionID, p.ActionType, p.Key, p.NewValue, t.BlockTime)
 	if err != nil {
 		return err
 	}
+	if err := samaState.SettleActions(p.ActionType, p.Key, t.TxID, t.BlockTime); err != nil {
+		return err
+	}
 	txIDs := []ids.ID{}
 	txIDs = append(txIDs, t.TxID)
-	voters := []common.Address{}
-
-	routeNum, _, _ := samaState.GetStakersNum()
-	sroot := samaState.GetRootAddress()
-
-	if t.Sender == common.HexToAddress(sroot) && routeNum < 3 {
-		voters = append(voters, t.Sender)
-	} else {
-		ok, _ := samaState.IsRoute(t.Sender)
-		if ok {
-			voters = append(voters, t.Sender)
-		}
+	voters, err := proposalVoters(samaState, p.ActionType, t.Sender)
+	if err != nil {
+		return err
 	}
-	err = samaState.PutAction(t.Database, p.ActionID, &ActionMeta{
+	return putVotedAction(t, &ActionMeta{
 		ActionID:   p.ActionID,
 		ActionType: p.ActionType,
 		StartTime:  t.BlockTime,
@@ -97,9 +63,8 @@ func (p *ProposalTx) Execute(t *TransactionContext) error {
 		EndTime:    t.BlockTime + Seconds7Day,
 		TxIDs:      txIDs,
 		Voters:     voters,
+		Proposer:   t.Sender,
 	})
-
-	return err
 }
 
 func (p *ProposalTx) FeeUnits(genesis *Genesis) uint64 {
@@ -112,12 +77,13 @@ func (p *ProposalTx) LoadUnits(genesis *Genesis) uint64 {
 
 func (p *ProposalTx) Copy() UnsignedTransaction {
 	return &ProposalTx{
-		BaseTx:    p.BaseTx.Copy(),
-		ActionID:  p.ActionID,
-		StartTime: p.StartTime,
-		EndTime:   p.EndTime,
-		Key:       p.Key,
-		NewValue:  p.NewValue,
+		BaseTx:     p.BaseTx.Copy(),
+		ActionID:   p.ActionID,
+		StartTime:  p.StartTime,
+		EndTime:    p.EndTime,
+		ActionType: p.ActionType,
+		Key:        p.Key,
+		NewValue:   p.NewValue,
 	}
 }
 
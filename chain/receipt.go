@@ -0,0 +1,308 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"sort"
+
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ava-labs/avalanchego/database/versiondb"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// 0x19/[txID] (receipts)
+//   -> receipt of the accepted tx
+// 0x19/[blockID]/ (actions closed by the block)
+//   -> receipt IDs
+
+const (
+	EffectBalance       = "balance"
+	EffectStake         = "stake"
+	EffectReward        = "reward"
+	EffectStakerAdded   = "stakerAdded"
+	EffectStakerRemoved = "stakerRemoved"
+	EffectSlashed       = "slashed"
+)
+
+// Effect is a change a transaction made to an account. Amounts are the
+// balance, stake, unclaimed reward or staked amount before and after the
+// transaction, or the slashable stake before and after a slash.
+type Effect struct {
+	Type    string         `serialize:"true" json:"type"`
+	Address common.Address `serialize:"true" json:"address"`
+	Before  uint64         `serialize:"true" json:"before"`
+	After   uint64         `serialize:"true" json:"after"`
+}
+
+type Receipt struct {
+	TxID      ids.ID    `serialize:"true" json:"txId"`
+	BlkID     ids.ID    `serialize:"true" json:"blockId"`
+	Hght      uint64    `serialize:"true" json:"height"`
+	Index     uint32    `serialize:"true" json:"index"`
+	Price     uint64    `serialize:"true" json:"price"`
+	FeeUnits  uint64    `serialize:"true" json:"feeUnits"`
+	LoadUnits uint64    `serialize:"true" json:"loadUnits"`
+	Fee       uint64    `serialize:"true" json:"fee"`
+	Effects   []*Effect `serialize:"true" json:"effects"`
+	// Set for an action closed by the block (see [ProcessActions])
+	Actions []*ActionReceipt `serialize:"true" json:"actions,omitempty"`
+}
+
+// [receiptPrefix] + [delimiter] + [txID]
+func PrefixReceiptKey(txID ids.ID) (k []byte) {
+	k = make([]byte, 2+len(txID))
+	k[0] = receiptPrefix
+	k[1] = ByteDelimiter
+	copy(k[2:], txID[:])
+	return k
+}
+
+// [receiptPrefix] + [delimiter] + [blockID] + [delimiter]
+func PrefixBlockReceiptsKey(blkID ids.ID) (k []byte) {
+	k = make([]byte, 3+len(blkID))
+	k[0] = receiptPrefix
+	k[1] = ByteDelimiter
+	copy(k[2:], blkID[:])
+	k[len(k)-1] = ByteDelimiter
+	return k
+}
+
+// PutBlockReceipts stores the receipts of the actions closed by [blkID]
+func PutBlockReceipts(db database.KeyValueWriter, blkID ids.ID, receipts []*Receipt) error {
+	if len(receipts) == 0 {
+		return nil
+	}
+	txIDs := make([]ids.ID, len(receipts))
+	for i, r := range receipts {
+		if err := PutReceipt(db, r); err != nil {
+			return err
+		}
+		txIDs[i] = r.TxID
+	}
+	b, err := Marshal(txIDs)
+	if err != nil {
+		return err
+	}
+	return db.Put(PrefixBlockReceiptsKey(blkID), b)
+}
+
+// GetBlockReceipts returns the receipts of the actions closed by [blkID]
+func GetBlockReceipts(db database.KeyValueReader, blkID ids.ID) ([]*Receipt, error) {
+	b, err := db.Get(PrefixBlockReceiptsKey(blkID))
+	if errors.Is(err, database.ErrNotFound) {
+		return []*Receipt{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	txIDs := []ids.ID{}
+	if _, err := Unmarshal(b, &txIDs); err != nil {
+		return nil, err
+	}
+	receipts := make([]*Receipt, 0, len(txIDs))
+	for _, txID := range txIDs {
+		r, ok, err := GetReceipt(db, txID)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			receipts = append(receipts, r)
+		}
+	}
+	return receipts, nil
+}
+
+func PutReceipt(db database.KeyValueWriter, r *Receipt) error {
+	b, err := Marshal(r)
+	if err != nil {
+		return err
+	}
+	return db.Put(PrefixReceiptKey(r.TxID), b)
+}
+
+func GetReceipt(db database.KeyValueReader, txID ids.ID) (*Receipt, bool, error) {
+	b, err := db.Get(PrefixReceiptKey(txID))
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	r := new(Receipt)
+	if _, err := Unmarshal(b, r); err != nil {
+		return nil, false, err
+	}
+	return r, true, nil
+}
+
+type effectRecorder struct {
+	db   database.KeyValueReader
+	keys [][]byte
+	vals [][]byte
+}
+
+func (r *effectRecorder) Put(k []byte, v []byte) error {
+	r.keys = append(r.keys, k)
+	r.vals = append(r.vals, v)
+	return nil
+}
+
+func (r *effectRecorder) Delete(k []byte) error { return r.Put(k, nil) }
+
+// effects compares the writes recorded against [r.db] and returns the
+// changes made to accounts, sorted by key
+func (r *effectRecorder) effects() ([]*Effect, error) {
+	order := make([]int, len(r.keys))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return bytes.Compare(r.keys[order[i]], r.keys[order[j]]) < 0 })
+
+	effects := []*Effect{}
+	for _, i := range order {
+		k, v := r.keys[i], r.vals[i]
+		prev, err := r.db.Get(k)
+		if errors.Is(err, database.ErrNotFound) {
+			prev, err = nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		e, err := effect(k, prev, v)
+		if err != nil {
+			return nil, err
+		}
+		if e != nil && e.Before != e.After {
+			effects = append(effects, e)
+		}
+	}
+	return effects, nil
+}
+
+// effect describes the change of [k] from [prev] to [next] (nil if missing)
+// or returns nil if [k] is not an account record
+func effect(k []byte, prev []byte, next []byte) (*Effect, error) {
+	switch {
+	case len(k) == 2+common.AddressLength && k[0] == balancePrefix:
+		return &Effect{
+			Type:    EffectBalance,
+			Address: common.BytesToAddress(k[2:]),
+			Before:  decodeAmount(prev),
+			After:   decodeAmount(next),
+		}, nil
+
+	case len(k) == 2+common.AddressLength && k[0] == stakerPrefix:
+		return &Effect{
+			Type:    EffectStake,
+			Address: common.BytesToAddress(k[2:]),
+			Before:  decodeAmount(prev),
+			After:   decodeAmount(next),
+		}, nil
+
+	case len(k) == 4+common.AddressLength && k[0] == stakerPrefix:
+		if (prev == nil) == (next == nil) {
+			return nil, nil
+		}
+		e := &Effect{Type: EffectStakerAdded, Address: common.BytesToAddress(k[4:])}
+		meta := new(StakerMeta)
+		if prev != nil {
+			e.Type = EffectStakerRemoved
+			if _, err := Unmarshal(prev, meta); err != nil {
+				return nil, err
+			}
+			e.Before = meta.StakeAmount
+		} else {
+			if _, err := Unmarshal(next, meta); err != nil {
+				return nil, err
+			}
+			e.After = meta.StakeAmount
+		}
+		return e, nil
+
+	case len(k) == 4+common.AddressLength && k[0] == rewardPrefix && k[2] == LocalPrefix:
+		e := &Effect{Type: EffectReward, Address: common.BytesToAddress(k[4:])}
+		var err error
+		if e.Before, err = unclaimedReward(prev); err != nil {
+			return nil, err
+		}
+		if e.After, err = unclaimedReward(next); err != nil {
+			return nil, err
+		}
+		return e, nil
+
+	case len(k) == 2+common.AddressLength+len(ids.Empty) && k[0] == slashesPrefix:
+		if prev != nil || next == nil {
+			return nil, nil
+		}
+		record := new(SlashRecord)
+		if _, err := Unmarshal(next, record); err != nil {
+			return nil, err
+		}
+		return &Effect{
+			Type:    EffectSlashed,
+			Address: record.Staker,
+			Before:  record.StakeBefore,
+			After:   record.StakeBefore - record.Amount,
+		}, nil
+	}
+	return nil, nil
+}
+
+func decodeAmount(v []byte) uint64 {
+	if len(v) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(v)
+}
+
+func unclaimedReward(v []byte) (uint64, error) {
+	if v == nil {
+		return 0, nil
+	}
+	meta := new(RewardMeta)
+	if _, err := Unmarshal(v, meta); err != nil {
+		return 0, err
+	}
+	return meta.BaseReward + meta.MeritReward + meta.YieldReward, nil
+}
+
+// executeWithReceipt executes [t] like [Transaction.Execute] and returns the
+// receipt of the changes it made to [db]
+func (t *Transaction) executeWithReceipt(g *Genesis, db *versiondb.Database, blk *StatelessBlock, context *Context) (*Receipt, error) {
+	tdb := versiondb.New(db)
+	defer tdb.Abort()
+	if err := t.Execute(g, tdb, blk, context); err != nil {
+		return nil, err
+	}
+	batch, err := tdb.CommitBatch()
+	if err != nil {
+		return nil, err
+	}
+	r := &effectRecorder{db: db}
+	if err := batch.Replay(r); err != nil {
+		return nil, err
+	}
+	effects, err := r.effects()
+	if err != nil {
+		return nil, err
+	}
+	if err := batch.Write(); err != nil {
+		return nil, err
+	}
+	feeUnits := t.FeeUnits(g)
+	return &Receipt{
+		TxID:      t.ID(),
+		BlkID:     blk.ID(),
+		Hght:      blk.Hght,
+		Price:     t.GetPrice(),
+		FeeUnits:  feeUnits,
+		LoadUnits: t.LoadUnits(g),
+		Fee:       feeUnits * t.GetPrice(),
+		Effects:   effects,
+	}, nil
+}
@@ -0,0 +1,120 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/database/memdb"
+	"github.com/ava-labs/avalanchego/database/versiondb"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/set"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestReceipt(t *testing.T) {
+	t.Parallel()
+
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := crypto.PubkeyToAddress(priv.PublicKey)
+	recipient := common.HexToAddress("0x0000000000000000000000000000000000000001")
+
+	db := memdb.New()
+	defer db.Close()
+	g := DefaultGenesis()
+	g.CustomAllocation = []*CustomAllocation{{Address: sender, Balance: 10000000}}
+	if err := g.Load(db, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	parentID := ids.GenerateTestID()
+	tx := &Transaction{
+		UnsignedTransaction: &TransferTx{
+			BaseTx: &BaseTx{BlockID: parentID, Magic: g.Magic, Price: g.MinPrice},
+			To:     recipient,
+			Units:  100,
+		},
+	}
+	dh, err := DigestHash(tx.UnsignedTransaction)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Signature, err = Sign(dh, priv); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Init(g); err != nil {
+		t.Fatal(err)
+	}
+
+	blk := &StatelessBlock{StatefulBlock: &StatefulBlock{Hght: 5, Tmstmp: 1}, id: ids.GenerateTestID()}
+	context := &Context{RecentBlockIDs: set.Set[ids.ID]{}, RecentTxIDs: set.Set[ids.ID]{}}
+	context.RecentBlockIDs.Add(parentID)
+	vdb := versiondb.New(db)
+	receipt, err := tx.executeWithReceipt(g, vdb, blk, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := PutReceipt(vdb, receipt); err != nil {
+		t.Fatal(err)
+	}
+	if err := vdb.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	fee := tx.FeeUnits(g) * g.MinPrice
+	expected := &Receipt{
+		TxID:      tx.ID(),
+		BlkID:     blk.ID(),
+		Hght:      5,
+		Price:     g.MinPrice,
+		FeeUnits:  tx.FeeUnits(g),
+		LoadUnits: tx.LoadUnits(g),
+		Fee:       fee,
+		Effects: []*Effect{
+			// Sorted by key
+			{Type: EffectBalance, Address: recipient, Before: 0, After: 100},
+			{Type: EffectBalance, Address: sender, Before: 10000000, After: 10000000 - 100 - fee},
+		},
+		Actions: []*ActionReceipt{},
+	}
+	if bytes.Compare(sender[:], recipient[:]) < 0 {
+		expected.Effects[0], expected.Effects[1] = expected.Effects[1], expected.Effects[0]
+	}
+	stored, ok, err := GetReceipt(db, tx.ID())
+	if err != nil || !ok {
+		t.Fatalf("receipt not stored (%v)", err)
+	}
+	if !reflect.DeepEqual(stored, expected) {
+		t.Fatalf("unexpected receipt %+v", stored)
+	}
+	if _, ok, _ := GetReceipt(db, ids.GenerateTestID()); ok {
+		t.Fatal("found receipt of unknown tx")
+	}
+
+	// Staker records are reported when they are added or removed
+	staker := &StakerMeta{StakerType: stakerTypeRoute, StakerAddr: recipient, StakeAmount: 7}
+	b, err := Marshal(staker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k := PrefixStaker4Key(stakerTypeRoute, recipient)
+	if e, _ := effect(k, nil, b); e == nil || e.Type != EffectStakerAdded || e.After != 7 {
+		t.Fatalf("unexpected effect %+v", e)
+	}
+	if e, _ := effect(k, b, nil); e == nil || e.Type != EffectStakerRemoved || e.Before != 7 {
+		t.Fatalf("unexpected effect %+v", e)
+	}
+	if e, _ := effect(k, b, b); e != nil {
+		t.Fatalf("unexpected effect %+v", e)
+	}
+	if e, _ := effect(PrefixTxKey(tx.ID()), nil, nil); e != nil {
+		t.Fatalf("unexpected effect %+v", e)
+	}
+}
@@ -32,7 +32,7 @@ func IsRepeated(d *DetailMeta, r *RefreshTx) bool {
 }
 
 func (r *RefreshTx) Execute(t *TransactionContext) error {
-	samaState := t.vm.SamaState()
+	samaState := t.State
 	ok, _, err := samaState.IsValidWorkAddress(t.Sender)
 	if err != nil {
 		return err
@@ -53,7 +53,7 @@ func (r *RefreshTx) Execute(t *TransactionContext) error {
 		return fmt.Errorf("not found")
 	}
 
-	err = samaState.PutDetail(t.Database, t.Sender, &DetailMeta{
+	err = samaState.PutDetail(t.Sender, &DetailMeta{
 		StakerType:     pmate.StakerType,
 		Country:        r.Country,
 		WorkKey:        r.WorkKey,
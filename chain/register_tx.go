@@ -53,7 +53,7 @@ func (r *RegisterTx) Execute(t *TransactionContext) error {
 	}
 	addr := crypto.PubkeyToAddress(*pbk)
 
-	samaState := t.vm.SamaState()
+	samaState := t.State
 	ok, _, _ := samaState.IsStaker(r.StakerAddr)
 	if ok {
 		return fmt.Errorf("have already been staker")
@@ -71,21 +71,19 @@ func (r *RegisterTx) Execute(t *TransactionContext) error {
 			}
 		}
 	}
+	if _, exist, _ := samaState.GetActionMeta(r.ActionID, t.BlockTime); exist {
+		return fmt.Errorf("action ID exist")
+	}
+	if err := samaState.SettleActions(actionTypeAddStaker, r.StakerAddr.Hex(), t.TxID, t.BlockTime); err != nil {
+		return err
+	}
 	txIDs := []ids.ID{}
 	txIDs = append(txIDs, t.TxID)
-	voters := []common.Address{}
-	routeNum, _, _ := samaState.GetStakersNum()
-	sroot := samaState.GetRootAddress()
-
-	if t.Sender == common.HexToAddress(sroot) && routeNum < 3 {
-		voters = append(voters, t.Sender)
-	} else {
-		ok, _ := samaState.IsRoute(t.Sender)
-		if ok {
-			voters = append(voters, t.Sender)
-		}
+	voters, err := proposalVoters(samaState, uint64(actionTypeAddStaker), t.Sender)
+	if err != nil {
+		return err
 	}
-	err = samaState.PutAction(t.Database, r.ActionID, &ActionMeta{
+	err = putVotedAction(t, &ActionMeta{
 		ActionID:   r.ActionID,
 		ActionType: uint64(actionTypeAddStaker),
 		StartTime:  t.BlockTime,
@@ -93,11 +91,12 @@ func (r *RegisterTx) Execute(t *TransactionContext) error {
 		Key:        r.StakerAddr.Hex(),
 		TxIDs:      txIDs,
 		Voters:     voters,
+		Proposer:   t.Sender,
 	})
 	if err != nil {
 		return err
 	}
-	err = samaState.UpdateNodeParams(t.Database, &DetailMeta{
+	err = samaState.UpdateNodeParams(&DetailMeta{
 		StakerType:     r.StakerType,
 		Country:        r.Country,
 		LocalIP:        r.LocalIP,
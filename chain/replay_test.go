@@ -0,0 +1,300 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ava-labs/avalanchego/database/memdb"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow/choices"
+	"github.com/ava-labs/avalanchego/utils/set"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	gomock "github.com/golang/mock/gomock"
+)
+
+var errAnyFailure = errors.New("any failure")
+
+// TestReplayDeterminism executes transactions at historical block times and
+// ensures the outcome only depends on the block timestamps (and never on the
+// wall clock of the node replaying them).
+func TestReplayDeterminism(t *testing.T) {
+	t.Parallel()
+
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := crypto.PubkeyToAddress(priv.PublicKey)
+	user := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	user2 := common.HexToAddress("0x0000000000000000000000000000000000000002")
+
+	// Well in the past of any machine running this test
+	blkTime := DefaultGenesis().ChainCreateTime + 30*SecondsDay
+
+	tt := []struct {
+		utx        UnsignedTransaction
+		blockTime  uint64
+		parentTime uint64
+		err        error
+	}{
+		{ // subscription starting shortly before the block is valid
+			utx: &AddUserTx{
+				BaseTx:    &BaseTx{},
+				StartTime: blkTime - 30, EndTime: blkTime - 30 + SecondsMonth,
+				PayAmount: 100, UserType: 1, Address: user,
+			},
+			blockTime:  blkTime,
+			parentTime: blkTime - 1,
+		},
+		{ // subscription starting too far before the block is invalid
+			utx: &AddUserTx{
+				BaseTx:    &BaseTx{},
+				StartTime: blkTime - maxUserStartDrift - 1, EndTime: blkTime - maxUserStartDrift - 1 + SecondsMonth,
+				PayAmount: 100, UserType: 1, Address: user2,
+			},
+			blockTime:  blkTime,
+			parentTime: blkTime - 1,
+			err:        errAnyFailure,
+		},
+		{ // reward end time after the block
+			utx:        &UnStakeTx{BaseTx: &BaseTx{}, StakerType: stakerTypeRoute, EndTime: blkTime + 1},
+			blockTime:  blkTime,
+			parentTime: blkTime - 1,
+			err:        ErrEndTimeTooLate,
+		},
+		{ // reward end time too far before the block
+			utx:        &UnStakeTx{BaseTx: &BaseTx{}, StakerType: stakerTypeRoute, EndTime: blkTime - EffectiveSecs - 1},
+			blockTime:  blkTime,
+			parentTime: blkTime - 1,
+			err:        ErrEndTimeTooEarly,
+		},
+		{ // time window is valid but sender is not staking
+			utx:        &UnStakeTx{BaseTx: &BaseTx{}, StakerType: stakerTypeRoute, EndTime: blkTime - EffectiveSecs},
+			blockTime:  blkTime,
+			parentTime: blkTime - 1,
+			err:        errAnyFailure,
+		},
+	}
+
+	replay := func() []error {
+		db := memdb.New()
+		defer db.Close()
+
+		g := DefaultGenesis()
+		g.CustomAllocation = []*CustomAllocation{{Address: sender, Balance: 10000000}}
+		if err := g.Load(db, nil); err != nil {
+			t.Fatal(err)
+		}
+		state := SamaNew(db, g)
+		if err := state.AddUserType(&UserType{TypeID: 1, FeeUnits: 100}); err != nil {
+			t.Fatal(err)
+		}
+
+		errs := make([]error, len(tt))
+		for i, tv := range tt {
+			errs[i] = tv.utx.Execute(&TransactionContext{
+				Genesis:    g,
+				Database:   db,
+				BlockTime:  tv.blockTime,
+				ParentTime: tv.parentTime,
+				TxID:       ids.GenerateTestID(),
+				Sender:     sender,
+				State:      state,
+			})
+		}
+		return errs
+	}
+
+	errs := replay()
+	for i, tv := range tt {
+		switch {
+		case tv.err == nil && errs[i] != nil:
+			t.Fatalf("#%d: tx.Execute err expected nil, got %v", i, errs[i])
+		case tv.err == errAnyFailure && errs[i] == nil:
+			t.Fatalf("#%d: tx.Execute err expected failure, got nil", i)
+		case tv.err != nil && tv.err != errAnyFailure && !errors.Is(errs[i], tv.err):
+			t.Fatalf("#%d: tx.Execute err expected %v, got %v", i, tv.err, errs[i])
+		}
+	}
+
+	// Blocks built in the past must verify to the same state on a node whose
+	// clock is days ahead
+	built := newReplayNode(t, sender, time.Unix(int64(blkTime), 0))
+	defer built.db.Close()
+	replayed := newReplayNode(t, sender, time.Now().Add(30*24*time.Hour))
+	defer replayed.db.Close()
+	for i, utx := range []UnsignedTransaction{
+		&AddUserTx{
+			BaseTx:    &BaseTx{},
+			StartTime: blkTime - 30, EndTime: blkTime - 30 + SecondsMonth,
+			PayAmount: 100, UserType: 1, Address: user,
+		},
+		&TransferTx{BaseTx: &BaseTx{}, To: user2, Units: 10},
+	} {
+		utx.SetBlockID(built.last.ID())
+		utx.SetMagic(built.genesis.Magic)
+		dh, err := DigestHash(utx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx := &Transaction{UnsignedTransaction: utx}
+		if tx.Signature, err = Sign(dh, priv); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Init(built.genesis); err != nil {
+			t.Fatal(err)
+		}
+		built.mempool.Add(tx)
+		built.now = time.Unix(int64(blkTime)+int64(i)*10, 0)
+
+		b, err := BuildBlock(built.vm, built.last.ID())
+		if err != nil {
+			t.Fatal(err)
+		}
+		blk := b.(*StatelessBlock)
+		if len(blk.Txs) != 1 {
+			t.Fatalf("#%d: expected 1 tx in block, got %d", i, len(blk.Txs))
+		}
+		built.accept(t, blk)
+		parsed, err := ParseBlock(blk.Bytes(), choices.Processing, replayed.vm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		replayed.accept(t, parsed)
+
+		for _, db := range []database.Database{built.db, replayed.db} {
+			root, err := StateRoot(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if root != blk.StateRoot {
+				t.Fatalf("#%d: expected state root %s, got %s", i, blk.StateRoot, root)
+			}
+		}
+		expected, found, err := GetReceipt(built.db, tx.ID())
+		if err != nil || !found {
+			t.Fatalf("#%d: missing receipt (%v)", i, err)
+		}
+		receipt, found, err := GetReceipt(replayed.db, tx.ID())
+		if err != nil || !found {
+			t.Fatalf("#%d: missing replayed receipt (%v)", i, err)
+		}
+		if !reflect.DeepEqual(expected, receipt) {
+			t.Fatalf("#%d: replay mismatch: %+v != %+v", i, expected, receipt)
+		}
+	}
+}
+
+// replayNode is a chain with a local clock set to [now]
+type replayNode struct {
+	vm      *MockVM
+	db      database.Database
+	genesis *Genesis
+	mempool *replayMempool
+	now     time.Time
+
+	blocks map[ids.ID]*StatelessBlock
+	last   *StatelessBlock
+}
+
+func newReplayNode(t *testing.T, sender common.Address, now time.Time) *replayNode {
+	t.Helper()
+
+	n := &replayNode{
+		db:      memdb.New(),
+		genesis: DefaultGenesis(),
+		mempool: &replayMempool{},
+		now:     now,
+		blocks:  map[ids.ID]*StatelessBlock{},
+	}
+	n.genesis.CustomAllocation = []*CustomAllocation{{Address: sender, Balance: 10000000}}
+	if err := n.genesis.Load(n.db, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := SamaNew(n.db, n.genesis).AddUserType(&UserType{TypeID: 1, FeeUnits: 100}); err != nil {
+		t.Fatal(err)
+	}
+	if err := EnsureStateTree(n.db); err != nil {
+		t.Fatal(err)
+	}
+
+	n.vm = NewMockVM(gomock.NewController(t))
+	n.vm.EXPECT().Genesis().Return(n.genesis).AnyTimes()
+	n.vm.EXPECT().Now().DoAndReturn(func() time.Time { return n.now }).AnyTimes()
+	n.vm.EXPECT().State().Return(n.db).AnyTimes()
+	n.vm.EXPECT().Mempool().Return(n.mempool).AnyTimes()
+	n.vm.EXPECT().GetStatelessBlock(gomock.Any()).DoAndReturn(func(id ids.ID) (*StatelessBlock, error) {
+		blk, ok := n.blocks[id]
+		if !ok {
+			return nil, database.ErrNotFound
+		}
+		return blk, nil
+	}).AnyTimes()
+	n.vm.EXPECT().ExecutionContext(gomock.Any(), gomock.Any()).DoAndReturn(func(_ int64, parent *StatelessBlock) (*Context, error) {
+		context := &Context{
+			NextPrice:       parent.Price,
+			NextCost:        parent.Cost,
+			ParentTimestamp: parent.Tmstmp,
+			Rules:           Rules{IsPhase1: true},
+		}
+		for id, blk := range n.blocks {
+			context.RecentBlockIDs.Add(id)
+			for _, tx := range blk.Txs {
+				context.RecentTxIDs.Add(tx.ID())
+			}
+		}
+		return context, nil
+	}).AnyTimes()
+	n.vm.EXPECT().Verified(gomock.Any()).AnyTimes()
+	n.vm.EXPECT().Accepted(gomock.Any()).AnyTimes()
+
+	genesis, err := ParseStatefulBlock(n.genesis.StatefulBlock(), nil, choices.Accepted, n.vm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.blocks[genesis.ID()] = genesis
+	n.last = genesis
+	return n
+}
+
+func (n *replayNode) accept(t *testing.T, blk *StatelessBlock) {
+	t.Helper()
+
+	if err := blk.Verify(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := blk.Accept(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	n.blocks[blk.ID()] = blk
+	n.last = blk
+}
+
+// replayMempool hands out its txs in the order they were added
+type replayMempool struct {
+	txs []*Transaction
+}
+
+func (m *replayMempool) Len() int                     { return len(m.txs) }
+func (m *replayMempool) Prune(set.Set[ids.ID])        {}
+func (m *replayMempool) Drop(ids.ID, error)           {}
+func (m *replayMempool) NewTxs(uint64) []*Transaction { return nil }
+
+func (m *replayMempool) PopMax() (*Transaction, uint64) {
+	tx := m.txs[0]
+	m.txs = m.txs[1:]
+	return tx, tx.GetPrice()
+}
+
+func (m *replayMempool) Add(tx *Transaction) bool {
+	m.txs = append(m.txs, tx)
+	return true
+}
@@ -0,0 +1,90 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"strconv"
+
+	"github.com/SamaNetwork/SamaVM/tdata"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+var _ UnsignedTransaction = &ReportOffenceTx{}
+
+// ReportOffenceTx slashes [Staker] for the offence of [OffenceType] proven by
+// [Evidence], anyone can report it
+type ReportOffenceTx struct {
+	*BaseTx     `serialize:"true" json:"baseTx"`
+	Staker      common.Address `serialize:"true" json:"staker"`
+	OffenceType uint64         `serialize:"true" json:"offenceType"`
+	Evidence    []byte         `serialize:"true" json:"evidence"`
+}
+
+func (*ReportOffenceTx) minFork() string {
+	return ForkPhase4
+}
+
+func (r *ReportOffenceTx) Execute(t *TransactionContext) error {
+	offence, err := GetOffence(r.OffenceType)
+	if err != nil {
+		return err
+	}
+	policy, err := t.State.GetSlashingPolicy(r.OffenceType)
+	if err != nil {
+		return err
+	}
+	evidenceID, err := offence.Verify(t, r.Staker, r.Evidence, policy)
+	if err != nil {
+		return err
+	}
+	return slashStaker(t, r.Staker, r.OffenceType, evidenceID)
+}
+
+func (r *ReportOffenceTx) FeeUnits(g *Genesis) uint64 {
+	return r.BaseTx.FeeUnits(g) + valueUnits(g, uint64(len(r.Evidence)))
+}
+
+func (r *ReportOffenceTx) LoadUnits(g *Genesis) uint64 {
+	return r.FeeUnits(g)
+}
+
+func (r *ReportOffenceTx) Copy() UnsignedTransaction {
+	evidence := make([]byte, len(r.Evidence))
+	copy(evidence, r.Evidence)
+	return &ReportOffenceTx{
+		BaseTx:      r.BaseTx.Copy(),
+		Staker:      r.Staker,
+		OffenceType: r.OffenceType,
+		Evidence:    evidence,
+	}
+}
+
+func (r *ReportOffenceTx) TypedData() *tdata.TypedData {
+	return tdata.CreateTypedData(
+		r.Magic, ReportOffence,
+		[]tdata.Type{
+			{Name: tdStakeAddr, Type: tdAddress},
+			{Name: tdOffenceType, Type: tdUint64},
+			{Name: tdEvidence, Type: tdBytes},
+			{Name: tdPrice, Type: tdUint64},
+			{Name: tdBlockID, Type: tdString},
+		},
+		tdata.TypedDataMessage{
+			tdStakeAddr:   r.Staker.Hex(),
+			tdOffenceType: strconv.FormatUint(r.OffenceType, 10),
+			tdEvidence:    hexutil.Encode(r.Evidence),
+			tdPrice:       strconv.FormatUint(r.Price, 10),
+			tdBlockID:     r.BlockID.String(),
+		},
+	)
+}
+
+func (r *ReportOffenceTx) Activity() *Activity {
+	return &Activity{
+		Typ:        ReportOffence,
+		StakerAddr: r.Staker.Hex(),
+		ParamType:  r.OffenceType,
+	}
+}
@@ -0,0 +1,430 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// From [ForkPhase2] staker rewards are accounted with a [RewardIndex] per
+// staker type: the reward a single node earned since the index started,
+// advanced on every reward update of the type, where [StakerReword] used to
+// be settled for every staker of the type. Each staker keeps a
+// [RewardCheckpoint] of the index when it was last settled, so its reward
+// is the difference and claims, stakes and proofs only touch the caller's
+// records instead of every staker of its type.
+
+const (
+	IndexPrefix      = 2
+	CheckpointPrefix = 3
+
+	// meritScale is the precision of [RewardIndex.MeritPerPow]
+	meritScale = uint64(1_000_000)
+)
+
+// RewardIndex is the cumulative reward of a node of [StakerType]
+type RewardIndex struct {
+	StakerType uint64 `serialize:"true" json:"stakerType"`
+	// Base is the base reward of a node
+	Base uint64 `serialize:"true" json:"base"`
+	// MeritPerPow is the merit reward per pow minute, times [meritScale]
+	MeritPerPow uint64 `serialize:"true" json:"meritPerPow"`
+	// Yield is the share of a node in the user fees
+	Yield          uint64 `serialize:"true" json:"yield"`
+	LastUpdateTime uint64 `serialize:"true" json:"lastUpdateTime"`
+}
+
+// RewardCheckpoint is the [RewardIndex] a staker was last settled at, and
+// the reward it had earned by then and not claimed
+type RewardCheckpoint struct {
+	StakerAddr  common.Address `serialize:"true" json:"stakerAddr"`
+	StakerType  uint64         `serialize:"true" json:"stakerType"`
+	Base        uint64         `serialize:"true" json:"base"`
+	MeritPerPow uint64         `serialize:"true" json:"meritPerPow"`
+	Yield       uint64         `serialize:"true" json:"yield"`
+
+	PendingBase  uint64 `serialize:"true" json:"pendingBase"`
+	PendingMerit uint64 `serialize:"true" json:"pendingMerit"`
+	PendingYield uint64 `serialize:"true" json:"pendingYield"`
+}
+
+// settle moves the reward earned since the checkpoint up to [idx] into the
+// pending reward, [pow] is the pow minutes of the staker
+func (c *RewardCheckpoint) settle(idx *RewardIndex, pow uint64) {
+	c.PendingBase += idx.Base - c.Base
+	c.PendingMerit += mulDiv(pow, idx.MeritPerPow-c.MeritPerPow, meritScale)
+	c.PendingYield += idx.Yield - c.Yield
+	c.Base, c.MeritPerPow, c.Yield = idx.Base, idx.MeritPerPow, idx.Yield
+}
+
+func PrefixRewardIndexKey(stakerType byte) (k []byte) {
+	k = make([]byte, 5)
+	k[0] = rewardPrefix
+	k[1] = ByteDelimiter
+	k[2] = IndexPrefix
+	k[3] = ByteDelimiter
+	k[4] = stakerType
+	return
+}
+
+func PrefixRewardCheckpointKey(address common.Address) (k []byte) {
+	k = make([]byte, 4+common.AddressLength)
+	k[0] = rewardPrefix
+	k[1] = ByteDelimiter
+	k[2] = CheckpointPrefix
+	k[3] = ByteDelimiter
+	copy(k[4:], address[:])
+	return
+}
+
+var _ RewardIndexState = &rewardIndexState{}
+
+type RewardIndexState interface {
+	GetRewardIndex(stakerType byte) (*RewardIndex, bool, error)
+	PutRewardIndex(idx *RewardIndex) error
+	GetRewardCheckpoint(address common.Address) (*RewardCheckpoint, bool, error)
+	PutRewardCheckpoint(checkpoint *RewardCheckpoint) error
+	DelRewardCheckpoint(address common.Address) error
+}
+
+type rewardIndexState struct {
+	db database.Database
+}
+
+func NewRewardIndexState(db database.Database) *rewardIndexState {
+	return &rewardIndexState{db: db}
+}
+
+func (r *rewardIndexState) GetRewardIndex(stakerType byte) (*RewardIndex, bool, error) {
+	pmeta := new(RewardIndex)
+	exist, err := getState(r.db, PrefixRewardIndexKey(stakerType), pmeta)
+	if !exist || err != nil {
+		return nil, false, err
+	}
+	return pmeta, true, nil
+}
+
+func (r *rewardIndexState) PutRewardIndex(idx *RewardIndex) error {
+	return putState(r.db, PrefixRewardIndexKey(byte(idx.StakerType)), idx)
+}
+
+func (r *rewardIndexState) GetRewardCheckpoint(address common.Address) (*RewardCheckpoint, bool, error) {
+	pmeta := new(RewardCheckpoint)
+	exist, err := getState(r.db, PrefixRewardCheckpointKey(address), pmeta)
+	if !exist || err != nil {
+		return nil, false, err
+	}
+	return pmeta, true, nil
+}
+
+func (r *rewardIndexState) PutRewardCheckpoint(checkpoint *RewardCheckpoint) error {
+	return putState(r.db, PrefixRewardCheckpointKey(checkpoint.StakerAddr), checkpoint)
+}
+
+func (r *rewardIndexState) DelRewardCheckpoint(address common.Address) error {
+	return r.db.Delete(PrefixRewardCheckpointKey(address))
+}
+
+// rewardIndexed reports whether the rewards moved to index accounting
+func (s *samaState) rewardIndexed() (bool, error) {
+	_, exist, err := s.GetRewardIndex(stakerTypeRoute)
+	return exist, err
+}
+
+// MigrateRewards moves the rewards to index accounting at [blkTime]. The
+// stakers of a type were all settled by the last update of the type, so its
+// index starts there and the reward they had then becomes pending in their
+// checkpoints. It does nothing once the rewards moved.
+func (s *samaState) MigrateRewards(blkTime uint64) error {
+	if indexed, err := s.rewardIndexed(); err != nil || indexed {
+		return err
+	}
+	for _, stakerType := range StakerTypes {
+		stakers, err := s.GetStakers(stakerType)
+		if err != nil {
+			return err
+		}
+		roleNum := uint64(len(stakers))
+		rewards := make([]*RewardMeta, len(stakers))
+		starts := make([]uint64, len(stakers))
+		lastUpdate := blkTime
+		if roleNum > 0 {
+			lastUpdate = 0
+		}
+		for i, staker := range stakers {
+			starts[i] = staker.StakeTime
+			reward, exist, err := s.GetRewardMeta(staker.StakerAddr)
+			if err != nil {
+				return err
+			}
+			if exist {
+				rewards[i], starts[i] = reward, reward.LastOprTime
+			}
+			if starts[i] > lastUpdate {
+				lastUpdate = starts[i]
+			}
+		}
+		for i, staker := range stakers {
+			checkpoint := &RewardCheckpoint{StakerAddr: staker.StakerAddr, StakerType: staker.StakerType}
+			switch {
+			case starts[i] < lastUpdate:
+				// Not settled by the last update, which only pays the fee
+				// share to the stakers it settles
+				base, merit, yield, err := s.StakerReword(stakerType, len(stakers), staker.StakerAddr, staker.StakeTime, lastUpdate)
+				if err != nil {
+					return err
+				}
+				checkpoint.PendingBase, checkpoint.PendingMerit = base, merit
+				checkpoint.PendingYield = yield - s.yieldShare(stakerType, roleNum)
+			case rewards[i] != nil:
+				checkpoint.PendingBase = rewards[i].BaseReward
+				checkpoint.PendingMerit = rewards[i].MeritReward
+				checkpoint.PendingYield = rewards[i].YieldReward
+			}
+			if err := s.PutRewardCheckpoint(checkpoint); err != nil {
+				return err
+			}
+		}
+		if err := s.PutRewardIndex(&RewardIndex{StakerType: uint64(stakerType), LastUpdateTime: lastUpdate}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateRewards moves the rewards in [db] to index accounting once
+// [ForkPhase2] is active
+func migrateRewards(g *Genesis, db database.Database, rules Rules, blkTime uint64) error {
+	if !rules.IsPhase2 {
+		return nil
+	}
+	return SamaNew(db, g).MigrateRewards(blkTime)
+}
+
+// rewardIndex returns the index of [stakerType]
+func (s *samaState) rewardIndex(stakerType byte) (*RewardIndex, error) {
+	idx, exist, err := s.GetRewardIndex(stakerType)
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		idx = &RewardIndex{StakerType: uint64(stakerType)}
+	}
+	return idx, nil
+}
+
+// yieldShare returns the share of a node of [stakerType] in the fees, which
+// [StakerReword] pays on every update
+func (s *samaState) yieldShare(stakerType byte, roleNum uint64) uint64 {
+	return s.GetChainYields() * uint64(s.StakePercentage(stakerType)) / 100 / roleNum
+}
+
+// advanceRewardIndex adds the reward of a node and of a pow minute from the
+// last update of [idx] to [now], while [roleNum] nodes share it. It follows
+// [StakerReword], which adds the merit of the period so far once per year.
+func (s *samaState) advanceRewardIndex(idx *RewardIndex, roleNum uint64, now uint64) error {
+	if now <= idx.LastUpdateTime {
+		return nil
+	}
+	stakerType := byte(idx.StakerType)
+	percBase, percMerit := uint32(0), uint32(0)
+	switch stakerType {
+	case stakerTypeRoute:
+		percBase, percMerit = s.GetRoutePercBase(), s.GetRoutePercMerit()
+	case stakerTypeSer:
+		percBase, percMerit = s.GetSerPercBase(), s.GetSerPercMerit()
+	case stakerTypeValidator:
+		percBase = 100
+	}
+	totalPow, err := s.ChainTotalPowMinutes(stakerType)
+	if err != nil {
+		return err
+	}
+
+	createTime := s.GetChainCreateTime()
+	startTime := idx.LastUpdateTime
+	meritInc := uint64(0)
+	for year := (startTime - createTime) / SecondsYear; year <= (now-createTime)/SecondsYear; year++ {
+		start, end := createTime+year*SecondsYear, createTime+(year+1)*SecondsYear
+		if start < startTime {
+			start = startTime
+		}
+		if end > now {
+			end = now
+		}
+		workSecs := end - start
+		roleTotalYear := s.RewardCurYear(uint32(year)) * uint64(s.StakePercentage(stakerType)) / 100
+		baseTotal := roleTotalYear * uint64(percBase) / 100
+		meritTotal := roleTotalYear * uint64(percMerit) / 100
+
+		idx.Base += (baseTotal / (roleNum * SecondsYear)) * workSecs
+		meritInc += (meritTotal / SecondsYear) * workSecs
+		if totalPow != 0 {
+			idx.MeritPerPow += mulDiv(meritInc, meritScale, totalPow)
+		}
+	}
+	idx.LastUpdateTime = now
+	return nil
+}
+
+// advanceRewards makes an update of the rewards of [stakerType] at [now]:
+// the index advances by what every staker of the type earned since the last
+// update, including its fee share. Like [UpdateStakerReward], it does
+// nothing if rewards were already updated at [now].
+func (s *samaState) advanceRewards(stakerType byte, txID ids.ID, now uint64) (*RewardIndex, error) {
+	idx, err := s.rewardIndex(stakerType)
+	if err != nil {
+		return nil, err
+	}
+	lastTime, _ := s.GetLastUpdateTime()
+	switch {
+	case lastTime > now:
+		return nil, ErrEndTimeTooEarly
+	case lastTime == now:
+		return idx, nil
+	}
+	roleNum := uint64(s.StakersNum(stakerType))
+	if roleNum == 0 {
+		// Nothing was earned while there were no stakers
+		idx.LastUpdateTime = now
+		return idx, s.PutRewardIndex(idx)
+	}
+	if err := s.advanceRewardIndex(idx, roleNum, now); err != nil {
+		return nil, err
+	}
+	idx.Yield += s.yieldShare(stakerType, roleNum)
+	if err := s.PutRewardIndex(idx); err != nil {
+		return nil, err
+	}
+	if err := s.UpdateGlobal(&RewardGlobal{LastOprTime: now, LastOprTXID: txID}); err != nil {
+		return nil, err
+	}
+	return idx, s.ModifyYields(0, txID, now)
+}
+
+// indexedReward returns the unclaimed reward of [address] at [endTime], as
+// if the rewards were updated then
+func (s *samaState) indexedReward(stakerType byte, address common.Address, endTime uint64) (uint64, uint64, uint64, error) {
+	idx, err := s.rewardIndex(stakerType)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if roleNum := uint64(s.StakersNum(stakerType)); roleNum > 0 {
+		if err := s.advanceRewardIndex(idx, roleNum, endTime); err != nil {
+			return 0, 0, 0, err
+		}
+		idx.Yield += s.yieldShare(stakerType, roleNum)
+	}
+	checkpoint, err := s.rewardCheckpoint(stakerType, address, idx)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	pow, err := s.StakePowMinutes(stakerType, address)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	checkpoint.settle(idx, pow)
+	return checkpoint.PendingBase, checkpoint.PendingMerit, checkpoint.PendingYield, nil
+}
+
+// rewardCheckpoint returns the checkpoint of [address], a new one at [idx]
+// if it has none
+func (s *samaState) rewardCheckpoint(stakerType byte, address common.Address, idx *RewardIndex) (*RewardCheckpoint, error) {
+	checkpoint, exist, err := s.GetRewardCheckpoint(address)
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		checkpoint = &RewardCheckpoint{
+			StakerAddr:  address,
+			StakerType:  uint64(stakerType),
+			Base:        idx.Base,
+			MeritPerPow: idx.MeritPerPow,
+			Yield:       idx.Yield,
+		}
+	}
+	return checkpoint, nil
+}
+
+// settleReward updates the rewards of [stakerType] at [now] and settles
+// [address], paying its pending reward if [claimed]
+func (s *samaState) settleReward(stakerType byte, address common.Address, txID ids.ID, now uint64, claimed bool) error {
+	idx, err := s.advanceRewards(stakerType, txID, now)
+	if err != nil {
+		return err
+	}
+	checkpoint, err := s.rewardCheckpoint(stakerType, address, idx)
+	if err != nil {
+		return err
+	}
+	pow, err := s.StakePowMinutes(stakerType, address)
+	if err != nil {
+		return err
+	}
+	checkpoint.settle(idx, pow)
+	if claimed {
+		checkpoint.PendingBase, checkpoint.PendingMerit, checkpoint.PendingYield = 0, 0, 0
+	}
+	return s.PutRewardCheckpoint(checkpoint)
+}
+
+// SettlePow settles the merit reward of [address] up to the last update of
+// [powType] before its pow minutes change. An update applies the pow minutes
+// of the time to the whole period since the previous one, so the new minutes
+// count from the last update.
+func (s *samaState) SettlePow(powType byte, address common.Address) error {
+	if indexed, err := s.rewardIndexed(); err != nil || !indexed {
+		return err
+	}
+	checkpoint, exist, err := s.GetRewardCheckpoint(address)
+	if err != nil || !exist || checkpoint.StakerType != uint64(powType) {
+		return err
+	}
+	idx, err := s.rewardIndex(powType)
+	if err != nil {
+		return err
+	}
+	pow, err := s.StakePowMinutes(powType, address)
+	if err != nil {
+		return err
+	}
+	checkpoint.settle(idx, pow)
+	return s.PutRewardCheckpoint(checkpoint)
+}
+
+// claimIndexedReward settles [address] at [endTime] with its reward paid
+func (s *samaState) claimIndexedReward(stakerType byte, address common.Address, txID ids.ID, endTime uint64) error {
+	if err := s.settleReward(stakerType, address, txID, endTime, true); err != nil {
+		return err
+	}
+	return s.UpdateOwner(
+		&RewardMeta{
+			LastOprTime:   endTime,
+			LastOprTXID:   txID,
+			LastClaimTime: endTime,
+			LastClaimTXID: txID,
+			RewardAddr:    address,
+		})
+}
+
+// stakeIndexed adds [staker], which earns from the update at its stake time
+func (s *samaState) stakeIndexed(staker *StakerMeta) error {
+	// The reward so far is shared by the stakers before this one
+	idx, err := s.advanceRewards(byte(staker.StakerType), staker.TxID, staker.StakeTime)
+	if err != nil {
+		return err
+	}
+	if err := s.PutStaker(staker); err != nil {
+		return err
+	}
+	return s.PutRewardCheckpoint(&RewardCheckpoint{
+		StakerAddr:  staker.StakerAddr,
+		StakerType:  staker.StakerType,
+		Base:        idx.Base,
+		MeritPerPow: idx.MeritPerPow,
+		Yield:       idx.Yield,
+	})
+}
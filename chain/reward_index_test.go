@@ -0,0 +1,285 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/database/memdb"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// rewardTestGenesis returns a genesis with merit rates per second that are
+// multiples of 2^15. While the pow totals are powers of two up to that,
+// neither [StakerReword] nor the indexes round the merit reward, so both
+// must agree exactly.
+func rewardTestGenesis() *Genesis {
+	g := DefaultGenesis()
+	g.TotalTokens = SecondsYear * (1 << 10) * 50000
+	return g
+}
+
+// TestRewardIndexMatchesFormula runs random stake, claim, unstake and proof
+// sequences on a chain keeping [RewardMeta] and on one migrating to the
+// indexes midway: both must compute the same rewards.
+func TestRewardIndexMatchesFormula(t *testing.T) {
+	t.Parallel()
+
+	g := rewardTestGenesis()
+	for seed := int64(0); seed < 20; seed++ {
+		r := rand.New(rand.NewSource(seed)) //nolint:gosec
+		oldDB, newDB := memdb.New(), memdb.New()
+		for _, db := range []*memdb.Database{oldDB, newDB} {
+			if err := g.Load(db, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+		oldState, newState := SamaNew(oldDB, g), SamaNew(newDB, g)
+		states := []SamaState{oldState, newState}
+
+		// The pow of each type starts at 64 minutes and doubles with every
+		// proof
+		addrs := make([]common.Address, 12)
+		left := map[byte]uint64{stakerTypeRoute: 64, stakerTypeSer: 64}
+		for i := range addrs {
+			addrs[i] = common.Address{byte(i + 1)}
+			stakerType := StakerTypes[i%len(StakerTypes)]
+			if stakerType == stakerTypeValidator {
+				continue
+			}
+			pow := left[stakerType]
+			if i+len(StakerTypes) < len(addrs) {
+				pow = uint64(r.Intn(16) + 1)
+			}
+			left[stakerType] -= pow
+			proof := &ProofMeta{Netflow: 1, WorkTime: pow, Miner: addrs[i]}
+			for _, s := range states {
+				if err := s.PutPow(stakerType, proof); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+
+		events := 40
+		migrateAt := r.Intn(events)
+		now := g.ChainCreateTime
+		staked := map[common.Address]bool{}
+		unstaked := map[common.Address]bool{}
+		compare := func(addr common.Address, now uint64) {
+			t.Helper()
+			stakerType := StakerTypes[int(addr[0]-1)%len(StakerTypes)]
+			ob, om, oy, err := oldState.CalcReward(stakerType, addr, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			nb, nm, ny, err := newState.CalcReward(stakerType, addr, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ob != nb || om != nm || oy != ny {
+				t.Fatalf("seed %d: %x expected %d/%d/%d, got %d/%d/%d", seed, addr, ob, om, oy, nb, nm, ny)
+			}
+		}
+
+		for i := 0; i < events; i++ {
+			now += uint64(r.Intn(int(SecondsDay*7))) + 1
+			if i == migrateAt {
+				if err := newState.MigrateRewards(now); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if r.Intn(3) == 0 {
+				fees := uint64(r.Intn(1_000_000))
+				for _, s := range states {
+					if err := s.ModifyYields(fees, ids.Empty, now); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			addr := addrs[r.Intn(len(addrs))]
+			stakerType := StakerTypes[int(addr[0]-1)%len(StakerTypes)]
+			txID := ids.GenerateTestID()
+			if stakerType != stakerTypeValidator && r.Intn(4) == 0 {
+				total, err := newState.TotalPowTime(stakerType)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if total >= 1<<12 {
+					continue
+				}
+				if err := newState.SettlePow(stakerType, addr); err != nil {
+					t.Fatal(err)
+				}
+				proof := &ProofMeta{Netflow: 1, WorkTime: total, Miner: addr}
+				for _, s := range states {
+					if err := s.PutPow(stakerType, proof); err != nil {
+						t.Fatal(err)
+					}
+				}
+				continue
+			}
+			switch {
+			case unstaked[addr]:
+				continue
+			case !staked[addr]:
+				staked[addr] = true
+				for _, s := range states {
+					if err := s.DealStakeTx(&StakerMeta{TxID: txID, StakerType: uint64(stakerType), StakeAmount: 1, StakeTime: now, StakerAddr: addr}); err != nil {
+						t.Fatal(err)
+					}
+				}
+			default:
+				compare(addr, now)
+				unstake := r.Intn(4) == 0
+				var before [][]byte
+				if i >= migrateAt {
+					before = checkpointsBytes(t, newState, addrs, addr)
+				}
+				for _, s := range states {
+					var err error
+					if unstake {
+						err = s.DealUnStakeTx(stakerType, addr, txID, now)
+					} else {
+						err = s.UpdateStakerReward(stakerType, addr, txID, now)
+					}
+					if err != nil {
+						t.Fatal(err)
+					}
+				}
+				if unstake {
+					unstaked[addr] = true
+				}
+				// Only the claimer's records change
+				if i >= migrateAt {
+					after := checkpointsBytes(t, newState, addrs, addr)
+					for j := range before {
+						if !bytes.Equal(before[j], after[j]) {
+							t.Fatalf("seed %d: claim of %x changed checkpoint of %x", seed, addr, addrs[j])
+						}
+					}
+				}
+			}
+		}
+
+		now += SecondsDay
+		fees := uint64(r.Intn(1_000_000))
+		for _, s := range states {
+			if err := s.ModifyYields(fees, ids.Empty, now); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, addr := range addrs {
+			if staked[addr] && !unstaked[addr] {
+				compare(addr, now)
+			}
+		}
+		oldDB.Close()
+		newDB.Close()
+	}
+}
+
+// checkpointsBytes returns the encoded checkpoints of [addrs] except [skip]
+func checkpointsBytes(t *testing.T, s SamaState, addrs []common.Address, skip common.Address) [][]byte {
+	t.Helper()
+
+	b := make([][]byte, len(addrs))
+	for i, addr := range addrs {
+		if addr == skip {
+			continue
+		}
+		checkpoint, exist, err := s.GetRewardCheckpoint(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !exist {
+			continue
+		}
+		if b[i], err = Marshal(checkpoint); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return b
+}
+
+// TestRewardIndexPow changes the pow of stakers between reward updates: like
+// [StakerReword], an update applies the pow minutes of the time to the whole
+// period since the previous update.
+func TestRewardIndexPow(t *testing.T) {
+	t.Parallel()
+
+	g := rewardTestGenesis()
+	oldDB, newDB := memdb.New(), memdb.New()
+	defer oldDB.Close()
+	defer newDB.Close()
+	for _, db := range []*memdb.Database{oldDB, newDB} {
+		if err := g.Load(db, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	oldState, newState := SamaNew(oldDB, g), SamaNew(newDB, g)
+	states := []SamaState{oldState, newState}
+	start := g.ChainCreateTime
+	if err := newState.MigrateRewards(start); err != nil {
+		t.Fatal(err)
+	}
+	prove := func(addr common.Address, pow uint64) {
+		t.Helper()
+		if err := newState.SettlePow(stakerTypeRoute, addr); err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range states {
+			if err := s.PutPow(stakerTypeRoute, &ProofMeta{Netflow: 1, WorkTime: pow, Miner: addr}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	merit := func(addr common.Address, now uint64) uint64 {
+		t.Helper()
+		_, om, _, err := oldState.CalcReward(stakerTypeRoute, addr, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, nm, _, err := newState.CalcReward(stakerTypeRoute, addr, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if om != nm {
+			t.Fatalf("%x: expected merit %d, got %d", addr, om, nm)
+		}
+		return nm
+	}
+
+	r1, r2 := common.Address{1}, common.Address{2}
+	for _, addr := range []common.Address{r1, r2} {
+		prove(addr, 32)
+		for _, s := range states {
+			if err := s.DealStakeTx(&StakerMeta{StakerType: stakerTypeRoute, StakeAmount: 1, StakeTime: start, StakerAddr: addr}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// r1 proves 64 more minutes halfway, which count from the stakes
+	prove(r1, 64)
+	end := start + 2*SecondsDay
+	if m1, m2 := merit(r1, end), merit(r2, end); m2 == 0 || m1 != 3*m2 {
+		t.Fatalf("unexpected merit %d and %d", m1, m2)
+	}
+
+	// r2 claims, then proves 128 more minutes, which count from the claim
+	for _, s := range states {
+		if err := s.UpdateStakerReward(stakerTypeRoute, r2, ids.Empty, end); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m1 := merit(r1, end)
+	prove(r2, 128)
+	next := end + 2*SecondsDay
+	if m1, m2 := merit(r1, next)-m1, merit(r2, next); m1 == 0 || 5*m1 != 3*m2 {
+		t.Fatalf("unexpected merit %d and %d", m1, m2)
+	}
+}
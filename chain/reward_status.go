@@ -64,190 +64,77 @@ type RewardMeta struct {
 
 type RewardState interface {
 	GetRewardMeta(address common.Address) (*RewardMeta, bool, error)
-	UpdateReward(db database.Database, pG *RewardGlobal, pmeta *RewardMeta) error
-	UpdateOwner(db database.Database, pmeta *RewardMeta) error
-	UpdateGlobal(db database.Database, pG *RewardGlobal) error
+	UpdateReward(pG *RewardGlobal, pmeta *RewardMeta) error
+	UpdateOwner(pmeta *RewardMeta) error
+	UpdateGlobal(pG *RewardGlobal) error
 	GetRewards() ([]*RewardMeta, error)
-	DelReward(db database.Database, address common.Address) error
-	ReloadRewards(db database.Database) error
-	CacheRewardsCommit() error
-	CacheRewardsAbort() error
+	DelReward(address common.Address) error
 	GetLastUpdateTime() (uint64, error)
 	GetLastClaimTime(address common.Address) (uint64, error)
 }
 
 type rewardState struct {
-	curGlobal         *RewardGlobal
-	pendigGlobal      *RewardGlobal
-	curRewards        map[common.Address]*RewardMeta
-	pendingRewardsAdd map[common.Address]*RewardMeta
-	pendingRewardsDel map[common.Address]*RewardMeta
-}
-
-func NewRewardState(db database.Database) (*rewardState, error) {
-	state := &rewardState{
-		curGlobal: &RewardGlobal{
-			LastOprTime: 0,
-			LastOprTXID: ids.ID{},
-		},
-		pendigGlobal: &RewardGlobal{
-			LastOprTime: 0,
-			LastOprTXID: ids.ID{},
-		},
-		curRewards:        make(map[common.Address]*RewardMeta),
-		pendingRewardsAdd: make(map[common.Address]*RewardMeta),
-		pendingRewardsDel: make(map[common.Address]*RewardMeta),
-	}
-	err := state.ReloadRewards(db)
-	return state, err
+	db database.Database
+}
+
+func NewRewardState(db database.Database) *rewardState {
+	return &rewardState{db: db}
 }
 
 func (r *rewardState) GetLastUpdateTime() (uint64, error) {
-	if r.pendigGlobal.LastOprTime > r.curGlobal.LastOprTime {
-		return r.pendigGlobal.LastOprTime, nil
+	pG := new(RewardGlobal)
+	if _, err := getState(r.db, RewardGlobalPrefix(), pG); err != nil {
+		return 0, err
 	}
-	return r.curGlobal.LastOprTime, nil
+	return pG.LastOprTime, nil
 }
 
 func (r *rewardState) GetLastClaimTime(address common.Address) (uint64, error) {
-	pmeta, ok := r.curRewards[address]
-	if !ok {
-		return 0, nil
+	pmeta, exist, err := r.GetRewardMeta(address)
+	if !exist || err != nil {
+		return 0, err
 	}
 	return pmeta.LastClaimTime, nil
 }
 
 func (r *rewardState) GetRewardMeta(address common.Address) (*RewardMeta, bool, error) {
-	pmeta, ok := r.curRewards[address]
-	if !ok {
-		return nil, false, nil
+	pmeta := new(RewardMeta)
+	exist, err := getState(r.db, PrefixRewardKey(address), pmeta)
+	if !exist || err != nil {
+		return nil, false, err
 	}
 	return pmeta, true, nil
 }
 
-func (r *rewardState) UpdateReward(db database.Database, pG *RewardGlobal, pmeta *RewardMeta) error {
-	address := pmeta.RewardAddr
-
-	delete(r.pendingRewardsDel, address)
-	r.pendingRewardsAdd[address] = pmeta
-	k := PrefixRewardKey(address)
-	pvmeta, err := Marshal(pmeta)
-	if err != nil {
+func (r *rewardState) UpdateReward(pG *RewardGlobal, pmeta *RewardMeta) error {
+	if err := r.UpdateOwner(pmeta); err != nil {
 		return err
 	}
-	err = db.Put(k, pvmeta)
-	if err != nil {
-		return err
-	}
-	k = RewardGlobalPrefix()
-	pvG, err := Marshal(pG)
-	if err != nil {
-		return err
-	}
-	return db.Put(k, pvG)
-
+	return r.UpdateGlobal(pG)
 }
 
-func (r *rewardState) UpdateOwner(db database.Database, pmeta *RewardMeta) error {
-	address := pmeta.RewardAddr
-
-	delete(r.pendingRewardsDel, address)
-	r.pendingRewardsAdd[address] = pmeta
-	k := PrefixRewardKey(address)
-	pvmeta, err := Marshal(pmeta)
-	if err != nil {
-		return err
-	}
-	err = db.Put(k, pvmeta)
-	return err
+func (r *rewardState) UpdateOwner(pmeta *RewardMeta) error {
+	return putState(r.db, PrefixRewardKey(pmeta.RewardAddr), pmeta)
 }
 
-func (r *rewardState) UpdateGlobal(db database.Database, pG *RewardGlobal) error {
-	k := RewardGlobalPrefix()
-	pvG, err := Marshal(pG)
-	if err != nil {
-		return err
-	}
-	return db.Put(k, pvG)
+func (r *rewardState) UpdateGlobal(pG *RewardGlobal) error {
+	return putState(r.db, RewardGlobalPrefix(), pG)
 }
 
 func (r *rewardState) GetRewards() ([]*RewardMeta, error) {
 	rewards := []*RewardMeta(nil)
-	for _, reward := range r.curRewards {
-		rewards = append(rewards, reward)
-	}
-	return rewards, nil
-}
-
-func (r *rewardState) DelReward(db database.Database, address common.Address) error {
-	delete(r.pendingRewardsAdd, address)
-	r.pendingRewardsDel[address] = &RewardMeta{}
-
-	k := PrefixRewardKey(address)
-	return db.Delete(k)
-}
-
-func (r *rewardState) ReloadRewards(db database.Database) error {
-	basePrefix := baseRewardPrefix()
-	cursor := db.NewIteratorWithPrefix(basePrefix)
-	defer cursor.Release()
-	for cursor.Next() {
-		nmeta := cursor.Value()
-
+	err := iterateState(r.db, baseRewardPrefix(), common.AddressLength, func(_ []byte, v []byte) error {
 		pmeta := new(RewardMeta)
-		if _, err := Unmarshal(nmeta, pmeta); err != nil {
+		if _, err := Unmarshal(v, pmeta); err != nil {
 			return err
 		}
-		r.curRewards[pmeta.RewardAddr] = pmeta
-	}
-
-	k := RewardGlobalPrefix()
-	ymeta, err := db.Get(k)
-	if err != nil {
-		if err == database.ErrNotFound {
-			return nil
-		}
-		return err
-	}
-	if _, err := Unmarshal(ymeta, r.curGlobal); err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func (r *rewardState) CacheRewardsCommit() error {
-	for addr, pendingReward := range r.pendingRewardsAdd {
-		delete(r.curRewards, addr)
-		r.curRewards[addr] = pendingReward
-	}
-	for addr := range r.pendingRewardsDel {
-		delete(r.curRewards, addr)
-	}
-	for a := range r.pendingRewardsAdd {
-		delete(r.pendingRewardsAdd, a)
-	}
-	for a := range r.pendingRewardsDel {
-		delete(r.pendingRewardsDel, a)
-	}
-
-	if r.pendigGlobal.LastOprTime != 0 {
-		*r.curGlobal = *r.pendigGlobal
-		r.pendigGlobal.LastOprTime = 0
-		r.pendigGlobal.LastOprTXID = ids.ID{}
-	}
-	return nil
+		rewards = append(rewards, pmeta)
+		return nil
+	})
+	return rewards, err
 }
 
-func (r *rewardState) CacheRewardsAbort() error {
-	for a := range r.pendingRewardsAdd {
-		delete(r.pendingRewardsAdd, a)
-	}
-	for a := range r.pendingRewardsDel {
-		delete(r.pendingRewardsDel, a)
-	}
-	r.pendigGlobal.LastOprTime = 0
-	r.pendigGlobal.LastOprTXID = ids.ID{}
-
-	return nil
+func (r *rewardState) DelReward(address common.Address) error {
+	k := PrefixRewardKey(address)
+	return r.db.Delete(k)
 }
@@ -0,0 +1,77 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"strconv"
+
+	"github.com/SamaNetwork/SamaVM/tdata"
+)
+
+var _ UnsignedTransaction = &SetCommissionTx{}
+
+// SetCommissionTx sets the share of its node reward, in percent, the sender
+// keeps before splitting it with its delegators
+type SetCommissionTx struct {
+	*BaseTx        `serialize:"true" json:"baseTx"`
+	CommissionPerc uint32 `serialize:"true" json:"commissionPerc"`
+}
+
+func (*SetCommissionTx) minFork() string {
+	return ForkPhase4
+}
+
+func (s *SetCommissionTx) Execute(t *TransactionContext) error {
+	samaState := t.State
+	if s.CommissionPerc > 100 {
+		return ErrInvalidCommission
+	}
+	if _, err := delegatableStaker(samaState, t.Sender); err != nil {
+		return err
+	}
+	pool, err := samaState.GetStakerPool(t.Sender)
+	if err != nil {
+		return err
+	}
+	pool.CommissionPerc = s.CommissionPerc
+	return samaState.PutStakerPool(pool)
+}
+
+func (s *SetCommissionTx) FeeUnits(g *Genesis) uint64 {
+	return s.BaseTx.FeeUnits(g)
+}
+
+func (s *SetCommissionTx) LoadUnits(g *Genesis) uint64 {
+	return s.FeeUnits(g)
+}
+
+func (s *SetCommissionTx) Copy() UnsignedTransaction {
+	return &SetCommissionTx{
+		BaseTx:         s.BaseTx.Copy(),
+		CommissionPerc: s.CommissionPerc,
+	}
+}
+
+func (s *SetCommissionTx) TypedData() *tdata.TypedData {
+	return tdata.CreateTypedData(
+		s.Magic, SetCommission,
+		[]tdata.Type{
+			{Name: tdCommission, Type: tdUint64},
+			{Name: tdPrice, Type: tdUint64},
+			{Name: tdBlockID, Type: tdString},
+		},
+		tdata.TypedDataMessage{
+			tdCommission: strconv.FormatUint(uint64(s.CommissionPerc), 10),
+			tdPrice:      strconv.FormatUint(s.Price, 10),
+			tdBlockID:    s.BlockID.String(),
+		},
+	)
+}
+
+func (s *SetCommissionTx) Activity() *Activity {
+	return &Activity{
+		Typ:   SetCommission,
+		Value: strconv.FormatUint(uint64(s.CommissionPerc), 10),
+	}
+}
@@ -0,0 +1,603 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// 0x10/0x2/[offenceType] (slashing policies changed by governance)
+//   -> policy
+// 0x21/[staker]/[txID] (slash history)
+//   -> slash record
+// 0x21/[evidenceID] (evidence already slashed)
+//   -> txID
+
+// Offences are reported with a [ReportOffenceTx], or confirmed by governance
+// with an [actionTypeSlashStaker] action. The [SlashingPolicy] of the offence
+// type takes a percentage of the stake of the staker, including the stake it
+// is unbonding, and burns part of it. The rest goes to the foundation.
+// Slashing starts with [ForkPhase4].
+const (
+	// A signed [ProofTx] of a work address of the staker with invalid
+	// params
+	OffenceInvalidProof = iota + 1
+	// Two signed [ProofTx] of the same work address proving overlapping
+	// windows
+	OffenceDuplicateProof
+	// Misbehaviour confirmed by governance
+	OffenceMisbehaviour
+	// No proof from any work address of the staker for the policy period
+	OffenceDowntime
+)
+
+const slashingPolicyParam = 0x2
+
+var (
+	ErrUnknownOffence        = errors.New("unknown offence type")
+	ErrInvalidEvidence       = errors.New("invalid evidence")
+	ErrEvidenceSlashed       = errors.New("evidence already slashed")
+	ErrGovernedOffence       = errors.New("offence is confirmed by governance")
+	ErrNothingToSlash        = errors.New("no stake to slash")
+	ErrInvalidSlashingPolicy = errors.New("invalid slashing policy")
+
+	offences = map[uint64]Offence{}
+)
+
+func init() {
+	RegisterOffence(OffenceInvalidProof, &invalidProofOffence{})
+	RegisterOffence(OffenceDuplicateProof, &duplicateProofOffence{})
+	RegisterOffence(OffenceMisbehaviour, &governedOffence{})
+	RegisterOffence(OffenceDowntime, &downtimeOffence{})
+}
+
+// Offence proves offences of a type
+type Offence interface {
+	// Verify checks that [evidence] proves an offence of [staker] and returns
+	// the ID of the evidence, or [ids.Empty] if it can't be reused anyway.
+	// Each evidence is slashed once.
+	Verify(t *TransactionContext, staker common.Address, evidence []byte, policy *SlashingPolicy) (ids.ID, error)
+}
+
+// RegisterOffence makes [offenceType] slashable. It panics if the type is
+// already registered.
+func RegisterOffence(offenceType uint64, offence Offence) {
+	if _, ok := offences[offenceType]; ok {
+		panic(fmt.Sprintf("offence %d registered twice", offenceType))
+	}
+	offences[offenceType] = offence
+}
+
+func GetOffence(offenceType uint64) (Offence, error) {
+	offence, ok := offences[offenceType]
+	if !ok {
+		return nil, ErrUnknownOffence
+	}
+	return offence, nil
+}
+
+// SlashingPolicy is the penalty of the offences of a type
+type SlashingPolicy struct {
+	OffenceType uint64 `serialize:"true" json:"offenceType"`
+	// Percentage of the stake slashed
+	SlashPerc uint32 `serialize:"true" json:"slashPerc"`
+	// Percentage of the slashed stake burned, the rest goes to the
+	// foundation
+	BurnPerc uint32 `serialize:"true" json:"burnPerc"`
+	// Suspend the staker if it is still staking
+	Suspend bool `serialize:"true" json:"suspend"`
+	// Seconds without proofs after which a node is down, and between two
+	// downtime slashes of a staker. Only used by [OffenceDowntime].
+	Period uint64 `serialize:"true" json:"period"`
+}
+
+// DefaultSlashingPolicy burns half of what is slashed for the offences of
+// [offenceType]
+func DefaultSlashingPolicy(offenceType uint64) *SlashingPolicy {
+	policy := &SlashingPolicy{OffenceType: offenceType, BurnPerc: 50}
+	switch offenceType {
+	case OffenceInvalidProof:
+		policy.SlashPerc = 5
+	case OffenceDuplicateProof:
+		policy.SlashPerc = 10
+	case OffenceMisbehaviour:
+		policy.SlashPerc = 20
+		policy.Suspend = true
+	case OffenceDowntime:
+		policy.SlashPerc = 1
+		policy.Period = 7 * SecondsDay
+	}
+	return policy
+}
+
+func (p *SlashingPolicy) Verify() error {
+	if _, err := GetOffence(p.OffenceType); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSlashingPolicy, err)
+	}
+	if p.SlashPerc > 100 || p.BurnPerc > 100 {
+		return fmt.Errorf("%w: percentages above 100", ErrInvalidSlashingPolicy)
+	}
+	if p.OffenceType == OffenceDowntime && p.Period == 0 {
+		return fmt.Errorf("%w: downtime period is 0", ErrInvalidSlashingPolicy)
+	}
+	return nil
+}
+
+// [sysParamPrefix] + [delimiter] + [slashingPolicyParam] + [offenceType]
+func PrefixSlashingPolicyKey(offenceType uint64) (k []byte) {
+	k = make([]byte, 3+8)
+	k[0] = sysParamPrefix
+	k[1] = ByteDelimiter
+	k[2] = slashingPolicyParam
+	binary.BigEndian.PutUint64(k[3:], offenceType)
+	return
+}
+
+// GetSlashingPolicy returns the policy of [offenceType] last set by
+// governance, the one set in genesis or the default one, in that order
+func (s *sysParams) GetSlashingPolicy(offenceType uint64) (*SlashingPolicy, error) {
+	policy := new(SlashingPolicy)
+	exist, err := getState(s.db, PrefixSlashingPolicyKey(offenceType), policy)
+	if err != nil || exist {
+		return policy, err
+	}
+	for _, p := range s.genesis.SlashingPolicies {
+		if p.OffenceType == offenceType {
+			return p, nil
+		}
+	}
+	return DefaultSlashingPolicy(offenceType), nil
+}
+
+func (s *sysParams) PutSlashingPolicy(policy *SlashingPolicy) error {
+	return putState(s.db, PrefixSlashingPolicyKey(policy.OffenceType), policy)
+}
+
+// SlashRecord is the stake [Staker] lost in [TxID]
+type SlashRecord struct {
+	TxID        ids.ID         `serialize:"true" json:"txId"`
+	Staker      common.Address `serialize:"true" json:"staker"`
+	OffenceType uint64         `serialize:"true" json:"offenceType"`
+	EvidenceID  ids.ID         `serialize:"true" json:"evidenceId"`
+	// Stake of the staker before the slash, including the unbonding stake
+	StakeBefore uint64 `serialize:"true" json:"stakeBefore"`
+	Amount      uint64 `serialize:"true" json:"amount"`
+	Burned      uint64 `serialize:"true" json:"burned"`
+	Suspended   bool   `serialize:"true" json:"suspended"`
+	Time        uint64 `serialize:"true" json:"time"`
+}
+
+// [slashesPrefix] + [delimiter] + [staker] + [txID]
+func PrefixSlashKey(staker common.Address, txID ids.ID) (k []byte) {
+	k = make([]byte, 2+common.AddressLength+len(txID))
+	k[0] = slashesPrefix
+	k[1] = ByteDelimiter
+	copy(k[2:], staker[:])
+	copy(k[2+common.AddressLength:], txID[:])
+	return
+}
+
+// [slashesPrefix] + [delimiter] + [evidenceID]
+func PrefixSlashEvidenceKey(evidenceID ids.ID) (k []byte) {
+	k = make([]byte, 2+len(evidenceID))
+	k[0] = slashesPrefix
+	k[1] = ByteDelimiter
+	copy(k[2:], evidenceID[:])
+	return
+}
+
+var _ SlashesState = &slashesState{}
+
+type SlashesState interface {
+	// GetSlashes returns the slash history of [staker], oldest first
+	GetSlashes(staker common.Address) ([]*SlashRecord, error)
+	PutSlash(record *SlashRecord) error
+	IsEvidenceSlashed(evidenceID ids.ID) (bool, error)
+}
+
+type slashesState struct {
+	db database.Database
+}
+
+func NewSlashesState(db database.Database) *slashesState {
+	return &slashesState{db: db}
+}
+
+func (s *slashesState) GetSlashes(staker common.Address) ([]*SlashRecord, error) {
+	prefix := PrefixSlashKey(staker, ids.Empty)[:2+common.AddressLength]
+	records := []*SlashRecord(nil)
+	err := iterateState(s.db, prefix, len(ids.Empty), func(_ []byte, v []byte) error {
+		record := new(SlashRecord)
+		if _, err := Unmarshal(v, record); err != nil {
+			return err
+		}
+		records = append(records, record)
+		return nil
+	})
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Time < records[j].Time })
+	return records, err
+}
+
+// PutSlash records the slash, and its evidence if it has one
+func (s *slashesState) PutSlash(record *SlashRecord) error {
+	if record.EvidenceID != ids.Empty {
+		if err := s.db.Put(PrefixSlashEvidenceKey(record.EvidenceID), record.TxID[:]); err != nil {
+			return err
+		}
+	}
+	return putState(s.db, PrefixSlashKey(record.Staker, record.TxID), record)
+}
+
+func (s *slashesState) IsEvidenceSlashed(evidenceID ids.ID) (bool, error) {
+	return s.db.Has(PrefixSlashEvidenceKey(evidenceID))
+}
+
+// slashableStake returns the staker at [address], if it is still staking,
+// and the stake it is unbonding
+func slashableStake(s SamaState, address common.Address) (*StakerMeta, []*Unbonding, uint64, error) {
+	ok, stakerType, err := s.IsStaker(address)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	var staker *StakerMeta
+	total := uint64(0)
+	if ok {
+		if staker, _, err = s.GetStakerMeta(stakerType, address); err != nil {
+			return nil, nil, 0, err
+		}
+		total += staker.StakeAmount
+	}
+	unbondings, err := s.GetUnbondings(address)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	for _, unbonding := range unbondings {
+		total += unbonding.Amount
+	}
+	return staker, unbondings, total, nil
+}
+
+// distributeSlash takes [amount] from the stake balance of [address], burns
+// [burnPerc] of it and sends the rest to the foundation. It returns the
+// amount burned.
+func distributeSlash(t *TransactionContext, address common.Address, amount uint64, burnPerc uint32) (uint64, error) {
+	if _, err := ModifyStakeBalance(t.Database, address, false, amount); err != nil {
+		return 0, err
+	}
+	burned := amount * uint64(burnPerc) / 100
+	if amount == burned {
+		return burned, nil
+	}
+	foundation := common.HexToAddress(t.State.GetFoundationAddress())
+	if foundation == (common.Address{}) {
+		return 0, fmt.Errorf("foundation address not set")
+	}
+	if _, err := ModifyBalance(t.Database, foundation, true, amount-burned); err != nil {
+		return 0, err
+	}
+	return burned, nil
+}
+
+// slashStaker applies the policy of [offenceType] to the staker at
+// [address], for the offence proven by [evidenceID]
+func slashStaker(t *TransactionContext, address common.Address, offenceType uint64, evidenceID ids.ID) error {
+	samaState := t.State
+	if evidenceID != ids.Empty {
+		slashed, err := samaState.IsEvidenceSlashed(evidenceID)
+		if err != nil {
+			return err
+		}
+		if slashed {
+			return ErrEvidenceSlashed
+		}
+	}
+	policy, err := samaState.GetSlashingPolicy(offenceType)
+	if err != nil {
+		return err
+	}
+	staker, unbondings, total, err := slashableStake(samaState, address)
+	if err != nil {
+		return err
+	}
+	if total == 0 {
+		return ErrNothingToSlash
+	}
+
+	// The active stake is slashed first, then the unbonding one
+	amount := total * uint64(policy.SlashPerc) / 100
+	left := amount
+	if staker != nil && left > 0 {
+		taken := left
+		if taken > staker.StakeAmount {
+			taken = staker.StakeAmount
+		}
+		staker.StakeAmount -= taken
+		left -= taken
+		if err := samaState.PutStaker(staker); err != nil {
+			return err
+		}
+	}
+	for _, unbonding := range unbondings {
+		if left == 0 {
+			break
+		}
+		taken := left
+		if taken > unbonding.Amount {
+			taken = unbonding.Amount
+		}
+		unbonding.Amount -= taken
+		left -= taken
+		if unbonding.Amount == 0 {
+			err = samaState.DelUnbonding(address, unbonding.TxID)
+		} else {
+			err = samaState.PutUnbonding(unbonding)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	burned, err := distributeSlash(t, address, amount, policy.BurnPerc)
+	if err != nil {
+		return err
+	}
+
+	suspended := false
+	if policy.Suspend && staker != nil {
+		_, suspended, err = samaState.GetSuspension(address)
+		if err != nil {
+			return err
+		}
+		if !suspended {
+			if err := samaState.PutSuspension(&SuspensionMeta{
+				StakerAddr:  address,
+				StakerType:  staker.StakerType,
+				TxID:        t.TxID,
+				SuspendTime: t.BlockTime,
+			}); err != nil {
+				return err
+			}
+			suspended = true
+		}
+	}
+	return samaState.PutSlash(&SlashRecord{
+		TxID:        t.TxID,
+		Staker:      address,
+		OffenceType: offenceType,
+		EvidenceID:  evidenceID,
+		StakeBefore: total,
+		Amount:      amount,
+		Burned:      burned,
+		Suspended:   suspended,
+		Time:        t.BlockTime,
+	})
+}
+
+// signedProof decodes the [ProofTx] signed in [evidence] and checks it was
+// signed for this chain by a work address of [staker]
+func signedProof(t *TransactionContext, staker common.Address, evidence []byte) (*Transaction, *ProofTx, error) {
+	tx := new(Transaction)
+	if _, err := Unmarshal(evidence, tx); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidEvidence, err)
+	}
+	if err := tx.Init(t.Genesis); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidEvidence, err)
+	}
+	proof, ok := tx.UnsignedTransaction.(*ProofTx)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: not a proof", ErrInvalidEvidence)
+	}
+	if proof.Magic != t.Genesis.Magic {
+		return nil, nil, fmt.Errorf("%w: proof of another chain", ErrInvalidEvidence)
+	}
+	detail, exist, err := t.State.GetDetailByWorkAddress(tx.Sender())
+	if err != nil {
+		return nil, nil, err
+	}
+	if !exist || detail.StakeAddress != staker {
+		return nil, nil, fmt.Errorf("%w: proof not signed by a node of %s", ErrInvalidEvidence, staker)
+	}
+	return tx, proof, nil
+}
+
+// invalidProofOffence takes a [Transaction] of a [ProofTx] whose params are
+// invalid, whenever it is submitted
+type invalidProofOffence struct{}
+
+func (*invalidProofOffence) Verify(t *TransactionContext, staker common.Address, evidence []byte, _ *SlashingPolicy) (ids.ID, error) {
+	tx, proof, err := signedProof(t, staker, evidence)
+	if err != nil {
+		return ids.Empty, err
+	}
+	if proof.verifyParams() == nil {
+		return ids.Empty, fmt.Errorf("%w: valid proof", ErrInvalidEvidence)
+	}
+	return tx.ID(), nil
+}
+
+// DuplicateProofEvidence is the evidence of an [OffenceDuplicateProof], two
+// encoded [Transaction]
+type DuplicateProofEvidence struct {
+	First  []byte `serialize:"true" json:"first"`
+	Second []byte `serialize:"true" json:"second"`
+}
+
+// duplicateProofOffence takes two proofs of the same work address whose
+// windows overlap, so the same minutes are claimed twice
+type duplicateProofOffence struct{}
+
+func (*duplicateProofOffence) Verify(t *TransactionContext, staker common.Address, evidence []byte, _ *SlashingPolicy) (ids.ID, error) {
+	duplicate := new(DuplicateProofEvidence)
+	if _, err := Unmarshal(evidence, duplicate); err != nil {
+		return ids.Empty, fmt.Errorf("%w: %v", ErrInvalidEvidence, err)
+	}
+	first, firstProof, err := signedProof(t, staker, duplicate.First)
+	if err != nil {
+		return ids.Empty, err
+	}
+	second, secondProof, err := signedProof(t, staker, duplicate.Second)
+	if err != nil {
+		return ids.Empty, err
+	}
+	firstID, secondID := first.ID(), second.ID()
+	switch {
+	case first.Sender() != second.Sender():
+		return ids.Empty, fmt.Errorf("%w: proofs of different nodes", ErrInvalidEvidence)
+	case firstID == secondID:
+		return ids.Empty, fmt.Errorf("%w: same proof", ErrInvalidEvidence)
+	case firstProof.StartTime >= secondProof.EndTime || secondProof.StartTime >= firstProof.EndTime:
+		return ids.Empty, fmt.Errorf("%w: proofs don't overlap", ErrInvalidEvidence)
+	}
+
+	// The evidence is the same in any order
+	if bytes.Compare(firstID[:], secondID[:]) > 0 {
+		firstID, secondID = secondID, firstID
+	}
+	return ids.ToID(crypto.Keccak256(firstID[:], secondID[:]))
+}
+
+// governedOffence can't be reported, it is slashed by an approved
+// [actionTypeSlashStaker] action
+type governedOffence struct{}
+
+func (*governedOffence) Verify(*TransactionContext, common.Address, []byte, *SlashingPolicy) (ids.ID, error) {
+	return ids.Empty, ErrGovernedOffence
+}
+
+// downtimeOffence takes no evidence: the staker is a route or ser node none
+// of whose work addresses submitted a proof for the policy period, since it
+// staked, since [ForkPhase4] activated and since it was last slashed for
+// downtime
+type downtimeOffence struct{}
+
+func (*downtimeOffence) Verify(t *TransactionContext, staker common.Address, evidence []byte, policy *SlashingPolicy) (ids.ID, error) {
+	if len(evidence) > 0 {
+		return ids.Empty, fmt.Errorf("%w: downtime takes no evidence", ErrInvalidEvidence)
+	}
+	samaState := t.State
+	meta, err := governedStaker(samaState, staker.Hex())
+	if err != nil {
+		return ids.Empty, err
+	}
+	// Suspended nodes can't submit proofs
+	if _, suspended, err := samaState.GetSuspension(staker); err != nil || suspended {
+		if err == nil {
+			err = ErrSuspended
+		}
+		return ids.Empty, err
+	}
+
+	last := meta.StakeTime
+	if activation := uint64(t.Rules.Phase4Time); activation > last {
+		last = activation
+	}
+	details, err := samaState.GetDetails()
+	if err != nil {
+		return ids.Empty, err
+	}
+	for _, detail := range details {
+		if detail.StakeAddress != staker {
+			continue
+		}
+		pow, exist, err := samaState.GetPowMeta(byte(meta.StakerType), detail.WorkAddress)
+		if err != nil {
+			return ids.Empty, err
+		}
+		if exist && pow.LastUpdateTime > last {
+			last = pow.LastUpdateTime
+		}
+	}
+	slashes, err := samaState.GetSlashes(staker)
+	if err != nil {
+		return ids.Empty, err
+	}
+	for _, slash := range slashes {
+		if slash.OffenceType == OffenceDowntime && slash.Time > last {
+			last = slash.Time
+		}
+	}
+	if last+policy.Period > t.BlockTime {
+		return ids.Empty, fmt.Errorf("%w: last active at %d", ErrInvalidEvidence, last)
+	}
+	return ids.Empty, nil
+}
+
+// slashStakerAction slashes the staker at the address [key] for
+// [OffenceMisbehaviour], [newValue] describes the misbehaviour
+type slashStakerAction struct {
+	tallyApproval
+}
+
+func (*slashStakerAction) minFork() string {
+	return ForkPhase4
+}
+
+func (*slashStakerAction) Validate(t *TransactionContext, key string, _ string) error {
+	if !common.IsHexAddress(key) {
+		return fmt.Errorf("%w: %s is not an address", ErrInvalidKey, key)
+	}
+	_, _, total, err := slashableStake(t.State, common.HexToAddress(key))
+	if err != nil {
+		return err
+	}
+	if total == 0 {
+		return ErrNothingToSlash
+	}
+	return nil
+}
+
+func (*slashStakerAction) Execute(t *TransactionContext, action *ActionMeta) error {
+	if !common.IsHexAddress(action.Key) {
+		return fmt.Errorf("%w: %s is not an address", ErrInvalidKey, action.Key)
+	}
+	return slashStaker(t, common.HexToAddress(action.Key), OffenceMisbehaviour, ids.Empty)
+}
+
+// slashingPolicyAction replaces the slashing policy of the offence type
+// [key] with the JSON encoded policy [newValue]
+type slashingPolicyAction struct {
+	tallyApproval
+}
+
+func (*slashingPolicyAction) minFork() string {
+	return ForkPhase4
+}
+
+func parseSlashingPolicy(key string, newValue string) (*SlashingPolicy, error) {
+	offenceType, err := strconv.ParseUint(key, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	policy := new(SlashingPolicy)
+	if err := json.Unmarshal([]byte(newValue), policy); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSlashingPolicy, err)
+	}
+	if policy.OffenceType != offenceType {
+		return nil, fmt.Errorf("%w: policy of offence type %d", ErrInvalidSlashingPolicy, policy.OffenceType)
+	}
+	return policy, policy.Verify()
+}
+
+func (*slashingPolicyAction) Validate(_ *TransactionContext, key string, newValue string) error {
+	_, err := parseSlashingPolicy(key, newValue)
+	return err
+}
+
+func (*slashingPolicyAction) Execute(t *TransactionContext, action *ActionMeta) error {
+	policy, err := parseSlashingPolicy(action.Key, action.NewValue)
+	if err != nil {
+		return err
+	}
+	return t.State.PutSlashingPolicy(policy)
+}
@@ -0,0 +1,193 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"encoding/hex"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/database/memdb"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestSlashing(t *testing.T) {
+	t.Parallel()
+
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	work := crypto.PubkeyToAddress(priv.PublicKey)
+
+	db := memdb.New()
+	defer db.Close()
+	g := DefaultGenesis()
+	if err := g.Load(db, nil); err != nil {
+		t.Fatal(err)
+	}
+	state := SamaNew(db, g)
+	route := common.Address{1}
+	foundation := common.HexToAddress(g.FoundationAddr)
+	stakeTime := g.ChainCreateTime + SecondsDay
+	if err := state.DealStakeTx(&StakerMeta{StakerType: stakerTypeRoute, StakeAmount: 1000, StakeTime: stakeTime, StakerAddr: route}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetStakeBalance(db, route, 1000); err != nil {
+		t.Fatal(err)
+	}
+	if err := state.PutDetail(work, &DetailMeta{
+		StakerType:   stakerTypeRoute,
+		WorkKey:      hex.EncodeToString(crypto.FromECDSAPub(&priv.PublicKey)),
+		WorkAddress:  work,
+		StakeAddress: route,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	proof := func(netflow uint64, startTime uint64, price uint64) []byte {
+		t.Helper()
+		tx := &Transaction{
+			UnsignedTransaction: &ProofTx{
+				BaseTx:    &BaseTx{BlockID: ids.GenerateTestID(), Magic: g.Magic, Price: price},
+				Netflow:   netflow,
+				StartTime: startTime,
+				EndTime:   startTime + 30,
+			},
+		}
+		dh, err := DigestHash(tx.UnsignedTransaction)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tx.Signature, err = Sign(dh, priv); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Init(g); err != nil {
+			t.Fatal(err)
+		}
+		return tx.Bytes()
+	}
+	duplicate := func(first []byte, second []byte) []byte {
+		t.Helper()
+		evidence, err := Marshal(&DuplicateProofEvidence{First: first, Second: second})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return evidence
+	}
+	// The fork activates a day after the stake
+	activation := stakeTime + SecondsDay
+	tctx := testTxContexts(g, db, Rules{IsPhase4: true, Phase4Time: int64(activation)})
+	report := func(blockTime uint64, offenceType uint64, evidence []byte) error {
+		tx := &ReportOffenceTx{BaseTx: &BaseTx{}, Staker: route, OffenceType: offenceType, Evidence: evidence}
+		return tx.Execute(tctx(common.Address{2}, blockTime))
+	}
+	checkStake := func(stake uint64, foundationBalance uint64) {
+		t.Helper()
+		staker, _, err := state.GetStakerMeta(stakerTypeRoute, route)
+		if err != nil || staker.StakeAmount != stake {
+			t.Fatalf("expected stake %d, got %+v (%v)", stake, staker, err)
+		}
+		if b, err := GetStakeBalance(db, route); err != nil || b != stake {
+			t.Fatalf("expected stake balance %d, got %d (%v)", stake, b, err)
+		}
+		if b, err := GetBalance(db, foundation); err != nil || b != foundationBalance {
+			t.Fatalf("expected foundation balance %d, got %d (%v)", foundationBalance, b, err)
+		}
+	}
+
+	// A valid proof proves nothing, an invalid one is slashed once
+	now := stakeTime + SecondsDay
+	if err := report(now, OffenceInvalidProof, proof(10, now-100, 0)); !errors.Is(err, ErrInvalidEvidence) {
+		t.Fatalf("expected %v, got %v", ErrInvalidEvidence, err)
+	}
+	invalid := proof(maxFlow+1, now-100, 0)
+	if err := report(now, OffenceInvalidProof, invalid); err != nil {
+		t.Fatal(err)
+	}
+	checkStake(950, 25)
+	if err := report(now, OffenceInvalidProof, invalid); !errors.Is(err, ErrEvidenceSlashed) {
+		t.Fatalf("expected %v, got %v", ErrEvidenceSlashed, err)
+	}
+
+	// Overlapping proofs are slashed once, in any order
+	first, second := proof(10, now-100, 0), proof(10, now-90, 1)
+	if err := report(now, OffenceDuplicateProof, duplicate(first, proof(10, now-70, 1))); !errors.Is(err, ErrInvalidEvidence) {
+		t.Fatalf("expected %v, got %v", ErrInvalidEvidence, err)
+	}
+	if err := report(now, OffenceDuplicateProof, duplicate(first, first)); !errors.Is(err, ErrInvalidEvidence) {
+		t.Fatalf("expected %v, got %v", ErrInvalidEvidence, err)
+	}
+	if err := report(now, OffenceDuplicateProof, duplicate(first, second)); err != nil {
+		t.Fatal(err)
+	}
+	checkStake(855, 25+48)
+	if err := report(now, OffenceDuplicateProof, duplicate(second, first)); !errors.Is(err, ErrEvidenceSlashed) {
+		t.Fatalf("expected %v, got %v", ErrEvidenceSlashed, err)
+	}
+
+	// Misbehaviour can only be confirmed by governance
+	if err := report(now, OffenceMisbehaviour, nil); !errors.Is(err, ErrGovernedOffence) {
+		t.Fatalf("expected %v, got %v", ErrGovernedOffence, err)
+	}
+
+	// Downtime is counted from the activation of the fork, after the stake,
+	// then from the last downtime slash
+	if err := state.PutSlashingPolicy(&SlashingPolicy{OffenceType: OffenceDowntime, SlashPerc: 10, BurnPerc: 100, Period: 3 * SecondsDay}); err != nil {
+		t.Fatal(err)
+	}
+	if err := report(stakeTime+3*SecondsDay, OffenceDowntime, nil); !errors.Is(err, ErrInvalidEvidence) {
+		t.Fatalf("expected %v, got %v", ErrInvalidEvidence, err)
+	}
+	down := activation + 3*SecondsDay
+	if err := report(down-1, OffenceDowntime, nil); !errors.Is(err, ErrInvalidEvidence) {
+		t.Fatalf("expected %v, got %v", ErrInvalidEvidence, err)
+	}
+	if err := report(down, OffenceDowntime, nil); err != nil {
+		t.Fatal(err)
+	}
+	checkStake(770, 25+48)
+	if err := report(down+SecondsDay, OffenceDowntime, nil); !errors.Is(err, ErrInvalidEvidence) {
+		t.Fatalf("expected %v, got %v", ErrInvalidEvidence, err)
+	}
+
+	slashes, err := state.GetSlashes(route)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(slashes) != 3 {
+		t.Fatalf("expected 3 slashes, got %d", len(slashes))
+	}
+	// Slashes of the same block are ordered by tx ID
+	sort.SliceStable(slashes, func(i, j int) bool {
+		return slashes[i].Time < slashes[j].Time || (slashes[i].Time == slashes[j].Time && slashes[i].OffenceType < slashes[j].OffenceType)
+	})
+	for i, expected := range []SlashRecord{
+		{OffenceType: OffenceInvalidProof, StakeBefore: 1000, Amount: 50, Burned: 25, Time: now},
+		{OffenceType: OffenceDuplicateProof, StakeBefore: 950, Amount: 95, Burned: 47, Time: now},
+		{OffenceType: OffenceDowntime, StakeBefore: 855, Amount: 85, Burned: 85, Time: down},
+	} {
+		slash := slashes[i]
+		if slash.Staker != route || slash.OffenceType != expected.OffenceType || slash.StakeBefore != expected.StakeBefore ||
+			slash.Amount != expected.Amount || slash.Burned != expected.Burned || slash.Time != expected.Time {
+			t.Fatalf("slash %d: expected %+v, got %+v", i, expected, slash)
+		}
+	}
+
+	// Receipts show the slash
+	v, err := Marshal(slashes[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := effect(PrefixSlashKey(route, slashes[2].TxID), nil, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Type != EffectSlashed || e.Address != route || e.Before != 855 || e.After != 770 {
+		t.Fatalf("unexpected effect %+v", e)
+	}
+}
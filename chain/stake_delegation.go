@@ -0,0 +1,311 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Any address may bond BLB to a route or ser node. The reward of a node is
+// still computed by [StakerReword] as if the operator staked alone; when it
+// is paid (see [payStakerReward]) the operator keeps its commission and the
+// rest is split pro rata to the stake times the minutes it was bonded since
+// the last payment, between the operator's own stake and its delegators.
+// Delegation starts with [ForkPhase4], the operator was paid all the reward
+// before.
+
+var (
+	ErrInvalidCommission = errors.New("commission must be at most 100")
+	ErrNotDelegatable    = errors.New("only route and ser nodes take delegations")
+	ErrNoDelegation      = errors.New("delegation not found")
+)
+
+// StakerPool holds the delegation terms of a staker
+type StakerPool struct {
+	StakerAddr     common.Address `serialize:"true" json:"stakerAddr"`
+	CommissionPerc uint32         `serialize:"true" json:"commissionPerc"`
+	// Delegated is the total bonded to the staker
+	Delegated uint64 `serialize:"true" json:"delegated"`
+	// LastSplitTime is when the reward of the staker was last paid, the
+	// weights count from it
+	LastSplitTime uint64 `serialize:"true" json:"lastSplitTime"`
+}
+
+// StakeDelegation is the position of [Delegator] with [StakerAddr]
+type StakeDelegation struct {
+	Delegator  common.Address `serialize:"true" json:"delegator"`
+	StakerAddr common.Address `serialize:"true" json:"stakerAddr"`
+	Amount     uint64         `serialize:"true" json:"amount"`
+	// Weight is the amount times the minutes it was bonded between the
+	// last payment and [LastUpdateTime]
+	Weight         uint64 `serialize:"true" json:"weight"`
+	LastUpdateTime uint64 `serialize:"true" json:"lastUpdateTime"`
+	TxID           ids.ID `serialize:"true" json:"txId"`
+}
+
+// accrue adds the weight of the position up to [now]
+func (d *StakeDelegation) accrue(now uint64) {
+	d.Weight = d.weightAt(now)
+	d.LastUpdateTime = now
+}
+
+func (d *StakeDelegation) weightAt(now uint64) uint64 {
+	if now <= d.LastUpdateTime {
+		return d.Weight
+	}
+	return d.Weight + stakeWeightOf(d.Amount, now-d.LastUpdateTime)
+}
+
+// stakeWeightOf returns the weight of [amount] bonded for [secs]
+func stakeWeightOf(amount uint64, secs uint64) uint64 {
+	return mulDiv(amount, secs, SecondsMinute)
+}
+
+// mulDiv returns a*b/c without overflowing the product
+func mulDiv(a uint64, b uint64, c uint64) uint64 {
+	r := new(big.Int).Mul(new(big.Int).SetUint64(a), new(big.Int).SetUint64(b))
+	return r.Div(r, new(big.Int).SetUint64(c)).Uint64()
+}
+
+// [stakeDelegationsPrefix] + [delimiter] + [staker]
+func PrefixStakerPoolKey(staker common.Address) (k []byte) {
+	k = make([]byte, 2+common.AddressLength)
+	k[0] = stakeDelegationsPrefix
+	k[1] = ByteDelimiter
+	copy(k[2:], staker[:])
+	return
+}
+
+// [stakeDelegationsPrefix] + [delimiter] + [staker] + [delegator]
+func PrefixStakeDelegationKey(staker common.Address, delegator common.Address) (k []byte) {
+	k = make([]byte, 2+2*common.AddressLength)
+	k[0] = stakeDelegationsPrefix
+	k[1] = ByteDelimiter
+	copy(k[2:], staker[:])
+	copy(k[2+common.AddressLength:], delegator[:])
+	return
+}
+
+func baseStakeDelegationPrefix() (k []byte) {
+	k = make([]byte, 2)
+	k[0] = stakeDelegationsPrefix
+	k[1] = ByteDelimiter
+	return
+}
+
+var _ StakeDelegationsState = &stakeDelegationsState{}
+
+type StakeDelegationsState interface {
+	// GetStakerPool returns the pool of [staker], a default one if it
+	// never took delegations
+	GetStakerPool(staker common.Address) (*StakerPool, error)
+	PutStakerPool(pool *StakerPool) error
+	DelStakerPool(staker common.Address) error
+
+	GetStakeDelegation(staker common.Address, delegator common.Address) (*StakeDelegation, bool, error)
+	PutStakeDelegation(delegation *StakeDelegation) error
+	DelStakeDelegation(staker common.Address, delegator common.Address) error
+	// GetStakeDelegations returns the delegations to [staker], or all of
+	// them if it is empty
+	GetStakeDelegations(staker common.Address) ([]*StakeDelegation, error)
+}
+
+type stakeDelegationsState struct {
+	db database.Database
+}
+
+func NewStakeDelegationsState(db database.Database) *stakeDelegationsState {
+	return &stakeDelegationsState{db: db}
+}
+
+func (d *stakeDelegationsState) GetStakerPool(staker common.Address) (*StakerPool, error) {
+	pool := &StakerPool{StakerAddr: staker}
+	if _, err := getState(d.db, PrefixStakerPoolKey(staker), pool); err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
+
+func (d *stakeDelegationsState) PutStakerPool(pool *StakerPool) error {
+	return putState(d.db, PrefixStakerPoolKey(pool.StakerAddr), pool)
+}
+
+func (d *stakeDelegationsState) DelStakerPool(staker common.Address) error {
+	return d.db.Delete(PrefixStakerPoolKey(staker))
+}
+
+func (d *stakeDelegationsState) GetStakeDelegation(staker common.Address, delegator common.Address) (*StakeDelegation, bool, error) {
+	pmeta := new(StakeDelegation)
+	exist, err := getState(d.db, PrefixStakeDelegationKey(staker, delegator), pmeta)
+	if !exist || err != nil {
+		return nil, false, err
+	}
+	return pmeta, true, nil
+}
+
+func (d *stakeDelegationsState) PutStakeDelegation(delegation *StakeDelegation) error {
+	return putState(d.db, PrefixStakeDelegationKey(delegation.StakerAddr, delegation.Delegator), delegation)
+}
+
+func (d *stakeDelegationsState) DelStakeDelegation(staker common.Address, delegator common.Address) error {
+	return d.db.Delete(PrefixStakeDelegationKey(staker, delegator))
+}
+
+func (d *stakeDelegationsState) GetStakeDelegations(staker common.Address) ([]*StakeDelegation, error) {
+	prefix, suffixLen := baseStakeDelegationPrefix(), 2*common.AddressLength
+	if staker != (common.Address{}) {
+		prefix, suffixLen = PrefixStakerPoolKey(staker), common.AddressLength
+	}
+	delegations := []*StakeDelegation(nil)
+	err := iterateState(d.db, prefix, suffixLen, func(_ []byte, v []byte) error {
+		pmeta := new(StakeDelegation)
+		if _, err := Unmarshal(v, pmeta); err != nil {
+			return err
+		}
+		delegations = append(delegations, pmeta)
+		return nil
+	})
+	return delegations, err
+}
+
+// RewardSplit is how the reward of a node is shared
+type RewardSplit struct {
+	Commission uint64 `json:"commission"`
+	// Operator includes the commission
+	Operator   uint64                    `json:"operator"`
+	Delegators map[common.Address]uint64 `json:"delegators"`
+}
+
+// SplitStakerReward splits [reward], the reward of the node of [staker],
+// between the operator and its delegators by their weights at [now]
+func SplitStakerReward(s SamaState, staker *StakerMeta, reward uint64, now uint64) (*RewardSplit, error) {
+	pool, err := s.GetStakerPool(staker.StakerAddr)
+	if err != nil {
+		return nil, err
+	}
+	delegations, err := s.GetStakeDelegations(staker.StakerAddr)
+	if err != nil {
+		return nil, err
+	}
+	split := &RewardSplit{
+		Commission: reward * uint64(pool.CommissionPerc) / 100,
+		Delegators: map[common.Address]uint64{},
+	}
+	since := staker.StakeTime
+	if pool.LastSplitTime > since {
+		since = pool.LastSplitTime
+	}
+	operatorWeight := uint64(0)
+	if now > since {
+		operatorWeight = stakeWeightOf(staker.StakeAmount, now-since)
+	}
+	total := operatorWeight
+	for _, delegation := range delegations {
+		total += delegation.weightAt(now)
+	}
+
+	shared := reward - split.Commission
+	paid := uint64(0)
+	if total > 0 {
+		for _, delegation := range delegations {
+			share := mulDiv(shared, delegation.weightAt(now), total)
+			if share == 0 {
+				continue
+			}
+			split.Delegators[delegation.Delegator] += share
+			paid += share
+		}
+	}
+	// The operator gets the rounding remainder
+	split.Operator = reward - paid
+	return split, nil
+}
+
+// payStakerReward pays [reward], the reward of the node of [staker], split
+// by [SplitStakerReward], and starts a new split
+func payStakerReward(t *TransactionContext, staker *StakerMeta, reward uint64) error {
+	if !t.Rules.IsPhase4 {
+		_, err := ModifyBalance(t.Database, staker.StakerAddr, true, reward)
+		return err
+	}
+	samaState := t.State
+	split, err := SplitStakerReward(samaState, staker, reward, t.BlockTime)
+	if err != nil {
+		return err
+	}
+	if _, err := ModifyBalance(t.Database, staker.StakerAddr, true, split.Operator); err != nil {
+		return err
+	}
+	delegations, err := samaState.GetStakeDelegations(staker.StakerAddr)
+	if err != nil {
+		return err
+	}
+	for _, delegation := range delegations {
+		if share := split.Delegators[delegation.Delegator]; share > 0 {
+			if _, err := ModifyBalance(t.Database, delegation.Delegator, true, share); err != nil {
+				return err
+			}
+		}
+		// Positions emptied since the last payment were kept for it only
+		if delegation.Amount == 0 {
+			if err := samaState.DelStakeDelegation(staker.StakerAddr, delegation.Delegator); err != nil {
+				return err
+			}
+			continue
+		}
+		delegation.Weight = 0
+		delegation.LastUpdateTime = t.BlockTime
+		if err := samaState.PutStakeDelegation(delegation); err != nil {
+			return err
+		}
+	}
+	pool, err := samaState.GetStakerPool(staker.StakerAddr)
+	if err != nil {
+		return err
+	}
+	pool.LastSplitTime = t.BlockTime
+	return samaState.PutStakerPool(pool)
+}
+
+// releaseDelegations returns the stake bonded to [staker], which is leaving
+func releaseDelegations(t *TransactionContext, staker common.Address) error {
+	if !t.Rules.IsPhase4 {
+		return nil
+	}
+	samaState := t.State
+	delegations, err := samaState.GetStakeDelegations(staker)
+	if err != nil {
+		return err
+	}
+	for _, delegation := range delegations {
+		if _, err := ModifyStakeBalance(t.Database, delegation.Delegator, false, delegation.Amount); err != nil {
+			return err
+		}
+		if _, err := ModifyBalance(t.Database, delegation.Delegator, true, delegation.Amount); err != nil {
+			return err
+		}
+		if err := samaState.DelStakeDelegation(staker, delegation.Delegator); err != nil {
+			return err
+		}
+	}
+	return samaState.DelStakerPool(staker)
+}
+
+// delegatableStaker returns the staker at [address] if it takes delegations
+func delegatableStaker(s SamaState, address common.Address) (*StakerMeta, error) {
+	ok, stakerType, err := s.IsStaker(address)
+	if err != nil {
+		return nil, err
+	}
+	if !ok || (stakerType != stakerTypeRoute && stakerType != stakerTypeSer) {
+		return nil, ErrNotDelegatable
+	}
+	staker, _, err := s.GetStakerMeta(stakerType, address)
+	return staker, err
+}
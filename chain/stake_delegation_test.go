@@ -0,0 +1,117 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/database/memdb"
+	"github.com/ava-labs/avalanchego/database/versiondb"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestStakeDelegation(t *testing.T) {
+	t.Parallel()
+
+	db := memdb.New()
+	defer db.Close()
+	g := DefaultGenesis()
+	if err := g.Load(db, nil); err != nil {
+		t.Fatal(err)
+	}
+	state := SamaNew(db, g)
+	route, d1, d2 := common.Address{1}, common.Address{2}, common.Address{3}
+	staker := &StakerMeta{StakerType: stakerTypeRoute, StakeAmount: 100, StakeTime: 1000, StakerAddr: route}
+	if err := state.PutStaker(staker); err != nil {
+		t.Fatal(err)
+	}
+	for _, addr := range []common.Address{d1, d2} {
+		if err := SetBalance(db, addr, 1000); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tctx := testTxContexts(g, db, Rules{IsPhase4: true})
+
+	for i, tt := range []struct {
+		utx    UnsignedTransaction
+		sender common.Address
+		err    error
+	}{
+		{&DelegateStakeTx{StakerAddr: route, Amount: 100}, route, ErrSelfDelegation},
+		{&DelegateStakeTx{StakerAddr: d2, Amount: 100}, d1, ErrNotDelegatable},
+		{&DelegateStakeTx{StakerAddr: route, Amount: 0}, d1, ErrStakeAmount},
+		{&UndelegateStakeTx{StakerAddr: route, Amount: 1}, d1, ErrNoDelegation},
+		{&SetCommissionTx{CommissionPerc: 10}, d1, ErrNotDelegatable},
+		{&SetCommissionTx{CommissionPerc: 101}, route, ErrInvalidCommission},
+	} {
+		if err := tt.utx.Execute(tctx(tt.sender, 1000)); !errors.Is(err, tt.err) {
+			t.Fatalf("#%d: expected %v, got %v", i, tt.err, err)
+		}
+	}
+
+	// Over 10 minutes the operator and d1 bond 100 each, d2 bonds 200 for
+	// the last 5 minutes: they share 90% of the reward equally
+	if err := (&SetCommissionTx{CommissionPerc: 10}).Execute(tctx(route, 1000)); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&DelegateStakeTx{StakerAddr: route, Amount: 100}).Execute(tctx(d1, 1000)); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&DelegateStakeTx{StakerAddr: route, Amount: 200}).Execute(tctx(d2, 1300)); err != nil {
+		t.Fatal(err)
+	}
+	checkBalance(t, db, d2, 800, 200)
+	split, err := SplitStakerReward(state, staker, 1000, 1600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if split.Commission != 100 || split.Operator != 400 || split.Delegators[d1] != 300 || split.Delegators[d2] != 300 {
+		t.Fatalf("unexpected split %+v", split)
+	}
+	// Before the fork the operator was paid the whole reward
+	vdb := versiondb.New(db)
+	if err := payStakerReward(testTxContexts(g, vdb, Rules{})(route, 1600), staker, 1000); err != nil {
+		t.Fatal(err)
+	}
+	checkBalance(t, vdb, route, 1000, 0)
+	checkBalance(t, vdb, d1, 900, 100)
+	if err := payStakerReward(tctx(route, 1600), staker, 1000); err != nil {
+		t.Fatal(err)
+	}
+	checkBalance(t, db, route, 400, 0)
+	checkBalance(t, db, d1, 1200, 100)
+	checkBalance(t, db, d2, 1100, 200)
+
+	// An emptied position keeps its weight until the next payment
+	if err := (&UndelegateStakeTx{StakerAddr: route, Amount: 101}).Execute(tctx(d1, 1900)); !errors.Is(err, ErrStakeAmount) {
+		t.Fatalf("expected %v, got %v", ErrStakeAmount, err)
+	}
+	if err := (&UndelegateStakeTx{StakerAddr: route, Amount: 100}).Execute(tctx(d1, 1900)); err != nil {
+		t.Fatal(err)
+	}
+	checkBalance(t, db, d1, 1300, 0)
+	if err := payStakerReward(tctx(route, 2200), staker, 900); err != nil {
+		t.Fatal(err)
+	}
+	// 810 shared by weights 1000, 500 and 2000
+	checkBalance(t, db, route, 400+900-115-462, 0)
+	checkBalance(t, db, d1, 1415, 0)
+	checkBalance(t, db, d2, 1562, 200)
+	if _, exist, err := state.GetStakeDelegation(route, d1); err != nil || exist {
+		t.Fatalf("emptied position not removed (%v)", err)
+	}
+	if pool, err := state.GetStakerPool(route); err != nil || pool.Delegated != 200 || pool.LastSplitTime != 2200 {
+		t.Fatalf("unexpected pool %+v (%v)", pool, err)
+	}
+
+	// Leaving stakers return the delegated stake
+	if err := releaseDelegations(tctx(route, 2300), route); err != nil {
+		t.Fatal(err)
+	}
+	checkBalance(t, db, d2, 1762, 0)
+	if delegations, err := state.GetStakeDelegations(common.Address{}); err != nil || len(delegations) != 0 {
+		t.Fatalf("delegations not released %v (%v)", delegations, err)
+	}
+}
@@ -7,7 +7,6 @@ import (
 	"github.com/ava-labs/avalanchego/database"
 	"github.com/ava-labs/avalanchego/ids"
 	"github.com/ethereum/go-ethereum/common"
-	log "github.com/inconshreveable/log15"
 )
 
 const (
@@ -40,6 +39,22 @@ func ActionTypeAddStaker() uint64 {
 	return actionTypeAddStaker
 }
 
+func ActionTypeRemoveStaker() uint64 {
+	return actionTypeRemoveStaker
+}
+
+func ActionTypeSuspendStaker() uint64 {
+	return actionTypeSuspendStaker
+}
+
+func ActionTypeSlashStaker() uint64 {
+	return actionTypeSlashStaker
+}
+
+func ActionTypeSetSlashingPolicy() uint64 {
+	return actionTypeSetSlashingPolicy
+}
+
 type StakerMeta struct {
 	TxID        ids.ID         `serialize:"true" json:"txId"`
 	StakerType  uint64         `serialize:"true" json:"stakerType"`
@@ -58,6 +73,25 @@ func PrefixStaker4Key(stakerType byte, address common.Address) (k []byte) {
 	return k
 }
 
+// SuspensionMeta records a staker suspended by governance. Suspended stakers
+// keep their stake but can't vote, submit proofs, claim or unstake until
+// they are reinstated or removed.
+type SuspensionMeta struct {
+	StakerAddr  common.Address `serialize:"true" json:"stakerAddr"`
+	StakerType  uint64         `serialize:"true" json:"stakerType"`
+	TxID        ids.ID         `serialize:"true" json:"txId"`
+	SuspendTime uint64         `serialize:"true" json:"suspendTime"`
+}
+
+// [suspensionsPrefix] + [delimiter] + [address]
+func PrefixSuspensionKey(address common.Address) (k []byte) {
+	k = make([]byte, 2+common.AddressLength)
+	k[0] = suspensionsPrefix
+	k[1] = ByteDelimiter
+	copy(k[2:], address[:])
+	return k
+}
+
 func baseStakerPrefix(stakerType byte) (k []byte) {
 	k = make([]byte, 4)
 	k[0] = stakerPrefix
@@ -71,14 +105,10 @@ var _ StakerState = &stakerState{}
 var StakerTypes = []byte{stakerTypeRoute, stakerTypeSer, stakerTypeValidator}
 
 type StakerState interface {
-	GetPendingStakers(stakerType byte) (map[common.Address]*StakerMeta, map[common.Address]*StakerMeta, error)
 	GetStakerMeta(stakerType byte, address common.Address) (*StakerMeta, bool, error)
-	PutStaker(db database.Database, pmeta *StakerMeta) error
+	PutStaker(pmeta *StakerMeta) error
 	GetStakers(stakerType byte) ([]*StakerMeta, error)
-	DelStaker(db database.Database, stakerType byte, address common.Address) error
-	ReloadStakers(db database.Database) error
-	CacheStakersCommit() error
-	CacheStakersAbort() error
+	DelStaker(stakerType byte, address common.Address) error
 	GetStakersNum() (int, int, int)
 
 	IsRoute(address common.Address) (bool, error)
@@ -86,195 +116,113 @@ type StakerState interface {
 	IsValidator(address common.Address) (bool, error)
 
 	IsStaker(address common.Address) (bool, byte, error)
-}
-
-type stakerState struct {
-	curRoute     map[common.Address]*StakerMeta
-	curSer       map[common.Address]*StakerMeta
-	curValidator map[common.Address]*StakerMeta
-
-	pendingRouteAdd     map[common.Address]*StakerMeta
-	pendingSerAdd       map[common.Address]*StakerMeta
-	pendingValidatorAdd map[common.Address]*StakerMeta
-
-	pendingRouteDel     map[common.Address]*StakerMeta
-	pendingSerDel       map[common.Address]*StakerMeta
-	pendingValidatorDel map[common.Address]*StakerMeta
-}
-
-func NewStakerState(db database.Database) (*stakerState, error) {
-	state := &stakerState{
-		curRoute:     make(map[common.Address]*StakerMeta),
-		curSer:       make(map[common.Address]*StakerMeta),
-		curValidator: make(map[common.Address]*StakerMeta),
 
-		pendingRouteAdd:     make(map[common.Address]*StakerMeta),
-		pendingSerAdd:       make(map[common.Address]*StakerMeta),
-		pendingValidatorAdd: make(map[common.Address]*StakerMeta),
-
-		pendingRouteDel:     make(map[common.Address]*StakerMeta),
-		pendingSerDel:       make(map[common.Address]*StakerMeta),
-		pendingValidatorDel: make(map[common.Address]*StakerMeta),
-	}
-	err := state.ReloadStakers(db)
-	return state, err
+	GetSuspension(address common.Address) (*SuspensionMeta, bool, error)
+	PutSuspension(suspension *SuspensionMeta) error
+	DelSuspension(address common.Address) error
 }
 
-func (s *stakerState) GetPendingStakers(stakerType byte) (map[common.Address]*StakerMeta, map[common.Address]*StakerMeta, error) {
-	switch stakerType {
-	case stakerTypeSer:
-		return s.pendingSerAdd, s.pendingSerDel, nil
-	case stakerTypeRoute:
-		return s.pendingRouteAdd, s.pendingRouteDel, nil
-	case stakerTypeValidator:
-		return s.pendingValidatorAdd, s.pendingValidatorDel, nil
-	default:
-		return nil, nil, ErrIDErr
-	}
+type stakerState struct {
+	db database.Database
 }
 
-func (s *stakerState) GetCurStakes(stakerType byte) (map[common.Address]*StakerMeta, error) {
-	switch stakerType {
-	case stakerTypeSer:
-		return s.curSer, nil
-	case stakerTypeRoute:
-		return s.curRoute, nil
-	case stakerTypeValidator:
-		return s.curValidator, nil
-	default:
-		return nil, ErrIDErr
-	}
+func NewStakerState(db database.Database) *stakerState {
+	return &stakerState{db: db}
 }
 
 func (s *stakerState) GetStakersNum() (int, int, int) {
-	return len(s.curRoute), len(s.curSer), len(s.curValidator)
+	routes, _ := getCount(s.db, PrefixCountKey(stakerPrefix, stakerTypeRoute))
+	sers, _ := getCount(s.db, PrefixCountKey(stakerPrefix, stakerTypeSer))
+	validators, _ := getCount(s.db, PrefixCountKey(stakerPrefix, stakerTypeValidator))
+	return int(routes), int(sers), int(validators)
 }
 
 func (s *stakerState) IsStaker(address common.Address) (bool, byte, error) {
-	ok := false
 	for _, stakerType := range StakerTypes {
-		curMap, _ := s.GetCurStakes(stakerType)
-		_, ok = curMap[address]
+		ok, err := s.db.Has(PrefixStaker4Key(stakerType, address))
+		if err != nil {
+			return false, 0, err
+		}
 		if ok {
 			return ok, stakerType, nil
 		}
 	}
-
-	return ok, 0, nil
+	return false, 0, nil
 }
 
 func (s *stakerState) IsRoute(address common.Address) (bool, error) {
-	curMap, _ := s.GetCurStakes(stakerTypeRoute)
-	_, ok := curMap[address]
-	return ok, nil
+	return s.db.Has(PrefixStaker4Key(stakerTypeRoute, address))
 }
 
 func (s *stakerState) IsSer(address common.Address) (bool, error) {
-	curMap, _ := s.GetCurStakes(stakerTypeSer)
-	_, ok := curMap[address]
-	return ok, nil
+	return s.db.Has(PrefixStaker4Key(stakerTypeSer, address))
 }
 
 func (s *stakerState) IsValidator(address common.Address) (bool, error) {
-	curMap, _ := s.GetCurStakes(stakerTypeValidator)
-	_, ok := curMap[address]
-	return ok, nil
+	return s.db.Has(PrefixStaker4Key(stakerTypeValidator, address))
 }
 
 func (s *stakerState) GetStakerMeta(stakerType byte, address common.Address) (*StakerMeta, bool, error) {
-	curMap, _ := s.GetCurStakes(stakerType)
-	pmeta, ok := curMap[address]
-	if !ok {
-		return nil, false, nil
+	pmeta := new(StakerMeta)
+	exist, err := getState(s.db, PrefixStaker4Key(stakerType, address), pmeta)
+	if !exist || err != nil {
+		return nil, false, err
 	}
 	return pmeta, true, nil
 }
 
-func (s *stakerState) PutStaker(db database.Database, staker *StakerMeta) error {
-	pendingAdd, pendingDel, _ := s.GetPendingStakers(byte(staker.StakerType))
-	delete(pendingDel, staker.StakerAddr)
-	pendingAdd[staker.StakerAddr] = staker
-
+func (s *stakerState) PutStaker(staker *StakerMeta) error {
 	k := PrefixStaker4Key(byte(staker.StakerType), staker.StakerAddr)
-	pvmeta, err := Marshal(staker)
+	exist, err := s.db.Has(k)
 	if err != nil {
 		return err
 	}
-	return db.Put(k, pvmeta)
+	if !exist {
+		if err := modifyCount(s.db, PrefixCountKey(stakerPrefix, byte(staker.StakerType)), true, 1); err != nil {
+			return err
+		}
+	}
+	return putState(s.db, k, staker)
 }
 
 func (s *stakerState) GetStakers(stakerType byte) ([]*StakerMeta, error) {
 	stakers := []*StakerMeta(nil)
-	curMap, _ := s.GetCurStakes(stakerType)
-	for _, staker := range curMap {
-		stakers = append(stakers, staker)
-	}
-	return stakers, nil
+	err := iterateState(s.db, baseStakerPrefix(stakerType), common.AddressLength, func(_ []byte, v []byte) error {
+		pmeta := new(StakerMeta)
+		if _, err := Unmarshal(v, pmeta); err != nil {
+			return err
+		}
+		stakers = append(stakers, pmeta)
+		return nil
+	})
+	return stakers, err
 }
 
-func (s *stakerState) DelStaker(db database.Database, stakerType byte, address common.Address) error {
-	pendingAdd, pendingDel, _ := s.GetPendingStakers(stakerType)
-	delete(pendingAdd, address)
-	pendingDel[address] = &StakerMeta{}
-
+func (s *stakerState) DelStaker(stakerType byte, address common.Address) error {
 	k := PrefixStaker4Key(stakerType, address)
-	return db.Delete(k)
+	exist, err := s.db.Has(k)
+	if err != nil || !exist {
+		return err
+	}
+	if err := modifyCount(s.db, PrefixCountKey(stakerPrefix, stakerType), false, 1); err != nil {
+		return err
+	}
+	return s.db.Delete(k)
 }
 
-func (s *stakerState) ReloadStakers(db database.Database) error {
-	for _, stakerType := range StakerTypes {
-		log.Debug("ReloadStakers start", "stakerType", stakerType)
-		basePrefix := baseStakerPrefix(stakerType)
-		cursor := db.NewIteratorWithPrefix(basePrefix)
-		defer cursor.Release()
-		for cursor.Next() {
-			log.Debug("ReloadStakers", "stakerType", stakerType)
-			nmeta := cursor.Value()
-
-			pmeta := new(StakerMeta)
-			if _, err := Unmarshal(nmeta, pmeta); err != nil {
-				return err
-			}
-			curMap, _ := s.GetCurStakes(stakerType)
-			curMap[pmeta.StakerAddr] = pmeta
-		}
+func (s *stakerState) GetSuspension(address common.Address) (*SuspensionMeta, bool, error) {
+	pmeta := new(SuspensionMeta)
+	exist, err := getState(s.db, PrefixSuspensionKey(address), pmeta)
+	if !exist || err != nil {
+		return nil, false, err
 	}
-	return nil
+	return pmeta, true, nil
 }
 
-func (s *stakerState) CacheStakersCommit() error {
-	for _, stakerType := range StakerTypes {
-		pendingAdd, pendingDel, _ := s.GetPendingStakers(stakerType)
-		curMap, _ := s.GetCurStakes(stakerType)
-
-		for addr, pendingReward := range pendingAdd {
-			delete(curMap, addr)
-			curMap[addr] = pendingReward
-		}
-
-		for addr := range pendingDel {
-			delete(curMap, addr)
-		}
-
-		for a := range pendingAdd {
-			delete(pendingAdd, a)
-		}
-		for a := range pendingDel {
-			delete(pendingDel, a)
-		}
-	}
-	return nil
+func (s *stakerState) PutSuspension(suspension *SuspensionMeta) error {
+	return putState(s.db, PrefixSuspensionKey(suspension.StakerAddr), suspension)
 }
 
-func (s *stakerState) CacheStakersAbort() error {
-	for _, stakerType := range StakerTypes {
-		pendingAdd, pendingDel, _ := s.GetPendingStakers(stakerType)
-		for a := range pendingAdd {
-			delete(pendingAdd, a)
-		}
-		for a := range pendingDel {
-			delete(pendingDel, a)
-		}
-	}
-	return nil
+func (s *stakerState) DelSuspension(address common.Address) error {
+	return s.db.Delete(PrefixSuspensionKey(address))
 }
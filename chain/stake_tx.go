@@ -22,25 +22,24 @@ type StakeTx struct {
 }
 
 func (s *StakeTx) Execute(t *TransactionContext) error {
-	g := t.Genesis
+	samaState := t.State
 	switch {
 	case s.StakerType != stakerTypeRoute && s.StakerType != stakerTypeSer && s.StakerType != stakerTypeValidator:
 		return ErrStakerType
-	case s.StakeAmount != g.RouteStake && s.StakeAmount != g.SerStake:
+	case s.StakeAmount != samaState.GetRouteStakeAmount() && s.StakeAmount != samaState.GetSerStakeAmount():
 		return ErrStakeAmount
 	}
 
 	if bytes.Equal(s.StakerAddr[:], zeroAddress[:]) {
 		return ErrNonActionable
 	}
-	samaState := t.vm.SamaState()
 
 	exists, _, _ := samaState.IsStaker(s.StakerAddr)
 	if exists {
 		return fmt.Errorf("have already been staker")
 	}
 
-	ok, actionID, _ := samaState.IsBeConfirmed(actionTypeAddStaker, t.Sender.Hex())
+	ok, actionID, _ := samaState.IsBeConfirmed(actionTypeAddStaker, t.Sender.Hex(), t.BlockTime)
 	if !ok {
 		return fmt.Errorf("no be confirmed")
 	}
@@ -52,7 +51,7 @@ func (s *StakeTx) Execute(t *TransactionContext) error {
 		return err
 	}
 
-	err := samaState.DealStakeTx(t.Database, &StakerMeta{
+	err := samaState.DealStakeTx(&StakerMeta{
 		TxID:        t.TxID,
 		StakerType:  s.StakerType,
 		StakerAddr:  s.StakerAddr,
@@ -62,9 +61,13 @@ func (s *StakeTx) Execute(t *TransactionContext) error {
 	if err != nil {
 		return err
 	}
-	err = samaState.DelAction(t.Database, actionID)
-
-	return err
+	action, _, err := samaState.GetActionMeta(actionID, t.BlockTime)
+	if err != nil {
+		return err
+	}
+	action.TxIDs = append(action.TxIDs, t.TxID)
+	action.Transition(ActionExecuted, t.TxID, t.BlockTime)
+	return samaState.PutAction(actionID, action)
 }
 
 func (s *StakeTx) FeeUnits(g *Genesis) uint64 {
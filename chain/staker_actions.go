@@ -0,0 +1,199 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var ErrInvalidRemoval = errors.New("invalid staker removal")
+
+// StakerRemoval says how a removed staker is settled. It is the JSON encoded
+// value of [actionTypeRemoveStaker] proposals, an empty value settles the
+// rewards and returns the whole stake.
+type StakerRemoval struct {
+	// Pending rewards are paid to the staker unless forfeited
+	ForfeitRewards bool `json:"forfeitRewards"`
+	// Percentage of the stake sent to the foundation, the rest is returned.
+	// The slash is recorded as an [OffenceMisbehaviour].
+	SlashPerc uint32 `json:"slashPerc"`
+}
+
+func parseStakerRemoval(newValue string) (*StakerRemoval, error) {
+	removal := new(StakerRemoval)
+	if len(newValue) == 0 {
+		return removal, nil
+	}
+	if err := json.Unmarshal([]byte(newValue), removal); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRemoval, err)
+	}
+	if removal.SlashPerc > 100 {
+		return nil, fmt.Errorf("%w: slash percentage %d", ErrInvalidRemoval, removal.SlashPerc)
+	}
+	return removal, nil
+}
+
+// governedStaker returns the route or ser node staked at the address [key]
+func governedStaker(s SamaState, key string) (*StakerMeta, error) {
+	if !common.IsHexAddress(key) {
+		return nil, fmt.Errorf("%w: %s is not an address", ErrInvalidKey, key)
+	}
+	address := common.HexToAddress(key)
+	ok, stakerType, err := s.IsStaker(address)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("not found staker %s", key)
+	}
+	if stakerType != stakerTypeRoute && stakerType != stakerTypeSer {
+		return nil, ErrStakerType
+	}
+	staker, _, err := s.GetStakerMeta(stakerType, address)
+	return staker, err
+}
+
+// removeStakerAction removes the staker at the address [key], settling it
+// as described by the [StakerRemoval] in [newValue]
+type removeStakerAction struct {
+	tallyApproval
+}
+
+func (*removeStakerAction) minFork() string {
+	return ForkPhase4
+}
+
+func (*removeStakerAction) Validate(t *TransactionContext, key string, newValue string) error {
+	if _, err := parseStakerRemoval(newValue); err != nil {
+		return err
+	}
+	_, err := governedStaker(t.State, key)
+	return err
+}
+
+func (*removeStakerAction) Execute(t *TransactionContext, action *ActionMeta) error {
+	removal, err := parseStakerRemoval(action.NewValue)
+	if err != nil {
+		return err
+	}
+	samaState := t.State
+	staker, err := governedStaker(samaState, action.Key)
+	if err != nil {
+		return err
+	}
+	address := staker.StakerAddr
+
+	reward := uint64(0)
+	if !removal.ForfeitRewards {
+		base, merit, yield, err := samaState.CalcReward(byte(staker.StakerType), address, t.BlockTime)
+		if err != nil {
+			return err
+		}
+		reward = base + merit + yield
+	}
+	slashed := staker.StakeAmount * uint64(removal.SlashPerc) / 100
+	if slashed > 0 {
+		if _, err := distributeSlash(t, address, slashed, 0); err != nil {
+			return err
+		}
+		if err := samaState.PutSlash(&SlashRecord{
+			TxID:        t.TxID,
+			Staker:      address,
+			OffenceType: OffenceMisbehaviour,
+			StakeBefore: staker.StakeAmount,
+			Amount:      slashed,
+			Time:        t.BlockTime,
+		}); err != nil {
+			return err
+		}
+	}
+	if _, err := ModifyStakeBalance(t.Database, address, false, staker.StakeAmount-slashed); err != nil {
+		return err
+	}
+	if _, err := ModifyBalance(t.Database, address, true, staker.StakeAmount-slashed); err != nil {
+		return err
+	}
+	if err := payStakerReward(t, staker, reward); err != nil {
+		return err
+	}
+	if err := releaseDelegations(t, address); err != nil {
+		return err
+	}
+	if err := samaState.DealUnStakeTx(byte(staker.StakerType), address, t.TxID, t.BlockTime); err != nil {
+		return err
+	}
+	if err := samaState.DelSuspension(address); err != nil {
+		return err
+	}
+
+	// The node has to register again to stake
+	details, err := samaState.GetDetails()
+	if err != nil {
+		return err
+	}
+	for _, detail := range details {
+		if detail.StakeAddress != address {
+			continue
+		}
+		if err := samaState.DelDetail(detail.WorkAddress); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// suspendStakerAction suspends the staker at the address [key] if
+// [newValue] is true, or reinstates it if false
+type suspendStakerAction struct {
+	tallyApproval
+}
+
+func (*suspendStakerAction) minFork() string {
+	return ForkPhase4
+}
+
+func parseSuspension(s SamaState, key string, newValue string) (*StakerMeta, bool, error) {
+	suspend, err := strconv.ParseBool(newValue)
+	if err != nil {
+		return nil, false, err
+	}
+	staker, err := governedStaker(s, key)
+	if err != nil {
+		return nil, false, err
+	}
+	_, suspended, err := s.GetSuspension(staker.StakerAddr)
+	if err != nil {
+		return nil, false, err
+	}
+	if suspend == suspended {
+		return nil, false, fmt.Errorf("equal")
+	}
+	return staker, suspend, nil
+}
+
+func (*suspendStakerAction) Validate(t *TransactionContext, key string, newValue string) error {
+	_, _, err := parseSuspension(t.State, key, newValue)
+	return err
+}
+
+func (*suspendStakerAction) Execute(t *TransactionContext, action *ActionMeta) error {
+	staker, suspend, err := parseSuspension(t.State, action.Key, action.NewValue)
+	if err != nil {
+		return err
+	}
+	if !suspend {
+		return t.State.DelSuspension(staker.StakerAddr)
+	}
+	return t.State.PutSuspension(&SuspensionMeta{
+		StakerAddr:  staker.StakerAddr,
+		StakerType:  staker.StakerType,
+		TxID:        t.TxID,
+		SuspendTime: t.BlockTime,
+	})
+}
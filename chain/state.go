@@ -4,16 +4,14 @@
 package chain
 
 import (
-	"encoding/hex"
+	"errors"
 	"fmt"
 	"math"
 
 	"github.com/ava-labs/avalanchego/database"
 	"github.com/ava-labs/avalanchego/ids"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
-	"github.com/prometheus/client_golang/prometheus"
 )
 
 var _ SamaState = &samaState{}
@@ -37,21 +35,30 @@ type SamaState interface {
 	DetailsState
 	ActionsState
 	UserTypesState
-	Commit() error
-	Abort() error
+	DelegationsState
+	MultisigState
+	StakeDelegationsState
+	RewardIndexState
+	VestingState
+	UnbondingsState
+	SlashesState
 	CalcReward(claimerType byte, address common.Address, endTime uint64) (uint64, uint64, uint64, error)
-	CheckPayAmount(db database.Database, userType uint64, amount uint64, startTime uint64, endTime uint64) (bool, error)
-
-	DealStakeTx(db database.Database, staker *StakerMeta) error
-	DealUnStakeTx(db database.Database, stakerType byte, address common.Address, txID ids.ID, endTime uint64) error
-	DealAddUserTx(db database.Database, txID ids.ID, blkTime uint64, user *UserMeta) error
-	UpdateNodeParams(db database.Database, detail *DetailMeta) error
-
-	UpdateStakerReward(db database.Database, stakerType byte, address common.Address, txID ids.ID, endTime uint64) error
-	UpdateFoundationReward(db database.Database, address common.Address, txID ids.ID, endTime uint64) error
-
-	IsBeConfirmed(actionType uint64, key string) (bool, ids.ShortID, error)
-	ProposalStatus(actionID ids.ShortID) (bool, error)
+	CheckPayAmount(userType uint64, amount uint64, startTime uint64, endTime uint64) (bool, error)
+
+	DealStakeTx(staker *StakerMeta) error
+	DealUnStakeTx(stakerType byte, address common.Address, txID ids.ID, endTime uint64) error
+	DealAddUserTx(txID ids.ID, blkTime uint64, user *UserMeta) error
+	UpdateNodeParams(detail *DetailMeta) error
+
+	UpdateStakerReward(stakerType byte, address common.Address, txID ids.ID, endTime uint64) error
+	UpdateFoundationReward(address common.Address, txID ids.ID, endTime uint64) error
+	MigrateRewards(blkTime uint64) error
+	MigrateFoundation() error
+	FoundationVesting(endTime uint64) (*VestingStatus, error)
+	SettlePow(powType byte, address common.Address) error
+
+	IsBeConfirmed(actionType uint64, key string, blkTime uint64) (bool, ids.ShortID, error)
+	ProposalStatus(actionID ids.ShortID, blkTime uint64) (bool, error)
 	IsValidWorkAddress(address common.Address) (bool, byte, error)
 	CheckClaimAddress(address common.Address) (bool, byte, error)
 }
@@ -66,170 +73,102 @@ type samaState struct {
 	DetailsState
 	ActionsState
 	UserTypesState
+	DelegationsState
+	MultisigState
+	StakeDelegationsState
+	RewardIndexState
+	VestingState
+	UnbondingsState
+	SlashesState
 }
 
-func SamaNew(db database.Database, metrics prometheus.Registerer, g *Genesis) (SamaState, error) {
-	sysParams, err := NewSysParamsState(db, g)
-	if err != nil {
-		return nil, err
-	}
-	stakeState, err := NewStakerState(db)
-	if err != nil {
-		return nil, err
-	}
-	rewardState, err := NewRewardState(db)
-	if err != nil {
-		return nil, err
-	}
-	powState, err := NewPowState(db)
-	if err != nil {
-		return nil, err
-	}
-	yieldsState, err := NewYieldsState(db)
-	if err != nil {
-		return nil, err
-	}
-	usersState, err := NewUserstate(db, metrics)
-	if err != nil {
-		return nil, err
-	}
-	detailsState, err := NewDetailstate(db)
-	if err != nil {
-		return nil, err
-	}
-	actionsState, err := NewActionsState(db)
-	if err != nil {
-		return nil, err
-	}
-	userTypesState, err := NewUserTypesState(db)
-	if err != nil {
-		return nil, err
-	}
+// SamaNew returns a view of the chain state stored in [db]. All reads and
+// writes go through [db], so a view over a block's [versiondb.Database]
+// observes that block and its ancestors only, and changes made through it
+// are discarded along with the block if it is rejected.
+func SamaNew(db database.Database, g *Genesis) SamaState {
 	return &samaState{
-		SysParams:      sysParams,
-		StakerState:    stakeState,
-		RewardState:    rewardState,
-		PowState:       powState,
-		YieldsState:    yieldsState,
-		UsersState:     usersState,
-		DetailsState:   detailsState,
-		ActionsState:   actionsState,
-		UserTypesState: userTypesState,
-	}, err
-}
-
-func (s *samaState) IsValidWorkAddress(address common.Address) (bool, byte, error) {
-	nodes, err := s.GetDetails()
-	if err != nil {
-		return false, 0, err
-	}
-	ok := bool(false)
-	for _, node := range nodes {
-		pbkb, err := hex.DecodeString(node.WorkKey)
-		if err != nil {
-			return false, 0, err
-		}
-		pbk, err := crypto.UnmarshalPubkey(pbkb) //(*ecdsa.PublicKey, error)
-		if err != nil {
-			return false, 0, err
-		}
-		addr := crypto.PubkeyToAddress(*pbk)
-		if addr == address {
-			if node.StakerType == stakerTypeRoute {
-				ok, err = s.IsRoute(node.StakeAddress)
-				if err != nil {
-					return false, 0, err
-				}
-			} else if node.StakerType == stakerTypeSer {
-				ok, err = s.IsSer(node.StakeAddress)
-				if err != nil {
-					return false, 0, err
-				}
-			} else {
-				return false, 0, fmt.Errorf("type err")
-			}
-			return ok, byte(node.StakerType), err
-		}
+		SysParams:             NewSysParamsState(db, g),
+		StakerState:           NewStakerState(db),
+		RewardState:           NewRewardState(db),
+		PowState:              NewPowState(db),
+		YieldsState:           NewYieldsState(db),
+		UsersState:            NewUserstate(db),
+		DetailsState:          NewDetailstate(db),
+		ActionsState:          NewActionsState(db),
+		UserTypesState:        NewUserTypesState(db),
+		DelegationsState:      NewDelegationsState(db),
+		MultisigState:         NewMultisigState(db),
+		StakeDelegationsState: NewStakeDelegationsState(db),
+		RewardIndexState:      NewRewardIndexState(db),
+		VestingState:          NewVestingState(db),
+		UnbondingsState:       NewUnbondingsState(db),
+		SlashesState:          NewSlashesState(db),
 	}
-	return false, 0, err
 }
 
-func (s *samaState) Commit() error {
-	err := s.CacheRewardsCommit()
-	if err != nil {
-		return err
-	}
-	err = s.CacheStakersCommit()
-	if err != nil {
-		return err
-	}
-	err = s.CacheDetailsCommit()
-	if err != nil {
-		return err
-	}
-	err = s.CacheActionsCommit()
-	if err != nil {
-		return err
-	}
-	err = s.CacheUsersCommit()
-	if err != nil {
-		return err
-	}
-	err = s.CachePowsCommit()
-	if err != nil {
-		return err
-	}
-	err = s.CacheYieldsCommit()
-	if err != nil {
-		return err
+// getState decodes the value stored at [k] into [v]. It reports false if
+// there is no such value.
+func getState(db database.KeyValueReader, k []byte, v interface{}) (bool, error) {
+	b, err := db.Get(k)
+	if errors.Is(err, database.ErrNotFound) {
+		return false, nil
 	}
-	err = s.CacheParamsCommit()
 	if err != nil {
-		return err
+		return false, err
 	}
-	err = s.CacheUserTypesCommit()
-	if err != nil {
-		return err
+	if _, err := Unmarshal(b, v); err != nil {
+		return false, err
 	}
-	return err
+	return true, nil
 }
 
-func (s *samaState) Abort() error {
-	err := s.CacheRewardsAbort()
-	if err != nil {
-		return err
-	}
-	err = s.CacheActionsAbort()
-	if err != nil {
-		return err
-	}
-	err = s.CacheUsersAbort()
+func putState(db database.KeyValueWriter, k []byte, v interface{}) error {
+	b, err := Marshal(v)
 	if err != nil {
 		return err
 	}
-	err = s.CacheDetailsAbort()
-	if err != nil {
-		return err
+	return db.Put(k, b)
+}
+
+// iterateState calls [f] for every key made of [prefix] followed by
+// [suffixLen] bytes. Keys of any other length share the prefix byte but
+// belong to another table and are skipped.
+func iterateState(db database.Iteratee, prefix []byte, suffixLen int, f func(k []byte, v []byte) error) error {
+	cursor := db.NewIteratorWithPrefix(prefix)
+	defer cursor.Release()
+	for cursor.Next() {
+		if len(cursor.Key()) != len(prefix)+suffixLen {
+			continue
+		}
+		if err := f(cursor.Key(), cursor.Value()); err != nil {
+			return err
+		}
 	}
-	err = s.CachePowsAbort()
-	if err != nil {
-		return err
+	return cursor.Error()
+}
+
+func (s *samaState) IsValidWorkAddress(address common.Address) (bool, byte, error) {
+	node, exist, err := s.GetDetailByWorkAddress(address)
+	if err != nil || !exist {
+		return false, 0, err
 	}
-	err = s.CacheParamsAbort()
-	if err != nil {
-		return err
+	ok := false
+	switch node.StakerType {
+	case stakerTypeRoute:
+		ok, err = s.IsRoute(node.StakeAddress)
+	case stakerTypeSer:
+		ok, err = s.IsSer(node.StakeAddress)
+	default:
+		return false, 0, fmt.Errorf("type err")
 	}
-	err = s.CacheYieldsAbort()
-	if err != nil {
-		return err
+	if err != nil || !ok {
+		return false, 0, err
 	}
-	err = s.CacheUserTypesAbort()
-	if err != nil {
-		return err
+	if _, suspended, err := s.GetSuspension(node.StakeAddress); err != nil || suspended {
+		return false, 0, err
 	}
-	err = s.CacheStakersAbort()
-	return err
-
+	return ok, byte(node.StakerType), nil
 }
 
 func (s *samaState) RewardCurYear(index uint32) uint64 {
@@ -379,89 +318,82 @@ func (s *samaState) StakerReword(stakerType byte, roleNum int, address common.Ad
 	return baseReward, meritReward, yieldReward, nil
 }
 
+// FoundationReword returns the foundation share vested at [endTime] and
+// not claimed yet
 func (s *samaState) FoundationReword(address common.Address, endTime uint64) (uint64, error) {
-	createTime := s.GetChainCreateTime()
-	startTime := createTime
-	reward, exist, err := s.GetRewardMeta(address)
+	if vested, err := s.foundationVested(); err != nil || !vested {
+		return 0, err
+	}
+	status, err := s.FoundationVesting(endTime)
 	if err != nil {
 		return 0, err
 	}
-	if exist {
-		startTime = reward.LastClaimTime
+	return status.Claimable, nil
+}
+
+func (s *samaState) UpdateFoundationReward(address common.Address, txID ids.ID, endTime uint64) error {
+	vested, err := s.foundationVested()
+	if err != nil {
+		return err
 	}
-	if endTime < startTime {
-		return 0, fmt.Errorf("end time err")
+	if !vested {
+		return s.updateLegacyFoundationReward(address, txID, endTime)
 	}
-
-	fReward := uint64(0)
-	sYear := (startTime - createTime) / SecondsYear
-	eYear := (endTime - createTime) / SecondsYear
-	log.Error("calc FoundationReword start ")
-	for i := sYear; i < eYear+1; i++ {
-		tmpStart := createTime + i*SecondsYear
-		tmpEnd := createTime + (i+1)*SecondsYear
-
-		totalRewardYear := s.RewardCurYear(uint32(i))
-		roleTotalYear := totalRewardYear * uint64(s.GetPercFoundation()) / 100
-
-		workSecs := uint64(0)
-		if tmpEnd < endTime {
-			if tmpStart > startTime {
-				workSecs = tmpEnd - tmpStart
-			} else {
-				workSecs = tmpEnd - startTime
-			}
-		} else {
-			if tmpStart > startTime {
-				workSecs = endTime - tmpStart
-			} else {
-				workSecs = endTime - startTime
-			}
-		}
-
-		fReward += (roleTotalYear / SecondsYear) * workSecs
-
+	status, err := s.FoundationVesting(endTime)
+	if err != nil {
+		return err
 	}
-	return 0, nil
+	err = s.PutFoundationVesting(
+		&FoundationVestingMeta{
+			Claimed:       status.Claimed + status.Claimable,
+			LastClaimTime: endTime,
+			LastClaimTXID: txID,
+		})
+	if err != nil {
+		return err
+	}
+	return s.UpdateOwner(
+		&RewardMeta{
+			LastOprTime:   endTime,
+			LastOprTXID:   txID,
+			LastClaimTime: endTime,
+			LastClaimTXID: txID,
+			RewardAddr:    address,
+		})
 }
 
-func (s *samaState) UpdateFoundationReward(db database.Database, address common.Address, txID ids.ID, endTime uint64) error {
+// updateLegacyFoundationReward records a claim of the foundation from before
+// its share vests, which is always 0
+func (s *samaState) updateLegacyFoundationReward(address common.Address, txID ids.ID, endTime uint64) error {
 	lastTime, _ := s.GetLastUpdateTime()
 	if lastTime > endTime {
 		return ErrEndTimeTooEarly
 	} else if lastTime == endTime {
 		return nil
 	}
-
-	base := uint64(0)
-
-	base, err := s.FoundationReword(address, endTime)
-	if err != nil {
-		return err
-	}
-
-	err = s.UpdateOwner(db,
+	return s.UpdateOwner(
 		&RewardMeta{
-			BaseReward:    base,
-			MeritReward:   0,
-			YieldReward:   0,
 			LastOprTime:   endTime,
 			LastOprTXID:   txID,
 			LastClaimTime: endTime,
 			LastClaimTXID: txID,
 			RewardAddr:    address,
 		})
-	if err != nil {
-		return err
-	}
-	return nil
 }
 
-func (s *samaState) UpdateStakerReward(db database.Database, stakerType byte, address common.Address, txID ids.ID, endTime uint64) error {
+func (s *samaState) UpdateStakerReward(stakerType byte, address common.Address, txID ids.ID, endTime uint64) error {
 	lastTime, _ := s.GetLastUpdateTime()
 	if lastTime > endTime {
 		return ErrEndTimeTooEarly
-	} else if lastTime == endTime {
+	}
+	indexed, err := s.rewardIndexed()
+	if err != nil {
+		return err
+	}
+	if indexed {
+		return s.claimIndexedReward(stakerType, address, txID, endTime)
+	}
+	if lastTime == endTime {
 		return nil
 	}
 	roleNum := s.StakersNum(stakerType)
@@ -487,7 +419,7 @@ func (s *samaState) UpdateStakerReward(db database.Database, stakerType byte, ad
 				return err
 			}
 		}
-		err = s.UpdateOwner(db,
+		err = s.UpdateOwner(
 			&RewardMeta{
 				BaseReward:    base,
 				MeritReward:   merit,
@@ -502,14 +434,14 @@ func (s *samaState) UpdateStakerReward(db database.Database, stakerType byte, ad
 			return err
 		}
 	}
-	err = s.UpdateGlobal(db, &RewardGlobal{
+	err = s.UpdateGlobal(&RewardGlobal{
 		LastOprTime: endTime,
 		LastOprTXID: txID,
 	})
 	if err != nil {
 		return err
 	}
-	err = s.ModifyYields(db, 0, txID, endTime)
+	err = s.ModifyYields(0, txID, endTime)
 	if err != nil {
 		return err
 	}
@@ -538,37 +470,57 @@ func (s *samaState) CalcReward(claimerType byte, address common.Address, endTime
 	if endTime < staker.StakeTime { //|| endTime-staker.StakeTime < Seconds7Day {
 		return 0, 0, 0, fmt.Errorf("endTime err %d %d", endTime, staker.StakeTime)
 	}
+	indexed, err := s.rewardIndexed()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if indexed {
+		return s.indexedReward(claimerType, address, endTime)
+	}
 	stakeNum := s.StakersNum(claimerType)
 
 	return s.StakerReword(byte(staker.StakerType), stakeNum, staker.StakerAddr, staker.StakeTime, endTime)
 }
 
-func (s *samaState) DealStakeTx(db database.Database, staker *StakerMeta) error {
-	err := s.UpdateStakerReward(db, byte(staker.StakerType), staker.StakerAddr, staker.TxID, staker.StakeTime)
+func (s *samaState) DealStakeTx(staker *StakerMeta) error {
+	indexed, err := s.rewardIndexed()
+	if err != nil {
+		return err
+	}
+	if indexed {
+		return s.stakeIndexed(staker)
+	}
+	err = s.UpdateStakerReward(byte(staker.StakerType), staker.StakerAddr, staker.TxID, staker.StakeTime)
 	if err != nil {
 		return err
 	}
 
-	err = s.PutStaker(db, staker)
+	err = s.PutStaker(staker)
 	return err
 }
 
-func (s *samaState) DealUnStakeTx(db database.Database, stakerType byte, address common.Address, txID ids.ID, endTime uint64) error {
-	err := s.UpdateStakerReward(db, stakerType, address, txID, endTime)
+func (s *samaState) DealUnStakeTx(stakerType byte, address common.Address, txID ids.ID, endTime uint64) error {
+	err := s.UpdateStakerReward(stakerType, address, txID, endTime)
 	if err != nil {
 		return err
 	}
+	if err := s.DelVoteDelegation(address); err != nil {
+		return err
+	}
+	if err := s.DelRewardCheckpoint(address); err != nil {
+		return err
+	}
 
-	err = s.DelStaker(db, stakerType, address)
+	err = s.DelStaker(stakerType, address)
 	return err
 }
 
-func (s *samaState) CheckPayAmount(db database.Database, userType uint64, amount uint64, startTime uint64, endTime uint64) (bool, error) {
+func (s *samaState) CheckPayAmount(userType uint64, amount uint64, startTime uint64, endTime uint64) (bool, error) {
 	if (endTime-startTime)%SecondsMonth != 0 {
 		return false, nil
 	}
 
-	pmeta, exist, _ := s.GetUserType(db, userType)
+	pmeta, exist, _ := s.GetUserType(userType)
 	if !exist {
 		return false, nil
 	}
@@ -578,8 +530,8 @@ func (s *samaState) CheckPayAmount(db database.Database, userType uint64, amount
 	return false, nil
 }
 
-func (s *samaState) DealAddUserTx(db database.Database, txID ids.ID, blkTime uint64, user *UserMeta) error {
-	pmate, exist, err := s.GetUserMeta(db, user.Address)
+func (s *samaState) DealAddUserTx(txID ids.ID, blkTime uint64, user *UserMeta) error {
+	pmate, exist, err := s.GetUserMeta(user.Address)
 	if err != nil {
 		return err
 	}
@@ -591,73 +543,50 @@ func (s *samaState) DealAddUserTx(db database.Database, txID ids.ID, blkTime uin
 		user.TxsID = pmate.TxsID
 	}
 	user.TxsID = append(user.TxsID, txID)
-	err = s.PutUser(db, user)
+	err = s.PutUser(user)
 	if err != nil {
 		return err
 	}
 	perc := s.GetPercBurn()
 	yield := user.PayAmount * uint64(100-perc) / 100
-	err = s.ModifyYields(db, yield, txID, blkTime)
+	err = s.ModifyYields(yield, txID, blkTime)
 	return err
 }
 
-func (s *samaState) UpdateNodeParams(db database.Database, detail *DetailMeta) error {
-	s.PutDetail(db, detail.WorkAddress, detail)
-	return nil
+func (s *samaState) UpdateNodeParams(detail *DetailMeta) error {
+	return s.PutDetail(detail.WorkAddress, detail)
 }
 
-func (s *samaState) IsBeConfirmed(actionType uint64, key string) (bool, ids.ShortID, error) {
-	votersNum, actionID, err := s.GetVotersNum(actionType, key)
+// IsBeConfirmed returns the approved action of [actionType] targeting [key]
+func (s *samaState) IsBeConfirmed(actionType uint64, key string, blkTime uint64) (bool, ids.ShortID, error) {
+	_, actionID, err := s.GetVotersNum(actionType, key)
 	if err != nil {
 		return false, ids.ShortID{}, err
 	}
-	routeNum := s.StakersNum(stakerTypeRoute)
-	if routeNum < 3 {
-		action, _, err := s.GetActionMeta(actionID)
-		if err != nil {
-			return false, ids.ShortID{}, err
-		}
-		sroot := s.GetRootAddress()
-		for _, voter := range action.Voters {
-			if voter == common.HexToAddress(sroot) {
-				return true, actionID, nil
-			}
-		}
-	} else {
-		if votersNum >= routeNum*2/3 {
-			return true, actionID, nil
-		}
+	approved, err := s.ProposalStatus(actionID, blkTime)
+	if err != nil || !approved {
+		return false, ids.ShortID{}, err
 	}
-	return false, ids.ShortID{}, nil
+	return true, actionID, nil
 }
 
-func (s *samaState) ProposalStatus(actionID ids.ShortID) (bool, error) {
-	action, exist, err := s.GetActionMeta(actionID)
+// ProposalStatus reports whether the action [actionID] is approved
+func (s *samaState) ProposalStatus(actionID ids.ShortID, blkTime uint64) (bool, error) {
+	action, exist, err := s.GetActionMeta(actionID, blkTime)
 	if err != nil {
 		return false, err
 	}
 	if !exist {
 		return false, fmt.Errorf("not found")
 	}
-	votersNum := len(action.Voters)
-	routeNum := s.StakersNum(stakerTypeRoute)
-	if routeNum < 3 {
-		action, _, err := s.GetActionMeta(actionID)
-		if err != nil {
-			return false, err
-		}
-		sroot := s.GetRootAddress()
-		for _, voter := range action.Voters {
-			if voter == common.HexToAddress(sroot) {
-				return true, nil
-			}
-		}
-	} else {
-		if votersNum >= routeNum*2/3 {
-			return true, nil
-		}
+	if !action.IsOpen(blkTime) {
+		return false, ErrActionClosed
 	}
-	return false, nil
+	ga, err := GetGovernanceAction(action.ActionType)
+	if err != nil {
+		return false, err
+	}
+	return ga.Approved(s, action)
 }
 
 func (s *samaState) CheckClaimAddress(address common.Address) (bool, byte, error) {
@@ -0,0 +1,286 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ava-labs/avalanchego/database/memdb"
+	"github.com/ava-labs/avalanchego/database/nodb"
+	"github.com/ava-labs/avalanchego/database/versiondb"
+	log "github.com/inconshreveable/log15"
+)
+
+// Archive nodes keep every version of the state keys (see [SyncPrefixes]) so
+// the state after any block accepted since the archive was started can be
+// read without undoing diffs:
+//
+// 0x18/[key][^height][len(key)] (archived state)
+//   -> 0x0 (deleted) or 0x1 + [value]
+//
+// Heights are stored inverted so the newest version at or below a height is
+// the first one found when iterating from it. The key length is stored last
+// so entries of keys that share a prefix can be told apart.
+
+const (
+	archiveDeleted = 0x0
+	archiveValue   = 0x1
+)
+
+var (
+	archiveStart = []byte("archive_start")
+
+	ErrArchiveReadOnly = errors.New("archive is read-only")
+)
+
+// [archivePrefix] + [delimiter] + [key] + [^height] + [len(key)]
+func PrefixArchiveKey(key []byte, height uint64) (k []byte) {
+	k = make([]byte, 2+len(key)+8+2)
+	k[0] = archivePrefix
+	k[1] = ByteDelimiter
+	copy(k[2:], key)
+	binary.BigEndian.PutUint64(k[2+len(key):], ^height)
+	binary.BigEndian.PutUint16(k[2+len(key)+8:], uint16(len(key)))
+	return
+}
+
+// [archivePrefix] + [delimiter] + [prefix]
+func baseArchivePrefix(prefix []byte) (k []byte) {
+	k = make([]byte, 2+len(prefix))
+	k[0] = archivePrefix
+	k[1] = ByteDelimiter
+	copy(k[2:], prefix)
+	return
+}
+
+// parseArchiveKey returns the state key and height of an archive entry
+func parseArchiveKey(k []byte) ([]byte, uint64, bool) {
+	if len(k) < 2+8+2 {
+		return nil, 0, false
+	}
+	l := int(binary.BigEndian.Uint16(k[len(k)-2:]))
+	if len(k) != 2+l+8+2 {
+		return nil, 0, false
+	}
+	return k[2 : 2+l], ^binary.BigEndian.Uint64(k[2+l:]), true
+}
+
+func archiveEntry(v []byte, exists bool) []byte {
+	if !exists {
+		return []byte{archiveDeleted}
+	}
+	e := make([]byte, 1+len(v))
+	e[0] = archiveValue
+	copy(e[1:], v)
+	return e
+}
+
+// GetArchiveStart returns the first height kept by the archive
+func GetArchiveStart(db database.KeyValueReader) (uint64, bool, error) {
+	v, err := db.Get(archiveStart)
+	if errors.Is(err, database.ErrNotFound) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return binary.BigEndian.Uint64(v), true, nil
+}
+
+// EnableArchive starts archiving the state of [db] at [height], the height of
+// the last accepted block. It is a no-op if the archive is already enabled.
+func EnableArchive(db database.Database, height uint64) error {
+	_, enabled, err := GetArchiveStart(db)
+	if err != nil || enabled {
+		return err
+	}
+
+	start := time.Now()
+	batch := db.NewBatch()
+	for _, p := range SyncPrefixes {
+		it := db.NewIteratorWithPrefix([]byte{p, ByteDelimiter})
+		for it.Next() {
+			if err := batch.Put(PrefixArchiveKey(it.Key(), height), archiveEntry(it.Value(), true)); err != nil {
+				it.Release()
+				return err
+			}
+			if batch.Size() > treeBatchSize {
+				if err := batch.Write(); err != nil {
+					it.Release()
+					return err
+				}
+				batch.Reset()
+			}
+		}
+		err := it.Error()
+		it.Release()
+		if err != nil {
+			return err
+		}
+	}
+	v := make([]byte, 8)
+	binary.BigEndian.PutUint64(v, height)
+	if err := batch.Put(archiveStart, v); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	log.Info("enabled state archive", "height", height, "t", time.Since(start))
+	return nil
+}
+
+// DisableArchive deletes the archive (if any) from [db]
+func DisableArchive(db database.Database) error {
+	_, enabled, err := GetArchiveStart(db)
+	if err != nil || !enabled {
+		return err
+	}
+	if err := clearPrefix(db, archivePrefix); err != nil {
+		return err
+	}
+	log.Info("deleted state archive")
+	return db.Delete(archiveStart)
+}
+
+type archiveRecorder struct {
+	height  uint64
+	keys    [][]byte
+	entries [][]byte
+}
+
+func (r *archiveRecorder) record(k []byte, v []byte, exists bool) error {
+	if !IsSyncKey(k) {
+		return nil
+	}
+	r.keys = append(r.keys, PrefixArchiveKey(k, r.height))
+	r.entries = append(r.entries, archiveEntry(v, exists))
+	return nil
+}
+
+func (r *archiveRecorder) Put(k []byte, v []byte) error { return r.record(k, v, true) }
+
+func (r *archiveRecorder) Delete(k []byte) error { return r.record(k, nil, false) }
+
+// WriteArchive adds the new versions of all state keys written by [batch] to
+// [batch] if the archive of [db] is enabled.
+func WriteArchive(db database.KeyValueReader, batch database.Batch, height uint64) error {
+	_, enabled, err := GetArchiveStart(db)
+	if err != nil || !enabled {
+		return err
+	}
+	r := &archiveRecorder{height: height}
+	if err := batch.Replay(r); err != nil {
+		return err
+	}
+	for i, k := range r.keys {
+		if err := batch.Put(k, r.entries[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ArchiveState returns a read-only view of the state after the block at
+// [height] from the archive of [db]. [height] must not be less than the
+// archive start or greater than the last accepted height.
+func ArchiveState(db database.Database, height uint64) (database.Database, error) {
+	start, enabled, err := GetArchiveStart(db)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled || height < start {
+		return nil, ErrStateDiffMissing
+	}
+	// Writes are kept in memory by the versiondb and never committed
+	return versiondb.New(&archiveDB{Database: db, height: height}), nil
+}
+
+type archiveDB struct {
+	database.Database
+	height uint64
+}
+
+func (a *archiveDB) Has(k []byte) (bool, error) {
+	_, err := a.Get(k)
+	if errors.Is(err, database.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (a *archiveDB) Get(k []byte) ([]byte, error) {
+	it := a.Database.NewIteratorWithStartAndPrefix(PrefixArchiveKey(k, a.height), baseArchivePrefix(k))
+	defer it.Release()
+
+	for it.Next() {
+		key, _, ok := parseArchiveKey(it.Key())
+		if !ok || !bytes.Equal(key, k) {
+			// Version of a longer key with [k] as prefix
+			continue
+		}
+		v := it.Value()
+		if len(v) == 0 || v[0] == archiveDeleted {
+			return nil, database.ErrNotFound
+		}
+		return append([]byte(nil), v[1:]...), nil
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	return nil, database.ErrNotFound
+}
+
+func (a *archiveDB) Put([]byte, []byte) error { return ErrArchiveReadOnly }
+
+func (a *archiveDB) Delete([]byte) error { return ErrArchiveReadOnly }
+
+func (a *archiveDB) NewIterator() database.Iterator {
+	return a.NewIteratorWithStartAndPrefix(nil, nil)
+}
+
+func (a *archiveDB) NewIteratorWithStart(start []byte) database.Iterator {
+	return a.NewIteratorWithStartAndPrefix(start, nil)
+}
+
+func (a *archiveDB) NewIteratorWithPrefix(prefix []byte) database.Iterator {
+	return a.NewIteratorWithStartAndPrefix(nil, prefix)
+}
+
+// NewIteratorWithStartAndPrefix loads the versions of all keys with [prefix]
+// at the archived height into memory. It is meant for the small tables that
+// are listed by the API (stakers, nodes, etc.).
+func (a *archiveDB) NewIteratorWithStartAndPrefix(start, prefix []byte) database.Iterator {
+	mem := memdb.New()
+	seen := make(map[string]struct{})
+	it := a.Database.NewIteratorWithPrefix(baseArchivePrefix(prefix))
+	defer it.Release()
+
+	for it.Next() {
+		key, height, ok := parseArchiveKey(it.Key())
+		if !ok || height > a.height || !bytes.HasPrefix(key, prefix) || bytes.Compare(key, start) < 0 {
+			continue
+		}
+		// Versions of a key are sorted from newest to oldest
+		if _, ok := seen[string(key)]; ok {
+			continue
+		}
+		seen[string(key)] = struct{}{}
+		v := it.Value()
+		if len(v) == 0 || v[0] == archiveDeleted {
+			continue
+		}
+		if err := mem.Put(key, v[1:]); err != nil {
+			return &nodb.Iterator{Err: err}
+		}
+	}
+	if err := it.Error(); err != nil {
+		return &nodb.Iterator{Err: err}
+	}
+	return mem.NewIteratorWithStartAndPrefix(start, prefix)
+}
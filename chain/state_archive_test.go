@@ -0,0 +1,151 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ava-labs/avalanchego/database/memdb"
+	"github.com/ava-labs/avalanchego/database/versiondb"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestStateArchive(t *testing.T) {
+	t.Parallel()
+
+	db := memdb.New()
+	defer db.Close()
+	g := DefaultGenesis()
+	if err := g.Load(db, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	addr := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	short := []byte{balancePrefix, ByteDelimiter, 0xff}
+	long := []byte{balancePrefix, ByteDelimiter, 0xff, 0xff}
+
+	// accept applies [f] to the accepted state like [StatelessBlock.Accept]
+	accept := func(height uint64, f func(SamaState, *versiondb.Database)) {
+		vdb := versiondb.New(db)
+		f(SamaNew(vdb, g), vdb)
+		batch, err := vdb.CommitBatch()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteArchive(db, batch, height); err != nil {
+			t.Fatal(err)
+		}
+		if err := batch.Write(); err != nil {
+			t.Fatal(err)
+		}
+		vdb.Abort()
+	}
+	accept(1, func(s SamaState, vdb *versiondb.Database) {
+		if err := SetBalance(vdb, addr, 10); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if _, enabled, _ := GetArchiveStart(db); enabled {
+		t.Fatal("archive enabled by default")
+	}
+	if _, err := db.Get(PrefixArchiveKey(PrefixBalanceKey(addr), 1)); err != database.ErrNotFound {
+		t.Fatalf("archived block while disabled (%v)", err)
+	}
+
+	if err := EnableArchive(db, 1); err != nil {
+		t.Fatal(err)
+	}
+	accept(2, func(s SamaState, vdb *versiondb.Database) {
+		if err := SetBalance(vdb, addr, 20); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.PutStaker(&StakerMeta{StakerType: stakerTypeRoute, StakerAddr: addr, StakeAmount: 5}); err != nil {
+			t.Fatal(err)
+		}
+		if err := vdb.Put(long, []byte{2}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	accept(3, func(s SamaState, vdb *versiondb.Database) {
+		if err := SetBalance(vdb, addr, 30); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.DelStaker(stakerTypeRoute, addr); err != nil {
+			t.Fatal(err)
+		}
+		if err := vdb.Put(short, []byte{3}); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	if _, err := ArchiveState(db, 0); err != ErrStateDiffMissing {
+		t.Fatalf("expected %v before archive start, got %v", ErrStateDiffMissing, err)
+	}
+	for height, expected := range map[uint64]struct {
+		balance uint64
+		stakers int
+		short   bool
+		long    bool
+	}{
+		1: {balance: 10},
+		2: {balance: 20, stakers: 1, long: true},
+		3: {balance: 30, short: true, long: true},
+	} {
+		view, err := ArchiveState(db, height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bal, _ := GetBalance(view, addr); bal != expected.balance {
+			t.Fatalf("expected balance %d at height %d, got %d", expected.balance, height, bal)
+		}
+		stakers, err := SamaNew(view, g).GetStakers(stakerTypeRoute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(stakers) != expected.stakers {
+			t.Fatalf("expected %d stakers at height %d, got %d", expected.stakers, height, len(stakers))
+		}
+		if routes, _, _ := SamaNew(view, g).GetStakersNum(); routes != expected.stakers {
+			t.Fatalf("expected %d indexed stakers at height %d, got %d", expected.stakers, height, routes)
+		}
+		if has, _ := view.Has(short); has != expected.short {
+			t.Fatalf("expected short key %t at height %d", expected.short, height)
+		}
+		if has, _ := view.Has(long); has != expected.long {
+			t.Fatalf("expected long key %t at height %d", expected.long, height)
+		}
+
+		// Iteration must return the same keys as lookups, in order
+		it := view.NewIteratorWithPrefix([]byte{balancePrefix, ByteDelimiter})
+		var prev []byte
+		for it.Next() {
+			if bytes.Compare(it.Key(), prev) <= 0 {
+				t.Fatalf("unsorted iteration at height %d", height)
+			}
+			prev = append(prev[:0], it.Key()...)
+			v, err := view.Get(it.Key())
+			if err != nil || !bytes.Equal(v, it.Value()) {
+				t.Fatalf("iterated value of %x differs at height %d", it.Key(), height)
+			}
+		}
+		if err := it.Error(); err != nil {
+			t.Fatal(err)
+		}
+		it.Release()
+	}
+
+	if err := DisableArchive(db); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ArchiveState(db, 2); err != ErrStateDiffMissing {
+		t.Fatalf("expected %v after disabling, got %v", ErrStateDiffMissing, err)
+	}
+	it := db.NewIteratorWithPrefix([]byte{archivePrefix, ByteDelimiter})
+	defer it.Release()
+	if it.Next() {
+		t.Fatal("archive not deleted")
+	}
+}
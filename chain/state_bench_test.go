@@ -0,0 +1,134 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"encoding/binary"
+	"sync"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ava-labs/avalanchego/database/memdb"
+	"github.com/ava-labs/avalanchego/database/versiondb"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const benchUsers = 1_000_000
+
+var (
+	benchOnce sync.Once
+	benchDB   database.Database
+)
+
+func benchUserAddress(i int) (addr common.Address) {
+	binary.BigEndian.PutUint64(addr[common.AddressLength-8:], uint64(i))
+	return
+}
+
+// benchState returns an accepted database holding [benchUsers] users
+func benchState(b *testing.B) database.Database {
+	b.Helper()
+
+	benchOnce.Do(func() {
+		db := memdb.New()
+		g := DefaultGenesis()
+		if err := g.Load(db, nil); err != nil {
+			b.Fatal(err)
+		}
+		s := SamaNew(db, g)
+		for i := 0; i < benchUsers; i++ {
+			if err := s.PutUser(&UserMeta{
+				StartTime: g.ChainCreateTime,
+				EndTime:   g.ChainCreateTime + SecondsMonth,
+				PayAmount: 100,
+				TxsID:     []ids.ID{{1}},
+				UserType:  1,
+				Address:   benchUserAddress(i),
+			}); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if err := BuildStateIndexes(db); err != nil {
+			b.Fatal(err)
+		}
+		benchDB = db
+	})
+	return benchDB
+}
+
+func BenchmarkStateStartup(b *testing.B) {
+	db := benchState(b)
+	g := DefaultGenesis()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cdb, err := NewStateCache(db, DefaultStateCacheSize, prometheus.NewRegistry())
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := BuildStateIndexes(cdb); err != nil {
+			b.Fatal(err)
+		}
+		s := SamaNew(cdb, g)
+		if _, _, err := s.GetUserMeta(benchUserAddress(i % benchUsers)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetUserMeta(b *testing.B) {
+	db := benchState(b)
+	cdb, err := NewStateCache(db, DefaultStateCacheSize, prometheus.NewRegistry())
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, bc := range []struct {
+		name  string
+		db    database.Database
+		users int
+	}{
+		{"uncached", db, benchUsers},
+		{"cached-hot", cdb, DefaultStateCacheSize / 2},
+		{"cached-cold", cdb, benchUsers},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			s := SamaNew(bc.db, DefaultGenesis())
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, exist, err := s.GetUserMeta(benchUserAddress(i % bc.users)); err != nil || !exist {
+					b.Fatalf("user %d missing (%v)", i, err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDealAddUserTx(b *testing.B) {
+	db := benchState(b)
+	cdb, err := NewStateCache(db, DefaultStateCacheSize, prometheus.NewRegistry())
+	if err != nil {
+		b.Fatal(err)
+	}
+	g := DefaultGenesis()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// Each block writes to its own view of the accepted state
+		vdb := versiondb.New(cdb)
+		s := SamaNew(vdb, g)
+		if err := s.DealAddUserTx(ids.GenerateTestID(), g.ChainCreateTime, &UserMeta{
+			StartTime: g.ChainCreateTime + SecondsMonth,
+			EndTime:   g.ChainCreateTime + 2*SecondsMonth,
+			PayAmount: 100,
+			UserType:  1,
+			Address:   benchUserAddress(i % benchUsers),
+		}); err != nil {
+			b.Fatal(err)
+		}
+		vdb.Abort()
+	}
+}
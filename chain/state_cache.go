@@ -0,0 +1,167 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"sync"
+
+	"github.com/ava-labs/avalanchego/cache"
+	"github.com/ava-labs/avalanchego/cache/metercacher"
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const DefaultStateCacheSize = 65536
+
+var (
+	_ database.Database = &stateCache{}
+	_ database.Batch    = &stateCacheBatch{}
+)
+
+type cachedValue struct {
+	value  []byte
+	exists bool
+}
+
+// stateCache keeps recently read state records (balances, stakers, rewards,
+// users, ...) of the accepted database in a bounded LRU cache, so hot
+// records are not read from disk for every transaction. Absent records are
+// cached as well.
+//
+// Every write, including batches committed by a [versiondb.Database] on
+// Accept, goes through the cache, so it must wrap the accepted database.
+type stateCache struct {
+	database.Database
+
+	lock   sync.Mutex
+	writes uint64
+	cache  cache.Cacher[string, *cachedValue]
+}
+
+func NewStateCache(db database.Database, size int, metrics prometheus.Registerer) (database.Database, error) {
+	c, err := metercacher.New[string, *cachedValue](
+		"state_cache",
+		metrics,
+		&cache.LRU[string, *cachedValue]{Size: size},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &stateCache{
+		Database: db,
+		cache:    c,
+	}, nil
+}
+
+// cacheable reports whether [k] belongs to a state table
+func cacheable(k []byte) bool {
+	return len(k) > 0 && (k[0] >= balancePrefix && k[0] <= indexPrefix || k[0] == treePrefix)
+}
+
+func (c *stateCache) Has(k []byte) (bool, error) {
+	if cacheable(k) {
+		c.lock.Lock()
+		v, ok := c.cache.Get(string(k))
+		c.lock.Unlock()
+		if ok {
+			return v.exists, nil
+		}
+	}
+	return c.Database.Has(k)
+}
+
+func (c *stateCache) Get(k []byte) ([]byte, error) {
+	if !cacheable(k) {
+		return c.Database.Get(k)
+	}
+	key := string(k)
+	c.lock.Lock()
+	if v, ok := c.cache.Get(key); ok {
+		c.lock.Unlock()
+		if !v.exists {
+			return nil, database.ErrNotFound
+		}
+		return append([]byte(nil), v.value...), nil
+	}
+	writes := c.writes
+	c.lock.Unlock()
+
+	v, err := c.Database.Get(k)
+	if err != nil && err != database.ErrNotFound {
+		return nil, err
+	}
+
+	// Don't cache the value if it may have been overwritten while reading
+	c.lock.Lock()
+	if c.writes == writes {
+		c.cache.Put(key, &cachedValue{
+			value:  append([]byte(nil), v...),
+			exists: err == nil,
+		})
+	}
+	c.lock.Unlock()
+	return v, err
+}
+
+func (c *stateCache) Put(k []byte, v []byte) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.writes++
+	c.cache.Evict(string(k))
+	return c.Database.Put(k, v)
+}
+
+func (c *stateCache) Delete(k []byte) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.writes++
+	c.cache.Evict(string(k))
+	return c.Database.Delete(k)
+}
+
+func (c *stateCache) NewBatch() database.Batch {
+	return &stateCacheBatch{
+		Batch: c.Database.NewBatch(),
+		c:     c,
+	}
+}
+
+type stateCacheBatch struct {
+	database.Batch
+
+	c    *stateCache
+	keys []string
+}
+
+func (b *stateCacheBatch) Put(k []byte, v []byte) error {
+	if cacheable(k) {
+		b.keys = append(b.keys, string(k))
+	}
+	return b.Batch.Put(k, v)
+}
+
+func (b *stateCacheBatch) Delete(k []byte) error {
+	if cacheable(k) {
+		b.keys = append(b.keys, string(k))
+	}
+	return b.Batch.Delete(k)
+}
+
+func (b *stateCacheBatch) Write() error {
+	b.c.lock.Lock()
+	defer b.c.lock.Unlock()
+
+	b.c.writes++
+	for _, k := range b.keys {
+		b.c.cache.Evict(k)
+	}
+	return b.Batch.Write()
+}
+
+func (b *stateCacheBatch) Reset() {
+	b.keys = nil
+	b.Batch.Reset()
+}
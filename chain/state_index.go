@@ -0,0 +1,267 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"time"
+
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ava-labs/avalanchego/database/versiondb"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	log "github.com/inconshreveable/log15"
+)
+
+// Secondary indexes are maintained alongside the state tables so that
+// aggregate and range queries don't have to scan a whole table:
+//
+// 0x14/0x5/[stakerType] => number of stakers
+// 0x14/0xc/[powType] => sum of pow work time
+// 0x14/0x11/[hash(key)][actionID] => nil
+// 0x14/0x11 0x0 [endTime][actionID] => nil (actions not closed yet)
+// 0x14/0xe/[work address] => detail address
+// 0x14/0x1d/[delegate][delegator] => nil
+
+var stateIndexVersion = []byte("state_index_version")
+
+const currentStateIndexVersion = 3
+
+// [indexPrefix] + [delimiter] + [table] + [delimiter] + [sub]
+func PrefixCountKey(table byte, sub byte) (k []byte) {
+	k = make([]byte, 5)
+	k[0] = indexPrefix
+	k[1] = ByteDelimiter
+	k[2] = table
+	k[3] = ByteDelimiter
+	k[4] = sub
+	return
+}
+
+// [indexPrefix] + [delimiter] + [actionsPrefix] + [delimiter] + [hash(key)]
+func baseActionKeyIndexPrefix(key string) (k []byte) {
+	k = make([]byte, 4+common.HashLength)
+	k[0] = indexPrefix
+	k[1] = ByteDelimiter
+	k[2] = actionsPrefix
+	k[3] = ByteDelimiter
+	copy(k[4:], crypto.Keccak256([]byte(key)))
+	return
+}
+
+// [indexPrefix] + [delimiter] + [actionsPrefix] + [delimiter] + [hash(key)] + [actionID]
+func PrefixActionKeyIndex(key string, actionID ids.ShortID) (k []byte) {
+	k = append(baseActionKeyIndexPrefix(key), actionID[:]...)
+	return
+}
+
+// [indexPrefix] + [delimiter] + [actionsPrefix] + [0x0]
+func baseActionDeadlinePrefix() (k []byte) {
+	k = make([]byte, 4)
+	k[0] = indexPrefix
+	k[1] = ByteDelimiter
+	k[2] = actionsPrefix
+	return
+}
+
+// [indexPrefix] + [delimiter] + [actionsPrefix] + [0x0] + [endTime] + [actionID]
+func PrefixActionDeadlineIndex(endTime uint64, actionID ids.ShortID) (k []byte) {
+	k = make([]byte, 4+8+len(actionID))
+	copy(k, baseActionDeadlinePrefix())
+	binary.BigEndian.PutUint64(k[4:], endTime)
+	copy(k[12:], actionID[:])
+	return
+}
+
+// [indexPrefix] + [delimiter] + [detailsPrefix] + [delimiter] + [work address]
+func PrefixWorkAddressIndex(address common.Address) (k []byte) {
+	k = make([]byte, 4+common.AddressLength)
+	k[0] = indexPrefix
+	k[1] = ByteDelimiter
+	k[2] = detailsPrefix
+	k[3] = ByteDelimiter
+	copy(k[4:], address[:])
+	return
+}
+
+// [indexPrefix] + [delimiter] + [delegationsPrefix] + [delimiter] + [delegate]
+func baseDelegateIndexPrefix(delegate common.Address) (k []byte) {
+	k = make([]byte, 4+common.AddressLength)
+	k[0] = indexPrefix
+	k[1] = ByteDelimiter
+	k[2] = delegationsPrefix
+	k[3] = ByteDelimiter
+	copy(k[4:], delegate[:])
+	return
+}
+
+// [indexPrefix] + [delimiter] + [delegationsPrefix] + [delimiter] + [delegate] + [delegator]
+func PrefixDelegateIndex(delegate common.Address, delegator common.Address) (k []byte) {
+	k = append(baseDelegateIndexPrefix(delegate), delegator[:]...)
+	return
+}
+
+func getCount(db database.KeyValueReader, k []byte) (uint64, error) {
+	v, err := db.Get(k)
+	if err == database.ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(v), nil
+}
+
+func modifyCount(db database.KeyValueReaderWriter, k []byte, add bool, change uint64) error {
+	n, err := getCount(db, k)
+	if err != nil {
+		return err
+	}
+	if add {
+		n += change
+	} else if change > n {
+		n = 0
+	} else {
+		n -= change
+	}
+	v := make([]byte, 8)
+	binary.BigEndian.PutUint64(v, n)
+	return db.Put(k, v)
+}
+
+// workAddress derives the address of a node from its hex encoded public key
+func workAddress(workKey string) (common.Address, error) {
+	pbkb, err := hex.DecodeString(workKey)
+	if err != nil {
+		return common.Address{}, err
+	}
+	pbk, err := crypto.UnmarshalPubkey(pbkb)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pbk), nil
+}
+
+// migrateUserTypes moves the user types stored in the users table
+// (0xf/[typeID]) before they had their own table to [PrefixUserTypesKey]
+func migrateUserTypes(db database.Database) error {
+	ks := [][]byte{}
+	err := iterateState(db, baseUsersPrefix(), 8, func(k []byte, _ []byte) error {
+		ks = append(ks, append([]byte(nil), k...))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, k := range ks {
+		v, err := db.Get(k)
+		if err != nil {
+			return err
+		}
+		if err := db.Put(PrefixUserTypesKey(binary.BigEndian.Uint64(k[2:])), v); err != nil {
+			return err
+		}
+		if err := db.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BuildStateIndexes populates the secondary indexes from the state tables of
+// a database written before they existed. It is a no-op once the indexes are
+// up to date.
+func BuildStateIndexes(db database.Database) error {
+	version, err := getCount(db, stateIndexVersion)
+	if err != nil {
+		return err
+	}
+	if version >= currentStateIndexVersion {
+		return nil
+	}
+
+	start := time.Now()
+	vdb := versiondb.New(db)
+	if version < 1 {
+		for _, stakerType := range StakerTypes {
+			err := iterateState(vdb, baseStakerPrefix(stakerType), common.AddressLength, func(_ []byte, _ []byte) error {
+				return modifyCount(vdb, PrefixCountKey(stakerPrefix, stakerType), true, 1)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		for _, powType := range []byte{powTypeRoute, powTypeSer} {
+			err := iterateState(vdb, basePowPrefix(powType), common.AddressLength, func(_ []byte, v []byte) error {
+				pmeta := new(PowMeta)
+				if _, err := Unmarshal(v, pmeta); err != nil {
+					return err
+				}
+				return modifyCount(vdb, PrefixCountKey(powPrefix, powType), true, pmeta.TotalTime)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		err = iterateState(vdb, baseActionsPrefix(), len(ids.ShortID{}), func(_ []byte, v []byte) error {
+			pmeta := new(ActionMeta)
+			if _, err := Unmarshal(v, pmeta); err != nil {
+				return err
+			}
+			return vdb.Put(PrefixActionKeyIndex(pmeta.Key, pmeta.ActionID), nil)
+		})
+		if err != nil {
+			return err
+		}
+		err = iterateState(vdb, baseDetailsPrefix(), common.AddressLength, func(k []byte, v []byte) error {
+			pmeta := new(DetailMeta)
+			if _, err := Unmarshal(v, pmeta); err != nil {
+				return err
+			}
+			addr, err := workAddress(pmeta.WorkKey)
+			if err != nil {
+				// Invalid keys were never usable as a work address
+				return nil
+			}
+			return vdb.Put(PrefixWorkAddressIndex(addr), k[2:])
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if version < 2 {
+		err := iterateState(vdb, baseActionsPrefix(), len(ids.ShortID{}), func(_ []byte, v []byte) error {
+			pmeta := new(ActionMeta)
+			if _, err := Unmarshal(v, pmeta); err != nil {
+				return err
+			}
+			if pmeta.closed() {
+				return nil
+			}
+			return vdb.Put(PrefixActionDeadlineIndex(pmeta.EndTime, pmeta.ActionID), nil)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if version < 3 {
+		if err := migrateUserTypes(vdb); err != nil {
+			return err
+		}
+	}
+
+	v := make([]byte, 8)
+	binary.BigEndian.PutUint64(v, currentStateIndexVersion)
+	if err := vdb.Put(stateIndexVersion, v); err != nil {
+		return err
+	}
+	// Keep the state tree, if any, in line with the state keys written above
+	if _, err := commitState(vdb); err != nil {
+		return err
+	}
+	log.Info("built state indexes", "t", time.Since(start))
+	return vdb.Commit()
+}
@@ -0,0 +1,276 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ava-labs/avalanchego/database/memdb"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// populateState writes a few records of every indexed table
+func populateState(t *testing.T, s SamaState) (common.Address, common.Address) {
+	t.Helper()
+
+	staker := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	for i, stakerType := range []byte{stakerTypeRoute, stakerTypeRoute, stakerTypeSer} {
+		if err := s.PutStaker(&StakerMeta{
+			StakerType: uint64(stakerType),
+			StakerAddr: common.BigToAddress(big.NewInt(int64(i + 1))),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, workTime := range []uint64{10, 20} {
+		if err := s.PutPow(powTypeRoute, &ProofMeta{WorkTime: workTime, Miner: staker}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, key := range []string{"a", "a", "b"} {
+		actionID := ids.ShortID{byte(i + 1)}
+		if err := s.PutAction(actionID, &ActionMeta{
+			ActionID:   actionID,
+			ActionType: 1,
+			EndTime:    100,
+			Key:        key,
+			Voters:     make([]common.Address, i+1),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	workAddr := crypto.PubkeyToAddress(priv.PublicKey)
+	if err := s.PutDetail(workAddr, &DetailMeta{
+		StakerType:   stakerTypeRoute,
+		WorkKey:      hex.EncodeToString(crypto.FromECDSAPub(&priv.PublicKey)),
+		WorkAddress:  workAddr,
+		StakeAddress: staker,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return staker, workAddr
+}
+
+func checkStateIndexes(t *testing.T, s SamaState, staker common.Address, workAddr common.Address) {
+	t.Helper()
+
+	if routes, sers, validators := s.GetStakersNum(); routes != 2 || sers != 1 || validators != 0 {
+		t.Fatalf("unexpected stakers num %d/%d/%d", routes, sers, validators)
+	}
+	if total, err := s.TotalPowTime(powTypeRoute); err != nil || total != 30 {
+		t.Fatalf("expected pow time 30, got %d (%v)", total, err)
+	}
+	if num, actionID, err := s.GetVotersNum(1, "a"); err != nil || num != 1 || actionID != (ids.ShortID{1}) {
+		t.Fatalf("unexpected voters num %d for %s (%v)", num, actionID, err)
+	}
+	if err := s.ProposalRepeat(ids.ShortID{9}, 1, "b", "", 50); err == nil {
+		t.Fatal("expected repeated proposal")
+	}
+	if err := s.ProposalRepeat(ids.ShortID{9}, 1, "c", "", 50); err != nil {
+		t.Fatalf("unexpected repeated proposal: %v", err)
+	}
+	if ok, stakerType, err := s.IsValidWorkAddress(workAddr); err != nil || !ok || stakerType != stakerTypeRoute {
+		t.Fatalf("expected valid work address, got %t/%d (%v)", ok, stakerType, err)
+	}
+}
+
+func TestStateIndexes(t *testing.T) {
+	t.Parallel()
+
+	db := memdb.New()
+	defer db.Close()
+	s := SamaNew(db, DefaultGenesis())
+
+	staker, workAddr := populateState(t, s)
+	checkStateIndexes(t, s, staker, workAddr)
+
+	// Overwriting records must not double count
+	if err := s.PutStaker(&StakerMeta{StakerType: stakerTypeRoute, StakerAddr: common.BigToAddress(common.Big1)}); err != nil {
+		t.Fatal(err)
+	}
+	checkStateIndexes(t, s, staker, workAddr)
+
+	// Removing records updates the indexes
+	if err := s.DelStaker(stakerTypeSer, common.BigToAddress(common.Big3)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DelStaker(stakerTypeSer, common.BigToAddress(common.Big3)); err != nil {
+		t.Fatal(err)
+	}
+	if _, sers, _ := s.GetStakersNum(); sers != 0 {
+		t.Fatalf("expected no sers, got %d", sers)
+	}
+	if err := s.DelPow(powTypeRoute, staker); err != nil {
+		t.Fatal(err)
+	}
+	if total, _ := s.TotalPowTime(powTypeRoute); total != 0 {
+		t.Fatalf("expected no pow time, got %d", total)
+	}
+	if err := s.DelAction(ids.ShortID{1}); err != nil {
+		t.Fatal(err)
+	}
+	if num, actionID, _ := s.GetVotersNum(1, "a"); num != 2 || actionID != (ids.ShortID{2}) {
+		t.Fatalf("unexpected voters num %d for %s", num, actionID)
+	}
+	if err := s.DelDetail(workAddr); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _, _ := s.IsValidWorkAddress(workAddr); ok {
+		t.Fatal("removed detail is still a valid work address")
+	}
+}
+
+func TestBuildStateIndexes(t *testing.T) {
+	t.Parallel()
+
+	db := memdb.New()
+	defer db.Close()
+	s := SamaNew(db, DefaultGenesis())
+	staker, workAddr := populateState(t, s)
+
+	// Drop the indexes to simulate a database written before they existed
+	it := db.NewIteratorWithPrefix([]byte{indexPrefix})
+	for it.Next() {
+		if err := db.Delete(it.Key()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	it.Release()
+	if routes, _, _ := s.GetStakersNum(); routes != 0 {
+		t.Fatalf("expected no indexed routes, got %d", routes)
+	}
+
+	if err := BuildStateIndexes(db); err != nil {
+		t.Fatal(err)
+	}
+	checkStateIndexes(t, s, staker, workAddr)
+
+	// Only runs once
+	if err := db.Delete(PrefixCountKey(stakerPrefix, stakerTypeSer)); err != nil {
+		t.Fatal(err)
+	}
+	if err := BuildStateIndexes(db); err != nil {
+		t.Fatal(err)
+	}
+	if _, sers, _ := s.GetStakersNum(); sers != 0 {
+		t.Fatalf("expected indexes to be built once, got %d sers", sers)
+	}
+}
+
+func TestMigrateUserTypes(t *testing.T) {
+	t.Parallel()
+
+	db := memdb.New()
+	defer db.Close()
+	s := SamaNew(db, DefaultGenesis())
+
+	// User types were stored next to the users before they had their own
+	// table
+	legacyKey := func(id uint64) []byte {
+		k := PrefixUserTypesKey(id)
+		k[0] = usersPrefix
+		return k
+	}
+	for _, id := range []uint64{1, 6} {
+		v, err := Marshal(&UserType{TypeName: "t", TypeID: id, FeeUnits: 100 * id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Put(legacyKey(id), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	user := &UserMeta{UserType: 6, Address: common.Address{1}}
+	if err := s.PutUser(user); err != nil {
+		t.Fatal(err)
+	}
+	if err := EnsureStateTree(db); err != nil {
+		t.Fatal(err)
+	}
+	if s.CheckUserType(6) {
+		t.Fatal("user type found before the migration")
+	}
+
+	if err := BuildStateIndexes(db); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []uint64{1, 6} {
+		if !s.CheckFee(id, 100*id) {
+			t.Fatalf("user type %d not migrated", id)
+		}
+		if ok, _ := db.Has(legacyKey(id)); ok {
+			t.Fatalf("user type %d left in the users table", id)
+		}
+	}
+	if _, exist, err := s.GetUserMeta(user.Address); err != nil || !exist {
+		t.Fatalf("user not kept (%v)", err)
+	}
+
+	// The state tree follows the moved keys
+	root, err := StateRoot(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if built, err := BuildStateTree(db); err != nil || built != root {
+		t.Fatalf("expected state root %s, got %s (%v)", built, root, err)
+	}
+}
+
+func TestStateCache(t *testing.T) {
+	t.Parallel()
+
+	base := memdb.New()
+	defer base.Close()
+	db, err := NewStateCache(base, 16, prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k := PrefixBalanceKey(common.HexToAddress("0x0000000000000000000000000000000000000001"))
+	if _, err := db.Get(k); err != database.ErrNotFound {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	if err := db.Put(k, []byte{1}); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := db.Get(k); err != nil || v[0] != 1 {
+		t.Fatalf("expected 1, got %v (%v)", v, err)
+	}
+
+	// Returned values can't corrupt the cache
+	v, _ := db.Get(k)
+	v[0] = 2
+	if v, _ := db.Get(k); v[0] != 1 {
+		t.Fatalf("cached value was modified: %v", v)
+	}
+
+	// Batches evict the keys they write
+	b := db.NewBatch()
+	if err := b.Put(k, []byte{3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Write(); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := db.Get(k); v[0] != 3 {
+		t.Fatalf("expected 3 after batch, got %v", v)
+	}
+	if err := db.Delete(k); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := db.Has(k); ok {
+		t.Fatal("deleted key still cached")
+	}
+}
@@ -0,0 +1,303 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ava-labs/avalanchego/database/versiondb"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// 0x15/[height] (state diffs)
+//   -> values overwritten by the block at [height]
+// 0x16/[height] (sync summaries)
+//   -> summary of the state after the block at [height]
+
+var (
+	// SyncPrefixes are the tables that make up the chain state. They are
+	// transferred during state sync and committed to by the state tree.
+	SyncPrefixes = []byte{
+		txPrefix,
+		txValuePrefix,
+		keyPrefix,
+		balancePrefix,
+		stakerPrefix,
+		rewardPrefix,
+		powPrefix,
+		yieldsPrefix,
+		detailsPrefix,
+		usersPrefix,
+		sysParamPrefix,
+		actionsPrefix,
+		userTypesPrefix,
+		indexPrefix,
+		suspensionsPrefix,
+		delegationsPrefix,
+		multisigsPrefix,
+		stakeDelegationsPrefix,
+		unbondingsPrefix,
+		slashesPrefix,
+	}
+
+	ErrStateDiffMissing = errors.New("state diff missing")
+	ErrInvalidSyncRange = errors.New("invalid sync range")
+)
+
+// IsSyncKey reports whether [k] belongs to one of the [SyncPrefixes]
+func IsSyncKey(k []byte) bool {
+	if len(k) < 2 || k[1] != ByteDelimiter {
+		return false
+	}
+	for _, p := range SyncPrefixes {
+		if k[0] == p {
+			return true
+		}
+	}
+	return false
+}
+
+// [stateDiffPrefix] + [delimiter] + [height]
+func PrefixStateDiffKey(height uint64) (k []byte) {
+	k = make([]byte, 2+8)
+	k[0] = stateDiffPrefix
+	k[1] = ByteDelimiter
+	binary.BigEndian.PutUint64(k[2:], height)
+	return
+}
+
+// [syncSummaryPrefix] + [delimiter] + [height]
+func PrefixSyncSummaryKey(height uint64) (k []byte) {
+	k = make([]byte, 2+8)
+	k[0] = syncSummaryPrefix
+	k[1] = ByteDelimiter
+	binary.BigEndian.PutUint64(k[2:], height)
+	return
+}
+
+// StateChange is the value a key held before a block modified it
+type StateChange struct {
+	Key     []byte `serialize:"true" json:"key"`
+	Prev    []byte `serialize:"true" json:"prev"`
+	Existed bool   `serialize:"true" json:"existed"`
+}
+
+type StateDiff struct {
+	Changes []*StateChange `serialize:"true" json:"changes"`
+}
+
+type diffRecorder struct {
+	db      database.KeyValueReader
+	seen    map[string]struct{}
+	changes []*StateChange
+}
+
+func (r *diffRecorder) record(k []byte) error {
+	if !isStateKey(k) {
+		return nil
+	}
+	if _, ok := r.seen[string(k)]; ok {
+		return nil
+	}
+	r.seen[string(k)] = struct{}{}
+	c := &StateChange{Key: append([]byte(nil), k...)}
+	v, err := r.db.Get(k)
+	switch {
+	case err == nil:
+		c.Prev, c.Existed = v, true
+	case err != database.ErrNotFound:
+		return err
+	}
+	r.changes = append(r.changes, c)
+	return nil
+}
+
+func (r *diffRecorder) Put(k []byte, _ []byte) error { return r.record(k) }
+
+func (r *diffRecorder) Delete(k []byte) error { return r.record(k) }
+
+// WriteStateDiff adds the previous values (as found in [db]) of all state
+// keys written by [batch] to [batch], so the state before [height] can be
+// recovered with [StateAt].
+func WriteStateDiff(db database.KeyValueReader, batch database.Batch, height uint64) error {
+	r := &diffRecorder{db: db, seen: make(map[string]struct{})}
+	if err := batch.Replay(r); err != nil {
+		return err
+	}
+	b, err := Marshal(&StateDiff{Changes: r.changes})
+	if err != nil {
+		return err
+	}
+	return batch.Put(PrefixStateDiffKey(height), b)
+}
+
+func GetStateDiff(db database.KeyValueReader, height uint64) (*StateDiff, error) {
+	b, err := db.Get(PrefixStateDiffKey(height))
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, ErrStateDiffMissing
+	}
+	if err != nil {
+		return nil, err
+	}
+	diff := new(StateDiff)
+	if _, err := Unmarshal(b, diff); err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
+
+func DeleteStateDiff(db database.KeyValueDeleter, height uint64) error {
+	return db.Delete(PrefixStateDiffKey(height))
+}
+
+// StateAt returns a read-only view of the state after the block at [height],
+// reconstructed by undoing the diffs of the blocks accepted after it. [db]
+// must hold the state after the block at [tip].
+func StateAt(db database.Database, height uint64, tip uint64) (database.Database, error) {
+	if height > tip {
+		return nil, ErrStateDiffMissing
+	}
+	vdb := versiondb.New(db)
+	for h := tip; h > height; h-- {
+		diff, err := GetStateDiff(db, h)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range diff.Changes {
+			if !c.Existed {
+				err = vdb.Delete(c.Key)
+			} else {
+				err = vdb.Put(c.Key, c.Prev)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return vdb, nil
+}
+
+// GetStateRange returns the state keys (and values) in [db] starting at
+// [start] in ascending order. It stops after [limit] keys or once the
+// response reaches [maxBytes]; [more] is false once the range is exhausted.
+func GetStateRange(db database.Iteratee, start []byte, limit int, maxBytes int) (keys [][]byte, values [][]byte, more bool, err error) {
+	size := 0
+	for _, p := range SyncPrefixes {
+		prefix := []byte{p, ByteDelimiter}
+		if len(start) > 0 && bytes.Compare(prefix, start[:min(len(start), 2)]) < 0 {
+			continue
+		}
+		it := db.NewIteratorWithStartAndPrefix(start, prefix)
+		for it.Next() {
+			if len(keys) >= limit || size >= maxBytes {
+				it.Release()
+				return keys, values, true, nil
+			}
+			k, v := it.Key(), it.Value()
+			keys = append(keys, append([]byte(nil), k...))
+			values = append(values, append([]byte(nil), v...))
+			size += len(k) + len(v)
+		}
+		err := it.Error()
+		it.Release()
+		if err != nil {
+			return nil, nil, false, err
+		}
+	}
+	return keys, values, false, nil
+}
+
+// ClearState deletes every state key (and the state tree) in [db]
+func ClearState(db database.Database) error {
+	for _, p := range append(SyncPrefixes, treePrefix) {
+		if err := clearPrefix(db, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PutStateRange writes a range returned by [GetStateRange] to [db] after
+// checking it is sorted, only contains state keys and starts at [start].
+func PutStateRange(db database.KeyValueWriter, start []byte, keys [][]byte, values [][]byte) error {
+	if len(keys) != len(values) {
+		return ErrInvalidSyncRange
+	}
+	prev := start
+	for i, k := range keys {
+		if !IsSyncKey(k) || bytes.Compare(k, prev) < 0 || (i > 0 && bytes.Equal(k, prev)) {
+			return ErrInvalidSyncRange
+		}
+		if err := db.Put(k, values[i]); err != nil {
+			return err
+		}
+		prev = k
+	}
+	return nil
+}
+
+// SyncSummary describes the state after an accepted block
+type SyncSummary struct {
+	BlkID ids.ID      `serialize:"true" json:"blockID"`
+	Hght  uint64      `serialize:"true" json:"height"`
+	Root  common.Hash `serialize:"true" json:"root"`
+}
+
+func PutSyncSummary(db database.KeyValueWriter, summary *SyncSummary) error {
+	b, err := Marshal(summary)
+	if err != nil {
+		return err
+	}
+	return db.Put(PrefixSyncSummaryKey(summary.Hght), b)
+}
+
+// GetSyncSummary returns the summary stored at [height] and its bytes
+func GetSyncSummary(db database.KeyValueReader, height uint64) (*SyncSummary, []byte, error) {
+	b, err := db.Get(PrefixSyncSummaryKey(height))
+	if err != nil {
+		return nil, nil, err
+	}
+	summary := new(SyncSummary)
+	if _, err := Unmarshal(b, summary); err != nil {
+		return nil, nil, err
+	}
+	return summary, b, nil
+}
+
+// GetLastSyncSummary returns the summary with the greatest height
+func GetLastSyncSummary(db database.Iteratee) (*SyncSummary, []byte, error) {
+	it := db.NewIteratorWithPrefix([]byte{syncSummaryPrefix, ByteDelimiter})
+	defer it.Release()
+
+	var last []byte
+	for it.Next() {
+		last = append(last[:0], it.Value()...)
+	}
+	if err := it.Error(); err != nil {
+		return nil, nil, err
+	}
+	if last == nil {
+		return nil, nil, database.ErrNotFound
+	}
+	summary := new(SyncSummary)
+	if _, err := Unmarshal(last, summary); err != nil {
+		return nil, nil, err
+	}
+	return summary, last, nil
+}
+
+func DeleteSyncSummary(db database.KeyValueDeleter, height uint64) error {
+	return db.Delete(PrefixSyncSummaryKey(height))
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
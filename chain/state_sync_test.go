@@ -0,0 +1,146 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"testing"
+
+	"github.com/ava-labs/avalanchego/database/memdb"
+	"github.com/ava-labs/avalanchego/database/versiondb"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestStateSync(t *testing.T) {
+	t.Parallel()
+
+	db := memdb.New()
+	defer db.Close()
+	g := DefaultGenesis()
+	if err := g.Load(db, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	addr := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	user := common.HexToAddress("0x0000000000000000000000000000000000000002")
+
+	// accept applies [f] to the accepted state like [StatelessBlock.Accept]
+	accept := func(height uint64, f func(SamaState, *versiondb.Database)) {
+		vdb := versiondb.New(db)
+		f(SamaNew(vdb, g), vdb)
+		if _, err := commitState(vdb); err != nil {
+			t.Fatal(err)
+		}
+		batch, err := vdb.CommitBatch()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteStateDiff(db, batch, height); err != nil {
+			t.Fatal(err)
+		}
+		if err := batch.Write(); err != nil {
+			t.Fatal(err)
+		}
+		vdb.Abort()
+	}
+	accept(1, func(s SamaState, vdb *versiondb.Database) {
+		if err := SetBalance(vdb, addr, 10); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.PutStaker(&StakerMeta{StakerType: stakerTypeRoute, StakerAddr: addr}); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.PutUser(&UserMeta{Address: user, EndTime: 100}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	root1, err := StateRoot(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	accept(2, func(s SamaState, vdb *versiondb.Database) {
+		if err := SetBalance(vdb, addr, 20); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.DelStaker(stakerTypeRoute, addr); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.DelUser(user); err != nil {
+			t.Fatal(err)
+		}
+	})
+	root2, err := StateRoot(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root1 == root2 {
+		t.Fatal("state root did not change")
+	}
+
+	// Undo the diff of block 2
+	view, err := StateAt(db, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bal, _ := GetBalance(view, addr); bal != 10 {
+		t.Fatalf("expected balance 10 at height 1, got %d", bal)
+	}
+	if routes, _, _ := SamaNew(view, g).GetStakersNum(); routes != 1 {
+		t.Fatalf("expected 1 route at height 1, got %d", routes)
+	}
+	if root, _ := StateRoot(view); root != root1 {
+		t.Fatalf("expected root %s at height 1, got %s", root1, root)
+	}
+	if bal, _ := GetBalance(db, addr); bal != 20 {
+		t.Fatalf("view modified the accepted state (balance %d)", bal)
+	}
+	if _, err := StateAt(db, 0, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteStateDiff(db, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := StateAt(db, 0, 2); err != ErrStateDiffMissing {
+		t.Fatalf("expected %v, got %v", ErrStateDiffMissing, err)
+	}
+
+	// Copy the state at height 1 in small ranges
+	synced := memdb.New()
+	defer synced.Close()
+	var cursor []byte
+	for {
+		keys, values, more, err := GetStateRange(view, cursor, 2, 1024)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := PutStateRange(synced, cursor, keys, values); err != nil {
+			t.Fatal(err)
+		}
+		if !more {
+			break
+		}
+		cursor = append(append([]byte(nil), keys[len(keys)-1]...), 0)
+	}
+	if root, _ := BuildStateTree(synced); root != root1 {
+		t.Fatalf("synced root %s, expected %s", root, root1)
+	}
+
+	// Ranges must be sorted state keys after the cursor
+	k := PrefixBalanceKey(addr)
+	if err := PutStateRange(synced, k, [][]byte{PrefixTxKey([32]byte{})}, [][]byte{nil}); err != ErrInvalidSyncRange {
+		t.Fatalf("expected %v, got %v", ErrInvalidSyncRange, err)
+	}
+	if err := PutStateRange(synced, nil, [][]byte{k, k}, [][]byte{nil, nil}); err != ErrInvalidSyncRange {
+		t.Fatalf("expected %v, got %v", ErrInvalidSyncRange, err)
+	}
+	if err := PutStateRange(synced, nil, [][]byte{lastAccepted}, [][]byte{nil}); err != ErrInvalidSyncRange {
+		t.Fatalf("expected %v, got %v", ErrInvalidSyncRange, err)
+	}
+
+	if err := ClearState(synced); err != nil {
+		t.Fatal(err)
+	}
+	if keys, _, _, _ := GetStateRange(synced, nil, 10, 1024); len(keys) != 0 {
+		t.Fatalf("expected empty state, got %d keys", len(keys))
+	}
+}
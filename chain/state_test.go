@@ -0,0 +1,76 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"testing"
+
+	"github.com/ava-labs/avalanchego/database/memdb"
+	"github.com/ava-labs/avalanchego/database/versiondb"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestSamaStateForks(t *testing.T) {
+	t.Parallel()
+
+	db := memdb.New()
+	defer db.Close()
+	g := DefaultGenesis()
+
+	staker := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	actionID := ids.ShortID{1}
+
+	// Two sibling blocks built on the same parent
+	parentDB := versiondb.New(db)
+	left, right := versiondb.New(parentDB), versiondb.New(parentDB)
+	leftState, rightState := SamaNew(left, g), SamaNew(right, g)
+
+	if err := leftState.PutStaker(&StakerMeta{
+		StakerType: stakerTypeRoute,
+		StakerAddr: staker,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := leftState.PutPow(powTypeRoute, &ProofMeta{Netflow: 1, WorkTime: 10, Miner: staker}); err != nil {
+		t.Fatal(err)
+	}
+	if err := rightState.PutAction(actionID, &ActionMeta{ActionID: actionID, EndTime: 100}); err != nil {
+		t.Fatal(err)
+	}
+
+	if routes, _, _ := leftState.GetStakersNum(); routes != 1 {
+		t.Fatalf("left fork expected 1 route, got %d", routes)
+	}
+	if routes, _, _ := rightState.GetStakersNum(); routes != 0 {
+		t.Fatalf("right fork expected no routes, got %d", routes)
+	}
+	if total, _ := rightState.TotalPowTime(powTypeRoute); total != 0 {
+		t.Fatalf("right fork expected no pow, got %d", total)
+	}
+	if _, exist, _ := leftState.GetActionMeta(actionID, 0); exist {
+		t.Fatal("left fork observed an action of the right fork")
+	}
+
+	// Accepting the left fork only advances the accepted view
+	accepted := SamaNew(db, g)
+	if ok, _ := accepted.IsRoute(staker); ok {
+		t.Fatal("accepted view observed a processing block")
+	}
+	if err := left.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := parentDB.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := accepted.IsRoute(staker); !ok {
+		t.Fatal("accepted view missing staker after accept")
+	}
+	if total, _ := accepted.TotalPowTime(powTypeRoute); total != 10 {
+		t.Fatalf("accepted view expected pow 10, got %d", total)
+	}
+	if _, exist, _ := accepted.GetActionMeta(actionID, 0); exist {
+		t.Fatal("accepted view observed a rejected block")
+	}
+}
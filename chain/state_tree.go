@@ -0,0 +1,455 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ava-labs/avalanchego/utils/units"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	log "github.com/inconshreveable/log15"
+)
+
+// The state tree is a sparse Merkle tree over the keccak256 hashes of all
+// state keys (see [SyncPrefixes]). A subtree holding a single key is stored
+// as a leaf at the top of the subtree, so lookups only descend as deep as
+// needed to tell keys apart.
+//
+// 0x17/[depth][path] (tree nodes)
+//   -> leaf: 0x0 + [key hash] + [value hash]
+//   -> internal: 0x1 + [left hash] + [right hash]
+//
+// The hash of a node is the keccak256 hash of its encoding and the hash of an
+// empty subtree is the zero hash.
+
+const (
+	leafNode     = 0x0
+	internalNode = 0x1
+
+	treeNodeLen = 1 + 2*common.HashLength
+	treeDepth   = 8 * common.HashLength
+
+	treeBatchSize = 4 * units.MiB
+)
+
+var (
+	stateTreeVersion = []byte("state_tree_version")
+
+	ErrInvalidStateProof = errors.New("invalid state proof")
+	ErrInvalidTreeNode   = errors.New("invalid tree node")
+)
+
+const currentStateTreeVersion = 1
+
+// [treePrefix] + [delimiter] + [depth] + [path]
+func PrefixTreeNodeKey(depth int, keyHash common.Hash) (k []byte) {
+	k = make([]byte, 4+common.HashLength)
+	k[0] = treePrefix
+	k[1] = ByteDelimiter
+	binary.BigEndian.PutUint16(k[2:], uint16(depth))
+	copy(k[4:], keyHash[:depth/8])
+	if r := depth % 8; r > 0 {
+		k[4+depth/8] = keyHash[depth/8] & ^byte(0xff>>r)
+	}
+	return
+}
+
+// isStateKey reports whether [k] is part of the state committed to by the
+// state root
+func isStateKey(k []byte) bool {
+	return IsSyncKey(k) || (len(k) > 1 && k[0] == treePrefix && k[1] == ByteDelimiter)
+}
+
+func bit(h common.Hash, i int) byte {
+	return (h[i/8] >> (7 - i%8)) & 1
+}
+
+func leaf(keyHash common.Hash, valueHash common.Hash) []byte {
+	n := make([]byte, treeNodeLen)
+	n[0] = leafNode
+	copy(n[1:], keyHash[:])
+	copy(n[1+common.HashLength:], valueHash[:])
+	return n
+}
+
+func internal(left common.Hash, right common.Hash) []byte {
+	n := make([]byte, treeNodeLen)
+	n[0] = internalNode
+	copy(n[1:], left[:])
+	copy(n[1+common.HashLength:], right[:])
+	return n
+}
+
+func nodeHash(n []byte) common.Hash {
+	if n == nil {
+		return common.Hash{}
+	}
+	return common.BytesToHash(crypto.Keccak256(n))
+}
+
+// children returns the hashes stored in an internal node or the key and
+// value hashes stored in a leaf
+func children(n []byte) (common.Hash, common.Hash) {
+	return common.BytesToHash(n[1 : 1+common.HashLength]), common.BytesToHash(n[1+common.HashLength:])
+}
+
+type stateTree struct {
+	db database.KeyValueReaderWriterDeleter
+}
+
+func (t *stateTree) get(depth int, keyHash common.Hash) ([]byte, error) {
+	return getTreeNode(t.db, depth, keyHash)
+}
+
+func getTreeNode(db database.KeyValueReader, depth int, keyHash common.Hash) ([]byte, error) {
+	n, err := db.Get(PrefixTreeNodeKey(depth, keyHash))
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(n) != treeNodeLen || n[0] > internalNode {
+		return nil, ErrInvalidTreeNode
+	}
+	return n, nil
+}
+
+func (t *stateTree) put(depth int, keyHash common.Hash, n []byte) error {
+	if n == nil {
+		return t.db.Delete(PrefixTreeNodeKey(depth, keyHash))
+	}
+	return t.db.Put(PrefixTreeNodeKey(depth, keyHash), n)
+}
+
+// update sets the value hash of [keyHash] (or removes it if [valueHash] is
+// nil) in the subtree at [depth] and returns the new root node of the
+// subtree.
+func (t *stateTree) update(depth int, keyHash common.Hash, valueHash *common.Hash) ([]byte, error) {
+	n, err := t.get(depth, keyHash)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case n == nil:
+		if valueHash == nil {
+			return nil, nil
+		}
+		n = leaf(keyHash, *valueHash)
+		return n, t.put(depth, keyHash, n)
+
+	case n[0] == leafNode:
+		k, _ := children(n)
+		if k == keyHash {
+			if valueHash != nil {
+				n = leaf(keyHash, *valueHash)
+			} else {
+				n = nil
+			}
+			return n, t.put(depth, keyHash, n)
+		}
+		if valueHash == nil {
+			return n, nil
+		}
+		// Push the existing leaf down and continue as if this was an
+		// internal node
+		if err := t.put(depth+1, k, n); err != nil {
+			return nil, err
+		}
+	}
+
+	child, err := t.update(depth+1, keyHash, valueHash)
+	if err != nil {
+		return nil, err
+	}
+	sibling := keyHash
+	sibling[depth/8] ^= 1 << (7 - depth%8)
+	other, err := t.get(depth+1, sibling)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case child == nil && other == nil:
+		n = nil
+	case child == nil && other[0] == leafNode:
+		// Move a lone leaf up
+		if err := t.put(depth+1, sibling, nil); err != nil {
+			return nil, err
+		}
+		n = other
+	case other == nil && child[0] == leafNode:
+		if err := t.put(depth+1, keyHash, nil); err != nil {
+			return nil, err
+		}
+		n = child
+	case bit(keyHash, depth) == 0:
+		n = internal(nodeHash(child), nodeHash(other))
+	default:
+		n = internal(nodeHash(other), nodeHash(child))
+	}
+	return n, t.put(depth, keyHash, n)
+}
+
+// StateRoot returns the root of the state tree in [db]
+func StateRoot(db database.KeyValueReader) (common.Hash, error) {
+	n, err := getTreeNode(db, 0, common.Hash{})
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return nodeHash(n), nil
+}
+
+// UpdateStateTree applies the state keys written to [changes] (a batch of
+// the writes made to [db]) to the state tree in [db] and returns the new
+// state root.
+func UpdateStateTree(db database.KeyValueReaderWriterDeleter, changes database.Batch) (common.Hash, error) {
+	r := &keyRecorder{keys: make(map[string]struct{})}
+	if err := changes.Replay(r); err != nil {
+		return common.Hash{}, err
+	}
+	keys := make([]string, 0, len(r.keys))
+	for k := range r.keys {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	t := &stateTree{db: db}
+	for _, k := range keys {
+		var valueHash *common.Hash
+		v, err := db.Get([]byte(k))
+		switch {
+		case err == nil:
+			h := common.BytesToHash(crypto.Keccak256(v))
+			valueHash = &h
+		case !errors.Is(err, database.ErrNotFound):
+			return common.Hash{}, err
+		}
+		if _, err := t.update(0, common.BytesToHash(crypto.Keccak256([]byte(k))), valueHash); err != nil {
+			return common.Hash{}, err
+		}
+	}
+	return StateRoot(db)
+}
+
+type keyRecorder struct {
+	keys map[string]struct{}
+}
+
+func (r *keyRecorder) Put(k []byte, _ []byte) error {
+	if IsSyncKey(k) {
+		r.keys[string(k)] = struct{}{}
+	}
+	return nil
+}
+
+func (r *keyRecorder) Delete(k []byte) error { return r.Put(k, nil) }
+
+type treeLeaf struct {
+	keyHash   common.Hash
+	valueHash common.Hash
+}
+
+// BuildStateTree recreates the state tree from all state keys in [db] and
+// returns its root.
+func BuildStateTree(db database.Database) (common.Hash, error) {
+	start := time.Now()
+	if err := clearPrefix(db, treePrefix); err != nil {
+		return common.Hash{}, err
+	}
+	leaves := []treeLeaf{}
+	for _, p := range SyncPrefixes {
+		it := db.NewIteratorWithPrefix([]byte{p, ByteDelimiter})
+		for it.Next() {
+			leaves = append(leaves, treeLeaf{
+				keyHash:   common.BytesToHash(crypto.Keccak256(it.Key())),
+				valueHash: common.BytesToHash(crypto.Keccak256(it.Value())),
+			})
+		}
+		err := it.Error()
+		it.Release()
+		if err != nil {
+			return common.Hash{}, err
+		}
+	}
+	sort.Slice(leaves, func(i, j int) bool {
+		return bytes.Compare(leaves[i].keyHash[:], leaves[j].keyHash[:]) < 0
+	})
+
+	batch := db.NewBatch()
+	n, err := buildSubtree(batch, 0, leaves)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if err := batch.Write(); err != nil {
+		return common.Hash{}, err
+	}
+	root := nodeHash(n)
+	log.Info("built state tree", "keys", len(leaves), "root", root, "t", time.Since(start))
+	return root, nil
+}
+
+// buildSubtree writes the subtree at [depth] holding the sorted [leaves]
+func buildSubtree(batch database.Batch, depth int, leaves []treeLeaf) ([]byte, error) {
+	var n []byte
+	switch len(leaves) {
+	case 0:
+		return nil, nil
+	case 1:
+		n = leaf(leaves[0].keyHash, leaves[0].valueHash)
+	default:
+		split := sort.Search(len(leaves), func(i int) bool { return bit(leaves[i].keyHash, depth) == 1 })
+		left, err := buildSubtree(batch, depth+1, leaves[:split])
+		if err != nil {
+			return nil, err
+		}
+		right, err := buildSubtree(batch, depth+1, leaves[split:])
+		if err != nil {
+			return nil, err
+		}
+		n = internal(nodeHash(left), nodeHash(right))
+	}
+	if err := batch.Put(PrefixTreeNodeKey(depth, leaves[0].keyHash), n); err != nil {
+		return nil, err
+	}
+	if batch.Size() > treeBatchSize {
+		if err := batch.Write(); err != nil {
+			return nil, err
+		}
+		batch.Reset()
+	}
+	return n, nil
+}
+
+// EnsureStateTree builds the state tree of a database written before it
+// existed. It is a no-op once the tree is maintained by the chain.
+func EnsureStateTree(db database.Database) error {
+	version, err := getCount(db, stateTreeVersion)
+	if err != nil {
+		return err
+	}
+	if version >= currentStateTreeVersion {
+		return nil
+	}
+	if _, err := BuildStateTree(db); err != nil {
+		return err
+	}
+	v := make([]byte, 8)
+	binary.BigEndian.PutUint64(v, currentStateTreeVersion)
+	return db.Put(stateTreeVersion, v)
+}
+
+// StateProof proves the value of [Key] (or its absence) under a state root
+type StateProof struct {
+	Key   []byte `serialize:"true" json:"key"`
+	Value []byte `serialize:"true" json:"value"`
+	Found bool   `serialize:"true" json:"found"`
+
+	// Siblings are the hashes next to the path of [Key], from the root down
+	Siblings []common.Hash `serialize:"true" json:"siblings"`
+
+	// Leaf is the leaf found in place of [Key] when it is absent, if any
+	LeafKeyHash   common.Hash `serialize:"true" json:"leafKeyHash"`
+	LeafValueHash common.Hash `serialize:"true" json:"leafValueHash"`
+}
+
+// GetStateProof returns the proof of [k] in the state tree in [db]
+func GetStateProof(db database.KeyValueReader, k []byte) (*StateProof, error) {
+	if !IsSyncKey(k) {
+		return nil, ErrInvalidKeyFormat
+	}
+	keyHash := common.BytesToHash(crypto.Keccak256(k))
+	proof := &StateProof{Key: k}
+	for depth := 0; depth < treeDepth; depth++ {
+		n, err := getTreeNode(db, depth, keyHash)
+		if err != nil {
+			return nil, err
+		}
+		if n == nil {
+			return proof, nil
+		}
+		l, r := children(n)
+		if n[0] == leafNode {
+			if l != keyHash {
+				proof.LeafKeyHash, proof.LeafValueHash = l, r
+				return proof, nil
+			}
+			v, err := db.Get(k)
+			if err != nil {
+				return nil, err
+			}
+			proof.Value, proof.Found = v, true
+			return proof, nil
+		}
+		if bit(keyHash, depth) == 0 {
+			proof.Siblings = append(proof.Siblings, r)
+		} else {
+			proof.Siblings = append(proof.Siblings, l)
+		}
+	}
+	return nil, ErrInvalidTreeNode
+}
+
+// Verify checks [p] against [root]
+func (p *StateProof) Verify(root common.Hash) error {
+	keyHash := common.BytesToHash(crypto.Keccak256(p.Key))
+	depth := len(p.Siblings)
+	if depth > treeDepth {
+		return ErrInvalidStateProof
+	}
+
+	var h common.Hash
+	switch {
+	case p.Found:
+		h = nodeHash(leaf(keyHash, common.BytesToHash(crypto.Keccak256(p.Value))))
+	case p.LeafKeyHash != (common.Hash{}):
+		// The leaf must be in the subtree of [Key]
+		if p.LeafKeyHash == keyHash || !bytes.Equal(
+			PrefixTreeNodeKey(depth, p.LeafKeyHash),
+			PrefixTreeNodeKey(depth, keyHash),
+		) {
+			return ErrInvalidStateProof
+		}
+		h = nodeHash(leaf(p.LeafKeyHash, p.LeafValueHash))
+	}
+	for i := depth - 1; i >= 0; i-- {
+		if bit(keyHash, i) == 0 {
+			h = nodeHash(internal(h, p.Siblings[i]))
+		} else {
+			h = nodeHash(internal(p.Siblings[i], h))
+		}
+	}
+	if h != root {
+		return ErrInvalidStateProof
+	}
+	return nil
+}
+
+func clearPrefix(db database.Database, prefix byte) error {
+	it := db.NewIteratorWithPrefix([]byte{prefix, ByteDelimiter})
+	defer it.Release()
+
+	batch := db.NewBatch()
+	for it.Next() {
+		if err := batch.Delete(it.Key()); err != nil {
+			return err
+		}
+		if batch.Size() > treeBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	return batch.Write()
+}
@@ -0,0 +1,115 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/database/memdb"
+	"github.com/ava-labs/avalanchego/database/versiondb"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestStateTree(t *testing.T) {
+	t.Parallel()
+
+	db := memdb.New()
+	defer db.Close()
+	r := rand.New(rand.NewSource(1)) //nolint:gosec
+
+	addrs := make([]common.Address, 200)
+	for i := range addrs {
+		r.Read(addrs[i][:])
+	}
+
+	// Apply random blocks of changes and compare the incrementally updated
+	// tree with one built from scratch
+	for blk := 0; blk < 20; blk++ {
+		vdb := versiondb.New(db)
+		for i := 0; i < 30; i++ {
+			addr := addrs[r.Intn(len(addrs))]
+			if r.Intn(3) == 0 {
+				if err := vdb.Delete(PrefixBalanceKey(addr)); err != nil {
+					t.Fatal(err)
+				}
+				continue
+			}
+			if err := SetBalance(vdb, addr, r.Uint64()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		root, err := commitState(vdb)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := vdb.Commit(); err != nil {
+			t.Fatal(err)
+		}
+
+		rebuilt := memdb.New()
+		it := db.NewIterator()
+		for it.Next() {
+			if err := rebuilt.Put(it.Key(), it.Value()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		it.Release()
+		expected, err := BuildStateTree(rebuilt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if root != expected {
+			t.Fatalf("block %d: incremental root %s != rebuilt root %s", blk, root, expected)
+		}
+		if root2, _ := StateRoot(rebuilt); root2 != expected {
+			t.Fatalf("block %d: stored root %s != built root %s", blk, root2, expected)
+		}
+	}
+
+	root, err := StateRoot(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found, missing := 0, 0
+	for _, addr := range addrs {
+		k := PrefixBalanceKey(addr)
+		proof, err := GetStateProof(db, k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := proof.Verify(root); err != nil {
+			t.Fatalf("valid proof of %s rejected: %v", addr, err)
+		}
+		has, _ := db.Has(k)
+		if proof.Found != has {
+			t.Fatalf("proof of %s found=%t, expected %t", addr, proof.Found, has)
+		}
+		if !proof.Found {
+			missing++
+			continue
+		}
+		found++
+
+		// Tampered proofs are rejected
+		proof.Value = append([]byte(nil), proof.Value...)
+		proof.Value[7]++
+		if err := proof.Verify(root); err != ErrInvalidStateProof {
+			t.Fatalf("expected %v for tampered value, got %v", ErrInvalidStateProof, err)
+		}
+		proof.Value[7]--
+		proof.Found = false
+		if err := proof.Verify(root); err != ErrInvalidStateProof {
+			t.Fatalf("expected %v for hidden value, got %v", ErrInvalidStateProof, err)
+		}
+	}
+	if found == 0 || missing == 0 {
+		t.Fatalf("expected both present and absent keys, got %d/%d", found, missing)
+	}
+
+	// Only state keys have proofs
+	if _, err := GetStateProof(db, lastAccepted); err != ErrInvalidKeyFormat {
+		t.Fatalf("expected %v, got %v", ErrInvalidKeyFormat, err)
+	}
+}
@@ -56,6 +56,25 @@ const (
 
 	userTypesPrefix = 0x13
 
+	indexPrefix = 0x14
+
+	stateDiffPrefix   = 0x15
+	syncSummaryPrefix = 0x16
+
+	treePrefix     = 0x17
+	archivePrefix  = 0x18
+	receiptPrefix  = 0x19
+	activityPrefix = 0x1a
+	heightPrefix   = 0x1b
+
+	suspensionsPrefix = 0x1c
+	delegationsPrefix = 0x1d
+	multisigsPrefix   = 0x1e
+
+	stakeDelegationsPrefix = 0x1f
+	unbondingsPrefix       = 0x20
+	slashesPrefix          = 0x21
+
 	linkedTxLRUSize = 512
 
 	ByteDelimiter byte = '/'
@@ -227,17 +246,26 @@ func SetLastAccepted(db database.KeyValueWriter, block *StatelessBlock) error {
 	if err := db.Put(lastAccepted, bid[:]); err != nil {
 		return err
 	}
+	return PutBlock(db, block)
+}
+
+// PutBlock stores [block] without marking it as last accepted
+func PutBlock(db database.KeyValueWriter, block *StatelessBlock) error {
+	bid := block.ID()
 	ogTxs, err := linkValues(db, block)
 	if err != nil {
 		return err
 	}
-	sbytes, err := Marshal(block.StatefulBlock)
+	sbytes, err := marshalVersion(block.version, block.StatefulBlock)
 	if err != nil {
 		return err
 	}
 	if err := db.Put(PrefixBlockKey(bid), sbytes); err != nil {
 		return err
 	}
+	if err := db.Put(PrefixHeightKey(block.Hght), bid[:]); err != nil {
+		return err
+	}
 	// Restore the original transactions in the block in case it is cached for
 	// later use.
 	block.Txs = ogTxs
@@ -265,9 +293,11 @@ func GetBlock(db database.KeyValueReader, bid ids.ID) (*StatefulBlock, error) {
 		return nil, err
 	}
 	blk := new(StatefulBlock)
-	if _, err := Unmarshal(b, blk); err != nil {
+	version, err := Unmarshal(b, blk)
+	if err != nil {
 		return nil, err
 	}
+	blk.version = version
 	if err := restoreValues(db, blk); err != nil {
 		return nil, err
 	}
@@ -5,10 +5,10 @@ package chain
 
 import (
 	"fmt"
-	"strconv"
 
 	"github.com/ava-labs/avalanchego/database"
 	"github.com/ava-labs/avalanchego/ids"
+	log "github.com/inconshreveable/log15"
 )
 
 const (
@@ -62,10 +62,7 @@ type SysParamsMeta struct {
 }
 
 type SysParams interface {
-	ReloadParams(db database.Database) error
-	ModifyParams(db database.Database, key string, newValue string, txID ids.ID, updateTime uint64) error
-	CacheParamsCommit() error
-	CacheParamsAbort() error
+	ModifyParams(key string, newValue string, txID ids.ID, updateTime uint64) error
 	GetChainSymbol() string
 	GetChainTokenAmount() uint64
 	GetPercRoute() uint32
@@ -84,239 +81,208 @@ type SysParams interface {
 	GetSeasonCardPrice() uint64
 	GetAnnualCardPrice() uint64
 	GetMinStakeTime() uint64
+	GetRouteStakeAmount() uint64
+	GetSerStakeAmount() uint64
 	GetRootAddress() string
 	GetSysParams() *SysParamsMeta
 	CompCurParam(key string, newValue string) error
 	GetFoundationAddress() string
+	// GetFoundationSchedule returns the cliff and vesting seconds of the
+	// foundation share, they are fixed at genesis
+	GetFoundationSchedule() (uint64, uint64)
+	// LoadFoundationPerc sets the foundation share of the genesis, which
+	// is not loaded before [ForkPhase4]
+	LoadFoundationPerc() error
+	// GetUnbondingTime is fixed at genesis
+	GetUnbondingTime() uint64
+	GetVotingPolicy(actionType uint64) (*VotingPolicy, error)
+	PutVotingPolicy(policy *VotingPolicy) error
+	GetSlashingPolicy(offenceType uint64) (*SlashingPolicy, error)
+	PutSlashingPolicy(policy *SlashingPolicy) error
 }
 
 type sysParams struct {
-	curParams     *SysParamsMeta
-	pendingParams *SysParamsMeta
-}
-
-func NewSysParamsState(db database.Database, genesis *Genesis) (*sysParams, error) {
-	curParams := &SysParamsMeta{
-		TotalTokens:      genesis.TotalTokens,
-		ClaimMinUnits:    genesis.ClaimMinUnits,
-		ChainCreateTime:  genesis.ChainCreateTime,
-		ClaimMinInterval: genesis.ClaimMinInterval,
-		TotalYears:       genesis.TotalYears,
-		RateSustainYears: genesis.RateSustainYears,
-		RoutePerc:        genesis.RoutePerc,
-		SerPerc:          genesis.SerPerc,
-		MinerPerc:        genesis.MinerPerc,
-		BaseSerPerc:      genesis.BaseSerPerc,
-		MeritSerPerc:     genesis.MeritSerPerc,
-		BaseRoutePerc:    genesis.BaseRoutePerc,
-		MeritRoutePerc:   genesis.MeritRoutePerc,
-
-		RouteStakeAmount: genesis.RouteStake,
-		SerStakeAmount:   genesis.SerStake,
-		RootAddress:      genesis.RootAddress,
-		BurnPerc:         genesis.BurnPerc,
-		MonthCard:        genesis.MonthCard,
-		SeasonCard:       genesis.SeasonCard,
-		AnnualCard:       genesis.AnnualCard,
-		MinStakeTime:     genesis.MinStakeTime,
-	}
-	pendingParams := &SysParamsMeta{
-		TotalTokens: 0,
+	db      database.Database
+	genesis *Genesis
+}
+
+func NewSysParamsState(db database.Database, genesis *Genesis) *sysParams {
+	return &sysParams{
+		db:      db,
+		genesis: genesis,
 	}
-	state := &sysParams{
-		curParams:     curParams,
-		pendingParams: pendingParams,
+}
+
+// params returns the governed parameters, falling back to genesis when no
+// governance change has been made yet.
+func (s *sysParams) params() *SysParamsMeta {
+	ymeta := &SysParamsMeta{
+		TotalTokens:      s.genesis.TotalTokens,
+		ClaimMinUnits:    s.genesis.ClaimMinUnits,
+		ChainCreateTime:  s.genesis.ChainCreateTime,
+		ClaimMinInterval: s.genesis.ClaimMinInterval,
+		TotalYears:       s.genesis.TotalYears,
+		RateSustainYears: s.genesis.RateSustainYears,
+		RoutePerc:        s.genesis.RoutePerc,
+		SerPerc:          s.genesis.SerPerc,
+		MinerPerc:        s.genesis.MinerPerc,
+		BaseSerPerc:      s.genesis.BaseSerPerc,
+		MeritSerPerc:     s.genesis.MeritSerPerc,
+		BaseRoutePerc:    s.genesis.BaseRoutePerc,
+		MeritRoutePerc:   s.genesis.MeritRoutePerc,
+
+		RouteStakeAmount: s.genesis.RouteStake,
+		SerStakeAmount:   s.genesis.SerStake,
+		RootAddress:      s.genesis.RootAddress,
+		FoundationAddr:   s.genesis.FoundationAddr,
+		BurnPerc:         s.genesis.BurnPerc,
+		MonthCard:        s.genesis.MonthCard,
+		SeasonCard:       s.genesis.SeasonCard,
+		AnnualCard:       s.genesis.AnnualCard,
+		MinStakeTime:     s.genesis.MinStakeTime,
 	}
-	err := state.ReloadParams(db)
-	if err != nil {
-		if err != database.ErrNotFound {
-			return nil, err
-		}
+	if _, err := getState(s.db, PrefixSysParamsKey(), ymeta); err != nil {
+		log.Warn("failed to load system params", "err", err)
 	}
-	return state, nil
+	return ymeta
 }
 
 func (s *sysParams) GetChainSymbol() string {
-	return s.curParams.Symbol
+	return s.params().Symbol
 }
 
 func (s *sysParams) GetChainTokenAmount() uint64 {
-	return (s.curParams.TotalTokens / 100) * uint64(s.curParams.MinerPerc)
+	params := s.params()
+	return (params.TotalTokens / 100) * uint64(params.MinerPerc)
 }
 
 func (s *sysParams) GetPercRoute() uint32 {
-	return s.curParams.RoutePerc
+	return s.params().RoutePerc
 }
 
 func (s *sysParams) GetPercSer() uint32 {
-	return s.curParams.SerPerc
+	return s.params().SerPerc
 }
 
 func (s *sysParams) GetPercValidator() uint32 {
-	return s.curParams.ValidatorPerc
+	return s.params().ValidatorPerc
 }
 
 func (s *sysParams) GetRoutePercBase() uint32 {
-	return s.curParams.BaseRoutePerc
+	return s.params().BaseRoutePerc
 }
 
 func (s *sysParams) GetRoutePercMerit() uint32 {
-	return s.curParams.MeritRoutePerc
+	return s.params().MeritRoutePerc
 }
 
 func (s *sysParams) GetSerPercBase() uint32 {
-	return s.curParams.BaseSerPerc
+	return s.params().BaseSerPerc
 }
 
 func (s *sysParams) GetSerPercMerit() uint32 {
-	return s.curParams.MeritSerPerc
+	return s.params().MeritSerPerc
 }
 
 func (s *sysParams) GetPercBurn() uint32 {
-	return s.curParams.BurnPerc
+	return s.params().BurnPerc
 }
 
 func (s *sysParams) GetPercFoundation() uint32 {
-	return s.curParams.FoundationPerc
+	return s.params().FoundationPerc
 }
 
 func (s *sysParams) GetTotalYears() uint32 {
-	return s.curParams.TotalYears
+	return s.params().TotalYears
 }
 
 func (s *sysParams) GetRateSustainYears() uint32 {
-	return s.curParams.RateSustainYears
+	return s.params().RateSustainYears
 }
 
 func (s *sysParams) GetChainCreateTime() uint64 {
-	return s.curParams.ChainCreateTime
+	return s.params().ChainCreateTime
 }
 
 func (s *sysParams) GetMonthCardPrice() uint64 {
-	return s.curParams.MonthCard
+	return s.params().MonthCard
 }
 
 func (s *sysParams) GetSeasonCardPrice() uint64 {
-	return s.curParams.SeasonCard
+	return s.params().SeasonCard
 }
 
 func (s *sysParams) GetAnnualCardPrice() uint64 {
-	return s.curParams.AnnualCard
+	return s.params().AnnualCard
 }
 
 func (s *sysParams) GetRootAddress() string {
-	return s.curParams.RootAddress
+	return s.params().RootAddress
 }
 
 func (s *sysParams) GetFoundationAddress() string {
-	return s.curParams.FoundationAddr
+	return s.params().FoundationAddr
 }
 
-func (s *sysParams) GetMinStakeTime() uint64 {
-	return s.curParams.MinStakeTime
+func (s *sysParams) GetFoundationSchedule() (uint64, uint64) {
+	return s.genesis.FoundationCliff, s.genesis.FoundationVesting
 }
 
-func (s *sysParams) GetSysParams() *SysParamsMeta {
-	return s.curParams
+func (s *sysParams) LoadFoundationPerc() error {
+	ymeta := s.params()
+	ymeta.FoundationPerc = s.genesis.FoundationPerc
+	return putState(s.db, PrefixSysParamsKey(), ymeta)
 }
 
-func (s *sysParams) ModifyParams(db database.Database, key string, newValue string, txID ids.ID, updateTime uint64) error {
-	ymeta := new(SysParamsMeta)
-	*ymeta = *(s.curParams)
+func (s *sysParams) GetUnbondingTime() uint64 {
+	return s.genesis.UnbondingTime
+}
 
-	perc, err := strconv.ParseUint(newValue, 10, 64)
-	if err != nil {
-		return fmt.Errorf("%w: failed to parse newValue %s", err, newValue)
-	}
-	newPerc := uint32(perc)
-	if newPerc > MaxPercentage || newPerc < MinPercentage {
-		return fmt.Errorf("percentage err")
-	}
-	ymeta.UpdateTxID = txID
-	ymeta.UpdateTime = updateTime
+func (s *sysParams) GetMinStakeTime() uint64 {
+	return s.params().MinStakeTime
+}
 
-	if key == "routePerc" {
-		ymeta.RoutePerc = newPerc
-		ymeta.SerPerc = 100 - newPerc
-	} else if key == "serPerc" {
-		ymeta.SerPerc = newPerc
-		ymeta.RoutePerc = 100 - newPerc
-	} else if key == "routeBase" {
-		ymeta.BaseRoutePerc = newPerc
-		ymeta.MeritRoutePerc = 100 - newPerc
-	} else if key == "routeMerit" {
-		ymeta.MeritRoutePerc = newPerc
-		ymeta.BaseRoutePerc = 100 - newPerc
-	} else if key == "serBase" {
-		ymeta.BaseSerPerc = newPerc
-		ymeta.MeritSerPerc = 100 - newPerc
-	} else if key == "serMerit" {
-		ymeta.MeritSerPerc = newPerc
-		ymeta.BaseSerPerc = 100 - newPerc
-	} else if key == "burn" {
-		ymeta.BurnPerc = newPerc
-	} else {
-		return fmt.Errorf("err key %s", key)
-	}
-	*s.pendingParams = *ymeta
+func (s *sysParams) GetRouteStakeAmount() uint64 {
+	return s.params().RouteStakeAmount
+}
 
-	k := PrefixSysParamsKey()
-	pvmeta, err := Marshal(ymeta)
-	if err != nil {
-		return err
-	}
-	return db.Put(k, pvmeta)
+func (s *sysParams) GetSerStakeAmount() uint64 {
+	return s.params().SerStakeAmount
+}
+
+func (s *sysParams) GetSysParams() *SysParamsMeta {
+	return s.params()
 }
 
-func (s *sysParams) ReloadParams(db database.Database) error {
-	k := PrefixSysParamsKey()
-	ymeta, err := db.Get(k)
+// ModifyParams sets the param [key] to [newValue] as described by its
+// [ParamSchema]
+func (s *sysParams) ModifyParams(key string, newValue string, txID ids.ID, updateTime uint64) error {
+	schema, err := GetParamSchema(key)
 	if err != nil {
-		return nil
-	}
-	if _, err := Unmarshal(ymeta, s.curParams); err != nil {
 		return err
 	}
-	return nil
-}
-
-func (s *sysParams) CacheParamsCommit() error {
-	if s.pendingParams.TotalTokens != 0 {
-		*s.curParams = *s.pendingParams
-		s.pendingParams.TotalTokens = 0
+	ymeta := s.params()
+	if err := schema.Set(ymeta, newValue); err != nil {
+		return err
 	}
-	return nil
-}
-
-func (s *sysParams) CacheParamsAbort() error {
-	s.pendingParams.TotalTokens = 0
-	return nil
+	ymeta.UpdateTxID = txID
+	ymeta.UpdateTime = updateTime
+	return putState(s.db, PrefixSysParamsKey(), ymeta)
 }
 
+// CompCurParam checks that [newValue] is valid for the param [key] and
+// differs from its current value
 func (s *sysParams) CompCurParam(key string, newValue string) error {
-	oldPerc := uint32(0)
-	newPerc, err := strconv.ParseUint(newValue, 10, 64)
+	schema, err := GetParamSchema(key)
 	if err != nil {
-		return fmt.Errorf("%w: failed to parse newValue %s", err, newValue)
+		return err
 	}
-	if key == "routePerc" {
-		oldPerc = s.curParams.RoutePerc
-	} else if key == "serPerc" {
-		oldPerc = s.curParams.SerPerc
-	} else if key == "routeBase" {
-		oldPerc = s.curParams.BaseRoutePerc
-	} else if key == "routeMerit" {
-		oldPerc = s.curParams.MeritRoutePerc
-	} else if key == "serBase" {
-		oldPerc = s.curParams.BaseSerPerc
-	} else if key == "serMerit" {
-		oldPerc = s.curParams.MeritSerPerc
-	} else if key == "burn" {
-		oldPerc = s.curParams.BurnPerc
-	} else {
-		return fmt.Errorf("err key %s", key)
+	params := s.params()
+	updated := *params
+	if err := schema.Set(&updated, newValue); err != nil {
+		return err
 	}
-	if uint64(oldPerc) == newPerc {
+	if schema.Value(&updated) == schema.Value(params) {
 		return fmt.Errorf("equal CurParam")
 	}
 	return nil
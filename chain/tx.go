@@ -43,7 +43,9 @@ func DigestHash(utx UnsignedTransaction) ([]byte, error) {
 }
 
 func (t *Transaction) Init(g *Genesis) error {
-	stx, err := Marshal(t)
+	// Encode with the oldest codec that can, so txs keep their IDs when the
+	// codec changes
+	stx, err := marshalVersion(minCodecVersion(t.UnsignedTransaction), t)
 	if err != nil {
 		return err
 	}
@@ -100,20 +102,31 @@ func (t *Transaction) Execute(g *Genesis, db database.Database, blk *StatelessBl
 		return ErrDuplicateTx
 	}
 
+	if context.Rules.IsPhase1 && t.GetPrice() < context.NextPrice {
+		return ErrInsufficientPrice
+	}
+	// The block must be able to encode the tx
+	if minCodecVersion(t.UnsignedTransaction) > blockCodecVersion(context.Rules) {
+		return ErrInvalidCodecVersion
+	}
+	// The tx type must exist in the block
+	if !context.Rules.Enables(t.UnsignedTransaction) {
+		return ErrInactiveFork
+	}
+
 	// Ensure sender has balance
 	if _, err := ModifyBalance(db, t.sender, false, t.FeeUnits(g)*t.GetPrice()); err != nil {
 		return err
 	}
-	if t.GetPrice() < context.NextPrice {
-		//return ErrInsufficientPrice
-	}
 	if err := t.UnsignedTransaction.Execute(&TransactionContext{
-		Genesis:   g,
-		Database:  db,
-		BlockTime: uint64(blk.Tmstmp),
-		TxID:      t.id,
-		Sender:    t.sender,
-		vm:        blk.vm,
+		Genesis:    g,
+		Database:   db,
+		BlockTime:  uint64(blk.Tmstmp),
+		ParentTime: uint64(context.ParentTimestamp),
+		TxID:       t.id,
+		Sender:     t.sender,
+		Rules:      context.Rules,
+		State:      SamaNew(db, g),
 	}); err != nil {
 		return err
 	}
@@ -9,9 +9,11 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/ava-labs/avalanchego/database"
 	"github.com/ava-labs/avalanchego/database/memdb"
 	"github.com/ava-labs/avalanchego/ids"
 	"github.com/ava-labs/avalanchego/utils/set"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 )
 
@@ -144,3 +146,31 @@ func createTestTx(t *testing.T, blockID ids.ID, priv *ecdsa.PrivateKey) *Transac
 
 	return tx
 }
+
+// testTxContexts returns the contexts of txs executed on [db] under [rules],
+// from a sender in a block at a timestamp
+func testTxContexts(g *Genesis, db database.Database, rules Rules) func(common.Address, uint64) *TransactionContext {
+	state := SamaNew(db, g)
+	return func(sender common.Address, blockTime uint64) *TransactionContext {
+		return &TransactionContext{
+			Genesis:   g,
+			Database:  db,
+			BlockTime: blockTime,
+			TxID:      ids.GenerateTestID(),
+			Sender:    sender,
+			Rules:     rules,
+			State:     state,
+		}
+	}
+}
+
+// checkBalance fails the test unless [addr] has [balance] and [staked]
+func checkBalance(t *testing.T, db database.Database, addr common.Address, balance uint64, staked uint64) {
+	t.Helper()
+	if b, err := GetBalance(db, addr); err != nil || b != balance {
+		t.Fatalf("expected balance %d, got %d (%v)", balance, b, err)
+	}
+	if b, err := GetStakeBalance(db, addr); err != nil || b != staked {
+		t.Fatalf("expected stake balance %d, got %d (%v)", staked, b, err)
+	}
+}
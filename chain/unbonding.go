@@ -0,0 +1,92 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"errors"
+
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// From [ForkPhase3] an [UnStakeTx] pays the reward up to its block and
+// starts unbonding the stake: it stays in the stake balance of the staker,
+// earning nothing, until a [WithdrawStakeTx] after [GetUnbondingTime].
+
+var (
+	ErrRewardTooLow = errors.New("reward below the amount expected")
+	ErrNoUnbonded   = errors.New("no unbonded stake to withdraw")
+)
+
+// Unbonding is the stake [Address] unstaked in [TxID]
+type Unbonding struct {
+	TxID        ids.ID         `serialize:"true" json:"txId"`
+	Address     common.Address `serialize:"true" json:"address"`
+	StakerType  uint64         `serialize:"true" json:"stakerType"`
+	Amount      uint64         `serialize:"true" json:"amount"`
+	StartTime   uint64         `serialize:"true" json:"startTime"`
+	ReleaseTime uint64         `serialize:"true" json:"releaseTime"`
+}
+
+// [unbondingsPrefix] + [delimiter] + [address] + [txID]
+func PrefixUnbondingKey(address common.Address, txID ids.ID) (k []byte) {
+	k = make([]byte, 2+common.AddressLength+len(txID))
+	k[0] = unbondingsPrefix
+	k[1] = ByteDelimiter
+	copy(k[2:], address[:])
+	copy(k[2+common.AddressLength:], txID[:])
+	return
+}
+
+func baseUnbondingPrefix(address common.Address) (k []byte) {
+	k = make([]byte, 2+common.AddressLength)
+	k[0] = unbondingsPrefix
+	k[1] = ByteDelimiter
+	copy(k[2:], address[:])
+	return
+}
+
+var _ UnbondingsState = &unbondingsState{}
+
+type UnbondingsState interface {
+	// GetUnbondings returns the unbondings of [address], or all of them if
+	// it is empty
+	GetUnbondings(address common.Address) ([]*Unbonding, error)
+	PutUnbonding(unbonding *Unbonding) error
+	DelUnbonding(address common.Address, txID ids.ID) error
+}
+
+type unbondingsState struct {
+	db database.Database
+}
+
+func NewUnbondingsState(db database.Database) *unbondingsState {
+	return &unbondingsState{db: db}
+}
+
+func (u *unbondingsState) GetUnbondings(address common.Address) ([]*Unbonding, error) {
+	prefix, suffixLen := baseUnbondingPrefix(address), len(ids.Empty)
+	if address == (common.Address{}) {
+		prefix, suffixLen = prefix[:2], common.AddressLength+len(ids.Empty)
+	}
+	unbondings := []*Unbonding(nil)
+	err := iterateState(u.db, prefix, suffixLen, func(_ []byte, v []byte) error {
+		pmeta := new(Unbonding)
+		if _, err := Unmarshal(v, pmeta); err != nil {
+			return err
+		}
+		unbondings = append(unbondings, pmeta)
+		return nil
+	})
+	return unbondings, err
+}
+
+func (u *unbondingsState) PutUnbonding(unbonding *Unbonding) error {
+	return putState(u.db, PrefixUnbondingKey(unbonding.Address, unbonding.TxID), unbonding)
+}
+
+func (u *unbondingsState) DelUnbonding(address common.Address, txID ids.ID) error {
+	return u.db.Delete(PrefixUnbondingKey(address, txID))
+}
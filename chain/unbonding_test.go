@@ -0,0 +1,80 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/database/memdb"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestUnbonding(t *testing.T) {
+	t.Parallel()
+
+	db := memdb.New()
+	defer db.Close()
+	g := DefaultGenesis()
+	if err := g.Load(db, nil); err != nil {
+		t.Fatal(err)
+	}
+	state := SamaNew(db, g)
+	route := common.Address{1}
+	stakeTime := g.ChainCreateTime + SecondsDay
+	if err := state.DealStakeTx(&StakerMeta{StakerType: stakerTypeRoute, StakeAmount: 1000, StakeTime: stakeTime, StakerAddr: route}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetStakeBalance(db, route, 1000); err != nil {
+		t.Fatal(err)
+	}
+	tctx := testTxContexts(g, db, Rules{IsPhase1: true, IsPhase2: true, IsPhase3: true})
+
+	// The reward ends at the block, whatever the end time of the tx
+	unstakeTime := stakeTime + g.MinStakeTime
+	reward, _, _, err := state.CalcReward(stakerTypeRoute, route, unstakeTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reward == 0 {
+		t.Fatal("expected a reward")
+	}
+	unstake := &UnStakeTx{BaseTx: &BaseTx{}, StakerType: stakerTypeRoute, RewardAmount: reward + 1, EndTime: 1}
+	if err := unstake.Execute(tctx(route, unstakeTime)); !errors.Is(err, ErrRewardTooLow) {
+		t.Fatalf("expected %v, got %v", ErrRewardTooLow, err)
+	}
+	unstake.RewardAmount = reward - 1
+	utctx := tctx(route, unstakeTime)
+	if err := unstake.Execute(utctx); err != nil {
+		t.Fatal(err)
+	}
+	checkBalance(t, db, route, reward, 1000)
+	if ok, _, err := state.IsStaker(route); err != nil || ok {
+		t.Fatalf("staker not removed (%v)", err)
+	}
+	unbondings, err := state.GetUnbondings(route)
+	if err != nil {
+		t.Fatal(err)
+	}
+	releaseTime := unstakeTime + g.UnbondingTime
+	if len(unbondings) != 1 || unbondings[0].Amount != 1000 || unbondings[0].ReleaseTime != releaseTime || unbondings[0].TxID != utctx.TxID {
+		t.Fatalf("unexpected unbondings %+v", unbondings)
+	}
+
+	// The stake is locked until the end of the period
+	withdraw := &WithdrawStakeTx{BaseTx: &BaseTx{}}
+	if err := withdraw.Execute(tctx(route, releaseTime-1)); !errors.Is(err, ErrNoUnbonded) {
+		t.Fatalf("expected %v, got %v", ErrNoUnbonded, err)
+	}
+	if err := withdraw.Execute(tctx(route, releaseTime)); err != nil {
+		t.Fatal(err)
+	}
+	checkBalance(t, db, route, reward+1000, 0)
+	if unbondings, err := state.GetUnbondings(common.Address{}); err != nil || len(unbondings) != 0 {
+		t.Fatalf("unbonding not removed %+v (%v)", unbondings, err)
+	}
+	if err := withdraw.Execute(tctx(route, releaseTime)); !errors.Is(err, ErrNoUnbonded) {
+		t.Fatalf("expected %v, got %v", ErrNoUnbonded, err)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/SamaNetwork/SamaVM/tdata"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var _ UnsignedTransaction = &UndelegateStakeTx{}
+
+// UndelegateStakeTx returns [Amount] of the stake the sender bonded to
+// [StakerAddr]. The weight it gathered is kept, so the sender still gets its
+// share of the next payment of the node reward.
+type UndelegateStakeTx struct {
+	*BaseTx    `serialize:"true" json:"baseTx"`
+	StakerAddr common.Address `serialize:"true" json:"stakerAddr"`
+	Amount     uint64         `serialize:"true" json:"amount"`
+}
+
+func (*UndelegateStakeTx) minFork() string {
+	return ForkPhase4
+}
+
+func (u *UndelegateStakeTx) Execute(t *TransactionContext) error {
+	samaState := t.State
+	if u.Amount == 0 {
+		return ErrStakeAmount
+	}
+	delegation, exist, err := samaState.GetStakeDelegation(u.StakerAddr, t.Sender)
+	if err != nil {
+		return err
+	}
+	if !exist {
+		return ErrNoDelegation
+	}
+	if u.Amount > delegation.Amount {
+		return fmt.Errorf("%w: %d delegated", ErrStakeAmount, delegation.Amount)
+	}
+
+	if _, err := ModifyStakeBalance(t.Database, t.Sender, false, u.Amount); err != nil {
+		return err
+	}
+	if _, err := ModifyBalance(t.Database, t.Sender, true, u.Amount); err != nil {
+		return err
+	}
+
+	delegation.accrue(t.BlockTime)
+	delegation.Amount -= u.Amount
+	delegation.TxID = t.TxID
+	if err := samaState.PutStakeDelegation(delegation); err != nil {
+		return err
+	}
+	pool, err := samaState.GetStakerPool(u.StakerAddr)
+	if err != nil {
+		return err
+	}
+	pool.Delegated -= u.Amount
+	return samaState.PutStakerPool(pool)
+}
+
+func (u *UndelegateStakeTx) FeeUnits(g *Genesis) uint64 {
+	return u.BaseTx.FeeUnits(g)
+}
+
+func (u *UndelegateStakeTx) LoadUnits(g *Genesis) uint64 {
+	return u.FeeUnits(g)
+}
+
+func (u *UndelegateStakeTx) Copy() UnsignedTransaction {
+	return &UndelegateStakeTx{
+		BaseTx:     u.BaseTx.Copy(),
+		StakerAddr: u.StakerAddr,
+		Amount:     u.Amount,
+	}
+}
+
+func (u *UndelegateStakeTx) TypedData() *tdata.TypedData {
+	return tdata.CreateTypedData(
+		u.Magic, UndelegateStake,
+		[]tdata.Type{
+			{Name: tdStakeAddr, Type: tdAddress},
+			{Name: tdAmount, Type: tdUint64},
+			{Name: tdPrice, Type: tdUint64},
+			{Name: tdBlockID, Type: tdString},
+		},
+		tdata.TypedDataMessage{
+			tdStakeAddr: u.StakerAddr.Hex(),
+			tdAmount:    strconv.FormatUint(u.Amount, 10),
+			tdPrice:     strconv.FormatUint(u.Price, 10),
+			tdBlockID:   u.BlockID.String(),
+		},
+	)
+}
+
+func (u *UndelegateStakeTx) Activity() *Activity {
+	return &Activity{
+		Typ:         UndelegateStake,
+		StakerAddr:  u.StakerAddr.Hex(),
+		StakeAmount: u.Amount,
+	}
+}
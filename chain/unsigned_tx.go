@@ -12,12 +12,18 @@ import (
 )
 
 type TransactionContext struct {
-	Genesis   *Genesis
-	Database  database.Database
-	BlockTime uint64
-	TxID      ids.ID
-	Sender    common.Address
-	vm        VM
+	Genesis    *Genesis
+	Database   database.Database
+	BlockTime  uint64
+	ParentTime uint64
+	TxID       ids.ID
+	Sender     common.Address
+
+	// Rules are the execution rules of the block
+	Rules Rules
+
+	// State is a view of the chain state over [Database]
+	State SamaState
 }
 
 type UnsignedTransaction interface {
@@ -6,7 +6,6 @@ package chain
 import (
 	"fmt"
 	"strconv"
-	"time"
 
 	"github.com/SamaNetwork/SamaVM/tdata"
 )
@@ -27,14 +26,20 @@ func (u *UnStakeTx) Execute(t *TransactionContext) error {
 		return ErrStakerType
 	}
 
-	samaState := t.vm.SamaState()
-
-	if time.Now().After(time.Unix(int64(u.EndTime+EffectiveSecs), 0)) {
-		return ErrEndTimeTooEarly
-	}
-
-	if time.Now().Before(time.Unix(int64(u.EndTime), 0)) {
-		return ErrEndTimeTooLate
+	samaState := t.State
+
+	// From [ForkPhase3] the reward ends at the block and [EndTime] is
+	// ignored
+	endTime := t.BlockTime
+	if !t.Rules.IsPhase3 {
+		// The reward end time must fall within [BlockTime-EffectiveSecs, BlockTime]
+		if t.BlockTime > u.EndTime+EffectiveSecs {
+			return ErrEndTimeTooEarly
+		}
+		if t.BlockTime < u.EndTime {
+			return ErrEndTimeTooLate
+		}
+		endTime = u.EndTime
 	}
 
 	staker, exists, err := samaState.GetStakerMeta(byte(u.StakerType), t.Sender)
@@ -44,31 +49,62 @@ func (u *UnStakeTx) Execute(t *TransactionContext) error {
 	if !exists {
 		return fmt.Errorf("not found")
 	}
+	_, suspended, err := samaState.GetSuspension(t.Sender)
+	if err != nil {
+		return err
+	}
+	if suspended {
+		return ErrSuspended
+	}
 
 	minTime := samaState.GetMinStakeTime()
 
-	if time.Now().Before(time.Unix(int64(staker.StakeTime+minTime), 0)) {
+	if t.BlockTime < staker.StakeTime+minTime {
 		return fmt.Errorf("stake time must > 90 days")
 	}
 
-	if _, err := ModifyStakeBalance(t.Database, t.Sender, false, staker.StakeAmount); err != nil {
-		return err
-	}
-
-	base, merit, yield, err := samaState.CalcReward(byte(u.StakerType), t.Sender, u.EndTime)
+	base, merit, yield, err := samaState.CalcReward(byte(u.StakerType), t.Sender, endTime)
 	if err != nil {
 		return err
 	}
 	totalReawrd := base + merit + yield
-	if u.RewardAmount != totalReawrd {
+	switch {
+	case t.Rules.IsPhase3 && totalReawrd < u.RewardAmount:
+		// [RewardAmount] is the least reward the sender accepts
+		return ErrRewardTooLow
+	case !t.Rules.IsPhase3 && u.RewardAmount != totalReawrd:
 		return fmt.Errorf("reward amount is err")
 	}
 
-	if _, err := ModifyBalance(t.Database, t.Sender, true, staker.StakeAmount+uint64(totalReawrd)); err != nil {
+	if t.Rules.IsPhase3 {
+		// The stake stays locked until withdrawn
+		err = samaState.PutUnbonding(&Unbonding{
+			TxID:        t.TxID,
+			Address:     t.Sender,
+			StakerType:  u.StakerType,
+			Amount:      staker.StakeAmount,
+			StartTime:   t.BlockTime,
+			ReleaseTime: t.BlockTime + samaState.GetUnbondingTime(),
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		if _, err := ModifyStakeBalance(t.Database, t.Sender, false, staker.StakeAmount); err != nil {
+			return err
+		}
+		if _, err := ModifyBalance(t.Database, t.Sender, true, staker.StakeAmount); err != nil {
+			return err
+		}
+	}
+	if err := payStakerReward(t, staker, totalReawrd); err != nil {
+		return err
+	}
+	if err := releaseDelegations(t, t.Sender); err != nil {
 		return err
 	}
 
-	return samaState.DealUnStakeTx(t.Database, byte(u.StakerType), t.Sender, t.TxID, u.EndTime)
+	return samaState.DealUnStakeTx(byte(u.StakerType), t.Sender, t.TxID, endTime)
 }
 
 func (u *UnStakeTx) FeeUnits(g *Genesis) uint64 {
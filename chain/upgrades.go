@@ -0,0 +1,210 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Network upgrades, in activation order
+const (
+	// ForkPhase1 requires txs to pay at least the price of their block and
+	// blocks to be exactly one higher than their parent. Blocks are encoded
+	// with [codecVersion] and commit to their state root. Votes may be
+	// against or abstain, blocks close the actions due, and actions stored
+	// before move to the current layout.
+	ForkPhase1 = "phase1"
+	// ForkPhase2 moves staker rewards to index accounting, see
+	// [RewardIndex]
+	ForkPhase2 = "phase2"
+	// ForkPhase3 locks the stake of leaving stakers for an unbonding
+	// period, see [Unbonding]
+	ForkPhase3 = "phase3"
+	// ForkPhase4 vests the foundation share, see [FoundationVestingMeta].
+	// Stakers may delegate their votes with [DelegateVoteTx],
+	// [MultisigAccount]s send txs with [MultisigTx], route and ser nodes
+	// take stake delegations (see [StakerPool]) and stakers are slashed for
+	// offences (see [SlashingPolicy]). Governance may remove or suspend
+	// stakers and set the [VotingPolicy] of an action type.
+	ForkPhase4 = "phase4"
+)
+
+var (
+	Forks = []string{ForkPhase1, ForkPhase2, ForkPhase3, ForkPhase4}
+
+	ErrInvalidUpgrade = errors.New("invalid upgrade config")
+)
+
+// UpgradeConfig schedules the network upgrades. It is parsed from the
+// upgrade bytes given to the VM, so every node switches rules at the same
+// block instead of when its binary is replaced.
+type UpgradeConfig struct {
+	// Activation timestamp of each fork, a fork is active in blocks whose
+	// timestamp is at least its activation. Missing forks never activate.
+	Forks map[string]int64 `json:"forks"`
+	// Fee parameters from the activation of a fork, forks without any keep
+	// the previous ones (at first those of the [Genesis])
+	Fees map[string]*FeeConfig `json:"fees"`
+}
+
+// FeeConfig are the parameters of block prices and costs
+type FeeConfig struct {
+	MinPrice         uint64 `json:"minPrice"`
+	TargetBlockRate  int64  `json:"targetBlockRate"` // seconds
+	TargetBlockSize  uint64 `json:"targetBlockSize"` // units
+	BlockCostEnabled bool   `json:"blockCostEnabled"`
+}
+
+// TargetRangeUnits returns the units blocks should use in [lookbackWindow]
+// seconds
+func (f *FeeConfig) TargetRangeUnits(lookbackWindow int64) uint64 {
+	return f.TargetBlockSize / uint64(f.TargetBlockRate) * uint64(lookbackWindow)
+}
+
+// ParseUpgradeConfig parses and verifies [b], empty bytes schedule no forks
+func ParseUpgradeConfig(b []byte) (*UpgradeConfig, error) {
+	u := &UpgradeConfig{Forks: map[string]int64{}, Fees: map[string]*FeeConfig{}}
+	if len(b) == 0 {
+		return u, nil
+	}
+	if err := json.Unmarshal(b, u); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidUpgrade, err)
+	}
+	return u, u.Verify()
+}
+
+// Verify checks that only known forks are scheduled, in order, and that fees
+// only change with scheduled forks
+func (u *UpgradeConfig) Verify() error {
+	known := map[string]bool{}
+	for _, fork := range Forks {
+		known[fork] = true
+	}
+	for fork := range u.Forks {
+		if !known[fork] {
+			return fmt.Errorf("%w: unknown fork %s", ErrInvalidUpgrade, fork)
+		}
+	}
+	var (
+		prev      string
+		scheduled = true
+	)
+	for _, fork := range Forks {
+		time, ok := u.Forks[fork]
+		switch {
+		case ok && !scheduled:
+			return fmt.Errorf("%w: %s is scheduled but %s is not", ErrInvalidUpgrade, fork, prev)
+		case ok && len(prev) > 0 && time < u.Forks[prev]:
+			return fmt.Errorf("%w: %s activates before %s", ErrInvalidUpgrade, fork, prev)
+		}
+		prev, scheduled = fork, ok
+	}
+	for fork, fees := range u.Fees {
+		if _, ok := u.Forks[fork]; !ok {
+			return fmt.Errorf("%w: fees of unscheduled fork %s", ErrInvalidUpgrade, fork)
+		}
+		if fees == nil || fees.TargetBlockRate <= 0 {
+			return fmt.Errorf("%w: invalid fees of %s", ErrInvalidUpgrade, fork)
+		}
+	}
+	return nil
+}
+
+// IsActive reports whether [fork] is active in blocks at [timestamp]
+func (u *UpgradeConfig) IsActive(fork string, timestamp int64) bool {
+	if u == nil {
+		return false
+	}
+	time, ok := u.Forks[fork]
+	return ok && timestamp >= time
+}
+
+// Rules returns the execution rules of blocks at [timestamp]
+func (u *UpgradeConfig) Rules(timestamp int64) Rules {
+	rules := Rules{
+		Timestamp: timestamp,
+		IsPhase1:  u.IsActive(ForkPhase1, timestamp),
+		IsPhase2:  u.IsActive(ForkPhase2, timestamp),
+		IsPhase3:  u.IsActive(ForkPhase3, timestamp),
+		IsPhase4:  u.IsActive(ForkPhase4, timestamp),
+	}
+	if rules.IsPhase4 {
+		rules.Phase4Time = u.Forks[ForkPhase4]
+	}
+	for _, fork := range Forks {
+		if fees, ok := u.fees(fork); ok && u.IsActive(fork, timestamp) {
+			rules.Fees = fees
+		}
+	}
+	return rules
+}
+
+func (u *UpgradeConfig) fees(fork string) (*FeeConfig, bool) {
+	if u == nil {
+		return nil, false
+	}
+	fees, ok := u.Fees[fork]
+	return fees, ok
+}
+
+// Rules are the execution rules of a block, derived from the [UpgradeConfig]
+// at its timestamp. The zero value runs the rules before any fork.
+type Rules struct {
+	Timestamp int64
+	IsPhase1  bool
+	IsPhase2  bool
+	IsPhase3  bool
+	IsPhase4  bool
+	// Activation of [ForkPhase4], once it is active
+	Phase4Time int64
+
+	// Fees set by the last active fork, nil if none did
+	Fees *FeeConfig
+}
+
+// IsActive reports whether [fork] is active under the rules
+func (r Rules) IsActive(fork string) bool {
+	switch fork {
+	case ForkPhase1:
+		return r.IsPhase1
+	case ForkPhase2:
+		return r.IsPhase2
+	case ForkPhase3:
+		return r.IsPhase3
+	case ForkPhase4:
+		return r.IsPhase4
+	default:
+		return false
+	}
+}
+
+// forked is implemented by txs and governance actions introduced by a fork,
+// which don't exist in blocks before it
+type forked interface {
+	minFork() string
+}
+
+// Enables reports whether [source] exists under the rules, values that
+// don't implement [forked] always do
+func (r Rules) Enables(source interface{}) bool {
+	if f, ok := source.(forked); ok {
+		return r.IsActive(f.minFork())
+	}
+	return true
+}
+
+// FeeConfig returns the fee parameters of the block
+func (r Rules) FeeConfig(g *Genesis) *FeeConfig {
+	if r.Fees != nil {
+		return r.Fees
+	}
+	return &FeeConfig{
+		MinPrice:         g.MinPrice,
+		TargetBlockRate:  g.TargetBlockRate,
+		TargetBlockSize:  g.TargetBlockSize,
+		BlockCostEnabled: g.BlockCostEnabled,
+	}
+}
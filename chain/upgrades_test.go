@@ -0,0 +1,275 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/SamaNetwork/SamaVM/tdata"
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ava-labs/avalanchego/database/memdb"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/set"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestUpgradeConfig(t *testing.T) {
+	t.Parallel()
+
+	for _, b := range []string{
+		`{"forks":{"phase0":1}}`,
+		`{"forks":{"phase1":"soon"}}`,
+		`{"forks":{"phase2":100}}`,
+		`{"forks":{"phase1":100,"phase2":99}}`,
+		`{"forks":{"phase1":100},"fees":{"phase2":{"targetBlockRate":1}}}`,
+		`{"forks":{"phase1":100},"fees":{"phase1":{"targetBlockRate":0}}}`,
+	} {
+		if _, err := ParseUpgradeConfig([]byte(b)); !errors.Is(err, ErrInvalidUpgrade) {
+			t.Fatalf("%s: expected %v, got %v", b, ErrInvalidUpgrade, err)
+		}
+	}
+	u, err := ParseUpgradeConfig(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Rules(1<<62).IsPhase1 || (*UpgradeConfig)(nil).Rules(1).IsPhase1 {
+		t.Fatal("unscheduled fork activated")
+	}
+	u, err = ParseUpgradeConfig([]byte(`{"forks":{"phase1":100}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Rules(99).IsPhase1 || !u.Rules(100).IsPhase1 {
+		t.Fatal("fork not activated at its timestamp")
+	}
+
+	// Fees change with the fork that sets them and are kept by later ones
+	u, err = ParseUpgradeConfig([]byte(`{"forks":{"phase1":100,"phase2":200},"fees":{"phase1":{"minPrice":5,"targetBlockRate":2}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := DefaultGenesis()
+	for _, tt := range []struct {
+		timestamp int64
+		minPrice  uint64
+		blockRate int64
+	}{
+		{99, g.MinPrice, g.TargetBlockRate},
+		{100, 5, 2},
+		{200, 5, 2},
+	} {
+		fees := u.Rules(tt.timestamp).FeeConfig(g)
+		if fees.MinPrice != tt.minPrice || fees.TargetBlockRate != tt.blockRate {
+			t.Fatalf("block at %d: unexpected fees %+v", tt.timestamp, fees)
+		}
+	}
+}
+
+func TestPhase1Price(t *testing.T) {
+	t.Parallel()
+
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := DefaultGenesis()
+	g.CustomAllocation = []*CustomAllocation{
+		{Address: crypto.PubkeyToAddress(priv.PublicKey), Balance: 10000000},
+	}
+	u, err := ParseUpgradeConfig([]byte(`{"forks":{"phase1":100}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The test tx pays 10, below the price of the block
+	for _, tt := range []struct {
+		blockTime  int64
+		executeErr error
+	}{
+		{99, nil},
+		{100, ErrInsufficientPrice},
+		{101, ErrInsufficientPrice},
+	} {
+		db := memdb.New()
+		if err := g.Load(db, nil); err != nil {
+			t.Fatal(err)
+		}
+		tx := createTestTx(t, ids.ID{0, 1}, priv)
+		ctx := &Context{
+			RecentBlockIDs: set.Set[ids.ID]{{0, 1}: struct{}{}},
+			NextPrice:      11,
+			Rules:          u.Rules(tt.blockTime),
+		}
+		if err := tx.Execute(g, db, DummyBlock(tt.blockTime, tx, nil), ctx); !errors.Is(err, tt.executeErr) {
+			t.Fatalf("block at %d: expected %v, got %v", tt.blockTime, tt.executeErr, err)
+		}
+	}
+}
+
+func TestForkedTypes(t *testing.T) {
+	t.Parallel()
+
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := crypto.PubkeyToAddress(priv.PublicKey)
+	g := DefaultGenesis()
+	g.RootAddress = sender.Hex()
+	g.CustomAllocation = []*CustomAllocation{
+		{Address: sender, Balance: 10000000},
+	}
+	u, err := ParseUpgradeConfig([]byte(`{"forks":{"phase1":100,"phase2":100,"phase3":100,"phase4":200}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := func() *BaseTx {
+		return &BaseTx{BlockID: ids.ID{0, 1}, Price: 10}
+	}
+	// The sender is the only member of a multisig account holding funds
+	account, err := NewMultisigAccount([]common.Address{sender}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fund := func(s SamaState, db database.Database) error {
+		if err := s.PutMultisig(account); err != nil {
+			return err
+		}
+		return SetBalance(db, account.Address, 100)
+	}
+	transfer := &TransferTx{BaseTx: &BaseTx{}, To: common.Address{1}, Units: 100}
+	td, err := MultisigTypedData(g.Magic, account.Address, 0, transfer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dh, err := tdata.DigestHash(td)
+	if err != nil {
+		t.Fatal(err)
+	}
+	approval, err := Sign(dh, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// stake makes the sender a route node, and stakeOther another one the
+	// sender delegated to
+	stake := func(s SamaState, _ database.Database) error {
+		return s.PutStaker(&StakerMeta{StakerType: stakerTypeRoute, StakeAmount: 1000, StakeTime: 10, StakerAddr: sender})
+	}
+	stakeOther := func(s SamaState, db database.Database) error {
+		if err := s.PutStaker(&StakerMeta{StakerType: stakerTypeRoute, StakeAmount: 1000, StakeTime: 10, StakerAddr: common.Address{1}}); err != nil {
+			return err
+		}
+		if err := SetStakeBalance(db, sender, 100); err != nil {
+			return err
+		}
+		return s.PutStakeDelegation(&StakeDelegation{Delegator: sender, StakerAddr: common.Address{1}, Amount: 100, LastUpdateTime: 10})
+	}
+	// The sender is a work address of the other route node and signed an
+	// invalid proof
+	work := func(s SamaState, db database.Database) error {
+		if err := stakeOther(s, db); err != nil {
+			return err
+		}
+		if err := SetStakeBalance(db, common.Address{1}, 1000); err != nil {
+			return err
+		}
+		return s.PutDetail(sender, &DetailMeta{
+			StakerType:   stakerTypeRoute,
+			WorkKey:      hex.EncodeToString(crypto.FromECDSAPub(&priv.PublicKey)),
+			WorkAddress:  sender,
+			StakeAddress: common.Address{1},
+		})
+	}
+	invalid := &Transaction{UnsignedTransaction: &ProofTx{BaseTx: &BaseTx{BlockID: ids.ID{1}}, Netflow: maxFlow + 1, EndTime: 30}}
+	dh, err = DigestHash(invalid.UnsignedTransaction)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if invalid.Signature, err = Sign(dh, priv); err != nil {
+		t.Fatal(err)
+	}
+	if err := invalid.Init(g); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := json.Marshal(DefaultSlashingPolicy(OffenceDowntime))
+	if err != nil {
+		t.Fatal(err)
+	}
+	votingPolicy, err := json.Marshal(&VotingPolicy{ActionType: actionTypeAddUserType, VoterTypes: []uint64{stakerTypeRoute}, Quorum: 5000, Threshold: 5001})
+	if err != nil {
+		t.Fatal(err)
+	}
+	execute := func(utx UnsignedTransaction, setup func(SamaState, database.Database) error, blockTime int64) error {
+		db := memdb.New()
+		if err := g.Load(db, nil); err != nil {
+			t.Fatal(err)
+		}
+		if setup != nil {
+			if err := setup(SamaNew(db, g), db); err != nil {
+				t.Fatal(err)
+			}
+		}
+		tx := &Transaction{UnsignedTransaction: utx}
+		dh, err := DigestHash(tx.UnsignedTransaction)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tx.Signature, err = Sign(dh, priv); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Init(g); err != nil {
+			t.Fatal(err)
+		}
+		ctx := &Context{
+			RecentBlockIDs: set.Set[ids.ID]{{0, 1}: struct{}{}},
+			NextPrice:      10,
+			Rules:          u.Rules(blockTime),
+		}
+		return tx.Execute(g, db, DummyBlock(blockTime, tx, nil), ctx)
+	}
+
+	// Txs and action types don't exist in the blocks before their fork and
+	// run from it
+	for i, tt := range []struct {
+		utx   UnsignedTransaction
+		setup func(SamaState, database.Database) error
+		fork  string
+	}{
+		{&SetTx{BaseTx: base(), Value: []byte("a")}, nil, ""},
+		{&ProposalTx{BaseTx: base(), ActionID: ids.ShortID{1}, ActionType: actionTypeModifySysParam, Key: "burn", NewValue: "10"}, nil, ""},
+		{&DelegateVoteTx{BaseTx: base(), Delegate: common.Address{1}}, stake, ForkPhase4},
+		{&CreateMultisigTx{BaseTx: base(), Members: []common.Address{sender, {1}}, Threshold: 2}, nil, ForkPhase4},
+		{&MultisigTx{BaseTx: base(), Account: account.Address, Tx: transfer, Signatures: [][]byte{approval}}, fund, ForkPhase4},
+		{&DelegateStakeTx{BaseTx: base(), StakerAddr: common.Address{1}, Amount: 100}, stakeOther, ForkPhase4},
+		{&UndelegateStakeTx{BaseTx: base(), StakerAddr: common.Address{1}, Amount: 100}, stakeOther, ForkPhase4},
+		{&SetCommissionTx{BaseTx: base(), CommissionPerc: 10}, stake, ForkPhase4},
+		{&ReportOffenceTx{BaseTx: base(), Staker: common.Address{1}, OffenceType: OffenceInvalidProof, Evidence: invalid.Bytes()}, work, ForkPhase4},
+		{&ProposalTx{BaseTx: base(), ActionID: ids.ShortID{1}, ActionType: actionTypeSlashStaker, Key: common.Address{1}.Hex(), NewValue: "misbehaved"}, stakeOther, ForkPhase4},
+		{&ProposalTx{BaseTx: base(), ActionID: ids.ShortID{1}, ActionType: actionTypeSetSlashingPolicy, Key: "4", NewValue: string(policy)}, nil, ForkPhase4},
+		{&ProposalTx{BaseTx: base(), ActionID: ids.ShortID{1}, ActionType: actionTypeRemoveStaker, Key: common.Address{1}.Hex(), NewValue: `{"slashPerc":30}`}, stakeOther, ForkPhase4},
+		{&ProposalTx{BaseTx: base(), ActionID: ids.ShortID{1}, ActionType: actionTypeSuspendStaker, Key: common.Address{1}.Hex(), NewValue: "true"}, stakeOther, ForkPhase4},
+		{&ProposalTx{BaseTx: base(), ActionID: ids.ShortID{1}, ActionType: actionTypeSetVotingPolicy, Key: "3", NewValue: string(votingPolicy)}, nil, ForkPhase4},
+	} {
+		absent := ErrInactiveFork
+		if _, ok := tt.utx.(*ProposalTx); ok {
+			absent = ErrUnknownActionType
+		}
+		for _, blockTime := range []int64{199, 200} {
+			err := execute(tt.utx.Copy(), tt.setup, blockTime)
+			if len(tt.fork) > 0 && !u.IsActive(tt.fork, blockTime) {
+				if !errors.Is(err, absent) {
+					t.Fatalf("#%d: block at %d: expected %v, got %v", i, blockTime, absent, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("#%d: rejected in block at %d: %v", i, blockTime, err)
+			}
+		}
+	}
+}
@@ -11,7 +11,7 @@ import (
 
 func PrefixUserTypesKey(id uint64) (k []byte) {
 	k = make([]byte, 10)
-	k[0] = usersPrefix
+	k[0] = userTypesPrefix
 	k[1] = ByteDelimiter
 
 	binary.BigEndian.PutUint64(k[2:], id)
@@ -35,127 +35,63 @@ type UserType struct {
 }
 
 type UserTypesState interface {
-	GetUserType(db database.Database, id uint64) (*UserType, bool, error)
-	AddUserType(db database.Database, pmeta *UserType) error
-	DelUserType(db database.Database, id uint64) error
-	GetUserTypes(db database.Database) (map[uint64]*UserType, error)
-	ReloadUserTypes(db database.Database) error
-	CacheUserTypesCommit() error
-	CacheUserTypesAbort() error
+	GetUserType(id uint64) (*UserType, bool, error)
+	AddUserType(pmeta *UserType) error
+	DelUserType(id uint64) error
+	GetUserTypes() (map[uint64]*UserType, error)
 
 	CheckUserType(id uint64) bool
 	CheckFee(id uint64, fee uint64) bool
 }
 
 type userTypesState struct {
-	curUserTypes map[uint64]*UserType
-
-	pendingUserTypesAdd map[uint64]*UserType
-	pendingUserTypesDel map[uint64]*UserType
+	db database.Database
 }
 
-func NewUserTypesState(db database.Database) (*userTypesState, error) {
-	s := &userTypesState{
-		curUserTypes:        make(map[uint64]*UserType),
-		pendingUserTypesAdd: make(map[uint64]*UserType),
-		pendingUserTypesDel: make(map[uint64]*UserType),
-	}
-	err := s.ReloadUserTypes(db)
-	return s, err
+func NewUserTypesState(db database.Database) *userTypesState {
+	return &userTypesState{db: db}
 }
 
-func (s *userTypesState) GetUserType(db database.Database, id uint64) (*UserType, bool, error) {
-	pmeta, ok := s.curUserTypes[id]
-	if !ok {
-		return nil, false, nil
+func (s *userTypesState) GetUserType(id uint64) (*UserType, bool, error) {
+	pmeta := new(UserType)
+	exist, err := getState(s.db, PrefixUserTypesKey(id), pmeta)
+	if !exist || err != nil {
+		return nil, false, err
 	}
 	return pmeta, true, nil
-
 }
 
 func (s *userTypesState) CheckUserType(id uint64) bool {
-	_, ok := s.curUserTypes[id]
+	ok, _ := s.db.Has(PrefixUserTypesKey(id))
 	return ok
 }
 
 func (s *userTypesState) CheckFee(id uint64, fee uint64) bool {
-	userType, ok := s.curUserTypes[id]
+	userType, ok, _ := s.GetUserType(id)
 	if !ok {
 		return ok
 	}
-	if fee == userType.FeeUnits {
-		return true
-	}
-	return false
+	return fee == userType.FeeUnits
 }
 
-func (s *userTypesState) AddUserType(db database.Database, pmeta *UserType) error {
-	id := pmeta.TypeID
-
-	delete(s.pendingUserTypesDel, id)
-	s.pendingUserTypesAdd[id] = pmeta
-
-	k := PrefixUserTypesKey(id)
-	pvmeta, err := Marshal(pmeta)
-	if err != nil {
-		return err
-	}
-	return db.Put(k, pvmeta)
+func (s *userTypesState) AddUserType(pmeta *UserType) error {
+	return putState(s.db, PrefixUserTypesKey(pmeta.TypeID), pmeta)
 }
 
-func (s *userTypesState) DelUserType(db database.Database, id uint64) error {
-	delete(s.pendingUserTypesAdd, id)
-	s.pendingUserTypesDel[id] = &UserType{}
-
+func (s *userTypesState) DelUserType(id uint64) error {
 	k := PrefixUserTypesKey(id)
-	return db.Delete(k)
+	return s.db.Delete(k)
 }
 
-func (s *userTypesState) GetUserTypes(db database.Database) (map[uint64]*UserType, error) {
-	return s.curUserTypes, nil
-}
-
-func (s *userTypesState) ReloadUserTypes(db database.Database) error {
-	basePrefix := baseUserTypesPrefix()
-	cursor := db.NewIteratorWithPrefix(basePrefix)
-	defer cursor.Release()
-	for cursor.Next() {
-		nmeta := cursor.Value()
-
+func (s *userTypesState) GetUserTypes() (map[uint64]*UserType, error) {
+	userTypes := make(map[uint64]*UserType)
+	err := iterateState(s.db, baseUserTypesPrefix(), 8, func(_ []byte, v []byte) error {
 		pmeta := new(UserType)
-		if _, err := Unmarshal(nmeta, pmeta); err != nil {
+		if _, err := Unmarshal(v, pmeta); err != nil {
 			return err
 		}
-		s.curUserTypes[pmeta.TypeID] = pmeta
-
-	}
-	return nil
-}
-
-func (s *userTypesState) CacheUserTypesCommit() error {
-	for id, pendingUserType := range s.pendingUserTypesAdd {
-		delete(s.curUserTypes, id)
-		s.curUserTypes[id] = pendingUserType
-	}
-	for id := range s.pendingUserTypesDel {
-		delete(s.curUserTypes, id)
-	}
-
-	for a := range s.pendingUserTypesAdd {
-		delete(s.pendingUserTypesAdd, a)
-	}
-	for a := range s.pendingUserTypesDel {
-		delete(s.pendingUserTypesDel, a)
-	}
-	return nil
-}
-
-func (s *userTypesState) CacheUserTypesAbort() error {
-	for a := range s.pendingUserTypesAdd {
-		delete(s.pendingUserTypesAdd, a)
-	}
-	for a := range s.pendingUserTypesDel {
-		delete(s.pendingUserTypesDel, a)
-	}
-	return nil
+		userTypes[pmeta.TypeID] = pmeta
+		return nil
+	})
+	return userTypes, err
 }
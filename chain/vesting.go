@@ -0,0 +1,150 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ava-labs/avalanchego/ids"
+)
+
+// The foundation share, [GetPercFoundation] of the reward of each year,
+// accrues from chain creation. It vests linearly over
+// [Genesis.FoundationVesting] seconds from chain creation and nothing can be
+// claimed before [Genesis.FoundationCliff] seconds from it. A schedule of 0
+// seconds vests everything accrued at once. Before [ForkPhase4] the
+// foundation claims nothing.
+
+const VestingPrefix = 4
+
+// FoundationVestingMeta is how much of the foundation share was claimed. It
+// does not depend on the foundation address, so a new address can only
+// claim what the previous one left.
+type FoundationVestingMeta struct {
+	Claimed       uint64 `serialize:"true" json:"claimed"`
+	LastClaimTime uint64 `serialize:"true" json:"lastClaimTime"`
+	LastClaimTXID ids.ID `serialize:"true" json:"lastClaimTxId"`
+}
+
+// VestingStatus is the state of the foundation share at [EndTime]
+type VestingStatus struct {
+	EndTime   uint64 `serialize:"true" json:"endTime"`
+	Accrued   uint64 `serialize:"true" json:"accrued"`
+	Vested    uint64 `serialize:"true" json:"vested"`
+	Claimed   uint64 `serialize:"true" json:"claimed"`
+	Claimable uint64 `serialize:"true" json:"claimable"`
+	// CliffTime is the first time anything can be claimed and VestedTime
+	// when all accrued vests
+	CliffTime  uint64 `serialize:"true" json:"cliffTime"`
+	VestedTime uint64 `serialize:"true" json:"vestedTime"`
+}
+
+func PrefixFoundationVestingKey() (k []byte) {
+	k = make([]byte, 4)
+	k[0] = rewardPrefix
+	k[1] = ByteDelimiter
+	k[2] = VestingPrefix
+	k[3] = ByteDelimiter
+	return
+}
+
+var _ VestingState = &vestingState{}
+
+type VestingState interface {
+	GetFoundationVesting() (*FoundationVestingMeta, bool, error)
+	PutFoundationVesting(pmeta *FoundationVestingMeta) error
+}
+
+type vestingState struct {
+	db database.Database
+}
+
+func NewVestingState(db database.Database) *vestingState {
+	return &vestingState{db: db}
+}
+
+func (v *vestingState) GetFoundationVesting() (*FoundationVestingMeta, bool, error) {
+	pmeta := new(FoundationVestingMeta)
+	exist, err := getState(v.db, PrefixFoundationVestingKey(), pmeta)
+	if err != nil {
+		return nil, false, err
+	}
+	return pmeta, exist, nil
+}
+
+func (v *vestingState) PutFoundationVesting(pmeta *FoundationVestingMeta) error {
+	return putState(v.db, PrefixFoundationVestingKey(), pmeta)
+}
+
+// migrateFoundation starts vesting the foundation share once [ForkPhase4] is
+// active
+func migrateFoundation(g *Genesis, db database.Database, rules Rules) error {
+	if !rules.IsPhase4 {
+		return nil
+	}
+	return SamaNew(db, g).MigrateFoundation()
+}
+
+// foundationVested reports whether the foundation share vests, the
+// foundation claimed nothing before
+func (s *samaState) foundationVested() (bool, error) {
+	_, exist, err := s.GetFoundationVesting()
+	return exist, err
+}
+
+// MigrateFoundation loads the foundation share of the genesis and starts
+// vesting it. It does nothing once the share vests.
+func (s *samaState) MigrateFoundation() error {
+	if vested, err := s.foundationVested(); err != nil || vested {
+		return err
+	}
+	if err := s.LoadFoundationPerc(); err != nil {
+		return err
+	}
+	return s.PutFoundationVesting(&FoundationVestingMeta{})
+}
+
+// foundationAccrued returns the foundation share of the rewards from chain
+// creation to [endTime]
+func (s *samaState) foundationAccrued(endTime uint64) uint64 {
+	createTime := s.GetChainCreateTime()
+	accrued := uint64(0)
+	for year, startTime := uint64(0), createTime; startTime < endTime; year++ {
+		end := createTime + (year+1)*SecondsYear
+		if endTime < end {
+			end = endTime
+		}
+		roleTotalYear := s.RewardCurYear(uint32(year)) * uint64(s.GetPercFoundation()) / 100
+		accrued += (roleTotalYear / SecondsYear) * (end - startTime)
+		startTime = end
+	}
+	return accrued
+}
+
+// FoundationVesting returns the state of the foundation share at [endTime]
+func (s *samaState) FoundationVesting(endTime uint64) (*VestingStatus, error) {
+	pmeta, _, err := s.GetFoundationVesting()
+	if err != nil {
+		return nil, err
+	}
+	createTime := s.GetChainCreateTime()
+	cliff, vesting := s.GetFoundationSchedule()
+	status := &VestingStatus{
+		EndTime:    endTime,
+		Accrued:    s.foundationAccrued(endTime),
+		Claimed:    pmeta.Claimed,
+		CliffTime:  createTime + cliff,
+		VestedTime: createTime + vesting,
+	}
+	switch {
+	case endTime < status.CliffTime:
+	case endTime >= status.VestedTime:
+		status.Vested = status.Accrued
+	default:
+		status.Vested = mulDiv(status.Accrued, endTime-createTime, vesting)
+	}
+	if status.Vested > status.Claimed {
+		status.Claimable = status.Vested - status.Claimed
+	}
+	return status, nil
+}
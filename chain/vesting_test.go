@@ -0,0 +1,184 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"testing"
+
+	"github.com/ava-labs/avalanchego/database/memdb"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestFoundationVesting(t *testing.T) {
+	t.Parallel()
+
+	db := memdb.New()
+	defer db.Close()
+	g := DefaultGenesis()
+	g.FoundationCliff = 90 * SecondsDay
+	g.FoundationVesting = 2 * SecondsYear
+	if err := g.Load(db, nil); err != nil {
+		t.Fatal(err)
+	}
+	state := SamaNew(db, g).(*samaState)
+	if err := migrateFoundation(g, db, Rules{IsPhase4: true}); err != nil {
+		t.Fatal(err)
+	}
+	create := g.ChainCreateTime
+	foundation := common.HexToAddress(g.FoundationAddr)
+
+	// The share accrues at the rate of each year
+	rate := func(year uint32) uint64 {
+		return state.RewardCurYear(year) * uint64(g.FoundationPerc) / 100 / SecondsYear
+	}
+	if rate(1) >= rate(0) {
+		t.Fatal("expected a lower rate the second year")
+	}
+	for _, tt := range []struct {
+		endTime uint64
+		accrued uint64
+	}{
+		{create - 1, 0},
+		{create + SecondsDay, rate(0) * SecondsDay},
+		{create + SecondsYear, rate(0) * SecondsYear},
+		{create + SecondsYear + SecondsDay, rate(0)*SecondsYear + rate(1)*SecondsDay},
+		{create + 3*SecondsYear, rate(0)*SecondsYear + rate(1)*SecondsYear + rate(2)*SecondsYear},
+	} {
+		if accrued := state.foundationAccrued(tt.endTime); accrued != tt.accrued {
+			t.Fatalf("at %d: expected %d accrued, got %d", tt.endTime, tt.accrued, accrued)
+		}
+	}
+
+	claim := func(blockTime uint64) uint64 {
+		t.Helper()
+		reward, _, _, err := state.CalcReward(0, foundation, blockTime)
+		if err != nil {
+			t.Fatal(err)
+		}
+		balance, err := GetBalance(db, foundation)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx := &ClaimTx{BaseTx: &BaseTx{}, RewardAmount: reward, EndTime: blockTime}
+		if err := tx.Execute(&TransactionContext{
+			Genesis:    g,
+			Database:   db,
+			BlockTime:  blockTime,
+			ParentTime: blockTime,
+			TxID:       ids.GenerateTestID(),
+			Sender:     foundation,
+			State:      state,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if b, err := GetBalance(db, foundation); err != nil || b != balance+reward {
+			t.Fatalf("expected balance %d, got %d (%v)", balance+reward, b, err)
+		}
+		return reward
+	}
+
+	// Nothing vests before the cliff
+	status, err := state.FoundationVesting(create + 89*SecondsDay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Accrued == 0 || status.Vested != 0 || status.Claimable != 0 {
+		t.Fatalf("vested before the cliff %+v", status)
+	}
+
+	// Then linearly over 2 years, across the year boundary
+	claimed := uint64(0)
+	for _, blockTime := range []uint64{
+		create + 90*SecondsDay,
+		create + SecondsYear + 7*SecondsDay,
+		create + 2*SecondsYear - SecondsDay,
+	} {
+		accrued := state.foundationAccrued(blockTime)
+		vested := mulDiv(accrued, blockTime-create, 2*SecondsYear)
+		if reward := claim(blockTime); reward != vested-claimed {
+			t.Fatalf("at %d: expected %d claimed, got %d", blockTime, vested-claimed, reward)
+		}
+		claimed = vested
+	}
+
+	// All that accrued is vested after the schedule
+	end := create + 3*SecondsYear
+	claimed += claim(end)
+	if claimed != state.foundationAccrued(end) {
+		t.Fatalf("expected %d claimed in total, got %d", state.foundationAccrued(end), claimed)
+	}
+	status, err = state.FoundationVesting(end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Claimed != claimed || status.Claimable != 0 {
+		t.Fatalf("unexpected status after claiming %+v", status)
+	}
+}
+
+func TestFoundationVestingFork(t *testing.T) {
+	t.Parallel()
+
+	db := memdb.New()
+	defer db.Close()
+	g := DefaultGenesis()
+	if err := g.Load(db, nil); err != nil {
+		t.Fatal(err)
+	}
+	state := SamaNew(db, g)
+	foundation := common.HexToAddress(g.FoundationAddr)
+	claim := func(blockTime uint64, reward uint64) error {
+		tx := &ClaimTx{BaseTx: &BaseTx{}, RewardAmount: reward, EndTime: blockTime}
+		return tx.Execute(&TransactionContext{
+			Genesis:    g,
+			Database:   db,
+			BlockTime:  blockTime,
+			ParentTime: blockTime,
+			TxID:       ids.GenerateTestID(),
+			Sender:     foundation,
+			State:      state,
+		})
+	}
+
+	// Before the fork the foundation claims nothing, as it always did
+	blockTime := g.ChainCreateTime + SecondsYear
+	if err := migrateFoundation(g, db, Rules{IsPhase3: true}); err != nil {
+		t.Fatal(err)
+	}
+	if perc := state.GetPercFoundation(); perc != 0 {
+		t.Fatalf("expected no foundation share before the fork, got %d", perc)
+	}
+	if err := claim(blockTime, 1); err == nil {
+		t.Fatal("claimed a reward before the fork")
+	}
+	if err := claim(blockTime, 0); err != nil {
+		t.Fatal(err)
+	}
+	if balance, err := GetBalance(db, foundation); err != nil || balance != 0 {
+		t.Fatalf("expected no balance, got %d (%v)", balance, err)
+	}
+
+	// From the fork the share accrued since chain creation vests
+	blockTime += Seconds7Day
+	if err := migrateFoundation(g, db, Rules{IsPhase4: true}); err != nil {
+		t.Fatal(err)
+	}
+	if perc := state.GetPercFoundation(); perc != g.FoundationPerc {
+		t.Fatalf("expected foundation share %d, got %d", g.FoundationPerc, perc)
+	}
+	reward, _, _, err := state.CalcReward(0, foundation, blockTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reward == 0 {
+		t.Fatal("expected a vested reward")
+	}
+	if err := claim(blockTime, 0); err == nil {
+		t.Fatal("claimed no reward after the fork")
+	}
+	if err := claim(blockTime, reward); err != nil {
+		t.Fatal(err)
+	}
+}
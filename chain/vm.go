@@ -4,6 +4,8 @@
 package chain
 
 import (
+	"time"
+
 	"github.com/ava-labs/avalanchego/database"
 	"github.com/ava-labs/avalanchego/ids"
 	"github.com/ava-labs/avalanchego/utils/set"
@@ -19,11 +21,22 @@ type Context struct {
 
 	NextCost  uint64
 	NextPrice uint64
+
+	// ParentTimestamp is the timestamp of the block being built on. Time
+	// rules are evaluated against block timestamps only, never the local
+	// clock, so that every node (and every replay) reaches the same result.
+	ParentTimestamp int64
+
+	// Rules are the execution rules at the timestamp of the block
+	Rules Rules
 }
 
 type VM interface {
 	Genesis() *Genesis
 	IsBootstrapped() bool
+	// Now is the local clock, it only bounds the timestamps of new blocks
+	// and is never used to execute them
+	Now() time.Time
 	State() database.Database
 	Mempool() Mempool
 	GetStatelessBlock(ids.ID) (*StatelessBlock, error)
@@ -31,5 +44,4 @@ type VM interface {
 	Verified(*StatelessBlock)
 	Rejected(*StatelessBlock)
 	Accepted(*StatelessBlock)
-	SamaState() SamaState
 }
@@ -10,6 +10,7 @@ package chain
 
 import (
 	reflect "reflect"
+	time "time"
 
 	database "github.com/ava-labs/avalanchego/database"
 	ids "github.com/ava-labs/avalanchego/ids"
@@ -123,18 +124,18 @@ func (mr *MockVMMockRecorder) Mempool() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Mempool", reflect.TypeOf((*MockVM)(nil).Mempool))
 }
 
-// SamaState mocks base method.
-func (m *MockVM) SamaState() (SamaState) {
+// Now mocks base method.
+func (m *MockVM) Now() time.Time {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SamaState")
-	ret0, _ := ret[0].(SamaState)
+	ret := m.ctrl.Call(m, "Now")
+	ret0, _ := ret[0].(time.Time)
 	return ret0
 }
 
-// SamaState indicates an expected call of SamaState.
-func (mr *MockVMMockRecorder) SamaState() *gomock.Call {
+// Now indicates an expected call of Now.
+func (mr *MockVMMockRecorder) Now() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SamaState", reflect.TypeOf((*MockVM)(nil).SamaState))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Now", reflect.TypeOf((*MockVM)(nil).Now))
 }
 
 // Rejected mocks base method.
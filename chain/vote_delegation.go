@@ -0,0 +1,118 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"errors"
+
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Stakers may delegate their governance weight to another address, which
+// then votes with it (see [voterWeight]). Delegations are one level deep: an
+// address holding delegations can't delegate, and delegates can't have
+// delegated themselves.
+
+var (
+	ErrSelfDelegation   = errors.New("can't delegate to self")
+	ErrNestedDelegation = errors.New("delegations can't be chained")
+	ErrNotDelegated     = errors.New("weight is not delegated")
+)
+
+type VoteDelegation struct {
+	Delegator    common.Address `serialize:"true" json:"delegator"`
+	Delegate     common.Address `serialize:"true" json:"delegate"`
+	TxID         ids.ID         `serialize:"true" json:"txId"`
+	DelegateTime uint64         `serialize:"true" json:"delegateTime"`
+}
+
+// [delegationsPrefix] + [delimiter] + [delegator]
+func PrefixVoteDelegationKey(delegator common.Address) (k []byte) {
+	k = make([]byte, 2+common.AddressLength)
+	k[0] = delegationsPrefix
+	k[1] = ByteDelimiter
+	copy(k[2:], delegator[:])
+	return
+}
+
+func baseVoteDelegationPrefix() (k []byte) {
+	k = make([]byte, 2)
+	k[0] = delegationsPrefix
+	k[1] = ByteDelimiter
+	return
+}
+
+var _ DelegationsState = &delegationsState{}
+
+type DelegationsState interface {
+	GetVoteDelegation(delegator common.Address) (*VoteDelegation, bool, error)
+	PutVoteDelegation(delegation *VoteDelegation) error
+	DelVoteDelegation(delegator common.Address) error
+	// GetDelegators returns the addresses that delegated to [delegate]
+	GetDelegators(delegate common.Address) ([]common.Address, error)
+	GetVoteDelegations() ([]*VoteDelegation, error)
+}
+
+type delegationsState struct {
+	db database.Database
+}
+
+func NewDelegationsState(db database.Database) *delegationsState {
+	return &delegationsState{db: db}
+}
+
+func (d *delegationsState) GetVoteDelegation(delegator common.Address) (*VoteDelegation, bool, error) {
+	pmeta := new(VoteDelegation)
+	exist, err := getState(d.db, PrefixVoteDelegationKey(delegator), pmeta)
+	if !exist || err != nil {
+		return nil, false, err
+	}
+	return pmeta, true, nil
+}
+
+func (d *delegationsState) PutVoteDelegation(delegation *VoteDelegation) error {
+	if err := d.DelVoteDelegation(delegation.Delegator); err != nil {
+		return err
+	}
+	if err := d.db.Put(PrefixDelegateIndex(delegation.Delegate, delegation.Delegator), nil); err != nil {
+		return err
+	}
+	return putState(d.db, PrefixVoteDelegationKey(delegation.Delegator), delegation)
+}
+
+func (d *delegationsState) DelVoteDelegation(delegator common.Address) error {
+	prev, exist, err := d.GetVoteDelegation(delegator)
+	if err != nil || !exist {
+		return err
+	}
+	if err := d.db.Delete(PrefixDelegateIndex(prev.Delegate, delegator)); err != nil {
+		return err
+	}
+	return d.db.Delete(PrefixVoteDelegationKey(delegator))
+}
+
+func (d *delegationsState) GetDelegators(delegate common.Address) ([]common.Address, error) {
+	delegators := []common.Address(nil)
+	prefix := baseDelegateIndexPrefix(delegate)
+	err := iterateState(d.db, prefix, common.AddressLength, func(k []byte, _ []byte) error {
+		delegators = append(delegators, common.BytesToAddress(k[len(prefix):]))
+		return nil
+	})
+	return delegators, err
+}
+
+func (d *delegationsState) GetVoteDelegations() ([]*VoteDelegation, error) {
+	delegations := []*VoteDelegation(nil)
+	err := iterateState(d.db, baseVoteDelegationPrefix(), common.AddressLength, func(_ []byte, v []byte) error {
+		pmeta := new(VoteDelegation)
+		if _, err := Unmarshal(v, pmeta); err != nil {
+			return err
+		}
+		delegations = append(delegations, pmeta)
+		return nil
+	})
+	return delegations, err
+}
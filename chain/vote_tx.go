@@ -4,61 +4,92 @@
 package chain
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
-	"time"
 
 	"github.com/SamaNetwork/SamaVM/tdata"
 	"github.com/ava-labs/avalanchego/ids"
-	"github.com/ethereum/go-ethereum/common"
 )
 
 var _ UnsignedTransaction = &VoteTx{}
 
+const (
+	VoteFor     = "for"
+	VoteAgainst = "against"
+	VoteAbstain = "abstain"
+)
+
+var ErrInvalidVoteChoice = errors.New("invalid vote choice")
+
+// VoteTx votes on an action, or changes the vote of the sender while voting
+// is open
 type VoteTx struct {
 	*BaseTx  `serialize:"true" json:"baseTx"`
 	ActionID ids.ShortID `serialize:"true" json:"actionID"`
+	// One of [VoteFor], [VoteAgainst] or [VoteAbstain]. Votes from before
+	// [ForkPhase1] have none and are for the action.
+	Choice string `serializeV1:"true" json:"choice"`
+}
+
+// choice returns the vote cast by the tx
+func (v *VoteTx) choice() string {
+	if len(v.Choice) == 0 {
+		return VoteFor
+	}
+	return v.Choice
+}
+
+// Votes without a choice keep the layout from before [ForkPhase1]
+func (v *VoteTx) minCodecVersion() uint16 {
+	if len(v.Choice) == 0 {
+		return legacyCodecVersion
+	}
+	return codecVersion
 }
 
 func (v *VoteTx) Execute(t *TransactionContext) error {
-	samaState := t.vm.SamaState()
-	ok, err := samaState.IsRoute(t.Sender)
+	choice := v.choice()
+	switch choice {
+	case VoteFor, VoteAgainst, VoteAbstain:
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidVoteChoice, v.Choice)
+	}
+	samaState := t.State
+	action, exist, err := samaState.GetActionMeta(v.ActionID, t.BlockTime)
 	if err != nil {
 		return err
 	}
-	if !ok {
-		sroot := samaState.GetRootAddress()
-		if t.Sender != common.HexToAddress(sroot) {
-			return fmt.Errorf("sender is not route node %s %s", t.Sender.String(), sroot)
-		}
-		routeNum, _, _ := samaState.GetStakersNum()
-		if routeNum > 3 {
-			return fmt.Errorf("sender do not have permission")
-		}
+	if !exist {
+		return fmt.Errorf("not found action")
+	}
+	if !action.IsOpen(t.BlockTime) {
+		return ErrActionClosed
 	}
-	action, exist, err := samaState.GetActionMeta(v.ActionID)
+	policy, err := samaState.GetVotingPolicy(action.ActionType)
 	if err != nil {
 		return err
 	}
-	if !exist {
-		return fmt.Errorf("not found action")
+	ok, err := canVote(samaState, policy, t.Sender)
+	if err != nil {
+		return err
 	}
-	for _, voter := range action.Voters {
-		if voter == t.Sender {
-			return fmt.Errorf("voter is exist")
-		}
+	if !ok {
+		return fmt.Errorf("sender do not have permission %s", t.Sender.String())
+	}
+	if action.VoteOf(t.Sender) == choice {
+		return fmt.Errorf("voter is exist")
 	}
 
-	if uint64(time.Now().Unix()) > action.EndTime {
+	if t.BlockTime > action.EndTime {
 		return fmt.Errorf("action timeout")
 	}
 
 	pmate := new(ActionMeta)
 	*pmate = *action
 	pmate.TxIDs = append(pmate.TxIDs, t.TxID)
-	pmate.Voters = append(pmate.Voters, t.Sender)
-	samaState.PutAction(t.Database, v.ActionID, pmate)
-	return nil
+	pmate.setVote(t.Sender, choice)
+	return putVotedAction(t, pmate)
 }
 
 func (v *VoteTx) FeeUnits(genesis *Genesis) uint64 {
@@ -75,19 +106,38 @@ func (v *VoteTx) Copy() UnsignedTransaction {
 	return &VoteTx{
 		BaseTx:   v.BaseTx.Copy(),
 		ActionID: actionID,
+		Choice:   v.Choice,
 	}
 }
 
 func (v *VoteTx) TypedData() *tdata.TypedData {
+	if len(v.Choice) == 0 {
+		// Votes from before [ForkPhase1] are signed without a choice
+		return tdata.CreateTypedData(
+			v.Magic, Govern,
+			[]tdata.Type{
+				{Name: tdActionID, Type: tdString},
+				{Name: tdPrice, Type: tdUint64},
+				{Name: tdBlockID, Type: tdString},
+			},
+			tdata.TypedDataMessage{
+				tdActionID: v.ActionID.String(),
+				tdPrice:    strconv.FormatUint(v.Price, 10),
+				tdBlockID:  v.BlockID.String(),
+			},
+		)
+	}
 	return tdata.CreateTypedData(
-		v.Magic, Govern,
+		v.Magic, Vote,
 		[]tdata.Type{
 			{Name: tdActionID, Type: tdString},
+			{Name: tdChoice, Type: tdString},
 			{Name: tdPrice, Type: tdUint64},
 			{Name: tdBlockID, Type: tdString},
 		},
 		tdata.TypedDataMessage{
 			tdActionID: v.ActionID.String(),
+			tdChoice:   v.Choice,
 			tdPrice:    strconv.FormatUint(v.Price, 10),
 			tdBlockID:  v.BlockID.String(),
 		},
@@ -96,7 +146,8 @@ func (v *VoteTx) TypedData() *tdata.TypedData {
 
 func (v *VoteTx) Activity() *Activity {
 	return &Activity{
-		Typ:      Govern,
+		Typ:      Vote,
 		ActionID: v.ActionID,
+		Value:    v.choice(),
 	}
 }
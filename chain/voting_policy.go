@@ -0,0 +1,343 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// 0x10/0x1/[actionType] (voting policies changed by governance)
+//   -> policy
+
+const (
+	votingPolicyParam = 0x1
+
+	// Quorums and thresholds are in basis points
+	MaxBasisPoints = 10000
+)
+
+var ErrInvalidVotingPolicy = errors.New("invalid voting policy")
+
+// VotingPolicy decides who votes on the actions of a type and when they are
+// approved
+type VotingPolicy struct {
+	ActionType uint64 `serialize:"true" json:"actionType"`
+	// Staker types allowed to vote
+	VoterTypes []uint64 `serialize:"true" json:"voterTypes"`
+	// Weigh votes by the stake of the voter instead of one per node
+	StakeWeighted bool `serialize:"true" json:"stakeWeighted"`
+	// Weight that must have voted, of the weight of all voters
+	Quorum uint64 `serialize:"true" json:"quorum"`
+	// Weight that must be for the action, of the weight for and against it
+	Threshold uint64 `serialize:"true" json:"threshold"`
+	// Weight against the action, of the weight of all voters, that rejects
+	// it before voting ends (0 never rejects early)
+	Blocking uint64 `serialize:"true" json:"blocking"`
+	// While there are fewer voters than this, the root address decides
+	RootVoters uint64 `serialize:"true" json:"rootVoters"`
+	// Execute approved actions in the first block after voting ends and
+	// [Timelock] seconds passed, instead of with [GovernTx]
+	AutoExecute bool   `serialize:"true" json:"autoExecute"`
+	Timelock    uint64 `serialize:"true" json:"timelock"`
+}
+
+// DefaultVotingPolicy lets 2/3 of the route nodes (or the root address while
+// there are fewer than 3 of them) approve actions of [actionType], and 1/3 of
+// them reject it
+func DefaultVotingPolicy(actionType uint64) *VotingPolicy {
+	return &VotingPolicy{
+		ActionType: actionType,
+		VoterTypes: []uint64{stakerTypeRoute},
+		Quorum:     6667,
+		Threshold:  5001,
+		Blocking:   3334,
+		RootVoters: 3,
+	}
+}
+
+func (p *VotingPolicy) Verify() error {
+	if _, err := GetGovernanceAction(p.ActionType); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidVotingPolicy, err)
+	}
+	if len(p.VoterTypes) == 0 {
+		return fmt.Errorf("%w: no voter types", ErrInvalidVotingPolicy)
+	}
+	for i, voterType := range p.VoterTypes {
+		if !isStakerType(voterType) {
+			return fmt.Errorf("%w: voter type %d", ErrInvalidVotingPolicy, voterType)
+		}
+		for _, prev := range p.VoterTypes[:i] {
+			if prev == voterType {
+				return fmt.Errorf("%w: duplicate voter type %d", ErrInvalidVotingPolicy, voterType)
+			}
+		}
+	}
+	if p.Quorum > MaxBasisPoints || p.Threshold > MaxBasisPoints || p.Blocking > MaxBasisPoints {
+		return fmt.Errorf("%w: quorum and thresholds are basis points", ErrInvalidVotingPolicy)
+	}
+	if p.AutoExecute && p.ActionType == actionTypeAddStaker {
+		return fmt.Errorf("%w: stakers are added by StakeTx", ErrInvalidVotingPolicy)
+	}
+	if p.Timelock >= ActionExecutionWindow {
+		return fmt.Errorf("%w: timelock must be shorter than %ds", ErrInvalidVotingPolicy, ActionExecutionWindow)
+	}
+	return nil
+}
+
+func isStakerType(stakerType uint64) bool {
+	for _, t := range StakerTypes {
+		if uint64(t) == stakerType {
+			return true
+		}
+	}
+	return false
+}
+
+// [sysParamPrefix] + [delimiter] + [votingPolicyParam] + [actionType]
+func PrefixVotingPolicyKey(actionType uint64) (k []byte) {
+	k = make([]byte, 3+8)
+	k[0] = sysParamPrefix
+	k[1] = ByteDelimiter
+	k[2] = votingPolicyParam
+	binary.BigEndian.PutUint64(k[3:], actionType)
+	return
+}
+
+// GetVotingPolicy returns the policy of [actionType] last set by governance,
+// the one set in genesis or the default one, in that order
+func (s *sysParams) GetVotingPolicy(actionType uint64) (*VotingPolicy, error) {
+	policy := new(VotingPolicy)
+	exist, err := getState(s.db, PrefixVotingPolicyKey(actionType), policy)
+	if err != nil || exist {
+		return policy, err
+	}
+	for _, p := range s.genesis.VotingPolicies {
+		if p.ActionType == actionType {
+			return p, nil
+		}
+	}
+	return DefaultVotingPolicy(actionType), nil
+}
+
+func (s *sysParams) PutVotingPolicy(policy *VotingPolicy) error {
+	return putState(s.db, PrefixVotingPolicyKey(policy.ActionType), policy)
+}
+
+// Tally is the count of the votes on an action
+type Tally struct {
+	// Number and weight of the stakers allowed to vote
+	Voters      int    `serialize:"true" json:"voters"`
+	TotalWeight uint64 `serialize:"true" json:"totalWeight"`
+	// Weight of the votes cast, and of those for, against and abstaining
+	Cast    uint64 `serialize:"true" json:"cast"`
+	For     uint64 `serialize:"true" json:"for"`
+	Against uint64 `serialize:"true" json:"against"`
+	Abstain uint64 `serialize:"true" json:"abstain"`
+	// Weights needed to reach quorum, to approve and to reject the action
+	Quorum    uint64 `serialize:"true" json:"quorum"`
+	Threshold uint64 `serialize:"true" json:"threshold"`
+	Blocking  uint64 `serialize:"true" json:"blocking"`
+	// Set if the root address decides because there are too few voters
+	RootDecides bool `serialize:"true" json:"rootDecides"`
+	Approved    bool `serialize:"true" json:"approved"`
+	Rejected    bool `serialize:"true" json:"rejected"`
+}
+
+// weightOf returns the share [bp] (in basis points) of [weight], rounded up
+func weightOf(weight uint64, bp uint64) uint64 {
+	return (weight*bp + MaxBasisPoints - 1) / MaxBasisPoints
+}
+
+// stakeWeight returns the weight of the stake of [staker] under [policy] (0
+// if it is not allowed to vote)
+func stakeWeight(s SamaState, policy *VotingPolicy, staker common.Address) (uint64, error) {
+	for _, voterType := range policy.VoterTypes {
+		pmeta, exist, err := s.GetStakerMeta(byte(voterType), staker)
+		if err != nil {
+			return 0, err
+		}
+		if !exist {
+			continue
+		}
+		if _, suspended, err := s.GetSuspension(staker); err != nil || suspended {
+			return 0, err
+		}
+		if policy.StakeWeighted {
+			return pmeta.StakeAmount, nil
+		}
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// voterWeight returns the weight [voter] votes with under [policy]: its own
+// stake unless it delegated it, and the stakes delegated to it
+func voterWeight(s SamaState, policy *VotingPolicy, voter common.Address) (uint64, error) {
+	if _, suspended, err := s.GetSuspension(voter); err != nil || suspended {
+		return 0, err
+	}
+	weight := uint64(0)
+	_, delegated, err := s.GetVoteDelegation(voter)
+	if err != nil {
+		return 0, err
+	}
+	if !delegated {
+		if weight, err = stakeWeight(s, policy, voter); err != nil {
+			return 0, err
+		}
+	}
+	delegators, err := s.GetDelegators(voter)
+	if err != nil {
+		return 0, err
+	}
+	for _, delegator := range delegators {
+		w, err := stakeWeight(s, policy, delegator)
+		if err != nil {
+			return 0, err
+		}
+		weight += w
+	}
+	return weight, nil
+}
+
+// eligibleVoters returns the number and total weight of the stakers allowed
+// to vote under [policy]. Suspended stakers are left out.
+func eligibleVoters(s SamaState, policy *VotingPolicy) (int, uint64, error) {
+	voters, weight := 0, uint64(0)
+	for _, voterType := range policy.VoterTypes {
+		stakers, err := s.GetStakers(byte(voterType))
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, staker := range stakers {
+			_, suspended, err := s.GetSuspension(staker.StakerAddr)
+			if err != nil {
+				return 0, 0, err
+			}
+			if suspended {
+				continue
+			}
+			voters++
+			if policy.StakeWeighted {
+				weight += staker.StakeAmount
+			} else {
+				weight++
+			}
+		}
+	}
+	return voters, weight, nil
+}
+
+// canVote reports whether [voter] may vote under [policy]
+func canVote(s SamaState, policy *VotingPolicy, voter common.Address) (bool, error) {
+	weight, err := voterWeight(s, policy, voter)
+	if err != nil || weight > 0 {
+		return weight > 0, err
+	}
+	if voter != common.HexToAddress(s.GetRootAddress()) {
+		return false, nil
+	}
+	voters, _, err := eligibleVoters(s, policy)
+	if err != nil {
+		return false, err
+	}
+	return uint64(voters) < policy.RootVoters, nil
+}
+
+// TallyAction counts the votes on [action] under the policy of its type
+func TallyAction(s SamaState, action *ActionMeta) (*Tally, error) {
+	policy, err := s.GetVotingPolicy(action.ActionType)
+	if err != nil {
+		return nil, err
+	}
+	voters, total, err := eligibleVoters(s, policy)
+	if err != nil {
+		return nil, err
+	}
+	tally := &Tally{
+		Voters:      voters,
+		TotalWeight: total,
+		// Rounded down, so 2/3 of 4 voters is 2
+		Quorum: total * policy.Quorum / MaxBasisPoints,
+	}
+	if uint64(voters) < policy.RootVoters {
+		tally.RootDecides = true
+		switch action.VoteOf(common.HexToAddress(s.GetRootAddress())) {
+		case VoteFor:
+			tally.Approved = true
+		case VoteAgainst:
+			tally.Rejected = true
+		}
+		return tally, nil
+	}
+
+	for _, votes := range []struct {
+		voters []common.Address
+		weight *uint64
+	}{
+		{action.Voters, &tally.For},
+		{action.Against, &tally.Against},
+		{action.Abstain, &tally.Abstain},
+	} {
+		for _, voter := range votes.voters {
+			weight, err := voterWeight(s, policy, voter)
+			if err != nil {
+				return nil, err
+			}
+			*votes.weight += weight
+			tally.Cast += weight
+		}
+	}
+	tally.Threshold = weightOf(tally.For+tally.Against, policy.Threshold)
+	if policy.Blocking > 0 {
+		tally.Blocking = weightOf(total, policy.Blocking)
+		tally.Rejected = tally.Against > 0 && tally.Against >= tally.Blocking
+	}
+	tally.Approved = !tally.Rejected && tally.For > 0 && tally.Cast >= tally.Quorum && tally.For >= tally.Threshold
+	return tally, nil
+}
+
+// votingPolicyAction replaces the voting policy of the action type [key]
+// with the JSON encoded policy [newValue]
+type votingPolicyAction struct {
+	tallyApproval
+}
+
+func (*votingPolicyAction) minFork() string {
+	return ForkPhase4
+}
+
+func parseVotingPolicy(key string, newValue string) (*VotingPolicy, error) {
+	actionType, err := strconv.ParseUint(key, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	policy := new(VotingPolicy)
+	if err := json.Unmarshal([]byte(newValue), policy); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidVotingPolicy, err)
+	}
+	if policy.ActionType != actionType {
+		return nil, fmt.Errorf("%w: policy of action type %d", ErrInvalidVotingPolicy, policy.ActionType)
+	}
+	return policy, policy.Verify()
+}
+
+func (*votingPolicyAction) Validate(_ *TransactionContext, key string, newValue string) error {
+	_, err := parseVotingPolicy(key, newValue)
+	return err
+}
+
+func (*votingPolicyAction) Execute(t *TransactionContext, action *ActionMeta) error {
+	policy, err := parseVotingPolicy(action.Key, action.NewValue)
+	if err != nil {
+		return err
+	}
+	return t.State.PutVotingPolicy(policy)
+}
@@ -0,0 +1,328 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/database/memdb"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestVotingPolicy(t *testing.T) {
+	t.Parallel()
+
+	g := DefaultGenesis()
+	g.Magic = 1
+	g.VotingPolicies = []*VotingPolicy{{ActionType: actionTypeAddUserType}}
+	if err := g.Verify(); !errors.Is(err, ErrInvalidVotingPolicy) {
+		t.Fatalf("expected %v, got %v", ErrInvalidVotingPolicy, err)
+	}
+	policy := DefaultVotingPolicy(actionTypeModifyUserType)
+	policy.Threshold = 10000
+	g.VotingPolicies = []*VotingPolicy{policy}
+	if err := g.Verify(); err != nil {
+		t.Fatal(err)
+	}
+
+	db := memdb.New()
+	defer db.Close()
+	if err := g.Load(db, nil); err != nil {
+		t.Fatal(err)
+	}
+	state := SamaNew(db, g)
+	if p, err := state.GetVotingPolicy(actionTypeModifyUserType); err != nil || p.Threshold != 10000 {
+		t.Fatalf("genesis policy not used (%v)", err)
+	}
+	if p, err := state.GetVotingPolicy(actionTypeAddUserType); err != nil || p.Quorum != 6667 {
+		t.Fatalf("default policy not used (%v)", err)
+	}
+
+	// 4 route nodes with 100 staked in total and a service node
+	routes := []common.Address{{1}, {2}, {3}, {4}}
+	for i, amount := range []uint64{10, 10, 10, 70} {
+		if err := state.PutStaker(&StakerMeta{
+			StakerType:  stakerTypeRoute,
+			StakeAmount: amount,
+			StakerAddr:  routes[i],
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ser := common.Address{5}
+	if err := state.PutStaker(&StakerMeta{StakerType: stakerTypeSer, StakeAmount: 1000, StakerAddr: ser}); err != nil {
+		t.Fatal(err)
+	}
+
+	root := common.HexToAddress(state.GetRootAddress())
+	tctx := testTxContexts(g, db, Rules{IsPhase4: true})
+	vote := func(actionID ids.ShortID, voter common.Address) error {
+		return (&VoteTx{ActionID: actionID, Choice: VoteFor}).Execute(tctx(voter, 11))
+	}
+	tally := func(actionID ids.ShortID) *Tally {
+		action, _, err := state.GetActionMeta(actionID, 11)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tally, err := TallyAction(state, action)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tally
+	}
+
+	// Stake weight the votes on new user types
+	newPolicy, err := json.Marshal(&VotingPolicy{
+		ActionType:    actionTypeAddUserType,
+		VoterTypes:    []uint64{stakerTypeRoute},
+		StakeWeighted: true,
+		Quorum:        5000,
+		Threshold:     5001,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	proposal := &ProposalTx{ActionID: ids.ShortID{1}, ActionType: actionTypeSetVotingPolicy, Key: "3", NewValue: string(newPolicy)}
+	if err := proposal.Execute(tctx(routes[0], 10)); err != nil {
+		t.Fatal(err)
+	}
+	if tl := tally(ids.ShortID{1}); tl.Voters != 4 || tl.Cast != 1 || tl.Quorum != 2 || tl.Approved {
+		t.Fatalf("unexpected tally %+v", tl)
+	}
+	if err := vote(ids.ShortID{1}, root); err == nil {
+		t.Fatal("root voted with enough route nodes")
+	}
+	if err := vote(ids.ShortID{1}, ser); err == nil {
+		t.Fatal("service node voted")
+	}
+	if err := vote(ids.ShortID{1}, routes[1]); err != nil {
+		t.Fatal(err)
+	}
+	if tl := tally(ids.ShortID{1}); !tl.Approved {
+		t.Fatalf("action not approved %+v", tl)
+	}
+	if err := (&GovernTx{ActionID: ids.ShortID{1}}).Execute(tctx(root, 12)); err != nil {
+		t.Fatal(err)
+	}
+	if p, err := state.GetVotingPolicy(actionTypeAddUserType); err != nil || !p.StakeWeighted {
+		t.Fatalf("policy not changed (%v)", err)
+	}
+
+	// 3 small route nodes can't outvote the quorum
+	proposal = &ProposalTx{ActionID: ids.ShortID{2}, ActionType: actionTypeAddUserType, Key: "7", NewValue: "100"}
+	if err := proposal.Execute(tctx(routes[0], 10)); err != nil {
+		t.Fatal(err)
+	}
+	for _, route := range routes[1:3] {
+		if err := vote(ids.ShortID{2}, route); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if tl := tally(ids.ShortID{2}); tl.TotalWeight != 100 || tl.Cast != 30 || tl.Quorum != 50 || tl.Approved {
+		t.Fatalf("unexpected tally %+v", tl)
+	}
+	if err := vote(ids.ShortID{2}, routes[3]); err != nil {
+		t.Fatal(err)
+	}
+	if tl := tally(ids.ShortID{2}); tl.Cast != 100 || tl.Threshold != 51 || !tl.Approved {
+		t.Fatalf("unexpected tally %+v", tl)
+	}
+
+	// Policies must match the action type they are proposed for
+	proposal = &ProposalTx{ActionID: ids.ShortID{3}, ActionType: actionTypeSetVotingPolicy, Key: "4", NewValue: string(newPolicy)}
+	if err := proposal.Execute(tctx(routes[0], 10)); !errors.Is(err, ErrInvalidVotingPolicy) {
+		t.Fatalf("expected %v, got %v", ErrInvalidVotingPolicy, err)
+	}
+}
+
+func TestVoteChoices(t *testing.T) {
+	t.Parallel()
+
+	db := memdb.New()
+	defer db.Close()
+	g := DefaultGenesis()
+	if err := g.Load(db, nil); err != nil {
+		t.Fatal(err)
+	}
+	state := SamaNew(db, g)
+	routes := []common.Address{{1}, {2}, {3}, {4}, {5}, {6}}
+	for _, route := range routes {
+		if err := state.PutStaker(&StakerMeta{StakerType: stakerTypeRoute, StakerAddr: route}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tctx := testTxContexts(g, db, Rules{})
+	vote := func(actionID ids.ShortID, voter common.Address, choice string) error {
+		return (&VoteTx{ActionID: actionID, Choice: choice}).Execute(tctx(voter, 11))
+	}
+	status := func(actionID ids.ShortID) (*ActionMeta, *Tally) {
+		action, _, err := state.GetActionMeta(actionID, 11)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tally, err := TallyAction(state, action)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return action, tally
+	}
+	for i := byte(1); i <= 2; i++ {
+		proposal := &ProposalTx{ActionID: ids.ShortID{i}, ActionType: actionTypeAddUserType, Key: fmt.Sprint(i), NewValue: "100"}
+		if err := proposal.Execute(tctx(routes[0], 11)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := vote(ids.ShortID{1}, routes[1], "yes"); !errors.Is(err, ErrInvalidVoteChoice) {
+		t.Fatalf("expected %v, got %v", ErrInvalidVoteChoice, err)
+	}
+	if err := vote(ids.ShortID{1}, routes[0], VoteFor); err == nil {
+		t.Fatal("voted twice")
+	}
+
+	// Abstaining counts towards the quorum only, a tie is not approved
+	for _, v := range []struct {
+		voter  common.Address
+		choice string
+	}{
+		{routes[1], VoteAbstain},
+		{routes[2], VoteAbstain},
+		{routes[3], VoteAgainst},
+	} {
+		if err := vote(ids.ShortID{1}, v.voter, v.choice); err != nil {
+			t.Fatal(err)
+		}
+	}
+	action, tally := status(ids.ShortID{1})
+	if tally.For != 1 || tally.Against != 1 || tally.Abstain != 2 || tally.Threshold != 2 || tally.Approved || tally.Rejected {
+		t.Fatalf("unexpected tally %+v", tally)
+	}
+	if action.StatusAt(11) != ActionPending {
+		t.Fatalf("unexpected status %s", action.StatusAt(11))
+	}
+
+	// Changing a vote moves it
+	if err := vote(ids.ShortID{1}, routes[3], VoteFor); err != nil {
+		t.Fatal(err)
+	}
+	action, tally = status(ids.ShortID{1})
+	if len(action.Against) != 0 || tally.For != 2 || !tally.Approved || action.StatusAt(11) != ActionApproved {
+		t.Fatalf("unexpected tally %+v", tally)
+	}
+	if err := (&WithdrawnTx{ActionID: ids.ShortID{1}}).Execute(tctx(routes[3], 11)); err != nil {
+		t.Fatal(err)
+	}
+	if action, _ = status(ids.ShortID{1}); action.VoteOf(routes[3]) != "" || action.StatusAt(11) != ActionPending {
+		t.Fatal("vote not withdrawn")
+	}
+
+	// Half of the routes against rejects the action before voting ends, as
+	// the others can no longer approve it
+	for _, voter := range routes[1:4] {
+		if err := vote(ids.ShortID{2}, voter, VoteAgainst); err != nil {
+			t.Fatal(err)
+		}
+	}
+	action, tally = status(ids.ShortID{2})
+	if tally.Blocking != 3 || !tally.Rejected || action.Status != ActionRejected {
+		t.Fatalf("action not rejected %+v", tally)
+	}
+	if err := vote(ids.ShortID{2}, routes[4], VoteFor); !errors.Is(err, ErrActionClosed) {
+		t.Fatalf("expected %v, got %v", ErrActionClosed, err)
+	}
+}
+
+func TestVoteDelegation(t *testing.T) {
+	t.Parallel()
+
+	db := memdb.New()
+	defer db.Close()
+	g := DefaultGenesis()
+	if err := g.Load(db, nil); err != nil {
+		t.Fatal(err)
+	}
+	state := SamaNew(db, g)
+	routes := []common.Address{{1}, {2}, {3}, {4}, {5}, {6}}
+	for _, route := range routes {
+		if err := state.PutStaker(&StakerMeta{StakerType: stakerTypeRoute, StakerAddr: route}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	operator := common.Address{9}
+	tctx := testTxContexts(g, db, Rules{})
+	delegate := func(delegator common.Address, to common.Address) error {
+		return (&DelegateVoteTx{Delegate: to}).Execute(tctx(delegator, 11))
+	}
+	tally := func(actionID ids.ShortID) *Tally {
+		action, _, err := state.GetActionMeta(actionID, 11)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tally, err := TallyAction(state, action)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tally
+	}
+
+	for _, route := range routes[1:5] {
+		if err := delegate(route, operator); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := delegate(routes[0], routes[0]); !errors.Is(err, ErrSelfDelegation) {
+		t.Fatalf("expected %v, got %v", ErrSelfDelegation, err)
+	}
+	if err := delegate(routes[0], routes[1]); !errors.Is(err, ErrNestedDelegation) {
+		t.Fatalf("expected %v, got %v", ErrNestedDelegation, err)
+	}
+	if err := delegate(operator, routes[0]); err == nil {
+		t.Fatal("non staker delegated")
+	}
+	if delegators, err := state.GetDelegators(operator); err != nil || len(delegators) != 4 {
+		t.Fatalf("unexpected delegators %v (%v)", delegators, err)
+	}
+
+	// A delegator no longer votes itself
+	proposal := &ProposalTx{ActionID: ids.ShortID{1}, ActionType: actionTypeAddUserType, Key: "1", NewValue: "100"}
+	if err := proposal.Execute(tctx(routes[0], 11)); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&VoteTx{ActionID: ids.ShortID{1}, Choice: VoteFor}).Execute(tctx(routes[1], 11)); err == nil {
+		t.Fatal("delegator voted")
+	}
+	if tl := tally(ids.ShortID{1}); tl.Cast != 1 || tl.Approved {
+		t.Fatalf("unexpected tally %+v", tl)
+	}
+
+	// Revoking a delegation takes its weight back
+	if err := delegate(routes[4], common.Address{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := delegate(routes[4], common.Address{}); !errors.Is(err, ErrNotDelegated) {
+		t.Fatalf("expected %v, got %v", ErrNotDelegated, err)
+	}
+	if err := (&VoteTx{ActionID: ids.ShortID{1}, Choice: VoteFor}).Execute(tctx(operator, 11)); err != nil {
+		t.Fatal(err)
+	}
+	if tl := tally(ids.ShortID{1}); tl.Voters != 6 || tl.For != 4 || tl.Cast != 4 || !tl.Approved {
+		t.Fatalf("unexpected tally %+v", tl)
+	}
+	confirmed, _, err := state.IsBeConfirmed(actionTypeAddUserType, "1", 11)
+	if err != nil || !confirmed {
+		t.Fatalf("delegated weight not confirmed (%v)", err)
+	}
+
+	// Unstaking drops the delegation
+	if err := state.DealUnStakeTx(stakerTypeRoute, routes[1], ids.GenerateTestID(), 11); err != nil {
+		t.Fatal(err)
+	}
+	if delegators, err := state.GetDelegators(operator); err != nil || len(delegators) != 2 {
+		t.Fatalf("unexpected delegators %v (%v)", delegators, err)
+	}
+}
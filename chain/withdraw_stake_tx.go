@@ -0,0 +1,80 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package chain
+
+import (
+	"strconv"
+
+	"github.com/SamaNetwork/SamaVM/tdata"
+)
+
+var _ UnsignedTransaction = &WithdrawStakeTx{}
+
+// WithdrawStakeTx returns to the balance of the sender the stake it
+// unbonded, once the unbonding period ended
+type WithdrawStakeTx struct {
+	*BaseTx `serialize:"true" json:"baseTx"`
+}
+
+func (w *WithdrawStakeTx) Execute(t *TransactionContext) error {
+	samaState := t.State
+	unbondings, err := samaState.GetUnbondings(t.Sender)
+	if err != nil {
+		return err
+	}
+	withdrawn := false
+	for _, unbonding := range unbondings {
+		if unbonding.ReleaseTime > t.BlockTime {
+			continue
+		}
+		if _, err := ModifyStakeBalance(t.Database, t.Sender, false, unbonding.Amount); err != nil {
+			return err
+		}
+		if _, err := ModifyBalance(t.Database, t.Sender, true, unbonding.Amount); err != nil {
+			return err
+		}
+		if err := samaState.DelUnbonding(t.Sender, unbonding.TxID); err != nil {
+			return err
+		}
+		withdrawn = true
+	}
+	if !withdrawn {
+		return ErrNoUnbonded
+	}
+	return nil
+}
+
+func (w *WithdrawStakeTx) FeeUnits(g *Genesis) uint64 {
+	return w.BaseTx.FeeUnits(g)
+}
+
+func (w *WithdrawStakeTx) LoadUnits(g *Genesis) uint64 {
+	return w.FeeUnits(g)
+}
+
+func (w *WithdrawStakeTx) Copy() UnsignedTransaction {
+	return &WithdrawStakeTx{
+		BaseTx: w.BaseTx.Copy(),
+	}
+}
+
+func (w *WithdrawStakeTx) TypedData() *tdata.TypedData {
+	return tdata.CreateTypedData(
+		w.Magic, WithdrawStake,
+		[]tdata.Type{
+			{Name: tdPrice, Type: tdUint64},
+			{Name: tdBlockID, Type: tdString},
+		},
+		tdata.TypedDataMessage{
+			tdPrice:   strconv.FormatUint(w.Price, 10),
+			tdBlockID: w.BlockID.String(),
+		},
+	)
+}
+
+func (w *WithdrawStakeTx) Activity() *Activity {
+	return &Activity{
+		Typ: WithdrawStake,
+	}
+}
@@ -19,31 +19,33 @@ type WithdrawnTx struct {
 }
 
 func (w *WithdrawnTx) Execute(t *TransactionContext) error {
-	samaState := t.vm.SamaState()
-	ok, err := samaState.IsRoute(t.Sender)
-	if err != nil {
-		return err
-	}
-	if !ok {
-		return fmt.Errorf("sender is not route node")
-	}
-	pmate, exist, err := samaState.GetActionMeta(w.ActionID)
+	samaState := t.State
+	pmate, exist, err := samaState.GetActionMeta(w.ActionID, t.BlockTime)
 	if err != nil {
 		return err
 	}
 	if !exist {
 		return fmt.Errorf("not found action")
 	}
+	if !pmate.IsOpen(t.BlockTime) {
+		return ErrActionClosed
+	}
+	if t.BlockTime > pmate.EndTime {
+		return fmt.Errorf("action timeout")
+	}
 
-	for i, voter := range pmate.Voters {
-		if voter == t.Sender {
-			pmate.Voters = append(pmate.Voters[:i], pmate.Voters[i+1:]...)
-			break
-		}
+	// The proposer withdraws the action, others their vote
+	if t.Sender == pmate.Proposer {
+		pmate.TxIDs = append(pmate.TxIDs, t.TxID)
+		pmate.Transition(ActionWithdrawn, t.TxID, t.BlockTime)
+		return samaState.PutAction(w.ActionID, pmate)
+	}
+	if pmate.VoteOf(t.Sender) == "" {
+		return fmt.Errorf("sender did not vote")
 	}
+	pmate.setVote(t.Sender, "")
 	pmate.TxIDs = append(pmate.TxIDs, t.TxID)
-	samaState.PutAction(t.Database, w.ActionID, pmate)
-	return nil
+	return putVotedAction(t, pmate)
 }
 
 func (w *WithdrawnTx) FeeUnits(genesis *Genesis) uint64 {
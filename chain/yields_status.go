@@ -26,82 +26,45 @@ type YieldMeta struct {
 
 type YieldsState interface {
 	GetChainYields() uint64
-	ModifyYields(db database.Database, yield uint64, txID ids.ID, blkTime uint64) error
-	ReloadYields(db database.Database) error
-	CacheYieldsCommit() error
-	CacheYieldsAbort() error
+	ModifyYields(yield uint64, txID ids.ID, blkTime uint64) error
 }
 
 type yieldsState struct {
-	curYields     *YieldMeta
-	pendingYields *YieldMeta
+	db database.Database
 }
 
-func NewYieldsState(db database.Database) (*yieldsState, error) {
-	s := &yieldsState{
-		curYields: &YieldMeta{
-			Total:         0,
-			Undistributed: 0,
-			LastOprTime:   0,
-		},
-		pendingYields: &YieldMeta{
-			Total:         0,
-			Undistributed: 0,
-			LastOprTime:   0,
-		},
-	}
-	err := s.ReloadYields(db)
-	if err != nil {
-		if err != database.ErrNotFound {
-			return nil, err
-		}
-	}
-	return s, nil
-}
-
-func (y *yieldsState) GetChainYields() uint64 {
-	return y.curYields.Total
+func NewYieldsState(db database.Database) *yieldsState {
+	return &yieldsState{db: db}
 }
 
-func (y *yieldsState) ModifyYields(db database.Database, yield uint64, txID ids.ID, blkTime uint64) error {
-	y.pendingYields.Total = y.curYields.Total + yield
-	if yield == 0 {
-		y.pendingYields.Undistributed = 0
-	} else {
-		y.pendingYields.Undistributed = y.curYields.Undistributed + yield
+func (y *yieldsState) getYields() (*YieldMeta, error) {
+	ymeta := new(YieldMeta)
+	if _, err := getState(y.db, PrefixYieldsKey(), ymeta); err != nil {
+		return nil, err
 	}
-	y.pendingYields.LastOprTXID = txID
-	y.pendingYields.LastOprTime = blkTime
+	return ymeta, nil
+}
 
-	k := PrefixYieldsKey()
-	pvmeta, err := Marshal(y.pendingYields)
+func (y *yieldsState) GetChainYields() uint64 {
+	ymeta, err := y.getYields()
 	if err != nil {
-		return err
+		return 0
 	}
-	return db.Put(k, pvmeta)
+	return ymeta.Total
 }
 
-func (y *yieldsState) ReloadYields(db database.Database) error {
-	k := PrefixYieldsKey()
-	ymeta, err := db.Get(k)
+func (y *yieldsState) ModifyYields(yield uint64, txID ids.ID, blkTime uint64) error {
+	ymeta, err := y.getYields()
 	if err != nil {
 		return err
 	}
-	if _, err := Unmarshal(ymeta, y.curYields); err != nil {
-		return err
-	}
-	return nil
-}
-
-func (y *yieldsState) CacheYieldsCommit() error {
-	if y.pendingYields.Total != 0 {
-		*y.curYields = *y.pendingYields
-		y.pendingYields.Total = 0
+	ymeta.Total += yield
+	if yield == 0 {
+		ymeta.Undistributed = 0
+	} else {
+		ymeta.Undistributed += yield
 	}
-	return nil
-}
-
-func (y *yieldsState) CacheYieldsAbort() error {
-	y.pendingYields.Total = 0
-	return nil
+	ymeta.LastOprTXID = txID
+	ymeta.LastOprTime = blkTime
+	return putState(y.db, PrefixYieldsKey(), ymeta)
 }
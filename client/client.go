@@ -5,6 +5,7 @@
 package client
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"time"
@@ -34,7 +35,13 @@ type Client interface {
 	Accepted(ctx context.Context) (ids.ID, error)
 
 	// Balance returns the balance of an account
-	Balance(ctx context.Context, addr common.Address) (bal uint64, err error)
+	Balance(ctx context.Context, addr common.Address, opts ...StateOption) (bal uint64, err error)
+	// StakeBalance returns the amount staked by an account
+	StakeBalance(ctx context.Context, addr common.Address, opts ...StateOption) (uint64, error)
+	// GetProof returns a proof of the value of a state key (such as
+	// [chain.PrefixBalanceKey]) at [height] (or the last accepted block if
+	// nil), checked against the returned state root.
+	GetProof(ctx context.Context, key []byte, height *uint64) (*vm.GetProofReply, error)
 	// Resolve returns the value associated with a path
 	Resolve(ctx context.Context, key common.Hash) (exists bool, value []byte, valueMeta *chain.ValueMeta, err error)
 
@@ -51,16 +58,40 @@ type Client interface {
 
 	// Checks the status of the transaction, and returns "true" if confirmed.
 	HasTx(ctx context.Context, id ids.ID) (bool, error)
-	// Polls the transactions until its status is confirmed.
+	// Polls the transactions until its status is confirmed. Returns
+	// [ErrTxDropped] if the node dropped the transaction.
 	PollTx(ctx context.Context, txID ids.ID) (confirmed bool, err error)
+	// GetTxReceipt returns the status of the transaction and, once it is
+	// accepted, its receipt.
+	GetTxReceipt(ctx context.Context, txID ids.ID) (*vm.GetTxReceiptReply, error)
+	// GetTx returns an accepted transaction and the block it is in.
+	GetTx(ctx context.Context, txID ids.ID) (*vm.GetTxReply, error)
+
+	// Accepted blocks with their decoded transactions
+	GetBlockByHeight(ctx context.Context, height uint64) (*vm.APIBlock, error)
+	GetBlockByID(ctx context.Context, blkID ids.ID) (*vm.APIBlock, error)
 
 	// Recent actions on the network (sorted from recent to oldest)
 	RecentActivity(ctx context.Context) ([]*chain.Activity, error)
-
-	CalcReward(ctx context.Context, stakerType uint64, endTime uint64, address common.Address) (uint64, uint64, uint64, error)
+	// GetActivityByAddress returns a page of the accepted activity of
+	// [address] (of type [typ] if set) from [fromHeight], and the position
+	// of the next page if there is one
+	GetActivityByAddress(ctx context.Context, address common.Address, typ string, fromHeight uint64, fromIndex uint32, limit int) (*vm.GetActivityByAddressReply, error)
+
+	CalcReward(ctx context.Context, stakerType uint64, endTime uint64, address common.Address, opts ...StateOption) (uint64, uint64, uint64, error)
+	// GetFoundationVesting returns the foundation share vested and claimable
+	// at [endTime]
+	GetFoundationVesting(ctx context.Context, endTime uint64, opts ...StateOption) (*chain.VestingStatus, error)
 	GetUserFee(ctx context.Context, userType uint64, startTime uint64, endTime uint64) (uint64, error)
 
 	GetStakerType(ctx context.Context, address common.Address) (uint64, error)
+	// GetStakers returns the stakers of a type (or only [address] if set)
+	GetStakers(ctx context.Context, stakerType uint64, address common.Address, opts ...StateOption) ([]vm.APIStake, error)
+	GetSysParams(ctx context.Context, opts ...StateOption) (*chain.SysParamsMeta, error)
+	// GetUnbondings returns the stake [address] is unbonding or can withdraw
+	GetUnbondings(ctx context.Context, address common.Address, opts ...StateOption) ([]*chain.Unbonding, error)
+	// GetSlashes returns the slash history of [staker], oldest first
+	GetSlashes(ctx context.Context, staker common.Address, opts ...StateOption) ([]*chain.SlashRecord, error)
 
 	GetChainCreateTime(ctx context.Context) (uint64, error)
 	GetNodes(ctx context.Context, address common.Address) (vm.APINode, error)
@@ -69,6 +100,11 @@ type Client interface {
 	ImportKey(ctx context.Context, userName string, userPass string, privateKey string) error
 
 	GetLocalParams(ctx context.Context, address common.Address) (*vm.LocalParams, error)
+
+	// Subscribe streams the accepted blocks, activity and tx statuses
+	// matching [filter] from now on. It fails if the node doesn't serve
+	// subscriptions.
+	Subscribe(ctx context.Context, filter vm.SubscribeArgs) (*Subscription, error)
 }
 
 // New creates a new client object.
@@ -76,11 +112,15 @@ func New(uri string, _ time.Duration) Client {
 	req := rpc.NewEndpointRequester(
 		fmt.Sprintf("%s%s", uri, vm.PublicEndpoint),
 	)
-	return &client{req: req}
+	sub := rpc.NewEndpointRequester(
+		fmt.Sprintf("%s%s", uri, vm.SubscribeEndpoint),
+	)
+	return &client{req: req, sub: sub}
 }
 
 type client struct {
 	req rpc.EndpointRequester
+	sub rpc.EndpointRequester
 }
 
 func (cli *client) Ping(ctx context.Context) (bool, error) {
@@ -202,6 +242,24 @@ func (cli *client) IssueTx(ctx context.Context, td *tdata.TypedData, sig []byte)
 }
 
 func (cli *client) PollTx(ctx context.Context, txID ids.ID) (confirmed bool, err error) {
+	if sub, err := cli.Subscribe(ctx, vm.SubscribeArgs{TxIDs: []ids.ID{txID}}); err == nil {
+		for {
+			events, _, err := sub.Next(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return false, ctx.Err()
+				}
+				color.Red("subscription failed %v, polling transaction", err)
+				break
+			}
+			for _, ev := range events {
+				if ev.Type == vm.EventTx && ev.Tx.TxID == txID {
+					return txStatusConfirmed(txID, ev.Tx.Status, ev.Tx.Reason)
+				}
+			}
+		}
+	}
+
 done:
 	for ctx.Err() == nil {
 		select {
@@ -210,19 +268,79 @@ done:
 			break done
 		}
 
-		confirmed, err := cli.HasTx(ctx, txID)
+		resp, err := cli.GetTxReceipt(ctx, txID)
 		if err != nil {
 			color.Red("polling transaction failed %v", err)
 			continue
 		}
-		if confirmed {
-			color.Green("confirmed transaction %v", txID)
-			return true, nil
+		if resp.Status == vm.TxStatusAccepted || resp.Status == vm.TxStatusDropped {
+			return txStatusConfirmed(txID, resp.Status, resp.Reason)
 		}
 	}
 	return false, ctx.Err()
 }
 
+func txStatusConfirmed(txID ids.ID, status string, reason string) (bool, error) {
+	if status == vm.TxStatusDropped {
+		color.Red("dropped transaction %v: %s", txID, reason)
+		return false, fmt.Errorf("%w: %s", ErrTxDropped, reason)
+	}
+	color.Green("confirmed transaction %v", txID)
+	return true, nil
+}
+
+func (cli *client) GetTxReceipt(ctx context.Context, txID ids.ID) (*vm.GetTxReceiptReply, error) {
+	resp := new(vm.GetTxReceiptReply)
+	if err := cli.req.SendRequest(
+		ctx,
+		"samavm.getTxReceipt",
+		&vm.GetTxReceiptArgs{TxID: txID},
+		resp,
+	); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (cli *client) GetTx(ctx context.Context, txID ids.ID) (*vm.GetTxReply, error) {
+	resp := new(vm.GetTxReply)
+	if err := cli.req.SendRequest(
+		ctx,
+		"samavm.getTx",
+		&vm.GetTxArgs{TxID: txID},
+		resp,
+	); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (cli *client) GetBlockByHeight(ctx context.Context, height uint64) (*vm.APIBlock, error) {
+	resp := new(vm.GetBlockReply)
+	if err := cli.req.SendRequest(
+		ctx,
+		"samavm.getBlockByHeight",
+		&vm.GetBlockByHeightArgs{Height: height},
+		resp,
+	); err != nil {
+		return nil, err
+	}
+	return resp.Block, nil
+}
+
+func (cli *client) GetBlockByID(ctx context.Context, blkID ids.ID) (*vm.APIBlock, error) {
+	resp := new(vm.GetBlockReply)
+	if err := cli.req.SendRequest(
+		ctx,
+		"samavm.getBlockByID",
+		&vm.GetBlockByIDArgs{BlockID: blkID},
+		resp,
+	); err != nil {
+		return nil, err
+	}
+	return resp.Block, nil
+}
+
 func (cli *client) Resolve(ctx context.Context, key common.Hash) (bool, []byte, *chain.ValueMeta, error) {
 	resp := new(vm.ResolveReply)
 	if err := cli.req.SendRequest(
@@ -246,13 +364,30 @@ func (cli *client) Resolve(ctx context.Context, key common.Hash) (bool, []byte,
 	return true, resp.Value, resp.ValueMeta, nil
 }
 
-func (cli *client) Balance(ctx context.Context, addr common.Address) (bal uint64, err error) {
+func (cli *client) Balance(ctx context.Context, addr common.Address, opts ...StateOption) (bal uint64, err error) {
 	resp := new(vm.BalanceReply)
 	if err = cli.req.SendRequest(
 		ctx,
 		"samavm.balance",
 		&vm.BalanceArgs{
-			Address: addr,
+			StateArgs: stateArgs(opts),
+			Address:   addr,
+		},
+		resp,
+	); err != nil {
+		return 0, err
+	}
+	return resp.Balance, nil
+}
+
+func (cli *client) StakeBalance(ctx context.Context, addr common.Address, opts ...StateOption) (uint64, error) {
+	resp := new(vm.StakeBalanceReply)
+	if err := cli.req.SendRequest(
+		ctx,
+		"samavm.stakeBalance",
+		&vm.StakeBalanceArgs{
+			StateArgs: stateArgs(opts),
+			Address:   addr,
 		},
 		resp,
 	); err != nil {
@@ -261,6 +396,28 @@ func (cli *client) Balance(ctx context.Context, addr common.Address) (bal uint64
 	return resp.Balance, nil
 }
 
+func (cli *client) GetProof(ctx context.Context, key []byte, height *uint64) (*vm.GetProofReply, error) {
+	resp := new(vm.GetProofReply)
+	if err := cli.req.SendRequest(
+		ctx,
+		"samavm.getProof",
+		&vm.GetProofArgs{
+			Key:    key,
+			Height: height,
+		},
+		resp,
+	); err != nil {
+		return nil, err
+	}
+	if resp.Proof == nil || !bytes.Equal(resp.Proof.Key, key) {
+		return nil, ErrIntegrityFailure
+	}
+	if err := resp.Proof.Verify(resp.StateRoot); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
 func (cli *client) RecentActivity(ctx context.Context) (activity []*chain.Activity, err error) {
 	resp := new(vm.RecentActivityReply)
 	if err = cli.req.SendRequest(
@@ -274,12 +431,39 @@ func (cli *client) RecentActivity(ctx context.Context) (activity []*chain.Activi
 	return resp.Activity, nil
 }
 
-func (cli *client) CalcReward(ctx context.Context, stakerType uint64, endTime uint64, address common.Address) (uint64, uint64, uint64, error) {
+func (cli *client) GetActivityByAddress(
+	ctx context.Context,
+	address common.Address,
+	typ string,
+	fromHeight uint64,
+	fromIndex uint32,
+	limit int,
+) (*vm.GetActivityByAddressReply, error) {
+	resp := new(vm.GetActivityByAddressReply)
+	if err := cli.req.SendRequest(
+		ctx,
+		"samavm.getActivityByAddress",
+		&vm.GetActivityByAddressArgs{
+			Address:    address,
+			Type:       typ,
+			FromHeight: fromHeight,
+			FromIndex:  fromIndex,
+			Limit:      limit,
+		},
+		resp,
+	); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (cli *client) CalcReward(ctx context.Context, stakerType uint64, endTime uint64, address common.Address, opts ...StateOption) (uint64, uint64, uint64, error) {
 	resp := new(vm.CalcRewardReply)
 	if err := cli.req.SendRequest(
 		ctx,
 		"samavm.calcReward",
 		&vm.CalcRewardArgs{
+			StateArgs:  stateArgs(opts),
 			StakerType: stakerType,
 			EndTime:    endTime,
 			Address:    address,
@@ -291,6 +475,22 @@ func (cli *client) CalcReward(ctx context.Context, stakerType uint64, endTime ui
 	return resp.Base, resp.Merit, resp.Yield, nil
 }
 
+func (cli *client) GetFoundationVesting(ctx context.Context, endTime uint64, opts ...StateOption) (*chain.VestingStatus, error) {
+	resp := new(vm.GetFoundationVestingReply)
+	if err := cli.req.SendRequest(
+		ctx,
+		"samavm.getFoundationVesting",
+		&vm.GetFoundationVestingArgs{
+			StateArgs: stateArgs(opts),
+			EndTime:   endTime,
+		},
+		resp,
+	); err != nil {
+		return nil, err
+	}
+	return resp.VestingStatus, nil
+}
+
 func (cli *client) GetUserFee(ctx context.Context, userType uint64, startTime uint64, endTime uint64) (uint64, error) {
 	resp := new(vm.UserFeeReply)
 	err := cli.req.SendRequest(
@@ -319,6 +519,70 @@ func (cli *client) GetStakerType(ctx context.Context, address common.Address) (u
 	return resp.StakerType, err
 }
 
+func (cli *client) GetUnbondings(ctx context.Context, address common.Address, opts ...StateOption) ([]*chain.Unbonding, error) {
+	resp := new(vm.GetUnbondingsReply)
+	if err := cli.req.SendRequest(
+		ctx,
+		"samavm.getUnbondings",
+		&vm.GetUnbondingsArgs{
+			StateArgs: stateArgs(opts),
+			Address:   address,
+		},
+		resp,
+	); err != nil {
+		return nil, err
+	}
+	return resp.Unbondings, nil
+}
+
+func (cli *client) GetSlashes(ctx context.Context, staker common.Address, opts ...StateOption) ([]*chain.SlashRecord, error) {
+	resp := new(vm.GetSlashesReply)
+	if err := cli.req.SendRequest(
+		ctx,
+		"samavm.getSlashes",
+		&vm.GetSlashesArgs{
+			StateArgs: stateArgs(opts),
+			Staker:    staker,
+		},
+		resp,
+	); err != nil {
+		return nil, err
+	}
+	return resp.Slashes, nil
+}
+
+func (cli *client) GetStakers(ctx context.Context, stakerType uint64, address common.Address, opts ...StateOption) ([]vm.APIStake, error) {
+	resp := new(vm.GetStakersReply)
+	err := cli.req.SendRequest(ctx,
+		"samavm.getStakers",
+		&vm.GetStakersArgs{
+			StateArgs:  stateArgs(opts),
+			StakerType: stakerType,
+			Address:    address,
+		},
+		resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Stakers, nil
+}
+
+func (cli *client) GetSysParams(ctx context.Context, opts ...StateOption) (*chain.SysParamsMeta, error) {
+	resp := new(vm.GetSysParamsReply)
+	err := cli.req.SendRequest(ctx,
+		"samavm.getSysParams",
+		&vm.GetSysParamsArgs{
+			StateArgs: stateArgs(opts),
+		},
+		resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.Params, nil
+}
+
 func (cli *client) GetChainCreateTime(ctx context.Context) (uint64, error) {
 	resp := new(vm.GetCreateTimeReply)
 	err := cli.req.SendRequest(ctx,
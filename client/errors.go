@@ -5,4 +5,7 @@ package client
 
 import "errors"
 
-var ErrIntegrityFailure = errors.New("received file that does not match hash")
+var (
+	ErrIntegrityFailure = errors.New("received file that does not match hash")
+	ErrTxDropped        = errors.New("transaction dropped")
+)
@@ -15,6 +15,7 @@ import (
 
 	"github.com/SamaNetwork/SamaVM/chain"
 	"github.com/SamaNetwork/SamaVM/tdata"
+	"github.com/SamaNetwork/SamaVM/vm"
 )
 
 func PPActivity(a []*chain.Activity) error {
@@ -181,3 +182,26 @@ func WithPollTx() OpOption {
 func WithBalance() OpOption {
 	return func(op *Op) { op.balance = true }
 }
+
+// StateOption selects the accepted state a read is served from. Reads use the
+// last accepted state by default.
+type StateOption func(*vm.StateArgs)
+
+func stateArgs(opts []StateOption) vm.StateArgs {
+	args := vm.StateArgs{}
+	for _, opt := range opts {
+		opt(&args)
+	}
+	return args
+}
+
+// Reads the state after the accepted block at [height]. Nodes only serve
+// recent heights unless they run in archive mode.
+func AtHeight(height uint64) StateOption {
+	return func(args *vm.StateArgs) { args.Height = &height }
+}
+
+// Reads the state after the accepted block [blkID].
+func AtBlock(blkID ids.ID) StateOption {
+	return func(args *vm.StateArgs) { args.BlockID = &blkID }
+}
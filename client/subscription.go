@@ -0,0 +1,67 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/ava-labs/avalanchego/utils/rpc"
+
+	"github.com/SamaNetwork/SamaVM/vm"
+)
+
+// subscriptionTimeout is how long the node holds each poll open
+const subscriptionTimeout = 20 * time.Second
+
+// Subscription receives the events published by a node after it was created
+// (see [Client.Subscribe]).
+type Subscription struct {
+	req    rpc.EndpointRequester
+	filter vm.SubscribeArgs
+}
+
+func (cli *client) Subscribe(ctx context.Context, filter vm.SubscribeArgs) (*Subscription, error) {
+	s := &Subscription{req: cli.sub, filter: filter}
+	// Start after the last event (also checks subscriptions are enabled)
+	s.filter.Cursor = 0
+	s.filter.Timeout = 0
+	resp, err := s.poll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.filter.Cursor = resp.Cursor
+	s.filter.Timeout = subscriptionTimeout
+	return s, nil
+}
+
+func (s *Subscription) poll(ctx context.Context) (*vm.SubscribeReply, error) {
+	resp := new(vm.SubscribeReply)
+	if err := s.req.SendRequest(
+		ctx,
+		"samavm.poll",
+		&s.filter,
+		resp,
+	); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Next waits for the next events. [missed] is set if the node no longer had
+// some of the events since the last call (or was restarted).
+func (s *Subscription) Next(ctx context.Context) (events []*vm.Event, missed bool, err error) {
+	for ctx.Err() == nil {
+		resp, err := s.poll(ctx)
+		if err != nil {
+			return nil, false, err
+		}
+		s.filter.Cursor = resp.Cursor
+		missed = missed || resp.Missed
+		if len(resp.Events) > 0 {
+			return resp.Events, missed, nil
+		}
+	}
+	return nil, missed, ctx.Err()
+}
@@ -0,0 +1,70 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/SamaNetwork/SamaVM/chain"
+	"github.com/SamaNetwork/SamaVM/client"
+)
+
+var delegateVoteCmd = &cobra.Command{
+	Use:   "delegate-vote  [options]  [delegate]",
+	Short: "delegate governance weight to an address (revoke without one)",
+	RunE:  delegateVoteFunc,
+}
+
+func delegateVoteFunc(_ *cobra.Command, args []string) error {
+	priv, err := crypto.LoadECDSA(privateKeyFile)
+	if err != nil {
+		return err
+	}
+
+	delegate, err := getDelegateVoteOp(args)
+	if err != nil {
+		return err
+	}
+
+	cli := client.New(uri, requestTimeout)
+
+	opts := []client.OpOption{client.WithPollTx()}
+	if verbose {
+		opts = append(opts, client.WithBalance())
+	}
+
+	utx := &chain.DelegateVoteTx{
+		BaseTx:   &chain.BaseTx{},
+		Delegate: delegate,
+	}
+	if _, _, err := client.SignIssueRawTx(context.Background(), cli, utx, priv, opts...); err != nil {
+		return err
+	}
+
+	if delegate == (common.Address{}) {
+		color.Green("Revoked vote delegation")
+	} else {
+		color.Green("Delegated vote to %s", delegate.Hex())
+	}
+	return nil
+}
+
+func getDelegateVoteOp(args []string) (delegate common.Address, err error) {
+	if len(args) > 1 {
+		return common.Address{}, fmt.Errorf("expected at most 1 argument, got %d", len(args))
+	}
+	if len(args) == 0 {
+		return common.Address{}, nil
+	}
+	if !common.IsHexAddress(args[0]) {
+		return common.Address{}, fmt.Errorf("invalid delegate %s", args[0])
+	}
+	return common.HexToAddress(args[0]), nil
+}
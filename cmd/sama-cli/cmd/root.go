@@ -48,8 +48,10 @@ func init() {
 		networkCmd,
 		stakeCmd,
 		unStakeCmd,
+		withdrawStakeCmd,
 		registerCmd,
 		voteCmd,
+		delegateVoteCmd,
 		addUserCmd,
 		claimCmd,
 		proofCmd,
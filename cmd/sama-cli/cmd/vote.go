@@ -17,8 +17,8 @@ import (
 )
 
 var voteCmd = &cobra.Command{
-	Use:   "vote  [options]  <ActionID> ",
-	Short: "vote for action",
+	Use:   "vote  [options]  <ActionID> [for|against|abstain]",
+	Short: "vote on action (for by default)",
 	RunE:  voteFunc,
 }
 
@@ -28,7 +28,7 @@ func voteFunc(_ *cobra.Command, args []string) error {
 		return err
 	}
 
-	actionID, err := getVoteOp(args)
+	actionID, choice, err := getVoteOp(args)
 	if err != nil {
 		return err
 	}
@@ -43,21 +43,26 @@ func voteFunc(_ *cobra.Command, args []string) error {
 	utx := &chain.VoteTx{
 		BaseTx:   &chain.BaseTx{},
 		ActionID: actionID,
+		Choice:   choice,
 	}
 	if _, _, err := client.SignIssueRawTx(context.Background(), cli, utx, priv, opts...); err != nil {
 		return err
 	}
 
-	color.Green("Vote actionID=%s", actionID.String())
+	color.Green("Vote actionID=%s choice=%s", actionID.String(), choice)
 	return nil
 }
 
-func getVoteOp(args []string) (actionID ids.ShortID, err error) {
-	if len(args) != 1 {
-		return ids.ShortID{}, fmt.Errorf("expected exactly 1 argument, got %d", len(args))
+func getVoteOp(args []string) (actionID ids.ShortID, choice string, err error) {
+	if len(args) != 1 && len(args) != 2 {
+		return ids.ShortID{}, "", fmt.Errorf("expected 1 or 2 arguments, got %d", len(args))
 	}
 
 	actionID, _ = ids.ShortFromString(args[0])
+	choice = chain.VoteFor
+	if len(args) == 2 {
+		choice = args[1]
+	}
 
-	return actionID, nil
+	return actionID, choice, nil
 }
@@ -0,0 +1,67 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/SamaNetwork/SamaVM/chain"
+	"github.com/SamaNetwork/SamaVM/client"
+)
+
+var withdrawStakeCmd = &cobra.Command{
+	Use:   "withdraw-stake  [options]",
+	Short: "withdraw the unstaked tokens once their unbonding period ended",
+	RunE:  withdrawStakeFunc,
+}
+
+func withdrawStakeFunc(_ *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("expected no argument, got %d", len(args))
+	}
+	priv, err := crypto.LoadECDSA(privateKeyFile)
+	if err != nil {
+		return err
+	}
+	sender := crypto.PubkeyToAddress(priv.PublicKey)
+
+	cli := client.New(uri, requestTimeout)
+
+	unbondings, err := cli.GetUnbondings(context.Background(), sender)
+	if err != nil {
+		return err
+	}
+	now, amount := uint64(time.Now().Unix()), uint64(0)
+	for _, unbonding := range unbondings {
+		if unbonding.ReleaseTime <= now {
+			amount += unbonding.Amount
+		} else {
+			color.Yellow("%d unbonding until %s", unbonding.Amount, time.Unix(int64(unbonding.ReleaseTime), 0))
+		}
+	}
+	if amount == 0 {
+		return chain.ErrNoUnbonded
+	}
+
+	opts := []client.OpOption{client.WithPollTx()}
+	if verbose {
+		opts = append(opts, client.WithBalance())
+	}
+
+	utx := &chain.WithdrawStakeTx{
+		BaseTx: &chain.BaseTx{},
+	}
+	if _, _, err := client.SignIssueRawTx(context.Background(), cli, utx, priv, opts...); err != nil {
+		return err
+	}
+
+	color.Green("withdrew %d", amount)
+	return nil
+}
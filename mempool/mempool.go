@@ -5,8 +5,10 @@ package mempool
 
 import (
 	"container/heap"
+	"errors"
 	"sync"
 
+	"github.com/ava-labs/avalanchego/cache"
 	"github.com/ava-labs/avalanchego/ids"
 	"github.com/ava-labs/avalanchego/utils/set"
 
@@ -15,6 +17,11 @@ import (
 
 var _ chain.Mempool = &Mempool{}
 
+var (
+	ErrEvicted = errors.New("evicted by higher paying transactions")
+	ErrExpired = errors.New("referenced block is no longer recent")
+)
+
 type Mempool struct {
 	mu      sync.RWMutex
 	g       *chain.Genesis
@@ -27,6 +34,8 @@ type Mempool struct {
 	Pending chan struct{}
 	// newTxs is an array of [Tx] that are ready to be gossiped.
 	newTxs []*chain.Transaction
+	// dropped holds why recently removed transactions will never be issued
+	dropped *cache.LRU[ids.ID, error]
 }
 
 // New creates a new [Mempool]. [maxSize] must be > 0 or else the
@@ -38,6 +47,7 @@ func New(g *chain.Genesis, maxSize int) *Mempool {
 		maxHeap: newTxHeap(maxSize, false),
 		minHeap: newTxHeap(maxSize, true),
 		Pending: make(chan struct{}, 1),
+		dropped: &cache.LRU[ids.ID, error]{Size: maxSize},
 	}
 }
 
@@ -75,10 +85,12 @@ func (th *Mempool) Add(tx *chain.Transaction) bool {
 	// lowest paying transaction
 	if th.maxHeap.Len() > th.maxSize {
 		t, _ := th.popMin()
+		th.dropped.Put(t.ID(), ErrEvicted)
 		if t.ID() == txID {
 			return false
 		}
 	}
+	th.dropped.Evict(txID)
 
 	// When adding [tx] to the mempool make sure that there is an item in Pending
 	// to signal the VM to produce a block. Note: if the VM's buildStatus has already
@@ -145,9 +157,23 @@ func (th *Mempool) Prune(validHashes set.Set[ids.ID]) {
 
 	for _, txID := range toRemove { // O(K * log N)
 		th.Remove(txID)
+		th.dropped.Put(txID, ErrExpired)
 	}
 }
 
+// Drop records that [id] was removed from the mempool because it can't be
+// issued (for example, if it failed execution while building a block).
+func (th *Mempool) Drop(id ids.ID, err error) {
+	th.dropped.Put(id, err)
+}
+
+// Dropped reports whether [id] was dropped recently and hasn't been added
+// again since, and why.
+func (th *Mempool) Dropped(id ids.ID) (bool, error) {
+	reason, dropped := th.dropped.Get(id)
+	return dropped, reason
+}
+
 func (th *Mempool) Len() int {
 	th.mu.RLock()
 	defer th.mu.RUnlock()
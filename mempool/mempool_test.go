@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/set"
 	"github.com/ethereum/go-ethereum/crypto"
 
 	"github.com/SamaNetwork/SamaVM/chain"
@@ -55,3 +57,68 @@ func TestMempool(t *testing.T) {
 		t.Fatalf("length expected 3, got %d", length)
 	}
 }
+
+func TestMempoolDropped(t *testing.T) {
+	g := chain.DefaultGenesis()
+	txm := mempool.New(g, 2)
+	priv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recent := ids.GenerateTestID()
+	txs := []*chain.Transaction{}
+	for _, i := range []int{100, 200, 300} {
+		blkID := recent
+		if i == 200 {
+			blkID = ids.GenerateTestID()
+		}
+		tx := &chain.Transaction{
+			UnsignedTransaction: &chain.SetTx{
+				BaseTx: &chain.BaseTx{
+					BlockID: blkID,
+					Price:   uint64(i),
+				},
+				Value: []byte(fmt.Sprintf("0x%064x", i)),
+			},
+		}
+		dh, err := chain.DigestHash(tx.UnsignedTransaction)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := chain.Sign(dh, priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx.Signature = sig
+		if err := tx.Init(g); err != nil {
+			t.Fatal(err)
+		}
+		txm.Add(tx)
+		txs = append(txs, tx)
+	}
+	if ok, reason := txm.Dropped(txs[0].ID()); !ok || reason != mempool.ErrEvicted {
+		t.Fatalf("expected %v, got %v", mempool.ErrEvicted, reason)
+	}
+
+	valid := set.Set[ids.ID]{}
+	valid.Add(recent)
+	txm.Prune(valid)
+	if ok, reason := txm.Dropped(txs[1].ID()); !ok || reason != mempool.ErrExpired {
+		t.Fatalf("expected %v, got %v", mempool.ErrExpired, reason)
+	}
+	if ok, _ := txm.Dropped(txs[2].ID()); ok {
+		t.Fatal("pending tx reported as dropped")
+	}
+
+	tx, _ := txm.PopMax()
+	txm.Drop(tx.ID(), chain.ErrInvalidBalance)
+	if ok, reason := txm.Dropped(tx.ID()); !ok || reason != chain.ErrInvalidBalance {
+		t.Fatalf("expected %v, got %v", chain.ErrInvalidBalance, reason)
+	}
+
+	// Re-adding a tx clears the reason it was dropped
+	txm.Add(tx)
+	if ok, _ := txm.Dropped(tx.ID()); ok {
+		t.Fatal("re-added tx reported as dropped")
+	}
+}
@@ -4,6 +4,8 @@
 package vm
 
 import (
+	"time"
+
 	"github.com/ava-labs/avalanchego/database"
 	"github.com/ava-labs/avalanchego/ids"
 	"github.com/ava-labs/avalanchego/utils/set"
@@ -20,6 +22,10 @@ func (vm *VM) IsBootstrapped() bool {
 	return vm.bootstrapped.Get()
 }
 
+func (vm *VM) Now() time.Time {
+	return vm.clock.Time()
+}
+
 func (vm *VM) State() database.Database {
 	return vm.db
 }
@@ -54,6 +60,13 @@ func (vm *VM) Accepted(b *chain.StatelessBlock) {
 	vm.lastAccepted = b
 	log.Debug("accepted block", "blkID", b.ID())
 
+	if err := vm.updateSyncState(b); err != nil {
+		log.Error("could not update sync state", "height", b.Hght, "err", err)
+	}
+	if vm.events != nil {
+		vm.publishAccepted(b)
+	}
+
 	if vm.config.ActivityCacheSize == 0 {
 		return
 	}
@@ -68,6 +81,8 @@ func (vm *VM) Accepted(b *chain.StatelessBlock) {
 
 func (vm *VM) ExecutionContext(currTime int64, lastBlock *chain.StatelessBlock) (*chain.Context, error) {
 	g := vm.genesis
+	rules := vm.upgrades.Rules(currTime)
+	fees := rules.FeeConfig(g)
 	recentBlockIDs := set.Set[ids.ID]{}
 	recentTxIDs := set.Set[ids.ID]{}
 	recentUnits := uint64(0)
@@ -90,30 +105,31 @@ func (vm *VM) ExecutionContext(currTime int64, lastBlock *chain.StatelessBlock)
 	// compute new block cost
 	secondsSinceLast := currTime - lastBlock.Tmstmp
 	nextCost := lastBlock.Cost
-	if secondsSinceLast < g.TargetBlockRate {
-		nextCost += uint64(g.TargetBlockRate - secondsSinceLast)
+	if secondsSinceLast < fees.TargetBlockRate {
+		nextCost += uint64(fees.TargetBlockRate - secondsSinceLast)
 	} else {
-		possibleDiff := uint64(secondsSinceLast - g.TargetBlockRate)
+		possibleDiff := uint64(secondsSinceLast - fees.TargetBlockRate)
 		if nextCost >= chain.MinBlockCost && possibleDiff < nextCost-chain.MinBlockCost {
 			nextCost -= possibleDiff
 		} else {
 			nextCost = chain.MinBlockCost
 		}
 	}
-	if !g.BlockCostEnabled {
+	if !fees.BlockCostEnabled {
 		nextCost = lastBlock.Cost
 	}
 
 	// compute new min price
 	nextPrice := lastBlock.Price
-	if recentUnits > vm.targetRangeUnits {
+	targetRangeUnits := fees.TargetRangeUnits(g.LookbackWindow)
+	if recentUnits > targetRangeUnits {
 		nextPrice++
-	} else if recentUnits < vm.targetRangeUnits {
+	} else if recentUnits < targetRangeUnits {
 		elapsedWindows := uint64(secondsSinceLast/g.LookbackWindow) + 1 // account for current window being less
-		if nextPrice >= g.MinPrice && elapsedWindows < nextPrice-g.MinPrice {
+		if nextPrice >= fees.MinPrice && elapsedWindows < nextPrice-fees.MinPrice {
 			nextPrice -= elapsedWindows
 		} else {
-			nextPrice = g.MinPrice
+			nextPrice = fees.MinPrice
 		}
 	}
 
@@ -127,5 +143,9 @@ func (vm *VM) ExecutionContext(currTime int64, lastBlock *chain.StatelessBlock)
 
 		NextPrice: nextPrice,
 		NextCost:  nextCost,
+
+		ParentTimestamp: lastBlock.Tmstmp,
+
+		Rules: rules,
 	}, nil
 }
@@ -5,6 +5,8 @@ package vm
 
 import (
 	"time"
+
+	"github.com/SamaNetwork/SamaVM/chain"
 )
 
 type Config struct {
@@ -14,6 +16,17 @@ type Config struct {
 
 	MempoolSize       int `serialize:"true" json:"mempoolSize"`
 	ActivityCacheSize int `serialize:"true" json:"activityCacheSize"`
+	StateCacheSize    int `serialize:"true" json:"stateCacheSize"`
+	// Events kept for subscribers, 0 disables [SubscribeEndpoint]
+	SubscriptionBufferSize int `serialize:"true" json:"subscriptionBufferSize"`
+
+	// State sync
+	StateSyncEnabled   bool   `serialize:"true" json:"stateSyncEnabled"`
+	StateDiffRetention uint64 `serialize:"true" json:"stateDiffRetention"` // blocks, 0 keeps every diff
+
+	// Archive keeps the state after every accepted block so it can be queried
+	// by height (see [chain.ArchiveState]). Archive nodes don't state sync.
+	Archive bool `serialize:"true" json:"archive"`
 }
 
 func (c *Config) SetDefaults() {
@@ -23,4 +36,8 @@ func (c *Config) SetDefaults() {
 
 	c.MempoolSize = 1024
 	c.ActivityCacheSize = 128
+	c.StateCacheSize = chain.DefaultStateCacheSize
+	c.SubscriptionBufferSize = 1024
+
+	c.StateDiffRetention = 4096
 }
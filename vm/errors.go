@@ -13,4 +13,11 @@ var (
 	ErrInputIsNil     = errors.New("input is nil")
 	ErrInvalidEmptyTx = errors.New("invalid empty transaction")
 	ErrCorruption     = errors.New("corruption detected")
+
+	ErrBlockNotAccepted  = errors.New("block not accepted")
+	ErrHeightMismatch    = errors.New("height does not match block")
+	ErrStateUnavailable  = errors.New("state not available")
+	ErrHeightNotAccepted = errors.New("height not accepted")
+	ErrTxNotFound        = errors.New("tx not found")
+	ErrNoStateRoot       = errors.New("block has no state root")
 )
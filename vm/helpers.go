@@ -4,11 +4,14 @@
 package vm
 
 import (
+	"errors"
 	"fmt"
 	"sort"
 	"time"
 
+	"github.com/ava-labs/avalanchego/database"
 	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow/choices"
 
 	"github.com/SamaNetwork/SamaVM/chain"
 )
@@ -70,8 +73,8 @@ func (vm *VM) SuggestedFee() (uint64, uint64, error) {
 	// Sort useful costs/prices
 	sort.Slice(ctx.Prices, func(i, j int) bool { return ctx.Prices[i] < ctx.Prices[j] })
 	pPrice := ctx.Prices[(len(ctx.Prices)-1)*feePercentile/100]
-	if g := vm.genesis; pPrice < g.MinPrice {
-		pPrice = g.MinPrice
+	if minPrice := ctx.Rules.FeeConfig(vm.genesis).MinPrice; pPrice < minPrice {
+		pPrice = minPrice
 	}
 	sort.Slice(ctx.Costs, func(i, j int) bool { return ctx.Costs[i] < ctx.Costs[j] })
 	pCost := ctx.Costs[(len(ctx.Costs)-1)*feePercentile/100]
@@ -92,3 +95,78 @@ func (vm *VM) SuggestedFee() (uint64, uint64, error) {
 	}
 	return pPrice, cPerTx, nil
 }
+
+// historicalState returns the accepted state selected by [args], which is the
+// last accepted state if neither a height nor a block is given. Older states
+// are read from the archive or, if it is disabled, rebuilt from the retained
+// state diffs.
+func (vm *VM) historicalState(args *StateArgs) (database.Database, chain.SamaState, error) {
+	la := vm.lastAccepted
+	height := la.Hght
+	switch {
+	case args.BlockID != nil:
+		blk, err := vm.GetStatelessBlock(*args.BlockID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if blk.Status() != choices.Accepted {
+			return nil, nil, ErrBlockNotAccepted
+		}
+		if args.Height != nil && *args.Height != blk.Hght {
+			return nil, nil, fmt.Errorf("%w: block=%d height=%d", ErrHeightMismatch, blk.Hght, *args.Height)
+		}
+		height = blk.Hght
+	case args.Height != nil:
+		height = *args.Height
+	}
+	if height == la.Hght {
+		return vm.db, vm.samaState, nil
+	}
+	if height > la.Hght {
+		return nil, nil, fmt.Errorf("%w: height=%d last accepted=%d", ErrHeightNotAccepted, height, la.Hght)
+	}
+
+	var (
+		db  database.Database
+		err error
+	)
+	if vm.config.Archive {
+		db, err = chain.ArchiveState(vm.db, height)
+	} else {
+		db, err = vm.stateAt(height)
+	}
+	if errors.Is(err, chain.ErrStateDiffMissing) {
+		return nil, nil, fmt.Errorf("%w: height=%d", ErrStateUnavailable, height)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return db, chain.SamaNew(db, vm.genesis), nil
+}
+
+// txStatus returns the status of [txID] and, if it was accepted, its receipt
+// or, if it was dropped, the reason why
+func (vm *VM) txStatus(txID ids.ID) (string, string, *chain.Receipt, error) {
+	receipt, ok, err := chain.GetReceipt(vm.db, txID)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if ok {
+		return TxStatusAccepted, "", receipt, nil
+	}
+	// Accepted before receipts were stored or synced from a peer
+	has, err := chain.HasTransaction(vm.db, txID)
+	if err != nil {
+		return "", "", nil, err
+	}
+	switch {
+	case has:
+		return TxStatusAccepted, "", nil, nil
+	case vm.mempool.Has(txID):
+		return TxStatusPending, "", nil, nil
+	}
+	if dropped, reason := vm.mempool.Dropped(txID); dropped {
+		return TxStatusDropped, reason.Error(), nil, nil
+	}
+	return TxStatusUnknown, "", nil, nil
+}
@@ -11,10 +11,12 @@ import (
 	"fmt"
 	"math/rand"
 	"net/http"
+	"sort"
 	"time"
 
 	"github.com/ava-labs/avalanchego/api"
 	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow/choices"
 
 	"github.com/ava-labs/avalanchego/utils/crypto"
 	"github.com/ava-labs/avalanchego/utils/logging"
@@ -147,6 +149,37 @@ func (svc *PublicService) HasTx(_ *http.Request, args *HasTxArgs, reply *HasTxRe
 	return nil
 }
 
+const (
+	TxStatusAccepted = "accepted"
+	TxStatusPending  = "pending"
+	TxStatusDropped  = "dropped"
+	TxStatusUnknown  = "unknown"
+)
+
+type GetTxReceiptArgs struct {
+	TxID ids.ID `serialize:"true" json:"txId"`
+}
+
+type GetTxReceiptReply struct {
+	Status  string         `serialize:"true" json:"status"`
+	Reason  string         `serialize:"true" json:"reason,omitempty"` // why the tx was dropped
+	Receipt *chain.Receipt `serialize:"true" json:"receipt,omitempty"`
+}
+
+// GetTxReceipt returns the receipt of an accepted tx or, if it isn't accepted,
+// whether it is still pending in the mempool or was dropped from it.
+// Unknown txs may be processing or pending on other nodes.
+func (svc *PublicService) GetTxReceipt(_ *http.Request, args *GetTxReceiptArgs, reply *GetTxReceiptReply) error {
+	status, reason, receipt, err := svc.vm.txStatus(args.TxID)
+	if err != nil {
+		return err
+	}
+	reply.Status = status
+	reply.Reason = reason
+	reply.Receipt = receipt
+	return nil
+}
+
 type LastAcceptedReply struct {
 	Height  uint64 `serialize:"true" json:"height"`
 	BlockID ids.ID `serialize:"true" json:"blockId"`
@@ -159,6 +192,187 @@ func (svc *PublicService) LastAccepted(_ *http.Request, _ *struct{}, reply *Last
 	return nil
 }
 
+type APITx struct {
+	TxID      ids.ID           `serialize:"true" json:"txId"`
+	Sender    common.Address   `serialize:"true" json:"sender"`
+	Type      string           `serialize:"true" json:"type"`
+	TypedData *tdata.TypedData `serialize:"true" json:"typedData"`
+	Signature hexutil.Bytes    `serialize:"true" json:"signature"`
+	// Not stored for txs accepted before receipts were kept or synced
+	Receipt *chain.Receipt `serialize:"true" json:"receipt,omitempty"`
+}
+
+type APIBlock struct {
+	BlockID     ids.ID      `serialize:"true" json:"blockId"`
+	Parent      ids.ID      `serialize:"true" json:"parent"`
+	Height      uint64      `serialize:"true" json:"height"`
+	Timestamp   int64       `serialize:"true" json:"timestamp"`
+	Price       uint64      `serialize:"true" json:"price"`
+	Cost        uint64      `serialize:"true" json:"cost"`
+	AccessProof common.Hash `serialize:"true" json:"accessProof"`
+	StateRoot   common.Hash `serialize:"true" json:"stateRoot"`
+	Txs         []*APITx    `serialize:"true" json:"txs"`
+	// Receipts of the governance actions closed by the block
+	ActionReceipts []*chain.Receipt `serialize:"true" json:"actionReceipts"`
+}
+
+func (svc *PublicService) apiTx(tx *chain.Transaction) (*APITx, error) {
+	receipt, _, err := chain.GetReceipt(svc.vm.db, tx.ID())
+	if err != nil {
+		return nil, err
+	}
+	return &APITx{
+		TxID:      tx.ID(),
+		Sender:    tx.Sender(),
+		Type:      tx.Activity().Typ,
+		TypedData: tx.TypedData(),
+		Signature: tx.Signature,
+		Receipt:   receipt,
+	}, nil
+}
+
+func (svc *PublicService) apiBlock(blk *chain.StatelessBlock) (*APIBlock, error) {
+	txs := make([]*APITx, len(blk.Txs))
+	for i, tx := range blk.Txs {
+		atx, err := svc.apiTx(tx)
+		if err != nil {
+			return nil, err
+		}
+		txs[i] = atx
+	}
+	receipts, err := chain.GetBlockReceipts(svc.vm.db, blk.ID())
+	if err != nil {
+		return nil, err
+	}
+	return &APIBlock{
+		BlockID:     blk.ID(),
+		Parent:      blk.Prnt,
+		Height:      blk.Hght,
+		Timestamp:   blk.Tmstmp,
+		Price:       blk.Price,
+		Cost:        blk.Cost,
+		AccessProof: blk.AccessProof,
+		StateRoot:   blk.StateRoot,
+		Txs:         txs,
+
+		ActionReceipts: receipts,
+	}, nil
+}
+
+type GetBlockByHeightArgs struct {
+	Height uint64 `serialize:"true" json:"height"`
+}
+
+type GetBlockReply struct {
+	Block *APIBlock `serialize:"true" json:"block"`
+}
+
+// GetBlockByHeight returns the block accepted at a height
+func (svc *PublicService) GetBlockByHeight(_ *http.Request, args *GetBlockByHeightArgs, reply *GetBlockReply) error {
+	blk, err := svc.vm.acceptedBlockAt(args.Height)
+	if err != nil {
+		return err
+	}
+	reply.Block, err = svc.apiBlock(blk)
+	return err
+}
+
+type GetBlockByIDArgs struct {
+	BlockID ids.ID `serialize:"true" json:"blockId"`
+}
+
+// GetBlockByID returns an accepted block
+func (svc *PublicService) GetBlockByID(_ *http.Request, args *GetBlockByIDArgs, reply *GetBlockReply) error {
+	blk, err := svc.vm.GetStatelessBlock(args.BlockID)
+	if err != nil {
+		return err
+	}
+	if blk.Status() != choices.Accepted {
+		return ErrBlockNotAccepted
+	}
+	reply.Block, err = svc.apiBlock(blk)
+	return err
+}
+
+type GetTxArgs struct {
+	TxID ids.ID `serialize:"true" json:"txId"`
+}
+
+type GetTxReply struct {
+	Tx      *APITx `serialize:"true" json:"tx"`
+	BlockID ids.ID `serialize:"true" json:"blockId"`
+	Height  uint64 `serialize:"true" json:"height"`
+}
+
+// GetTx returns an accepted tx. Txs are found by their receipts, so those
+// accepted before receipts were kept are not returned.
+func (svc *PublicService) GetTx(_ *http.Request, args *GetTxArgs, reply *GetTxReply) error {
+	receipt, ok, err := chain.GetReceipt(svc.vm.db, args.TxID)
+	if err != nil {
+		return err
+	}
+	if !ok || len(receipt.Actions) > 0 {
+		// Actions closed by a block are only described by their receipt
+		return ErrTxNotFound
+	}
+	blk, err := svc.vm.GetStatelessBlock(receipt.BlkID)
+	if err != nil {
+		return err
+	}
+	if int(receipt.Index) >= len(blk.Txs) || blk.Txs[receipt.Index].ID() != args.TxID {
+		return fmt.Errorf("%w: tx %s not in block %s", ErrCorruption, args.TxID, receipt.BlkID)
+	}
+	reply.Tx, err = svc.apiTx(blk.Txs[receipt.Index])
+	if err != nil {
+		return err
+	}
+	reply.BlockID = blk.ID()
+	reply.Height = blk.Hght
+	return nil
+}
+
+type GetProofArgs struct {
+	Key    hexutil.Bytes `serialize:"true" json:"key"`
+	Height *uint64       `serialize:"true" json:"height,omitempty"` // defaults to last accepted
+}
+
+type GetProofReply struct {
+	Height    uint64            `serialize:"true" json:"height"`
+	BlockID   ids.ID            `serialize:"true" json:"blockId"`
+	StateRoot common.Hash       `serialize:"true" json:"stateRoot"`
+	Proof     *chain.StateProof `serialize:"true" json:"proof"`
+}
+
+// GetProof proves the value of a state key (e.g. [chain.PrefixBalanceKey])
+// against the state root of an accepted block
+func (svc *PublicService) GetProof(_ *http.Request, args *GetProofArgs, reply *GetProofReply) error {
+	blk := svc.vm.lastAccepted
+	db := svc.vm.db
+	if args.Height != nil && *args.Height != blk.Hght {
+		var err error
+		blk, err = svc.vm.acceptedBlockAt(*args.Height)
+		if err != nil {
+			return err
+		}
+		db, err = svc.vm.stateAt(blk.Hght)
+		if err != nil {
+			return err
+		}
+	}
+	if !blk.HasStateRoot() {
+		return fmt.Errorf("%w: %d", ErrNoStateRoot, blk.Hght)
+	}
+	proof, err := chain.GetStateProof(db, args.Key)
+	if err != nil {
+		return err
+	}
+	reply.Height = blk.Hght
+	reply.BlockID = blk.ID()
+	reply.StateRoot = blk.StateRoot
+	reply.Proof = proof
+	return nil
+}
+
 type SuggestedFeeArgs struct {
 	Input *chain.Input `serialize:"true" json:"input"`
 }
@@ -255,7 +469,15 @@ func (svc *PublicService) Resolve(_ *http.Request, args *ResolveArgs, reply *Res
 	return nil
 }
 
+// StateArgs selects the accepted state a read is served from. If both are
+// omitted, the last accepted state is used.
+type StateArgs struct {
+	Height  *uint64 `serialize:"true" json:"height,omitempty"`
+	BlockID *ids.ID `serialize:"true" json:"blockId,omitempty"`
+}
+
 type BalanceArgs struct {
+	StateArgs
 	Address common.Address `serialize:"true" json:"address"`
 }
 
@@ -264,7 +486,11 @@ type BalanceReply struct {
 }
 
 func (svc *PublicService) Balance(_ *http.Request, args *BalanceArgs, reply *BalanceReply) error {
-	bal, err := chain.GetBalance(svc.vm.db, args.Address)
+	db, _, err := svc.vm.historicalState(&args.StateArgs)
+	if err != nil {
+		return err
+	}
+	bal, err := chain.GetBalance(db, args.Address)
 	if err != nil {
 		return err
 	}
@@ -298,7 +524,53 @@ func (svc *PublicService) RecentActivity(_ *http.Request, _ *struct{}, reply *Re
 	return nil
 }
 
+const (
+	defaultActivityLimit = 100
+	maxActivityLimit     = 1000
+)
+
+type GetActivityByAddressArgs struct {
+	Address common.Address `serialize:"true" json:"address"`
+	// Only return activity of this type if set
+	Type       string `serialize:"true" json:"type"`
+	FromHeight uint64 `serialize:"true" json:"fromHeight"`
+	FromIndex  uint32 `serialize:"true" json:"fromIndex"`
+	Limit      int    `serialize:"true" json:"limit"`
+}
+
+type GetActivityByAddressReply struct {
+	Activity []*chain.Activity `serialize:"true" json:"activity"`
+	// Set to the position of the next page if there is one
+	More       bool   `serialize:"true" json:"more"`
+	NextHeight uint64 `serialize:"true" json:"nextHeight"`
+	NextIndex  uint32 `serialize:"true" json:"nextIndex"`
+}
+
+// GetActivityByAddress returns the accepted activity of an address, oldest
+// first
+func (svc *PublicService) GetActivityByAddress(_ *http.Request, args *GetActivityByAddressArgs, reply *GetActivityByAddressReply) error {
+	limit := args.Limit
+	if limit <= 0 {
+		limit = defaultActivityLimit
+	}
+	if limit > maxActivityLimit {
+		limit = maxActivityLimit
+	}
+	activity, more, err := chain.GetActivityByAddress(svc.vm.db, args.Address, args.Type, args.FromHeight, args.FromIndex, limit)
+	if err != nil {
+		return err
+	}
+	reply.Activity = activity
+	reply.More = more
+	if more {
+		last := activity[len(activity)-1]
+		reply.NextHeight, reply.NextIndex = last.Hght, last.Index+1
+	}
+	return nil
+}
+
 type StakeBalanceArgs struct {
+	StateArgs
 	Address common.Address `serialize:"true" json:"address"`
 }
 
@@ -307,7 +579,11 @@ type StakeBalanceReply struct {
 }
 
 func (svc *PublicService) StakeBalance(_ *http.Request, args *StakeBalanceArgs, reply *StakeBalanceReply) error {
-	bal, err := chain.GetStakeBalance(svc.vm.db, args.Address)
+	db, _, err := svc.vm.historicalState(&args.StateArgs)
+	if err != nil {
+		return err
+	}
+	bal, err := chain.GetStakeBalance(db, args.Address)
 	if err != nil {
 		return err
 	}
@@ -324,6 +600,7 @@ type APIStake struct {
 }
 
 type GetStakersArgs struct {
+	StateArgs
 	StakerType uint64         `serialize:"true" json:"stakerType"`
 	Address    common.Address `serialize:"true" json:"address"`
 }
@@ -338,9 +615,13 @@ func (svc *PublicService) GetStakers(_ *http.Request, args *GetStakersArgs, repl
 	if (args.StakerType != chain.RouteStake()) && (args.StakerType != chain.SerStake()) {
 		return fmt.Errorf("type err %d", args.StakerType)
 	}
+	_, state, err := svc.vm.historicalState(&args.StateArgs)
+	if err != nil {
+		return err
+	}
 
 	if bytes.Equal(args.Address[:], zeroAddress[:]) {
-		stakers, err := svc.vm.samaState.GetStakers(byte(args.StakerType))
+		stakers, err := state.GetStakers(byte(args.StakerType))
 		if err != nil {
 			return fmt.Errorf("couldn't GetStakers %w", err)
 		}
@@ -363,7 +644,7 @@ func (svc *PublicService) GetStakers(_ *http.Request, args *GetStakersArgs, repl
 		}
 
 	} else {
-		staker, exist, err := svc.vm.samaState.GetStakerMeta(byte(args.StakerType), args.Address)
+		staker, exist, err := state.GetStakerMeta(byte(args.StakerType), args.Address)
 		if err != nil {
 			return fmt.Errorf("getStakeMeta error %w", err)
 		}
@@ -392,6 +673,7 @@ func (svc *PublicService) GetStakers(_ *http.Request, args *GetStakersArgs, repl
 }
 
 type CalcRewardArgs struct {
+	StateArgs
 	StakerType uint64         `serialize:"true" json:"stakerType"`
 	Address    common.Address `serialize:"true" json:"address"`
 	EndTime    uint64         `serialize:"true" json:"endTime"`
@@ -411,7 +693,11 @@ func (svc *PublicService) CalcReward(_ *http.Request, args *CalcRewardArgs, repl
 	if time.Now().Before(time.Unix(int64(args.EndTime), 0)) {
 		return fmt.Errorf("too late %d-%d ", time.Now().Unix(), args.EndTime)
 	}
-	base, merit, yield, err := svc.vm.samaState.CalcReward(byte(args.StakerType), args.Address, args.EndTime)
+	_, state, err := svc.vm.historicalState(&args.StateArgs)
+	if err != nil {
+		return err
+	}
+	base, merit, yield, err := state.CalcReward(byte(args.StakerType), args.Address, args.EndTime)
 	if err != nil {
 		return fmt.Errorf("calc reward error %w", err)
 	}
@@ -422,6 +708,154 @@ func (svc *PublicService) CalcReward(_ *http.Request, args *CalcRewardArgs, repl
 	return err
 }
 
+type GetFoundationVestingArgs struct {
+	StateArgs
+	EndTime uint64 `serialize:"true" json:"endTime"`
+}
+
+type GetFoundationVestingReply struct {
+	*chain.VestingStatus
+	Foundation common.Address `serialize:"true" json:"foundation"`
+}
+
+// GetFoundationVesting returns the foundation share accrued, vested and
+// claimable at [EndTime]
+func (svc *PublicService) GetFoundationVesting(_ *http.Request, args *GetFoundationVestingArgs, reply *GetFoundationVestingReply) error {
+	_, state, err := svc.vm.historicalState(&args.StateArgs)
+	if err != nil {
+		return err
+	}
+	status, err := state.FoundationVesting(args.EndTime)
+	if err != nil {
+		return fmt.Errorf("couldn't FoundationVesting %w", err)
+	}
+	reply.VestingStatus = status
+	reply.Foundation = common.HexToAddress(state.GetFoundationAddress())
+	return nil
+}
+
+type GetUnbondingsArgs struct {
+	StateArgs
+	Address common.Address `serialize:"true" json:"address"`
+}
+
+type GetUnbondingsReply struct {
+	Unbondings []*chain.Unbonding `serialize:"true" json:"unbondings"`
+}
+
+// GetUnbondings returns the stake [Address] is unbonding or can withdraw,
+// or that of all addresses if it is empty
+func (svc *PublicService) GetUnbondings(_ *http.Request, args *GetUnbondingsArgs, reply *GetUnbondingsReply) error {
+	_, state, err := svc.vm.historicalState(&args.StateArgs)
+	if err != nil {
+		return err
+	}
+	unbondings, err := state.GetUnbondings(args.Address)
+	if err != nil {
+		return fmt.Errorf("couldn't GetUnbondings %w", err)
+	}
+	reply.Unbondings = append([]*chain.Unbonding{}, unbondings...)
+	return nil
+}
+
+type GetSlashesArgs struct {
+	StateArgs
+	Staker common.Address `serialize:"true" json:"staker"`
+}
+
+type GetSlashesReply struct {
+	Slashes []*chain.SlashRecord `serialize:"true" json:"slashes"`
+}
+
+// GetSlashes returns the slash history of [Staker], oldest first
+func (svc *PublicService) GetSlashes(_ *http.Request, args *GetSlashesArgs, reply *GetSlashesReply) error {
+	_, state, err := svc.vm.historicalState(&args.StateArgs)
+	if err != nil {
+		return err
+	}
+	slashes, err := state.GetSlashes(args.Staker)
+	if err != nil {
+		return fmt.Errorf("couldn't GetSlashes %w", err)
+	}
+	reply.Slashes = append([]*chain.SlashRecord{}, slashes...)
+	return nil
+}
+
+type GetStakeDelegationsArgs struct {
+	StateArgs
+	Delegator  common.Address `serialize:"true" json:"delegator"`
+	StakerAddr common.Address `serialize:"true" json:"stakerAddr"`
+	// EndTime is the reward end time the claimable amounts are computed
+	// at, none are computed if it is 0
+	EndTime uint64 `serialize:"true" json:"endTime"`
+}
+
+type APIStakeDelegation struct {
+	*chain.StakeDelegation
+	CommissionPerc uint32 `serialize:"true" json:"commissionPerc"`
+	Claimable      uint64 `serialize:"true" json:"claimable"`
+}
+
+type GetStakeDelegationsReply struct {
+	Delegations []APIStakeDelegation `serialize:"true" json:"delegations"`
+}
+
+// GetStakeDelegations returns the delegations of [Delegator] to [StakerAddr],
+// either may be empty to match all. Claimable is the share of the delegator
+// in the reward the node would be paid at [EndTime].
+func (svc *PublicService) GetStakeDelegations(_ *http.Request, args *GetStakeDelegationsArgs, reply *GetStakeDelegationsReply) error {
+	_, state, err := svc.vm.historicalState(&args.StateArgs)
+	if err != nil {
+		return err
+	}
+	delegations, err := state.GetStakeDelegations(args.StakerAddr)
+	if err != nil {
+		return fmt.Errorf("couldn't GetStakeDelegations %w", err)
+	}
+	splits := map[common.Address]*chain.RewardSplit{}
+	reply.Delegations = []APIStakeDelegation{}
+	for _, delegation := range delegations {
+		if !bytes.Equal(args.Delegator[:], zeroAddress[:]) && delegation.Delegator != args.Delegator {
+			continue
+		}
+		pool, err := state.GetStakerPool(delegation.StakerAddr)
+		if err != nil {
+			return err
+		}
+		apiDelegation := APIStakeDelegation{StakeDelegation: delegation, CommissionPerc: pool.CommissionPerc}
+		if args.EndTime > 0 {
+			split, ok := splits[delegation.StakerAddr]
+			if !ok {
+				if split, err = svc.rewardSplit(state, delegation.StakerAddr, args.EndTime); err != nil {
+					return err
+				}
+				splits[delegation.StakerAddr] = split
+			}
+			apiDelegation.Claimable = split.Delegators[delegation.Delegator]
+		}
+		reply.Delegations = append(reply.Delegations, apiDelegation)
+	}
+	return nil
+}
+
+// rewardSplit splits the reward the node of [address] would be paid at
+// [endTime]
+func (svc *PublicService) rewardSplit(state chain.SamaState, address common.Address, endTime uint64) (*chain.RewardSplit, error) {
+	ok, stakerType, err := state.IsStaker(address)
+	if err != nil || !ok {
+		return nil, fmt.Errorf("staker %s not found %v", address, err)
+	}
+	staker, _, err := state.GetStakerMeta(stakerType, address)
+	if err != nil {
+		return nil, err
+	}
+	base, merit, yield, err := state.CalcReward(stakerType, address, endTime)
+	if err != nil {
+		return nil, fmt.Errorf("calc reward error %w", err)
+	}
+	return chain.SplitStakerReward(state, staker, base+merit+yield, endTime)
+}
+
 func (svc *PublicService) SignSubmitRawTx(
 	ctx context.Context,
 	utx chain.UnsignedTransaction,
@@ -762,6 +1196,37 @@ type APIAction struct {
 	Key        string           `serialize:"true" json:"key"`
 	NewValue   string           `serialize:"true" json:"newValue"`
 	Voters     []common.Address `serialize:"true" json:"voters"`
+	Against    []common.Address `serialize:"true" json:"against"`
+	Abstain    []common.Address `serialize:"true" json:"abstain"`
+	Proposer   common.Address   `serialize:"true" json:"proposer"`
+	// Status at the last accepted block and how the action got there
+	Status      string                    `serialize:"true" json:"status"`
+	Transitions []*chain.ActionTransition `serialize:"true" json:"transitions"`
+	// Current votes against the quorum and threshold of the action type
+	Tally *chain.Tally `serialize:"true" json:"tally"`
+}
+
+func newAPIAction(s chain.SamaState, action *chain.ActionMeta, blkTime uint64) (APIAction, error) {
+	tally, err := chain.TallyAction(s, action)
+	if err != nil {
+		return APIAction{}, fmt.Errorf("couldn't tally action %w", err)
+	}
+	return APIAction{
+		TxID:        action.TxIDs[0],
+		ActionType:  action.ActionType,
+		ActionID:    action.ActionID,
+		StartTime:   action.StartTime,
+		EndTime:     action.EndTime,
+		Key:         action.Key,
+		NewValue:    action.NewValue,
+		Voters:      action.Voters,
+		Against:     action.Against,
+		Abstain:     action.Abstain,
+		Proposer:    action.Proposer,
+		Status:      action.StatusAt(blkTime),
+		Transitions: action.Transitions,
+		Tally:       tally,
+	}, nil
 }
 
 type GetActionsReply struct {
@@ -770,6 +1235,8 @@ type GetActionsReply struct {
 
 var zeroShortID = (ids.ShortID{})
 
+// GetActions returns the open actions, or the action [args.ActionID] in any
+// status. Closed actions are listed by [GetActionHistory].
 func (svc *PublicService) GetActions(_ *http.Request, args *GetActionsArgs, reply *GetActionsReply) error {
 
 	ok := svc.vm.samaState.CheckActionType(args.ActionType)
@@ -777,45 +1244,169 @@ func (svc *PublicService) GetActions(_ *http.Request, args *GetActionsArgs, repl
 		return fmt.Errorf("action type err")
 	}
 
+	blkTime := uint64(svc.vm.lastAccepted.Tmstmp)
 	if bytes.Equal(args.ActionID[:], zeroShortID[:]) {
 		actions, err := svc.vm.samaState.GetActions()
 		if err != nil {
 			return fmt.Errorf("couldn't GetActions %w", err)
 		}
 		for _, action := range actions {
-			reply.Actions = append(reply.Actions, APIAction{
-				TxID:       action.TxIDs[0],
-				ActionType: action.ActionType,
-				ActionID:   action.ActionID,
-				StartTime:  action.StartTime,
-				EndTime:    action.EndTime,
-				Key:        action.Key,
-				NewValue:   action.NewValue,
-				Voters:     action.Voters,
-			})
+			if !action.IsOpen(blkTime) {
+				continue
+			}
+			apiAction, err := newAPIAction(svc.vm.samaState, action, blkTime)
+			if err != nil {
+				return err
+			}
+			reply.Actions = append(reply.Actions, apiAction)
 		}
 	} else {
-		action, exist, err := svc.vm.samaState.GetActionMeta(args.ActionID)
+		action, exist, err := svc.vm.samaState.GetActionMeta(args.ActionID, blkTime)
 		if err != nil {
 			return fmt.Errorf("GetActionMeta error %w", err)
 		}
 		if !exist {
 			return fmt.Errorf("not found")
 		}
-		reply.Actions = append(reply.Actions, APIAction{
-			TxID:       action.TxIDs[0],
-			ActionType: action.ActionType,
-			ActionID:   action.ActionID,
-			StartTime:  action.StartTime,
-			EndTime:    action.EndTime,
-			Key:        action.Key,
-			NewValue:   action.NewValue,
-			Voters:     action.Voters,
-		})
+		apiAction, err := newAPIAction(svc.vm.samaState, action, blkTime)
+		if err != nil {
+			return err
+		}
+		reply.Actions = append(reply.Actions, apiAction)
+	}
+	return nil
+}
+
+type GetActionHistoryArgs struct {
+	// Only return actions of this type (0 returns every type)
+	ActionType uint64 `serialize:"true" json:"actionType"`
+	// Only return actions targeting this key
+	Key string `serialize:"true" json:"key"`
+}
+
+type GetActionHistoryReply struct {
+	// Ordered by start time
+	Actions []APIAction `serialize:"true" json:"actions"`
+}
+
+// GetActionHistory returns every action proposed on the chain, including
+// closed ones, with their status transitions
+func (svc *PublicService) GetActionHistory(_ *http.Request, args *GetActionHistoryArgs, reply *GetActionHistoryReply) error {
+	if args.ActionType != 0 && !svc.vm.samaState.CheckActionType(args.ActionType) {
+		return fmt.Errorf("action type err")
+	}
+
+	var (
+		actions []*chain.ActionMeta
+		err     error
+	)
+	if len(args.Key) > 0 {
+		actions, err = svc.vm.samaState.GetActionsByKey(args.Key)
+	} else {
+		actions, err = svc.vm.samaState.GetActions()
+	}
+	if err != nil {
+		return fmt.Errorf("couldn't GetActions %w", err)
+	}
+	sort.SliceStable(actions, func(i, j int) bool {
+		return actions[i].StartTime < actions[j].StartTime
+	})
+
+	blkTime := uint64(svc.vm.lastAccepted.Tmstmp)
+	reply.Actions = []APIAction{}
+	for _, action := range actions {
+		if args.ActionType != 0 && action.ActionType != args.ActionType {
+			continue
+		}
+		apiAction, err := newAPIAction(svc.vm.samaState, action, blkTime)
+		if err != nil {
+			return err
+		}
+		reply.Actions = append(reply.Actions, apiAction)
 	}
 	return nil
 }
 
+type GetVotingPolicyArgs struct {
+	ActionType uint64 `serialize:"true" json:"actionType"`
+}
+
+type GetVotingPolicyReply struct {
+	Policy *chain.VotingPolicy `serialize:"true" json:"policy"`
+}
+
+func (svc *PublicService) GetVotingPolicy(_ *http.Request, args *GetVotingPolicyArgs, reply *GetVotingPolicyReply) (err error) {
+	if !svc.vm.samaState.CheckActionType(args.ActionType) {
+		return fmt.Errorf("action type err")
+	}
+	reply.Policy, err = svc.vm.samaState.GetVotingPolicy(args.ActionType)
+	return err
+}
+
+type GetSlashingPolicyArgs struct {
+	OffenceType uint64 `serialize:"true" json:"offenceType"`
+}
+
+type GetSlashingPolicyReply struct {
+	Policy *chain.SlashingPolicy `serialize:"true" json:"policy"`
+}
+
+func (svc *PublicService) GetSlashingPolicy(_ *http.Request, args *GetSlashingPolicyArgs, reply *GetSlashingPolicyReply) (err error) {
+	if _, err := chain.GetOffence(args.OffenceType); err != nil {
+		return err
+	}
+	reply.Policy, err = svc.vm.samaState.GetSlashingPolicy(args.OffenceType)
+	return err
+}
+
+type GetVoteDelegationsArgs struct {
+	Address common.Address `serialize:"true" json:"address"`
+}
+
+type GetVoteDelegationsReply struct {
+	Delegations []*chain.VoteDelegation `serialize:"true" json:"delegations"`
+}
+
+// GetVoteDelegations returns the delegations made by or to [Address], or all
+// of them if it is empty
+func (svc *PublicService) GetVoteDelegations(_ *http.Request, args *GetVoteDelegationsArgs, reply *GetVoteDelegationsReply) error {
+	delegations, err := svc.vm.samaState.GetVoteDelegations()
+	if err != nil {
+		return fmt.Errorf("couldn't GetVoteDelegations %w", err)
+	}
+	reply.Delegations = []*chain.VoteDelegation{}
+	for _, delegation := range delegations {
+		if !bytes.Equal(args.Address[:], zeroAddress[:]) &&
+			delegation.Delegator != args.Address && delegation.Delegate != args.Address {
+			continue
+		}
+		reply.Delegations = append(reply.Delegations, delegation)
+	}
+	return nil
+}
+
+type GetMultisigArgs struct {
+	Address common.Address `serialize:"true" json:"address"`
+}
+
+type GetMultisigReply struct {
+	Account *chain.MultisigAccount `serialize:"true" json:"account"`
+}
+
+// GetMultisig returns the multisig account [Address], its nonce is the one
+// members approve the next tx with
+func (svc *PublicService) GetMultisig(_ *http.Request, args *GetMultisigArgs, reply *GetMultisigReply) error {
+	account, exist, err := svc.vm.samaState.GetMultisig(args.Address)
+	if err != nil {
+		return err
+	}
+	if !exist {
+		return chain.ErrNotMultisig
+	}
+	reply.Account = account
+	return nil
+}
+
 type UserFeeArgs struct {
 	UserType  uint64 `serialize:"true" json:"userType"`
 	StartTime uint64 `serialize:"true" json:"startTime"`
@@ -827,7 +1418,7 @@ type UserFeeReply struct {
 }
 
 func (svc *PublicService) GetUserFee(_ *http.Request, args *UserFeeArgs, reply *UserFeeReply) (err error) {
-	user, ok, err := svc.vm.samaState.GetUserType(svc.vm.db, args.UserType)
+	user, ok, err := svc.vm.samaState.GetUserType(args.UserType)
 	if err != nil {
 		return err
 	}
@@ -856,7 +1447,7 @@ type GetUsersReply struct {
 
 func (svc *PublicService) GetUsers(_ *http.Request, args *GetUsersArgs, reply *GetUsersReply) error {
 	if bytes.Equal(args.Address[:], zeroAddress[:]) {
-		users, err := svc.vm.samaState.GetUsers(svc.vm.db)
+		users, err := svc.vm.samaState.GetUsers()
 		if err != nil {
 			return fmt.Errorf("couldn't GetUsers %w", err)
 		}
@@ -869,7 +1460,7 @@ func (svc *PublicService) GetUsers(_ *http.Request, args *GetUsersArgs, reply *G
 			})
 		}
 	} else {
-		user, exist, err := svc.vm.samaState.GetUserMeta(svc.vm.db, args.Address)
+		user, exist, err := svc.vm.samaState.GetUserMeta(args.Address)
 		if err != nil {
 			return fmt.Errorf("GetUserMeta error %w", err)
 		}
@@ -1034,16 +1625,33 @@ func (svc *PublicService) CreateShortID(_ *http.Request, _ *struct{}, reply *Cre
 	return nil
 }
 
+type GetSysParamsArgs struct {
+	StateArgs
+}
+
 type GetSysParamsReply struct {
 	Params chain.SysParamsMeta `serialize:"true" json:"params"`
 }
 
-func (svc *PublicService) GetSysParams(_ *http.Request, _ *struct{}, reply *GetSysParamsReply) error {
-	params := svc.vm.samaState.GetSysParams()
+func (svc *PublicService) GetSysParams(_ *http.Request, args *GetSysParamsArgs, reply *GetSysParamsReply) error {
+	_, state, err := svc.vm.historicalState(&args.StateArgs)
+	if err != nil {
+		return err
+	}
+	params := state.GetSysParams()
 	reply.Params = *params
 	return nil
 }
 
+type GetParamSchemasReply struct {
+	Schemas []*chain.ParamSchema `serialize:"true" json:"schemas"`
+}
+
+func (svc *PublicService) GetParamSchemas(_ *http.Request, _ *struct{}, reply *GetParamSchemasReply) error {
+	reply.Schemas = chain.ParamSchemas()
+	return nil
+}
+
 type ProofArgs struct {
 	api.UserPass
 	Address   string `serialize:"true" json:"address"`
@@ -0,0 +1,501 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package vm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow/choices"
+	"github.com/ava-labs/avalanchego/snow/engine/common"
+	snowmanblock "github.com/ava-labs/avalanchego/snow/engine/snowman/block"
+	"github.com/ava-labs/avalanchego/utils/set"
+	"github.com/ava-labs/avalanchego/utils/units"
+	"github.com/ethereum/go-ethereum/crypto"
+	log "github.com/inconshreveable/log15"
+
+	"github.com/SamaNetwork/SamaVM/chain"
+)
+
+const (
+	syncRangeLimit    = 4096
+	syncRangeMaxBytes = 1 * units.MiB
+	syncBlocksLimit   = 64
+	syncRetryInterval = time.Second
+)
+
+const (
+	stateRangeRequestType byte = iota
+	blocksRequestType
+)
+
+var (
+	_ snowmanblock.StateSyncableVM = &VM{}
+	_ snowmanblock.StateSummary    = &stateSummary{}
+
+	ongoingSyncSummary = []byte("ongoing_sync_summary")
+
+	ErrStateRootMismatch = errors.New("state root mismatch")
+	ErrInvalidSyncBlock  = errors.New("invalid sync block")
+	ErrNoSyncPeers       = errors.New("no peers to sync from")
+)
+
+type stateRangeRequest struct {
+	Height uint64 `serialize:"true"`
+	Start  []byte `serialize:"true"`
+	Limit  uint32 `serialize:"true"`
+}
+
+type stateRangeResponse struct {
+	Keys   [][]byte `serialize:"true"`
+	Values [][]byte `serialize:"true"`
+	More   bool     `serialize:"true"`
+}
+
+type blocksRequest struct {
+	BlkID ids.ID `serialize:"true"`
+	Limit uint32 `serialize:"true"`
+}
+
+type blocksResponse struct {
+	Blocks [][]byte `serialize:"true"`
+}
+
+// stateSummary is a [chain.SyncSummary] exposed to the engine
+type stateSummary struct {
+	*chain.SyncSummary
+
+	id    ids.ID
+	bytes []byte
+	vm    *VM
+}
+
+func (vm *VM) newStateSummary(summary *chain.SyncSummary, b []byte) (*stateSummary, error) {
+	id, err := ids.ToID(crypto.Keccak256(b))
+	if err != nil {
+		return nil, err
+	}
+	return &stateSummary{SyncSummary: summary, id: id, bytes: b, vm: vm}, nil
+}
+
+func (s *stateSummary) ID() ids.ID     { return s.id }
+func (s *stateSummary) Height() uint64 { return s.Hght }
+func (s *stateSummary) Bytes() []byte  { return s.bytes }
+
+// Accept starts downloading the state of the summary in the background. The
+// engine waits for [common.StateSyncDone] before bootstrapping the blocks
+// that follow it.
+func (s *stateSummary) Accept(context.Context) (snowmanblock.StateSyncMode, error) {
+	vm := s.vm
+	if s.Hght <= vm.lastAccepted.Hght {
+		log.Info("skipping state sync", "summary", s.Hght, "last accepted", vm.lastAccepted.Hght)
+		return snowmanblock.StateSyncSkipped, vm.db.Delete(ongoingSyncSummary)
+	}
+	if err := vm.db.Put(ongoingSyncSummary, s.bytes); err != nil {
+		return 0, err
+	}
+	log.Info("starting state sync", "summary", s.Hght, "block", s.BlkID, "root", s.Root)
+	go vm.syncer.run(s)
+	return snowmanblock.StateSyncStatic, nil
+}
+
+// implements "snowmanblock.StateSyncableVM"
+func (vm *VM) StateSyncEnabled(context.Context) (bool, error) {
+	return vm.config.StateSyncEnabled, nil
+}
+
+// implements "snowmanblock.StateSyncableVM"
+func (vm *VM) GetOngoingSyncStateSummary(ctx context.Context) (snowmanblock.StateSummary, error) {
+	b, err := vm.db.Get(ongoingSyncSummary)
+	if err != nil {
+		return nil, err
+	}
+	return vm.ParseStateSummary(ctx, b)
+}
+
+// implements "snowmanblock.StateSyncableVM"
+func (vm *VM) GetLastStateSummary(context.Context) (snowmanblock.StateSummary, error) {
+	summary, b, err := chain.GetLastSyncSummary(vm.db)
+	if err != nil {
+		return nil, err
+	}
+	return vm.newStateSummary(summary, b)
+}
+
+// implements "snowmanblock.StateSyncableVM"
+func (vm *VM) ParseStateSummary(_ context.Context, b []byte) (snowmanblock.StateSummary, error) {
+	summary := new(chain.SyncSummary)
+	if _, err := chain.Unmarshal(b, summary); err != nil {
+		return nil, err
+	}
+	return vm.newStateSummary(summary, b)
+}
+
+// implements "snowmanblock.StateSyncableVM"
+func (vm *VM) GetStateSummary(_ context.Context, height uint64) (snowmanblock.StateSummary, error) {
+	summary, b, err := chain.GetSyncSummary(vm.db, height)
+	if err != nil {
+		return nil, err
+	}
+	return vm.newStateSummary(summary, b)
+}
+
+// updateSyncState prunes old state diffs and stores a summary every
+// [chain.Genesis.StateSyncInterval] blocks.
+func (vm *VM) updateSyncState(b *chain.StatelessBlock) error {
+	if r := vm.config.StateDiffRetention; r > 0 && b.Hght > r {
+		if err := chain.DeleteStateDiff(vm.db, b.Hght-r); err != nil {
+			return err
+		}
+		if err := chain.DeleteSyncSummary(vm.db, b.Hght-r); err != nil {
+			return err
+		}
+	}
+	// Summaries are only served for blocks committing to their state
+	interval := vm.genesis.StateSyncInterval
+	if interval == 0 || b.Hght%interval != 0 || !b.HasStateRoot() {
+		return nil
+	}
+	log.Debug("created sync summary", "height", b.Hght, "root", b.StateRoot)
+	return chain.PutSyncSummary(vm.db, &chain.SyncSummary{
+		BlkID: b.ID(),
+		Hght:  b.Hght,
+		Root:  b.StateRoot,
+	})
+}
+
+// acceptedBlockAt returns the accepted block at [height]
+func (vm *VM) acceptedBlockAt(height uint64) (*chain.StatelessBlock, error) {
+	if la := vm.lastAccepted; height == la.Hght {
+		return la, nil
+	}
+	blkID, err := chain.GetBlockIDAtHeight(vm.db, height)
+	if err != nil {
+		return nil, err
+	}
+	return vm.GetStatelessBlock(blkID)
+}
+
+// stateAt returns the accepted state after the block at [height]. The last
+// view is reused until another block is accepted.
+func (vm *VM) stateAt(height uint64) (database.Database, error) {
+	tip := vm.lastAccepted.Hght
+	if v := vm.syncView; v != nil && v.height == height && v.tip == tip {
+		return v.db, nil
+	}
+	db, err := chain.StateAt(vm.db, height, tip)
+	if err != nil {
+		return nil, err
+	}
+	vm.syncView = &stateView{height: height, tip: tip, db: db}
+	return db, nil
+}
+
+type stateView struct {
+	height uint64
+	tip    uint64
+	db     database.Database
+}
+
+// handleSyncRequest serves the state and blocks requested by syncing peers
+func (vm *VM) handleSyncRequest(request []byte) ([]byte, error) {
+	if len(request) == 0 {
+		return nil, ErrInputIsNil
+	}
+	switch request[0] {
+	case stateRangeRequestType:
+		req := new(stateRangeRequest)
+		if _, err := chain.Unmarshal(request[1:], req); err != nil {
+			return nil, err
+		}
+		db, err := vm.stateAt(req.Height)
+		if err != nil {
+			return nil, err
+		}
+		limit := int(req.Limit)
+		if limit == 0 || limit > syncRangeLimit {
+			limit = syncRangeLimit
+		}
+		keys, values, more, err := chain.GetStateRange(db, req.Start, limit, syncRangeMaxBytes)
+		if err != nil {
+			return nil, err
+		}
+		return chain.Marshal(&stateRangeResponse{Keys: keys, Values: values, More: more})
+
+	case blocksRequestType:
+		req := new(blocksRequest)
+		if _, err := chain.Unmarshal(request[1:], req); err != nil {
+			return nil, err
+		}
+		limit := int(req.Limit)
+		if limit == 0 || limit > syncBlocksLimit {
+			limit = syncBlocksLimit
+		}
+		resp := &blocksResponse{}
+		blkID := req.BlkID
+		for len(resp.Blocks) < limit {
+			blk, err := vm.GetStatelessBlock(blkID)
+			if err != nil {
+				return nil, err
+			}
+			if blk.Status() != choices.Accepted {
+				return nil, ErrInvalidSyncBlock
+			}
+			resp.Blocks = append(resp.Blocks, blk.Bytes())
+			if blk.Hght == 0 {
+				break
+			}
+			blkID = blk.Prnt
+		}
+		return chain.Marshal(resp)
+
+	default:
+		return nil, fmt.Errorf("unknown request type %d", request[0])
+	}
+}
+
+// stateSyncer downloads the state of a summary from peers
+type stateSyncer struct {
+	vm *VM
+
+	lock      sync.Mutex
+	requestID uint32
+	pending   map[uint32]chan []byte
+	peers     set.Set[ids.NodeID]
+}
+
+func newStateSyncer(vm *VM) *stateSyncer {
+	return &stateSyncer{
+		vm:      vm,
+		pending: make(map[uint32]chan []byte),
+	}
+}
+
+func (s *stateSyncer) connected(nodeID ids.NodeID) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.peers.Add(nodeID)
+}
+
+func (s *stateSyncer) disconnected(nodeID ids.NodeID) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.peers.Remove(nodeID)
+}
+
+// handleResponse delivers [response] to the pending request [requestID].
+// A nil response marks the request as failed.
+func (s *stateSyncer) handleResponse(requestID uint32, response []byte) {
+	s.lock.Lock()
+	ch, ok := s.pending[requestID]
+	delete(s.pending, requestID)
+	s.lock.Unlock()
+	if ok {
+		ch <- response
+	}
+}
+
+func (s *stateSyncer) randomPeer() (ids.NodeID, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	peers := s.peers.List()
+	if len(peers) == 0 {
+		return ids.EmptyNodeID, ErrNoSyncPeers
+	}
+	return peers[rand.Intn(len(peers))], nil //nolint:gosec
+}
+
+// request sends [msg] of type [typ] to [nodeID] and waits for the response
+func (s *stateSyncer) request(nodeID ids.NodeID, typ byte, msg interface{}, resp interface{}) error {
+	b, err := chain.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	ch := make(chan []byte, 1)
+	s.lock.Lock()
+	s.requestID++
+	requestID := s.requestID
+	s.pending[requestID] = ch
+	s.lock.Unlock()
+
+	nodes := set.NewSet[ids.NodeID](1)
+	nodes.Add(nodeID)
+	if err := s.vm.appSender.SendAppRequest(context.TODO(), nodes, requestID, append([]byte{typ}, b...)); err != nil {
+		s.handleResponse(requestID, nil)
+		return err
+	}
+	select {
+	case r := <-ch:
+		if r == nil {
+			return fmt.Errorf("request %d to %s failed", requestID, nodeID)
+		}
+		_, err := chain.Unmarshal(r, resp)
+		return err
+	case <-s.vm.stop:
+		return errors.New("vm stopped")
+	}
+}
+
+// run syncs to [summary], retrying with other peers until it succeeds or the
+// VM shuts down, and then notifies the engine.
+func (s *stateSyncer) run(summary *stateSummary) {
+	var blk *chain.StatelessBlock
+	for {
+		var err error
+		blk, err = s.sync(summary)
+		if err == nil {
+			break
+		}
+		log.Warn("state sync failed, retrying", "summary", summary.Hght, "err", err)
+		if !s.wait() {
+			return
+		}
+	}
+	for {
+		err := s.finish(blk)
+		if err == nil {
+			break
+		}
+		log.Warn("could not finish state sync, retrying", "summary", summary.Hght, "err", err)
+		if !s.wait() {
+			return
+		}
+	}
+
+	// The engine only starts bootstrapping once it is told that state sync
+	// is done, so the message can't be dropped
+	select {
+	case s.vm.toEngine <- common.StateSyncDone:
+	case <-s.vm.stop:
+	}
+}
+
+// wait waits before the next attempt, it returns false if the VM shuts down
+// in the meantime
+func (s *stateSyncer) wait() bool {
+	select {
+	case <-time.After(syncRetryInterval):
+		return true
+	case <-s.vm.stop:
+		return false
+	}
+}
+
+func (s *stateSyncer) sync(summary *stateSummary) (*chain.StatelessBlock, error) {
+	vm := s.vm
+	start := time.Now()
+	if err := chain.ClearState(vm.db); err != nil {
+		return nil, err
+	}
+
+	// Download the state
+	var cursor []byte
+	keys := 0
+	for {
+		peer, err := s.randomPeer()
+		if err != nil {
+			return nil, err
+		}
+		resp := new(stateRangeResponse)
+		if err := s.request(peer, stateRangeRequestType, &stateRangeRequest{
+			Height: summary.Hght,
+			Start:  cursor,
+			Limit:  syncRangeLimit,
+		}, resp); err != nil {
+			return nil, err
+		}
+		if resp.More && len(resp.Keys) == 0 {
+			return nil, chain.ErrInvalidSyncRange
+		}
+		batch := vm.db.NewBatch()
+		if err := chain.PutStateRange(batch, cursor, resp.Keys, resp.Values); err != nil {
+			return nil, fmt.Errorf("%w: from %s", err, peer)
+		}
+		if err := batch.Write(); err != nil {
+			return nil, err
+		}
+		keys += len(resp.Keys)
+		if !resp.More {
+			break
+		}
+		last := resp.Keys[len(resp.Keys)-1]
+		cursor = append(append(make([]byte, 0, len(last)+1), last...), 0)
+	}
+	root, err := chain.BuildStateTree(vm.db)
+	if err != nil {
+		return nil, err
+	}
+	if root != summary.Root {
+		return nil, fmt.Errorf("%w: expected %s, got %s", ErrStateRootMismatch, summary.Root, root)
+	}
+	log.Info("synced state", "keys", keys, "root", root, "t", time.Since(start))
+
+	// Download the blocks needed to verify the blocks after the summary
+	var (
+		last   *chain.StatelessBlock
+		expect = summary.BlkID
+	)
+	for {
+		peer, err := s.randomPeer()
+		if err != nil {
+			return nil, err
+		}
+		resp := new(blocksResponse)
+		if err := s.request(peer, blocksRequestType, &blocksRequest{BlkID: expect, Limit: syncBlocksLimit}, resp); err != nil {
+			return nil, err
+		}
+		if len(resp.Blocks) == 0 {
+			return nil, ErrInvalidSyncBlock
+		}
+		for _, b := range resp.Blocks {
+			blk, err := chain.ParseBlock(b, choices.Accepted, vm)
+			if err != nil {
+				return nil, err
+			}
+			if blk.ID() != expect {
+				return nil, fmt.Errorf("%w: expected %s, got %s", ErrInvalidSyncBlock, expect, blk.ID())
+			}
+			if last == nil {
+				if blk.Hght != summary.Hght || blk.StateRoot != summary.Root {
+					return nil, ErrInvalidSyncBlock
+				}
+				last = blk
+			}
+			if err := chain.PutBlock(vm.db, blk); err != nil {
+				return nil, err
+			}
+			// The lookback loads the first block outside of the window
+			if blk.Hght == 0 || last.Tmstmp-blk.Tmstmp > vm.genesis.LookbackWindow {
+				return last, nil
+			}
+			expect = blk.Prnt
+		}
+	}
+}
+
+// finish makes the block of the summary the last accepted block
+func (s *stateSyncer) finish(blk *chain.StatelessBlock) error {
+	vm := s.vm
+	vm.ctx.Lock.Lock()
+	defer vm.ctx.Lock.Unlock()
+
+	if err := chain.SetLastAccepted(vm.db, blk); err != nil {
+		return err
+	}
+	if err := vm.db.Delete(ongoingSyncSummary); err != nil {
+		return err
+	}
+	vm.blocks.Put(blk.ID(), blk)
+	vm.preferred, vm.lastAccepted = blk.ID(), blk
+	vm.syncView = nil
+	log.Info("finished state sync", "block", blk.ID(), "height", blk.Hght)
+	return nil
+}
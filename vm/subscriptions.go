@@ -0,0 +1,265 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package vm
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/set"
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/SamaNetwork/SamaVM/chain"
+)
+
+// Subscribers long-poll [SubscribeEndpoint] for the events published after
+// the last one they received (their cursor). Recent events are kept in
+// memory, so cursors are only valid until the node restarts.
+
+const (
+	EventBlock    = "block"
+	EventActivity = "activity"
+	EventTx       = "tx"
+
+	maxSubscribeTimeout   = 30 * time.Second
+	txStatusCheckInterval = time.Second
+)
+
+type BlockEvent struct {
+	BlockID   ids.ID   `serialize:"true" json:"blockId"`
+	Height    uint64   `serialize:"true" json:"height"`
+	Timestamp int64    `serialize:"true" json:"timestamp"`
+	Txs       []ids.ID `serialize:"true" json:"txs"`
+}
+
+type TxEvent struct {
+	TxID   ids.ID `serialize:"true" json:"txId"`
+	Status string `serialize:"true" json:"status"`
+	Reason string `serialize:"true" json:"reason,omitempty"` // why the tx was dropped
+}
+
+type Event struct {
+	// Tx events are not numbered
+	Seq      uint64          `serialize:"true" json:"seq"`
+	Type     string          `serialize:"true" json:"type"`
+	Block    *BlockEvent     `serialize:"true" json:"block,omitempty"`
+	Activity *chain.Activity `serialize:"true" json:"activity,omitempty"`
+	Tx       *TxEvent        `serialize:"true" json:"tx,omitempty"`
+
+	addresses []common.Address
+}
+
+// eventBuffer keeps the last published events
+type eventBuffer struct {
+	mu     sync.Mutex
+	events []*Event
+	head   uint64 // seq of the last event
+
+	// closed when new events are published
+	notify chan struct{}
+}
+
+func newEventBuffer(size int) *eventBuffer {
+	return &eventBuffer{
+		events: make([]*Event, size),
+		notify: make(chan struct{}),
+	}
+}
+
+func (e *eventBuffer) publish(events ...*Event) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	size := uint64(len(e.events))
+	for _, ev := range events {
+		e.head++
+		ev.Seq = e.head
+		e.events[e.head%size] = ev
+	}
+	close(e.notify)
+	e.notify = make(chan struct{})
+}
+
+// since returns the events after [cursor] that are still kept, the seq of
+// the last event, whether events after [cursor] are missing, and a channel
+// closed once more events are published
+func (e *eventBuffer) since(cursor uint64) ([]*Event, uint64, bool, <-chan struct{}) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	missed := false
+	if cursor > e.head {
+		// Cursor from before a restart
+		cursor, missed = e.head, true
+	}
+	size := uint64(len(e.events))
+	if e.head-cursor > size {
+		cursor, missed = e.head-size, true
+	}
+	events := make([]*Event, 0, e.head-cursor)
+	for seq := cursor + 1; seq <= e.head; seq++ {
+		events = append(events, e.events[seq%size])
+	}
+	return events, e.head, missed, e.notify
+}
+
+func (e *eventBuffer) last() uint64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.head
+}
+
+// publishAccepted notifies subscribers of [b] and its activity
+func (vm *VM) publishAccepted(b *chain.StatelessBlock) {
+	txIDs := make([]ids.ID, len(b.Txs))
+	events := make([]*Event, 0, 1+len(b.Txs))
+	events = append(events, &Event{
+		Type: EventBlock,
+		Block: &BlockEvent{
+			BlockID:   b.ID(),
+			Height:    b.Hght,
+			Timestamp: b.Tmstmp,
+			Txs:       txIDs,
+		},
+	})
+	for i, tx := range b.Txs {
+		txIDs[i] = tx.ID()
+		activity := tx.Activity()
+		activity.Tmstmp = b.Tmstmp
+		activity.Hght = b.Hght
+		activity.Index = uint32(i)
+		events = append(events, &Event{
+			Type:      EventActivity,
+			Activity:  activity,
+			addresses: chain.ActivityAddresses(tx),
+		})
+	}
+	vm.events.publish(events...)
+}
+
+type SubscriptionService struct {
+	vm *VM
+}
+
+type SubscribeArgs struct {
+	// Only return events after this one (0 starts from the last event)
+	Cursor uint64 `serialize:"true" json:"cursor"`
+
+	Blocks bool `serialize:"true" json:"blocks"`
+	// Activity may be filtered by the accounts involved and by type
+	Activity  bool             `serialize:"true" json:"activity"`
+	Addresses []common.Address `serialize:"true" json:"addresses"`
+	Types     []string         `serialize:"true" json:"types"`
+	// The status of these txs is reported once they are accepted or dropped
+	TxIDs []ids.ID `serialize:"true" json:"txIds"`
+
+	// How long to wait for events (up to 30s)
+	Timeout time.Duration `serialize:"true" json:"timeout"`
+}
+
+type SubscribeReply struct {
+	Events []*Event `serialize:"true" json:"events"`
+	// Cursor to poll the next events with
+	Cursor uint64 `serialize:"true" json:"cursor"`
+	// Set if events after the requested cursor are no longer kept
+	Missed bool `serialize:"true" json:"missed"`
+}
+
+func (args *SubscribeArgs) matches(ev *Event) bool {
+	switch ev.Type {
+	case EventBlock:
+		return args.Blocks
+	case EventActivity:
+		if !args.Activity {
+			return false
+		}
+		if len(args.Types) > 0 {
+			found := false
+			for _, typ := range args.Types {
+				if typ == ev.Activity.Typ {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		if len(args.Addresses) == 0 {
+			return true
+		}
+		for _, addr := range args.Addresses {
+			for _, involved := range ev.addresses {
+				if addr == involved {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// Poll waits until there are events matching [args] or the timeout expires
+func (svc *SubscriptionService) Poll(r *http.Request, args *SubscribeArgs, reply *SubscribeReply) error {
+	timeout := args.Timeout
+	if timeout > maxSubscribeTimeout {
+		timeout = maxSubscribeTimeout
+	}
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	cursor := args.Cursor
+	if cursor == 0 {
+		cursor = svc.vm.events.last()
+	}
+	watched := set.NewSet[ids.ID](len(args.TxIDs))
+	watched.Add(args.TxIDs...)
+	reply.Events = []*Event{}
+	for {
+		events, head, missed, notify := svc.vm.events.since(cursor)
+		cursor = head
+		reply.Cursor = head
+		reply.Missed = reply.Missed || missed
+		for _, ev := range events {
+			if args.matches(ev) {
+				reply.Events = append(reply.Events, ev)
+			}
+		}
+		for txID := range watched {
+			status, reason, _, err := svc.vm.txStatus(txID)
+			if err != nil {
+				return err
+			}
+			if status != TxStatusAccepted && status != TxStatusDropped {
+				continue
+			}
+			reply.Events = append(reply.Events, &Event{
+				Type: EventTx,
+				Tx:   &TxEvent{TxID: txID, Status: status, Reason: reason},
+			})
+		}
+		if len(reply.Events) > 0 {
+			return nil
+		}
+
+		// Dropped txs are only noticed by checking again
+		var recheck <-chan time.Time
+		if len(watched) > 0 {
+			recheck = time.After(txStatusCheckInterval)
+		}
+		select {
+		case <-notify:
+		case <-recheck:
+		case <-deadline.C:
+			return nil
+		case <-r.Context().Done():
+			return r.Context().Err()
+		case <-svc.vm.stop:
+			return nil
+		}
+	}
+}
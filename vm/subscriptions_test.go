@@ -0,0 +1,75 @@
+// Copyright (C) 2022-2023, Sama , Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package vm
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/SamaNetwork/SamaVM/chain"
+)
+
+func TestSubscriptionPoll(t *testing.T) {
+	vm := &VM{events: newEventBuffer(3), stop: make(chan struct{})}
+	svc := &SubscriptionService{vm: vm}
+	r := httptest.NewRequest("POST", SubscribeEndpoint, nil)
+	addr := common.HexToAddress("0x0000000000000000000000000000000000000001")
+
+	activity := func(typ string, addrs ...common.Address) *Event {
+		return &Event{Type: EventActivity, Activity: &chain.Activity{Typ: typ}, addresses: addrs}
+	}
+	vm.events.publish(&Event{Type: EventBlock, Block: &BlockEvent{Height: 1}})
+
+	// Polls from the last event wait for new ones
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		vm.events.publish(
+			&Event{Type: EventBlock, Block: &BlockEvent{Height: 2}},
+			activity(chain.Transfer, addr),
+			activity(chain.Stake),
+		)
+	}()
+	args := &SubscribeArgs{Activity: true, Addresses: []common.Address{addr}, Timeout: time.Second}
+	reply := new(SubscribeReply)
+	if err := svc.Poll(r, args, reply); err != nil {
+		t.Fatal(err)
+	}
+	if len(reply.Events) != 1 || reply.Events[0].Seq != 3 || reply.Cursor != 4 || reply.Missed {
+		t.Fatalf("unexpected reply %+v", reply)
+	}
+
+	// Filter by type from a cursor
+	args = &SubscribeArgs{Cursor: 1, Blocks: true, Activity: true, Types: []string{chain.Stake}}
+	reply = new(SubscribeReply)
+	if err := svc.Poll(r, args, reply); err != nil {
+		t.Fatal(err)
+	}
+	if len(reply.Events) != 2 || reply.Events[0].Block.Height != 2 || reply.Events[1].Seq != 4 {
+		t.Fatalf("unexpected reply %+v", reply)
+	}
+
+	// Events that are no longer kept are reported as missed
+	vm.events.publish(&Event{Type: EventBlock, Block: &BlockEvent{Height: 3}})
+	args = &SubscribeArgs{Cursor: 1, Blocks: true}
+	reply = new(SubscribeReply)
+	if err := svc.Poll(r, args, reply); err != nil {
+		t.Fatal(err)
+	}
+	if !reply.Missed || len(reply.Events) != 1 || reply.Events[0].Block.Height != 3 || reply.Cursor != 5 {
+		t.Fatalf("unexpected reply %+v", reply)
+	}
+
+	// Polls time out without events
+	args = &SubscribeArgs{Cursor: 5, Blocks: true, Timeout: 10 * time.Millisecond}
+	reply = new(SubscribeReply)
+	if err := svc.Poll(r, args, reply); err != nil {
+		t.Fatal(err)
+	}
+	if len(reply.Events) != 0 || reply.Cursor != 5 {
+		t.Fatalf("unexpected reply %+v", reply)
+	}
+}
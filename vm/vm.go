@@ -23,6 +23,7 @@ import (
 	snowmanblock "github.com/ava-labs/avalanchego/snow/engine/snowman/block"
 	"github.com/ava-labs/avalanchego/utils"
 	"github.com/ava-labs/avalanchego/utils/json"
+	"github.com/ava-labs/avalanchego/utils/timer/mockable"
 	"github.com/gorilla/rpc/v2"
 	log "github.com/inconshreveable/log15"
 	"github.com/prometheus/client_golang/prometheus"
@@ -36,11 +37,14 @@ import (
 const (
 	Name           = "samavm"
 	PublicEndpoint = "/public"
+	// Long-polled for accepted blocks, activity and tx status
+	SubscribeEndpoint = "/subscribe"
 )
 
 var (
-	_ snowmanblock.ChainVM = &VM{}
-	_ chain.VM             = &VM{}
+	_ snowmanblock.ChainVM              = &VM{}
+	_ snowmanblock.HeightIndexedChainVM = &VM{}
+	_ chain.VM                          = &VM{}
 )
 
 type VM struct {
@@ -76,11 +80,21 @@ type VM struct {
 	activityCacheCursor uint64
 	activityCache       []*chain.Activity
 
-	// Execution checks
-	targetRangeUnits uint64
+	// Published to subscribers
+	events *eventBuffer
+
+	// Network upgrades
+	upgrades *chain.UpgradeConfig
+
+	// Local clock
+	clock mockable.Clock
 
 	samaState chain.SamaState
 
+	// State sync
+	syncer   *stateSyncer
+	syncView *stateView
+
 	stop chan struct{}
 
 	builderStop chan struct{}
@@ -118,9 +132,26 @@ func (vm *VM) Initialize(
 	//ctx.Keystore = ks.NewBlockchainKeyStore(vm.ctx.ChainID)
 
 	vm.ctx = ctx
-	vm.db = dbManager.Current().Database
+
+	// Serve hot state records from memory
+	registry := prometheus.NewRegistry()
+	db, err := chain.NewStateCache(dbManager.Current().Database, vm.config.StateCacheSize, registry)
+	if err != nil {
+		log.Error("could not initialize state cache", "err", err)
+		return err
+	}
+	if ctx.Metrics != nil {
+		if err := ctx.Metrics.Register(registry); err != nil {
+			log.Error("could not register state cache metrics", "err", err)
+			return err
+		}
+	}
+	vm.db = db
 
 	vm.activityCache = make([]*chain.Activity, vm.config.ActivityCacheSize)
+	if vm.config.SubscriptionBufferSize > 0 {
+		vm.events = newEventBuffer(vm.config.SubscriptionBufferSize)
+	}
 
 	// Init channels before initializing other structs
 	vm.stop = make(chan struct{})
@@ -129,6 +160,7 @@ func (vm *VM) Initialize(
 	vm.doneGossip = make(chan struct{})
 	vm.appSender = appSender
 	vm.network = vm.NewPushNetwork()
+	vm.syncer = newStateSyncer(vm)
 
 	vm.blocks = &cache.LRU[ids.ID, *chain.StatelessBlock]{Size: blocksLRUSize}
 	vm.verifiedBlocks = make(map[ids.ID]*chain.StatelessBlock)
@@ -157,9 +189,25 @@ func (vm *VM) Initialize(
 		return err
 	}
 
-	targetUnitsPerSecond := vm.genesis.TargetBlockSize / uint64(vm.genesis.TargetBlockRate)
-	vm.targetRangeUnits = targetUnitsPerSecond * uint64(vm.genesis.LookbackWindow)
-	log.Debug("loaded genesis", "genesis", string(genesisBytes), "target range units", vm.targetRangeUnits)
+	if r := vm.config.StateDiffRetention; r > 0 && r < 2*vm.genesis.StateSyncInterval {
+		// Summaries must remain servable while peers sync to them
+		log.Warn("increasing state diff retention", "from", r, "to", 2*vm.genesis.StateSyncInterval)
+		vm.config.StateDiffRetention = 2 * vm.genesis.StateSyncInterval
+	}
+	if vm.config.Archive && vm.config.StateSyncEnabled {
+		// A synced archive would be missing the history before the summary
+		log.Warn("disabling state sync on archive node")
+		vm.config.StateSyncEnabled = false
+	}
+
+	vm.upgrades, err = chain.ParseUpgradeConfig(upgradeBytes)
+	if err != nil {
+		log.Error("could not parse upgrade bytes", "err", err)
+		return err
+	}
+	log.Info("loaded upgrades", "forks", vm.upgrades.Forks)
+
+	log.Debug("loaded genesis", "genesis", string(genesisBytes))
 
 	vm.mempool = mempool.New(vm.genesis, vm.config.MempoolSize)
 
@@ -205,13 +253,32 @@ func (vm *VM) Initialize(
 		vm.preferred, vm.lastAccepted = gBlkID, genesisBlk
 		log.Info("initialized samavm from genesis", "block", gBlkID)
 	}
-	registerer := prometheus.NewRegistry()
-
-	state, err := chain.SamaNew(vm.db, registerer, vm.genesis)
+	if err := chain.BuildStateIndexes(vm.db); err != nil {
+		log.Error("could not build state indexes", "err", err)
+		return err
+	}
+	if err := chain.BuildHeightIndex(vm.db); err != nil {
+		log.Error("could not build height index", "err", err)
+		return err
+	}
+	if err := chain.BuildActivityIndex(vm.db, vm.genesis); err != nil {
+		log.Error("could not build activity index", "err", err)
+		return err
+	}
+	if err := chain.EnsureStateTree(vm.db); err != nil {
+		log.Error("could not build state tree", "err", err)
+		return err
+	}
+	if vm.config.Archive {
+		err = chain.EnableArchive(vm.db, vm.lastAccepted.Hght)
+	} else {
+		err = chain.DisableArchive(vm.db)
+	}
 	if err != nil {
+		log.Error("could not update state archive", "err", err)
 		return err
 	}
-	vm.samaState = state
+	vm.samaState = chain.SamaNew(vm.db, vm.genesis)
 
 	vm.AirdropData = nil
 
@@ -222,6 +289,8 @@ func (vm *VM) Initialize(
 
 func (vm *VM) SetState(_ context.Context, state snow.State) error {
 	switch state {
+	case snow.StateSyncing:
+		return nil
 	case snow.Bootstrapping:
 		return vm.onBootstrapStarted()
 	case snow.NormalOp:
@@ -292,6 +361,15 @@ func (vm *VM) CreateHandlers(_ context.Context) (map[string]*common.HTTPHandler,
 		return nil, err
 	}
 	apis[PublicEndpoint] = public
+
+	if vm.events != nil {
+		// Polls wait for new events, so they must not hold the VM lock
+		subscribe, err := newHandler(Name, &SubscriptionService{vm: vm}, common.NoLock)
+		if err != nil {
+			return nil, err
+		}
+		apis[SubscribeEndpoint] = subscribe
+	}
 	return apis, nil
 }
 
@@ -302,20 +380,29 @@ func (vm *VM) CreateStaticHandlers(ctx context.Context) (map[string]*common.HTTP
 }
 
 // implements "snowmanblock.ChainVM.commom.VM.AppHandler"
+// serves state sync requests
 func (vm *VM) AppRequest(ctx context.Context, nodeID ids.NodeID, requestID uint32, deadline time.Time, request []byte) error {
-	// (currently) no app-specific messages
+	response, err := vm.handleSyncRequest(request)
+	if err != nil {
+		// only trace error to prevent VM's being shutdown
+		log.Debug("could not serve AppRequest", "peerID", nodeID, "requestID", requestID, "err", err)
+		return nil
+	}
+	if err := vm.appSender.SendAppResponse(ctx, nodeID, requestID, response); err != nil {
+		log.Debug("could not send AppResponse", "peerID", nodeID, "requestID", requestID, "err", err)
+	}
 	return nil
 }
 
 // implements "snowmanblock.ChainVM.commom.VM.AppHandler"
 func (vm *VM) AppRequestFailed(ctx context.Context, nodeID ids.NodeID, requestID uint32) error {
-	// (currently) no app-specific messages
+	vm.syncer.handleResponse(requestID, nil)
 	return nil
 }
 
 // implements "snowmanblock.ChainVM.commom.VM.AppHandler"
 func (vm *VM) AppResponse(ctx context.Context, nodeID ids.NodeID, requestID uint32, response []byte) error {
-	// (currently) no app-specific messages
+	vm.syncer.handleResponse(requestID, response)
 	return nil
 }
 
@@ -344,13 +431,15 @@ func (vm *VM) HealthCheck(ctx context.Context) (interface{}, error) {
 
 // implements "snowmanblock.ChainVM.commom.VM.validators.Connector"
 func (vm *VM) Connected(ctx context.Context, id ids.NodeID, nodeVersion *avagoversion.Application) error {
-	// no-op
+	if id != vm.ctx.NodeID {
+		vm.syncer.connected(id)
+	}
 	return nil
 }
 
 // implements "snowmanblock.ChainVM.commom.VM.validators.Connector"
 func (vm *VM) Disconnected(ctx context.Context, id ids.NodeID) error {
-	// no-op
+	vm.syncer.disconnected(id)
 	return nil
 }
 
@@ -389,6 +478,15 @@ func (vm *VM) GetStatelessBlock(blkID ids.ID) (*chain.StatelessBlock, error) {
 	return chain.ParseStatefulBlock(stBlk, nil, choices.Accepted, vm)
 }
 
+// implements "snowmanblock.HeightIndexedChainVM"
+// the index is built when the VM is initialized
+func (vm *VM) VerifyHeightIndex(context.Context) error { return nil }
+
+// implements "snowmanblock.HeightIndexedChainVM"
+func (vm *VM) GetBlockIDAtHeight(_ context.Context, height uint64) (ids.ID, error) {
+	return chain.GetBlockIDAtHeight(vm.db, height)
+}
+
 // implements "snowmanblock.ChainVM.commom.VM.Parser"
 // replaces "core.SnowmanVM.ParseBlock"
 func (vm *VM) ParseBlock(ctx context.Context, source []byte) (snowman.Block, error) {
@@ -454,7 +552,6 @@ func (vm *VM) Submit(_ context.Context, txs ...*chain.Transaction) (errs []error
 		}
 		vdb.Abort()
 	}
-	vm.SamaState().Abort()
 	return errs
 }
 
@@ -25,6 +25,7 @@ func TestBlockCache(t *testing.T) {
 	blkID := blk.ID()
 
 	vm := VM{
+		genesis:        chain.DefaultGenesis(),
 		blocks:         &cache.LRU[ids.ID, *chain.StatelessBlock]{Size: 3},
 		verifiedBlocks: make(map[ids.ID]*chain.StatelessBlock),
 	}
@@ -44,3 +45,35 @@ func TestBlockCache(t *testing.T) {
 		t.Fatalf("block expected %+v, got %+v", blk, blk2)
 	}
 }
+
+func TestExecutionContextFees(t *testing.T) {
+	upgrades, err := chain.ParseUpgradeConfig([]byte(`{"forks":{"phase1":100},"fees":{"phase1":{"minPrice":5,"targetBlockRate":1,"targetBlockSize":225}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vm := VM{
+		genesis:        chain.DefaultGenesis(),
+		upgrades:       upgrades,
+		blocks:         &cache.LRU[ids.ID, *chain.StatelessBlock]{Size: 3},
+		verifiedBlocks: make(map[ids.ID]*chain.StatelessBlock),
+	}
+	parent := &chain.StatelessBlock{StatefulBlock: &chain.StatefulBlock{Tmstmp: 98}}
+	vm.Accepted(parent)
+
+	// The price falls to the minimum of the rules of the block
+	for _, tt := range []struct {
+		currTime int64
+		price    uint64
+	}{
+		{99, vm.genesis.MinPrice},
+		{100, 5},
+	} {
+		ctx, err := vm.ExecutionContext(tt.currTime, parent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ctx.NextPrice != tt.price {
+			t.Fatalf("block at %d: expected price %d, got %d", tt.currTime, tt.price, ctx.NextPrice)
+		}
+	}
+}